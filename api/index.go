@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"os"
+	"sync"
 
 	"github.com/arnavshah/scheduler-api-go/pkg/auth"
 	"github.com/arnavshah/scheduler-api-go/pkg/database"
@@ -10,20 +12,40 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var r *gin.Engine
+var (
+	r      *gin.Engine
+	served http.Handler
+	h      = &handlers.Handler{}
+	dbOnce sync.Once
+)
+
+// initDB connects to the database on the first request that needs it, so
+// cold starts serving static files or the root route don't pay for it.
+// SKIP_ADMIN_SETUP skips creating the default admin on every cold start.
+func initDB() {
+	db := database.InitDB()
+	if os.Getenv("SKIP_ADMIN_SETUP") == "" {
+		_ = auth.EnsureAdminExists(db)
+	}
+	h.DB = db
+	h.Replica = database.InitReplica()
+}
+
+// lazyDB makes sure the database is ready before a route runs
+func lazyDB(c *gin.Context) {
+	dbOnce.Do(initDB)
+	c.Next()
+}
 
 func init() {
 	// Load .env if it exists (for local testing with vercel dev)
 	_ = godotenv.Load(".env")
 	_ = godotenv.Load("../.env")
 
-	// Initialize DB
-	db := database.InitDB()
-	_ = auth.EnsureAdminExists(db)
-	h := &handlers.Handler{DB: db}
-
 	// Initialize Gin
 	gin.SetMode(gin.ReleaseMode)
+	handlers.ConfigureRedaction()
+	handlers.AutotuneServerless()
 	r = gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
 
@@ -38,17 +60,46 @@ func init() {
 		})
 	})
 
+	// Everything below uses the database
+	r.Use(lazyDB, h.TrackWrites())
+
+	r.GET("/warmup", h.Warmup)
 	r.GET("/admin", h.AdminInterface)
 	r.POST("/admin/login", h.Login)
+	r.GET("/admin/assets/:name", h.GetAdminAsset)
 
 	admin := r.Group("/admin")
 	admin.Use(h.AuthMiddleware())
 	{
 		admin.POST("/keys", h.GenerateKey)
+		admin.POST("/keys/bulk", h.BulkKeys)
 		admin.GET("/keys", h.ListKeys)
 		admin.PUT("/keys/:id", h.UpdateKeyLimit)
+		admin.GET("/keys/:id/impact", h.KeyLimitImpact)
+		admin.GET("/keys/:id/storage", h.GetStoragePolicy)
+		admin.PUT("/keys/:id/storage", h.UpdateStoragePolicy)
 		admin.DELETE("/keys/:id", h.RevokeKey)
+		admin.POST("/keys/:id/impersonate", h.ImpersonateKey)
+		admin.PUT("/keys/:id/partner", h.SetPartner)
+		admin.GET("/keys/:id/credits", h.ListBurstCredits)
+		admin.POST("/keys/:id/credits", h.GrantBurstCredit)
+		admin.DELETE("/keys/:id/credits/:creditId", h.RevokeBurstCredit)
 		admin.GET("/usage/:id", h.GetUsage)
+		admin.POST("/captures/:id/replay", h.ReplayCapture)
+		admin.POST("/tokens", h.CreateServiceToken)
+		admin.GET("/tokens", h.ListServiceTokens)
+		admin.DELETE("/tokens/:id", h.RevokeServiceToken)
+		admin.POST("/reaper/run", h.RunReaperNow)
+		admin.GET("/tasks", h.ListTasks)
+		admin.POST("/tasks", h.TriggerTask)
+		admin.POST("/seed", h.SeedData)
+		admin.GET("/profiles", h.ListProfiles)
+		admin.PUT("/profiles/:name", h.PutProfile)
+		admin.DELETE("/profiles/:name", h.DeleteProfile)
+		admin.GET("/canary", h.CanaryReport)
+		admin.GET("/selftest", h.SelfTest)
+		admin.PUT("/assets/:name", h.PutAdminAsset)
+		admin.DELETE("/assets/:name", h.DeleteAdminAsset)
 	}
 
 	api := r.Group("/api")
@@ -56,14 +107,56 @@ func init() {
 	{
 		api.POST("/schedule", h.ScheduleJSON)
 		api.POST("/schedule/csv", h.ScheduleCSV)
+		api.POST("/schedule/repair", h.RepairSchedule)
+		api.POST("/explain", h.Explain)
+		api.POST("/forecast", h.Forecast)
+		api.POST("/swaps", h.SuggestSwaps)
+		api.GET("/schema", h.GetSchema)
+		api.PUT("/webhook-secret", h.PutWebhookSecret)
+		api.POST("/schedules/import", h.ImportScheduleBundle)
+		api.GET("/schedules", h.ListSchedules)
+		api.GET("/schedules/:id", h.GetSchedule)
+		api.DELETE("/schedules/:id", h.DeleteSchedule)
+		api.PATCH("/schedules/:id", h.EditSchedule)
+		api.GET("/schedules/:id/history", h.GetScheduleHistory)
+		api.GET("/schedules/:id/diff", h.DiffSchedule)
+		api.POST("/schedules/:id/regenerate", h.RegenerateSchedule)
+		api.GET("/schedules/:id/double-bookings", h.GetDoubleBookings)
+		api.GET("/schedules/:id/compliance", h.GetScheduleCompliance)
+		api.POST("/schedules/:id/undo", h.UndoSchedule)
+		api.POST("/schedules/:id/redo", h.RedoSchedule)
+		api.GET("/schedules/:id/bundle", h.GetScheduleBundle)
+		api.GET("/volunteers/:id/data", h.ExportVolunteerData)
+		api.DELETE("/volunteers/:id/data", h.EraseVolunteerData)
+		api.POST("/problems", h.CreateProblem)
+		api.POST("/problems/:id/volunteers", h.AddProblemVolunteers)
+		api.POST("/problems/:id/shifts", h.AddProblemShifts)
+		api.POST("/problems/:id/assignments", h.AddProblemAssignments)
+		api.POST("/problems/:id/solve", h.SolveProblem)
+		api.GET("/problems/:id", h.GetProblem)
+		api.DELETE("/problems/:id", h.DeleteProblem)
+		api.DELETE("/problems/:id/volunteers/:itemId", h.RemoveProblemVolunteer)
+		api.DELETE("/problems/:id/shifts/:itemId", h.RemoveProblemShift)
+		api.DELETE("/problems/:id/assignments", h.RemoveProblemAssignment)
+	}
+
+	// Partner Endpoints
+	partner := r.Group("/partner")
+	partner.Use(h.APIKeyMiddleware(), h.PartnerMiddleware())
+	{
+		partner.POST("/keys", h.CreateSubKey)
+		partner.GET("/keys", h.ListSubKeys)
+		partner.DELETE("/keys/:id", h.RevokeSubKey)
 	}
 
 	// Python Parity Routes
 	r.POST("/schedule/json", h.APIKeyMiddleware(), h.ScheduleJSON)
 	r.POST("/schedule/csv", h.APIKeyMiddleware(), h.ScheduleCSV)
+
+	served = handlers.WithBasePath(r)
 }
 
 // Handler is the entry point for Vercel Go Runtime
 func Handler(w http.ResponseWriter, r_req *http.Request) {
-	r.ServeHTTP(w, r_req)
+	served.ServeHTTP(w, r_req)
 }
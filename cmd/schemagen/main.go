@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+)
+
+// schemagen writes the JSON Schema document served by GET /api/schema. It is
+// run by go generate in pkg/handlers. Usage: schemagen [-o schema.json]
+func main() {
+	out := flag.String("o", "", "file to write; standard output when empty")
+	flag.Parse()
+
+	data, err := json.MarshalIndent(handlers.SchemaDocument(), "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
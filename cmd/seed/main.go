@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+	"github.com/joho/godotenv"
+)
+
+// seed applies a seed file to the database configured in the environment,
+// the same way the server would find it. Usage: go run ./cmd/seed seed.yaml
+func main() {
+	for _, p := range []string{".env", "../.env", "../../.env"} {
+		if _, err := os.Stat(p); err == nil {
+			_ = godotenv.Load(p)
+			break
+		}
+	}
+
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: seed <file.yaml>")
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	db := database.InitDB()
+	summary, err := handlers.Seed(db, data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	for _, name := range summary.Admins {
+		fmt.Printf("admin    %s\n", name)
+	}
+	for _, k := range summary.Keys {
+		state := "updated"
+		if k.Created {
+			state = "created"
+		}
+		fmt.Printf("key      %s (%s): %s\n", k.Name, state, k.Key)
+	}
+	for _, name := range summary.Profiles {
+		fmt.Printf("profile  %s\n", name)
+	}
+	for _, id := range summary.Problems {
+		fmt.Printf("problem  %s\n", id)
+	}
+}
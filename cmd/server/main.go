@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/arnavshah/scheduler-api-go/pkg/auth"
 	"github.com/arnavshah/scheduler-api-go/pkg/database"
@@ -26,12 +32,27 @@ func main() {
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	handlers.ConfigureRedaction()
+	handlers.Autotune()
 
 	db := database.InitDB()
-	_ = auth.EnsureAdminExists(db)
-	h := &handlers.Handler{DB: db}
+	if os.Getenv("SKIP_ADMIN_SETUP") == "" {
+		_ = auth.EnsureAdminExists(db)
+	}
+	h := &handlers.Handler{DB: db, Replica: database.InitReplica()}
+
+	if n := h.FailInterruptedJobs(time.Now()); n > 0 {
+		log.Printf("jobs: %d jobs were interrupted by a restart", n)
+	}
+
+	// Delete saved schedules past their retention period
+	go h.RunReaper(time.Hour)
+	if handlers.DegradedMode() {
+		go h.RunUsageFlusher(time.Minute)
+	}
 
 	r := gin.Default()
+	r.Use(h.TrackWrites())
 
 	// Admin interface - serve static files from embedded FS
 	r.StaticFS("/static", h.GetStaticFS())
@@ -44,18 +65,44 @@ func main() {
 		})
 	})
 
+	r.GET("/warmup", h.Warmup)
 	r.GET("/admin", h.AdminInterface)
 	r.POST("/admin/login", h.Login)
+	r.GET("/admin/assets/:name", h.GetAdminAsset)
 
 	// Admin Endpoints
 	admin := r.Group("/admin")
 	admin.Use(h.AuthMiddleware())
 	{
 		admin.POST("/keys", h.GenerateKey)
+		admin.POST("/keys/bulk", h.BulkKeys)
 		admin.GET("/keys", h.ListKeys)
 		admin.PUT("/keys/:id", h.UpdateKeyLimit)
+		admin.GET("/keys/:id/impact", h.KeyLimitImpact)
+		admin.GET("/keys/:id/storage", h.GetStoragePolicy)
+		admin.PUT("/keys/:id/storage", h.UpdateStoragePolicy)
 		admin.DELETE("/keys/:id", h.RevokeKey)
+		admin.POST("/keys/:id/impersonate", h.ImpersonateKey)
+		admin.PUT("/keys/:id/partner", h.SetPartner)
+		admin.GET("/keys/:id/credits", h.ListBurstCredits)
+		admin.POST("/keys/:id/credits", h.GrantBurstCredit)
+		admin.DELETE("/keys/:id/credits/:creditId", h.RevokeBurstCredit)
 		admin.GET("/usage/:id", h.GetUsage)
+		admin.POST("/captures/:id/replay", h.ReplayCapture)
+		admin.POST("/tokens", h.CreateServiceToken)
+		admin.GET("/tokens", h.ListServiceTokens)
+		admin.DELETE("/tokens/:id", h.RevokeServiceToken)
+		admin.POST("/reaper/run", h.RunReaperNow)
+		admin.GET("/tasks", h.ListTasks)
+		admin.POST("/tasks", h.TriggerTask)
+		admin.POST("/seed", h.SeedData)
+		admin.GET("/profiles", h.ListProfiles)
+		admin.PUT("/profiles/:name", h.PutProfile)
+		admin.DELETE("/profiles/:name", h.DeleteProfile)
+		admin.GET("/canary", h.CanaryReport)
+		admin.GET("/selftest", h.SelfTest)
+		admin.PUT("/assets/:name", h.PutAdminAsset)
+		admin.DELETE("/assets/:name", h.DeleteAdminAsset)
 	}
 
 	// Scheduler Endpoints
@@ -64,8 +111,51 @@ func main() {
 	{
 		api.POST("/schedule", h.ScheduleJSON)
 		api.POST("/schedule/csv", h.ScheduleCSV)
+		api.POST("/schedule/async", h.ScheduleAsync)
+		api.GET("/jobs/:id", h.GetJob)
+		api.DELETE("/jobs/:id", h.CancelJob)
+		api.POST("/schedule/repair", h.RepairSchedule)
 		api.POST("/validate", h.ValidateInput)
+		api.POST("/explain", h.Explain)
+		api.POST("/forecast", h.Forecast)
+		api.POST("/swaps", h.SuggestSwaps)
 		api.GET("/usage", h.GetMyUsage)
+		api.PUT("/webhook-secret", h.PutWebhookSecret)
+		api.GET("/schema", h.GetSchema)
+		api.POST("/schedules/import", h.ImportScheduleBundle)
+		api.GET("/schedules", h.ListSchedules)
+		api.GET("/schedules/:id", h.GetSchedule)
+		api.DELETE("/schedules/:id", h.DeleteSchedule)
+		api.PATCH("/schedules/:id", h.EditSchedule)
+		api.GET("/schedules/:id/history", h.GetScheduleHistory)
+		api.GET("/schedules/:id/diff", h.DiffSchedule)
+		api.POST("/schedules/:id/regenerate", h.RegenerateSchedule)
+		api.GET("/schedules/:id/double-bookings", h.GetDoubleBookings)
+		api.GET("/schedules/:id/compliance", h.GetScheduleCompliance)
+		api.POST("/schedules/:id/undo", h.UndoSchedule)
+		api.POST("/schedules/:id/redo", h.RedoSchedule)
+		api.GET("/schedules/:id/bundle", h.GetScheduleBundle)
+		api.GET("/volunteers/:id/data", h.ExportVolunteerData)
+		api.DELETE("/volunteers/:id/data", h.EraseVolunteerData)
+		api.POST("/problems", h.CreateProblem)
+		api.POST("/problems/:id/volunteers", h.AddProblemVolunteers)
+		api.POST("/problems/:id/shifts", h.AddProblemShifts)
+		api.POST("/problems/:id/assignments", h.AddProblemAssignments)
+		api.POST("/problems/:id/solve", h.SolveProblem)
+		api.GET("/problems/:id", h.GetProblem)
+		api.DELETE("/problems/:id", h.DeleteProblem)
+		api.DELETE("/problems/:id/volunteers/:itemId", h.RemoveProblemVolunteer)
+		api.DELETE("/problems/:id/shifts/:itemId", h.RemoveProblemShift)
+		api.DELETE("/problems/:id/assignments", h.RemoveProblemAssignment)
+	}
+
+	// Partner Endpoints
+	partner := r.Group("/partner")
+	partner.Use(h.APIKeyMiddleware(), h.PartnerMiddleware())
+	{
+		partner.POST("/keys", h.CreateSubKey)
+		partner.GET("/keys", h.ListSubKeys)
+		partner.DELETE("/keys/:id", h.RevokeSubKey)
 	}
 
 	// Python Parity Routes
@@ -77,8 +167,29 @@ func main() {
 		port = "8000"
 	}
 
+	// Requests inherit ctx, so a shutdown signal also cancels scheduling runs
+	// still in progress instead of waiting out their timeouts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{
+		Addr:        ":" + port,
+		Handler:     handlers.WithBasePath(r),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+
 	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("could not run server: %v", err)
 	}
+	<-drained
 }
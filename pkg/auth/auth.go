@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -18,9 +19,14 @@ import (
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
 var jwtAlgorithm = jwt.SigningMethodHS256
 
+// ImpersonationTTL is how long an impersonation token stays valid
+const ImpersonationTTL = 15 * time.Minute
+
 // Claims represents the JWT claims
 type Claims struct {
 	Username string `json:"username"`
+	// ImpersonatedKeyID is set when an admin is acting as an API key
+	ImpersonatedKeyID uint `json:"impersonated_key_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -50,6 +56,23 @@ func CreateToken(username string) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
+// CreateImpersonationToken creates a short-lived JWT that lets an admin call
+// the API as the given key
+func CreateImpersonationToken(username string, keyID uint) (string, time.Time, error) {
+	expirationTime := time.Now().Add(ImpersonationTTL)
+	claims := &Claims{
+		Username:          username,
+		ImpersonatedKeyID: keyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwtAlgorithm, claims)
+	signed, err := token.SignedString(jwtSecret)
+	return signed, expirationTime, err
+}
+
 // VerifyToken verifies a JWT token
 func VerifyToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
@@ -147,3 +170,21 @@ func VerifyHMACKey(key string) (string, error) {
 
 	return userID, nil
 }
+
+// ServiceTokenPrefix marks admin service tokens, distinguishing them from JWTs
+const ServiceTokenPrefix = "sst_"
+
+// GenerateServiceToken creates a random admin service token
+func GenerateServiceToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return ServiceTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// HashServiceToken returns the stored form of a service token
+func HashServiceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,8 +1,13 @@
 package database
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -13,12 +18,35 @@ import (
 // APIKey represents the api_keys table
 type APIKey struct {
 	ID         uint       `gorm:"primaryKey" json:"id"`
+	ExternalID string     `gorm:"index" json:"external_id"` // stable ID for infrastructure-as-code
 	Key        string     `gorm:"unique;not null" json:"key"`
 	Name       string     `gorm:"not null" json:"name"`
 	KeyPreview string     `json:"key_preview"`
 	RateLimit  int        `gorm:"default:10000" json:"rate_limit"`
 	CreatedAt  time.Time  `json:"created_at"`
 	LastUsed   *time.Time `json:"last_used"`
+	// PartnerID is the partner key that minted this key, if any
+	PartnerID *uint `gorm:"index" json:"partner_id,omitempty"`
+	// MaxSubKeys approves a key as a partner; zero means it can't mint keys
+	MaxSubKeys int `gorm:"default:0" json:"max_sub_keys"`
+	// RevokedAt marks a revoked key. The row is kept so the key, whose
+	// signature still verifies, isn't provisioned again on its next use.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// BeforeCreate assigns an external ID to new keys
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ExternalID == "" {
+		k.ExternalID = newExternalID()
+	}
+	return nil
+}
+
+// newExternalID returns a random, opaque key identifier
+func newExternalID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "key_" + hex.EncodeToString(b)
 }
 
 // APIUsage represents the api_usage table
@@ -29,6 +57,8 @@ type APIUsage struct {
 	RequestCount    int    `gorm:"default:0" json:"request_count"`
 	TotalShifts     int    `gorm:"default:0" json:"total_shifts"`
 	TotalVolunteers int    `gorm:"default:0" json:"total_volunteers"`
+	// ImpersonatedCount is how many of RequestCount were made by an admin impersonating the key
+	ImpersonatedCount int `gorm:"default:0" json:"impersonated_count"`
 }
 
 // MasterUser represents the master_users table
@@ -39,33 +69,395 @@ type MasterUser struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
-// InitDB initializes the database connection and migrates the schema
+// DebugCapture represents the debug_captures table. It stores a scheduling
+// request and its response so admins can replay it later.
+type DebugCapture struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	KeyID     uint      `gorm:"index" json:"key_id"`
+	Endpoint  string    `json:"endpoint"`
+	Request   string    `gorm:"type:text" json:"request"`
+	Response  string    `gorm:"type:text" json:"response"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DraftProblem represents the draft_problems table. A draft collects a
+// scheduling problem over several requests before it is solved.
+type DraftProblem struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	KeyID     uint      `gorm:"index;not null" json:"key_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DraftItem represents the draft_items table. Each row is one volunteer,
+// shift or assignment of a draft problem, stored as JSON.
+type DraftItem struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProblemID string `gorm:"uniqueIndex:idx_draft_item;not null" json:"problem_id"`
+	Kind      string `gorm:"uniqueIndex:idx_draft_item;not null" json:"kind"`
+	ItemID    string `gorm:"uniqueIndex:idx_draft_item;not null" json:"item_id"`
+	Data      string `gorm:"type:text" json:"data"`
+}
+
+// Schedule represents the schedules table. It stores a solved scheduling
+// request and its result as JSON so they can be retrieved later.
+type Schedule struct {
+	ID     string `gorm:"primaryKey" json:"id"`
+	KeyID  uint   `gorm:"index;not null" json:"key_id"`
+	Input  string `gorm:"type:text" json:"input"`
+	Result string `gorm:"type:text" json:"result"`
+	// Version increments on every edit, for optimistic concurrency
+	Version   int       `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// WarnedAt is set once the key's webhook has been told this schedule is about to expire
+	WarnedAt *time.Time `json:"warned_at,omitempty"`
+}
+
+// Assignment represents the assignments table. It holds each saved
+// schedule's current assignments, one per row, so schedules can be found by
+// volunteer or shift without decoding every result.
+type Assignment struct {
+	ID          uint   `gorm:"primaryKey" json:"-"`
+	ScheduleID  string `gorm:"index;not null" json:"schedule_id"`
+	ShiftID     string `gorm:"index;not null" json:"shift_id"`
+	VolunteerID string `gorm:"index;not null" json:"volunteer_id"`
+	Group       string `gorm:"column:group_name" json:"group,omitempty"`
+	Role        string `json:"role,omitempty"`
+}
+
+// ScheduleEvent represents the schedule_events table. Each change to a saved
+// schedule is appended as an event holding the result it produced, so the
+// history can be listed and earlier versions restored.
+type ScheduleEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ScheduleID string    `gorm:"index;not null" json:"schedule_id"`
+	Version    int       `gorm:"not null" json:"version"`
+	Kind       string    `json:"kind"`
+	Actor      string    `json:"actor"`
+	Detail     string    `gorm:"type:text" json:"detail,omitempty"`
+	Result     string    `gorm:"type:text" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// StoragePolicy represents the storage_policies table. It overrides the
+// default retention and quota for saved schedules of one key; zero values
+// fall back to the defaults.
+type StoragePolicy struct {
+	KeyID          uint      `gorm:"primaryKey" json:"key_id"`
+	RetentionDays  int       `json:"retention_days"`
+	MaxSchedules   int       `json:"max_schedules"`
+	MaxBytes       int64     `json:"max_bytes"`
+	WarningWebhook string    `json:"warning_webhook,omitempty"`
+	WarnDays       int       `json:"warn_days"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WebhookSecret represents the webhook_secrets table. It holds the secret
+// outbound webhooks for one key are signed with, encrypted at rest when PII
+// encryption is configured.
+type WebhookSecret struct {
+	KeyID     uint      `gorm:"primaryKey" json:"key_id"`
+	Secret    string    `gorm:"type:text;not null" json:"-"`
+	RotatedAt time.Time `gorm:"autoUpdateTime" json:"rotated_at"`
+}
+
+// AdminAsset represents the admin_assets table. It holds a custom logo or
+// footer shown in the admin UI of white-label deployments.
+type AdminAsset struct {
+	Name        string    `gorm:"primaryKey" json:"name"`
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"-"`
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BurstCredit represents the burst_credits table. A credit raises a key's
+// rate limit by Requests until it expires, on top of the base limit.
+type BurstCredit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	KeyID     uint      `gorm:"index;not null" json:"key_id"`
+	Requests  int       `gorm:"not null" json:"requests"`
+	Reason    string    `json:"reason,omitempty"`
+	GrantedBy string    `json:"granted_by"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	Expired   bool      `gorm:"default:false" json:"expired"` // set once the expiry has been audited
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditLog represents the audit_logs table
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	KeyID     uint      `gorm:"index" json:"key_id,omitempty"`
+	Actor     string    `json:"actor"`
+	Action    string    `gorm:"index" json:"action"`
+	Subject   string    `json:"subject"`
+	Detail    string    `gorm:"type:text" json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ServiceToken represents the service_tokens table. Service tokens let
+// automation call admin routes allowed by their role; only a hash is stored.
+type ServiceToken struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"not null" json:"name"`
+	TokenHash    string     `gorm:"unique;not null" json:"-"`
+	TokenPreview string     `json:"token_preview"`
+	Role         string     `gorm:"not null" json:"role"`
+	CreatedBy    string     `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsed     *time.Time `json:"last_used"`
+}
+
+// SolverProfile is a named set of solver settings that schedule requests can
+// reference instead of sending their own
+type SolverProfile struct {
+	Name      string    `gorm:"primaryKey" json:"name"`
+	Settings  string    `gorm:"type:text;not null" json:"-"` // JSON models.SolverSettings
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CanaryRun represents the canary_runs table. It records how a candidate
+// strategy compared with the one that answered a sampled request.
+type CanaryRun struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	KeyID           uint      `gorm:"index" json:"key_id"`
+	Algorithm       string    `json:"algorithm"`
+	Candidate       string    `gorm:"index" json:"candidate"`
+	FillDelta       float64   `json:"fill_delta"`
+	FairnessDelta   float64   `json:"fairness_delta"`
+	DurationDeltaMs int64     `json:"duration_delta_ms"`
+	Error           string    `json:"error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Job represents the jobs table. It tracks a scheduling request solved in
+// the background: its sealed input, its status and, once it succeeds, the
+// response the client would have got.
+type Job struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	KeyID      uint       `gorm:"index;not null" json:"key_id"`
+	Status     string     `gorm:"index;not null" json:"status"`
+	Input      string     `gorm:"type:text" json:"-"`
+	Result     string     `gorm:"type:text" json:"-"`
+	Error      string     `json:"error,omitempty"`
+	ScheduleID string     `json:"schedule_id,omitempty"` // set when the input asked to save
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `gorm:"index" json:"finished_at,omitempty"`
+	// WebhookURL is told when the job finishes; WebhookStatus is delivered or
+	// failed once it has been
+	WebhookURL      string `json:"webhook_url,omitempty"`
+	WebhookStatus   string `json:"webhook_status,omitempty"`
+	WebhookAttempts int    `json:"webhook_attempts,omitempty"`
+	// Instance is the process running the job, which renews LeaseUntil while
+	// it does. A job whose lease lapses was left behind by a dead process.
+	Instance   string     `json:"-"`
+	LeaseUntil *time.Time `gorm:"index" json:"-"`
+}
+
+// TaskRun represents the task_runs table. Each row is one run of a
+// background housekeeping task, scheduled or triggered by an admin.
+type TaskRun struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Task       string    `gorm:"index;not null" json:"task"`
+	Trigger    string    `json:"trigger"`
+	Actor      string    `json:"actor,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	Summary    string    `gorm:"type:text" json:"-"` // JSON
+}
+
+// CallerWrite represents the caller_writes table. It records when each
+// caller last wrote, so every instance can pin that caller's reads to the
+// primary until the replica has caught up.
+type CallerWrite struct {
+	Caller    string    `gorm:"primaryKey" json:"caller"`
+	WrittenAt time.Time `json:"written_at"`
+}
+
+// SchemaVersion represents the schema_versions table. Its single row holds
+// the fingerprint of the models the schema was last migrated for, so cold
+// starts can skip AutoMigrate when nothing changed.
+type SchemaVersion struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Fingerprint string    `json:"fingerprint"`
+	MigratedAt  time.Time `json:"migrated_at"`
+}
+
+// allModels lists every table AutoMigrate manages
+var allModels = []any{&APIKey{}, &APIUsage{}, &MasterUser{}, &DebugCapture{}, &DraftProblem{}, &DraftItem{}, &Schedule{}, &AuditLog{}, &ServiceToken{}, &StoragePolicy{}, &ScheduleEvent{}, &SolverProfile{}, &CanaryRun{}, &WebhookSecret{}, &AdminAsset{}, &BurstCredit{}, &Job{}, &TaskRun{}, &Assignment{}, &CallerWrite{}}
+
+// MemoryURL is the DATABASE_URL that keeps everything in memory, for demos
+// and tests. Nothing survives a restart.
+const MemoryURL = "memory"
+
+// InMemory reports whether the database is in memory
+func InMemory() bool {
+	return os.Getenv("DATABASE_URL") == MemoryURL
+}
+
+// InitDB initializes the database connection and migrates the schema.
+// Setting SKIP_MIGRATIONS skips migrating, for deployments that migrate
+// separately and want the fastest possible cold start.
 func InitDB() *gorm.DB {
 	var db *gorm.DB
 	var err error
 
 	dsn := os.Getenv("DATABASE_URL")
+	if dsn == MemoryURL {
+		return initMemoryDB()
+	}
 	if dsn != "" {
+		// The connection is made by the first query rather than here
 		db, err = gorm.Open(postgres.New(postgres.Config{
 			DSN:                  dsn,
 			PreferSimpleProtocol: true,
 		}), &gorm.Config{
-			PrepareStmt: false,
+			PrepareStmt:          false,
+			TranslateError:       true,
+			DisableAutomaticPing: true,
 		})
 	} else {
 		dbPath := os.Getenv("DATA_PATH")
 		if dbPath == "" {
 			dbPath = "api_keys.db"
 		}
-		db, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+		db, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{TranslateError: true})
 	}
 
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	// Auto Migration
-	db.AutoMigrate(&APIKey{}, &APIUsage{}, &MasterUser{})
+	if os.Getenv("SKIP_MIGRATIONS") == "" {
+		if err := Migrate(db); err != nil {
+			log.Printf("failed to migrate database: %v", err)
+		}
+	}
+	return db
+}
+
+// initMemoryDB opens the in-memory database for DATABASE_URL=memory
+func initMemoryDB() *gorm.DB {
+	db, err := OpenMemory()
+	if err != nil {
+		log.Fatalf("failed to open in-memory database: %v", err)
+	}
+	log.Print("using an in-memory database; nothing will be persisted")
+	return db
+}
+
+// OpenMemory opens a fresh in-memory database with the schema in place.
+// Each connection to :memory: is a separate database, so the pool is held to
+// one connection.
+func OpenMemory() (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// backfills fill new tables or columns from existing data. They run with
+// the migration, so only when the models change; see AddBackfill.
+var backfills []func(db *gorm.DB) error
+
+// AddBackfill registers a data migration to run after the schema is next
+// migrated. It should be safe to run again, since a failed migration is
+// retried in full.
+func AddBackfill(fn func(db *gorm.DB) error) {
+	backfills = append(backfills, fn)
+}
+
+// Migrate brings the schema up to date with the models. It is skipped when
+// the stored fingerprint shows the schema was already migrated for them,
+// which costs one query instead of inspecting every table.
+func Migrate(db *gorm.DB) error {
+	fingerprint := schemaFingerprint()
+	var version SchemaVersion
+	if db.Migrator().HasTable(&SchemaVersion{}) {
+		db.Limit(1).Find(&version, 1)
+		if version.Fingerprint == fingerprint {
+			return nil
+		}
+	}
+
+	if err := db.AutoMigrate(append([]any{&SchemaVersion{}}, allModels...)...); err != nil {
+		return err
+	}
+
+	// Backfill external IDs for keys created before they existed
+	var missing []APIKey
+	db.Where("external_id IS NULL OR external_id = ''").Find(&missing)
+	for _, k := range missing {
+		db.Model(&k).Update("external_id", newExternalID())
+	}
+	for _, backfill := range backfills {
+		if err := backfill(db); err != nil {
+			return err
+		}
+	}
+
+	return db.Save(&SchemaVersion{ID: 1, Fingerprint: fingerprint, MigratedAt: time.Now()}).Error
+}
+
+// ParseSchemas parses every model into gorm's schema cache, so the first
+// query on each table doesn't pay for reflection
+func ParseSchemas(db *gorm.DB) error {
+	for _, m := range allModels {
+		if err := db.Statement.Parse(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaFingerprint hashes every model's fields, types and tags, so any
+// change to a model changes it
+func schemaFingerprint() string {
+	h := sha256.New()
+	for _, m := range allModels {
+		t := reflect.TypeOf(m).Elem()
+		fmt.Fprintf(h, "%s{", t.Name())
+		for i := range t.NumField() {
+			f := t.Field(i)
+			fmt.Fprintf(h, "%s %s %q;", f.Name, f.Type, f.Tag)
+		}
+		h.Write([]byte("}"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
+// InitReplica opens the read replica named by DATABASE_URL_REPLICA, or returns
+// nil when none is configured. Replicas are only supported with Postgres.
+func InitReplica() *gorm.DB {
+	dsn := os.Getenv("DATABASE_URL_REPLICA")
+	if dsn == "" || os.Getenv("DATABASE_URL") == "" || InMemory() {
+		return nil
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{
+		DSN:                  dsn,
+		PreferSimpleProtocol: true,
+	}), &gorm.Config{
+		PrepareStmt: false,
+	})
+	if err != nil {
+		log.Printf("read replica unavailable, using primary: %v", err)
+		return nil
+	}
 	return db
 }
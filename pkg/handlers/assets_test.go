@@ -0,0 +1,64 @@
+package handlers_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+func uploadAsset(t *testing.T, srv *testutil.Server, name, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", name+".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/assets/"+name, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	srv.Engine.ServeHTTP(w, req)
+	return w
+}
+
+// TestPutAdminAsset_SanitizesFooter checks that a footer keeps its plain
+// markup but loses scripts, handlers and script links before it is stored
+func TestPutAdminAsset_SanitizesFooter(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Engine.PUT("/admin/assets/:name", srv.Handler.PutAdminAsset)
+	srv.Engine.GET("/admin/assets/:name", srv.Handler.GetAdminAsset)
+
+	cases := []struct{ upload, want string }{
+		{
+			`<p>Run by <a href="https://example.org" onclick="steal()">Example</a></p><script>steal()</script>`,
+			`<p>Run by <a href="https://example.org" rel="noopener noreferrer">Example</a></p>`,
+		},
+		{
+			`<div><img src=x onerror="steal()"><b>Bold</b> <a href="javascript:steal()">link</a></div>`,
+			`<b>Bold</b> <a>link</a>`,
+		},
+		{
+			"Questions? Mail 1 < 2 & <script>",
+			"Questions? Mail 1 &lt; 2 &amp; &lt;script&gt;",
+		},
+	}
+	for _, tc := range cases {
+		if w := uploadAsset(t, srv, "footer", tc.upload); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		w := srv.Do(t, http.MethodGet, "/admin/assets/footer", "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := w.Body.String(); got != tc.want {
+			t.Errorf("Footer %q\ngot:  %s\nwant: %s", tc.upload, got, tc.want)
+		}
+	}
+}
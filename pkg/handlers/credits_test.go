@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestBurstCredits grants, lists and revokes credits, checking only
+// unexpired ones raise the effective limit and that expiry is audited once
+func TestBurstCredits(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	admin := srv.Engine.Group("/admin", h.AuthMiddleware())
+	admin.GET("/keys/:id/credits", h.ListBurstCredits)
+	admin.POST("/keys/:id/credits", h.GrantBurstCredit)
+	admin.DELETE("/keys/:id/credits/:creditId", h.RevokeBurstCredit)
+	admin.POST("/tokens", h.CreateServiceToken)
+	srv.Engine.GET("/api/usage", h.APIKeyMiddleware(), h.GetMyUsage)
+	token := srv.AdminToken(t)
+	key := srv.APIKey(t, "event")
+	path := fmt.Sprintf("/admin/keys/%d/credits", key.ID)
+
+	for _, bad := range []map[string]any{
+		{"requests": 500},
+		{"requests": -1, "hours": 48},
+		{"requests": 500, "hours": 24*30 + 1},
+	} {
+		if w := srv.Do(t, http.MethodPost, path, token, bad); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected %v to be refused, got %d", bad, w.Code)
+		}
+	}
+	if w := srv.Do(t, http.MethodPost, "/admin/keys/999/credits", token, map[string]any{"requests": 500, "hours": 48}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a credit for a missing key to 404, got %d", w.Code)
+	}
+
+	w := srv.Do(t, http.MethodPost, path, token, map[string]any{"requests": 500, "hours": 48, "reason": "tournament"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var credit database.BurstCredit
+	decode(t, w.Body.String(), &credit)
+	// An already expired credit no longer counts, even before the reaper sees it
+	lapsed := database.BurstCredit{KeyID: key.ID, Requests: 300, ExpiresAt: time.Now().Add(-time.Hour)}
+	if err := srv.DB.Create(&lapsed).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var limits struct {
+		RateLimit          int `json:"rate_limit"`
+		EffectiveRateLimit int `json:"effective_rate_limit"`
+	}
+	decode(t, srv.Do(t, http.MethodGet, path, token, nil).Body.String(), &limits)
+	if limits.EffectiveRateLimit != limits.RateLimit+500 {
+		t.Errorf("Expected the effective limit to be %d, got %d", limits.RateLimit+500, limits.EffectiveRateLimit)
+	}
+	decode(t, srv.Do(t, http.MethodGet, "/api/usage", key.Key, nil).Body.String(), &limits)
+	if limits.EffectiveRateLimit != limits.RateLimit+500 {
+		t.Errorf("Expected usage to report an effective limit of %d, got %d", limits.RateLimit+500, limits.EffectiveRateLimit)
+	}
+
+	if n := h.ExpireBurstCredits(time.Now()); n != 1 {
+		t.Errorf("Expected one credit to expire, got %d", n)
+	}
+	if n := h.ExpireBurstCredits(time.Now()); n != 0 {
+		t.Errorf("Expected an expiry to be audited once, got %d more", n)
+	}
+	var audited int64
+	srv.DB.Model(&database.AuditLog{}).Where("action = ? AND subject = ?", "burst_credit.expire", key.Name).Count(&audited)
+	if audited != 1 {
+		t.Errorf("Expected one expiry audit entry, got %d", audited)
+	}
+
+	w = srv.Do(t, http.MethodPost, "/admin/tokens", token, map[string]string{"name": "dashboards", "role": "reader"})
+	var reader struct {
+		Token string `json:"token"`
+	}
+	decode(t, w.Body.String(), &reader)
+	if w := srv.Do(t, http.MethodGet, path, reader.Token, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected a reader token to list credits, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodPost, path, reader.Token, map[string]any{"requests": 500, "hours": 48}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a reader token not to grant credits, got %d", w.Code)
+	}
+
+	revoke := fmt.Sprintf("%s/%d", path, credit.ID)
+	if w := srv.Do(t, http.MethodDelete, fmt.Sprintf("/admin/keys/%d/credits/%d", key.ID+1, credit.ID), token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected revoking through another key to 404, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodDelete, revoke, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodDelete, revoke, token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a revoked credit to be gone, got %d", w.Code)
+	}
+	decode(t, srv.Do(t, http.MethodGet, path, token, nil).Body.String(), &limits)
+	if limits.EffectiveRateLimit != limits.RateLimit {
+		t.Errorf("Expected no credit left, got an effective limit of %d", limits.EffectiveRateLimit)
+	}
+}
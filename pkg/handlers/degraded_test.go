@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestDegradedMode solves with the database down, then brings it back and
+// checks the buffered usage is recorded against the key
+func TestDegradedMode(t *testing.T) {
+	buffer := filepath.Join(t.TempDir(), "usage.jsonl")
+	t.Setenv("USAGE_BUFFER_PATH", buffer)
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+
+	down, err := database.OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := down.DB()
+	sqlDB.Close()
+	h.DB = down
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	key := auth.GenerateHMACKey("degraded")
+
+	if w := srv.Do(t, http.MethodPost, "/api/schedule", key, input); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 outside degraded mode, got %d: %s", w.Code, w.Body.String())
+	}
+
+	t.Setenv("DEGRADED_MODE", "on")
+	w := srv.Do(t, http.MethodPost, "/api/schedule", key, input)
+	resp := testutil.DecodeSchedule(t, w)
+	if w.Header().Get("X-Degraded") != "true" {
+		t.Error("Expected an X-Degraded header")
+	}
+	if resp.ScheduleID != "" {
+		t.Errorf("Expected a degraded response not to be saved, got schedule %s", resp.ScheduleID)
+	}
+	if got := resp.AssignedShifts["shift_101"]; len(got) != 1 || got[0] != "vol_1" {
+		t.Errorf("Expected vol_1 on shift_101, got %v", got)
+	}
+
+	data, err := os.ReadFile(buffer)
+	if err != nil {
+		t.Fatalf("Expected usage buffered: %v", err)
+	}
+	if bytes.Contains(data, []byte(key)) {
+		t.Errorf("Expected the buffer not to hold the key, got %s", data)
+	}
+
+	h.DB = srv.DB
+	if n, err := h.FlushUsageBuffer(); n != 1 || err != nil {
+		t.Fatalf("Expected 1 buffered request flushed, got %d (%v)", n, err)
+	}
+	var usage database.APIUsage
+	err = srv.DB.Joins("JOIN api_keys ON api_keys.id = api_usages.key_id").
+		Where("api_keys.key = ?", key).First(&usage).Error
+	if err != nil {
+		t.Fatalf("Expected usage recorded for the key: %v", err)
+	}
+	if usage.RequestCount != 1 || usage.TotalShifts != 2 {
+		t.Errorf("Expected 1 request over 2 shifts, got %+v", usage)
+	}
+	if n, err := h.FlushUsageBuffer(); n != 0 || err != nil {
+		t.Errorf("Expected an empty buffer after flushing, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// editResult is the body of a successful schedule change
+type editResult struct {
+	Version    int                     `json:"version"`
+	Result     models.ScheduleResponse `json:"result"`
+	Violations []map[string]any        `json:"violations"`
+}
+
+// savedSchedule solves and saves the basic fixture for key, returning its ID
+func savedSchedule(t *testing.T, srv *testutil.Server, key string) string {
+	t.Helper()
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	return testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key, input)).ScheduleID
+}
+
+// patchSchedule sends an edit with ifMatch, when not empty, as its If-Match header
+func patchSchedule(t *testing.T, srv *testutil.Server, key, id, ifMatch string, edit any) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPatch, "/api/schedules/"+id, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	srv.Engine.ServeHTTP(w, req)
+	return w
+}
+
+// TestEditSchedule checks edits must name the version they were based on,
+// that a stale version is refused, and that only one of several concurrent
+// edits of the same version is applied
+func TestEditSchedule(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.GET("/api/schedules/:id", h.APIKeyMiddleware(), h.GetSchedule)
+	srv.Engine.PATCH("/api/schedules/:id", h.APIKeyMiddleware(), h.EditSchedule)
+	key := srv.APIKey(t, "edits").Key
+	id := savedSchedule(t, srv, key)
+
+	if w := srv.Do(t, http.MethodGet, "/api/schedules/"+id, key, nil); w.Header().Get("ETag") != `"1"` {
+		t.Fatalf(`Expected ETag "1", got %q`, w.Header().Get("ETag"))
+	}
+	remove := map[string]any{"remove": []models.Assignment{{ShiftID: "shift_102", VolunteerID: "vol_3"}}}
+	if w := patchSchedule(t, srv, key, id, "", remove); w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("Expected an edit without a version to get 428, got %d", w.Code)
+	}
+	if w := patchSchedule(t, srv, key, id, "yesterday", remove); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a malformed If-Match to get 400, got %d", w.Code)
+	}
+
+	w := patchSchedule(t, srv, key, id, `"1"`, remove)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var edited editResult
+	decode(t, w.Body.String(), &edited)
+	if edited.Version != 2 || w.Header().Get("ETag") != `"2"` || slices.Contains(edited.Result.AssignedShifts["shift_102"], "vol_3") {
+		t.Fatalf("Expected version 2 without vol_3 on shift_102, got %s", w.Body.String())
+	}
+	if w := patchSchedule(t, srv, key, id, `"1"`, remove); w.Code != http.StatusConflict || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("Expected a stale edit to get 409 with the current ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	missing := map[string]any{"version": 2, "remove": []models.Assignment{{ShiftID: "shift_101", VolunteerID: "vol_2"}}}
+	if w := patchSchedule(t, srv, key, id, "", missing); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected removing a missing assignment to get 422, got %d", w.Code)
+	}
+
+	// vol_1 is a lifeguard already working shift_101 at the same time
+	clash := map[string]any{"version": 2, "add": []models.Assignment{{ShiftID: "shift_102", VolunteerID: "vol_1"}}}
+	if w := patchSchedule(t, srv, key, id, "", clash); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected an edit breaking constraints to get 422, got %d: %s", w.Code, w.Body.String())
+	}
+	clash["force"] = true
+	w = patchSchedule(t, srv, key, id, "", clash)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected a forced edit to apply, got %d: %s", w.Code, w.Body.String())
+	}
+	decode(t, w.Body.String(), &edited)
+	if edited.Version != 3 || len(edited.Violations) == 0 || !slices.Contains(edited.Result.AssignedShifts["shift_102"], "vol_1") {
+		t.Fatalf("Expected version 3 with vol_1 on shift_102 and its violations, got %s", w.Body.String())
+	}
+
+	codes := make([]int, 5)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = patchSchedule(t, srv, key, id, `"3"`, map[string]any{"remove": []models.Assignment{{ShiftID: "shift_102", VolunteerID: "vol_1"}}}).Code
+		}()
+	}
+	wg.Wait()
+	slices.Sort(codes)
+	if want := []int{http.StatusOK, http.StatusConflict, http.StatusConflict, http.StatusConflict, http.StatusConflict}; !slices.Equal(codes, want) {
+		t.Errorf("Expected one concurrent edit to win and the rest to conflict, got %v", codes)
+	}
+}
+
+// TestUndoRedo steps back and forth through manual edits, checking restored
+// assignments are validated again and that a new edit clears the redo stack
+func TestUndoRedo(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.PATCH("/api/schedules/:id", h.APIKeyMiddleware(), h.EditSchedule)
+	srv.Engine.GET("/api/schedules/:id/history", h.APIKeyMiddleware(), h.GetScheduleHistory)
+	srv.Engine.POST("/api/schedules/:id/undo", h.APIKeyMiddleware(), h.UndoSchedule)
+	srv.Engine.POST("/api/schedules/:id/redo", h.APIKeyMiddleware(), h.RedoSchedule)
+	key := srv.APIKey(t, "undo").Key
+	id := savedSchedule(t, srv, key)
+
+	// step applies a change, expecting status, and returns the schedule
+	// after it when it succeeds
+	step := func(w *httptest.ResponseRecorder, status int) editResult {
+		t.Helper()
+		if w.Code != status {
+			t.Fatalf("Expected status %d, got %d: %s", status, w.Code, w.Body.String())
+		}
+		var r editResult
+		if status == http.StatusOK {
+			decode(t, w.Body.String(), &r)
+		}
+		return r
+	}
+	undo := func(version int) *httptest.ResponseRecorder {
+		return srv.Do(t, http.MethodPost, "/api/schedules/"+id+"/undo", key, map[string]any{"version": version})
+	}
+	redo := func(version int, force bool) *httptest.ResponseRecorder {
+		return srv.Do(t, http.MethodPost, "/api/schedules/"+id+"/redo", key, map[string]any{"version": version, "force": force})
+	}
+	on := func(r editResult, shiftID, volID string) bool {
+		return slices.Contains(r.Result.AssignedShifts[shiftID], volID)
+	}
+
+	// vol_1 already works shift_101, which overlaps shift_102
+	r := step(patchSchedule(t, srv, key, id, `"1"`, map[string]any{"force": true, "add": []models.Assignment{{ShiftID: "shift_102", VolunteerID: "vol_1"}}}), http.StatusOK)
+	if !on(r, "shift_102", "vol_1") {
+		t.Fatalf("Expected vol_1 on shift_102, got %v", r.Result.AssignedShifts)
+	}
+	step(patchSchedule(t, srv, key, id, `"2"`, map[string]any{"remove": []models.Assignment{{ShiftID: "shift_102", VolunteerID: "vol_2"}}}), http.StatusOK)
+
+	if r = step(undo(3), http.StatusOK); r.Version != 4 || !on(r, "shift_102", "vol_2") {
+		t.Fatalf("Expected undo to put vol_2 back on shift_102 as version 4, got %d %v", r.Version, r.Result.AssignedShifts)
+	}
+	if r = step(undo(4), http.StatusOK); on(r, "shift_102", "vol_1") {
+		t.Fatalf("Expected a second undo to take vol_1 off shift_102, got %v", r.Result.AssignedShifts)
+	}
+	step(undo(5), http.StatusConflict)
+
+	step(redo(5, false), http.StatusUnprocessableEntity)
+	if r = step(redo(5, true), http.StatusOK); !on(r, "shift_102", "vol_1") {
+		t.Fatalf("Expected a forced redo to put vol_1 back on shift_102, got %v", r.Result.AssignedShifts)
+	}
+	if r = step(redo(6, false), http.StatusOK); on(r, "shift_102", "vol_2") {
+		t.Fatalf("Expected a second redo to take vol_2 off shift_102, got %v", r.Result.AssignedShifts)
+	}
+	step(redo(7, false), http.StatusConflict)
+	step(undo(6), http.StatusConflict)
+
+	step(undo(7), http.StatusOK)
+	step(patchSchedule(t, srv, key, id, `"8"`, map[string]any{"remove": []models.Assignment{{ShiftID: "shift_102", VolunteerID: "vol_3"}}}), http.StatusOK)
+	step(redo(9, true), http.StatusConflict)
+
+	var history struct {
+		Events []struct {
+			Kind string `json:"kind"`
+		} `json:"events"`
+	}
+	decode(t, srv.Do(t, http.MethodGet, "/api/schedules/"+id+"/history", key, nil).Body.String(), &history)
+	var kinds []string
+	for _, e := range history.Events {
+		kinds = append(kinds, e.Kind)
+	}
+	if want := []string{"solve", "edit", "edit", "undo", "undo", "redo", "redo", "undo", "edit"}; !slices.Equal(kinds, want) {
+		t.Errorf("Expected history %v, got %v", want, kinds)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestEraseVolunteerData_Jobs checks async jobs are covered by a volunteer's
+// export and deleted by their erasure
+func TestEraseVolunteerData_Jobs(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule/async", h.APIKeyMiddleware(), h.ScheduleAsync)
+	srv.Engine.GET("/api/jobs/:id", h.APIKeyMiddleware(), h.GetJob)
+	srv.Engine.GET("/api/volunteers/:id/data", h.APIKeyMiddleware(), h.ExportVolunteerData)
+	srv.Engine.DELETE("/api/volunteers/:id/data", h.APIKeyMiddleware(), h.EraseVolunteerData)
+	key := srv.APIKey(t, "gdpr")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	var queued struct {
+		JobID string `json:"job_id"`
+	}
+	w := srv.Do(t, http.MethodPost, "/api/schedule/async", key.Key, input)
+	if err := json.Unmarshal(w.Body.Bytes(), &queued); err != nil || queued.JobID == "" {
+		t.Fatalf("Expected a job, got %d: %s", w.Code, w.Body.String())
+	}
+	var job struct {
+		Status string `json:"status"`
+	}
+	for deadline := time.Now().Add(10 * time.Second); job.Status != "succeeded"; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Job did not finish, last status %q", job.Status)
+		}
+		json.Unmarshal(srv.Do(t, http.MethodGet, "/api/jobs/"+queued.JobID, key.Key, nil).Body.Bytes(), &job)
+	}
+
+	var export struct {
+		Jobs []struct {
+			JobID          string   `json:"job_id"`
+			AssignedShifts []string `json:"assigned_shifts"`
+		} `json:"jobs"`
+	}
+	w = srv.Do(t, http.MethodGet, "/api/volunteers/vol_1/data", key.Key, nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if len(export.Jobs) != 1 || export.Jobs[0].JobID != queued.JobID || len(export.Jobs[0].AssignedShifts) != 1 {
+		t.Fatalf("Expected the job in the export with vol_1's shift, got %s", w.Body.String())
+	}
+
+	w = srv.Do(t, http.MethodDelete, "/api/volunteers/vol_1/data", key.Key, nil)
+	var erased struct {
+		Updated map[string]int `json:"updated"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &erased); err != nil || erased.Updated["jobs"] != 1 {
+		t.Fatalf("Expected one job erased, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/jobs/"+queued.JobID, key.Key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the erased job to be gone, got %d", w.Code)
+	}
+	w = srv.Do(t, http.MethodGet, "/api/volunteers/vol_1/data", key.Key, nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil || len(export.Jobs) != 0 {
+		t.Errorf("Expected no jobs in the export after erasure, got %s", w.Body.String())
+	}
+}
+
+// TestEraseVolunteerData_Unreadable checks erasure fails and erases nothing
+// while a stored row can't be decoded, and that once it succeeds the
+// volunteer's export audit entries no longer name them
+func TestEraseVolunteerData_Unreadable(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.GET("/api/volunteers/:id/data", h.APIKeyMiddleware(), h.ExportVolunteerData)
+	srv.Engine.DELETE("/api/volunteers/:id/data", h.APIKeyMiddleware(), h.EraseVolunteerData)
+	key := srv.APIKey(t, "gdpr")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	id := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input)).ScheduleID
+	srv.Do(t, http.MethodGet, "/api/volunteers/vol_1/data", key.Key, nil)
+	srv.DB.Create(&database.Schedule{ID: "corrupt", KeyID: key.ID, Input: "{", Result: "{}"})
+
+	w := srv.Do(t, http.MethodDelete, "/api/volunteers/vol_1/data", key.Key, nil)
+	var failed struct {
+		Unreadable map[string][]string `json:"unreadable"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &failed); err != nil || w.Code != http.StatusInternalServerError ||
+		len(failed.Unreadable["schedules"]) != 1 || failed.Unreadable["schedules"][0] != "corrupt" {
+		t.Fatalf("Expected the corrupt schedule reported, got %d: %s", w.Code, w.Body.String())
+	}
+	var saved database.Schedule
+	srv.DB.First(&saved, "id = ?", id)
+	if !strings.Contains(saved.Input, "vol_1") {
+		t.Errorf("Expected nothing erased after a failure, got %s", saved.Input)
+	}
+
+	srv.DB.Delete(&database.Schedule{}, "id = ?", "corrupt")
+	if w := srv.Do(t, http.MethodDelete, "/api/volunteers/vol_1/data", key.Key, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var named int64
+	srv.DB.Model(&database.AuditLog{}).Where("subject = ?", "vol_1").Count(&named)
+	if named != 0 {
+		t.Errorf("Expected no audit entries naming the erased volunteer, got %d", named)
+	}
+}
+
+// TestEraseVolunteerData_Pairings erases a volunteer other volunteers are
+// paired with, in a saved schedule and a draft: their lists must name the
+// alias, and the schedule must still solve
+func TestEraseVolunteerData_Pairings(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.POST("/api/schedules/:id/regenerate", h.APIKeyMiddleware(), h.RegenerateSchedule)
+	srv.Engine.POST("/api/problems", h.APIKeyMiddleware(), h.CreateProblem)
+	srv.Engine.POST("/api/problems/:id/volunteers", h.APIKeyMiddleware(), h.AddProblemVolunteers)
+	srv.Engine.DELETE("/api/volunteers/:id/data", h.APIKeyMiddleware(), h.EraseVolunteerData)
+	key := srv.APIKey(t, "gdpr")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	volunteers := input["volunteers"].([]any)
+	volunteers[1].(map[string]any)["cannot_work_with"] = []string{"vol_1"}
+	volunteers[2].(map[string]any)["must_work_with"] = []string{"vol_1", "vol_2"}
+	id := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input)).ScheduleID
+
+	var problem struct {
+		ProblemID string `json:"problem_id"`
+	}
+	json.Unmarshal(srv.Do(t, http.MethodPost, "/api/problems", key.Key, map[string]any{}).Body.Bytes(), &problem)
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+problem.ProblemID+"/volunteers", key.Key, volunteers); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := srv.Do(t, http.MethodDelete, "/api/volunteers/vol_1/data", key.Key, nil)
+	var erased struct {
+		Alias string `json:"alias"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &erased); err != nil || erased.Alias == "" {
+		t.Fatalf("Expected an alias, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var saved database.Schedule
+	srv.DB.First(&saved, "id = ?", id)
+	var items []database.DraftItem
+	srv.DB.Where("problem_id = ?", problem.ProblemID).Find(&items)
+	stored := []string{saved.Input}
+	for _, item := range items {
+		stored = append(stored, item.Data)
+	}
+	for _, data := range stored {
+		if strings.Contains(data, `"vol_1"`) || !strings.Contains(data, erased.Alias) {
+			t.Errorf("Expected vol_1 replaced by %s, got %s", erased.Alias, data)
+		}
+	}
+
+	var erasedInput map[string]any
+	json.Unmarshal([]byte(saved.Input), &erasedInput)
+	regenerate := map[string]any{"version": saved.Version, "input": erasedInput}
+	if w := srv.Do(t, http.MethodPost, "/api/schedules/"+id+"/regenerate", key.Key, regenerate); w.Code != http.StatusOK {
+		t.Errorf("Expected the erased schedule to solve again, got %d: %s", w.Code, w.Body.String())
+	}
+}
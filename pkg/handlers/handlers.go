@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"embed"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +31,12 @@ var staticEmbed embed.FS
 // Handler contains dependencies for the route handlers
 type Handler struct {
 	DB *gorm.DB
+	// Replica serves reads when set; see reader
+	Replica *gorm.DB
+
+	writes recentWrites
+	jobs   jobQueue
+	tasks  taskStates
 }
 
 // AuthMiddleware verifies the JWT token for admin routes
@@ -43,8 +54,14 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 			token = token[7:]
 		}
 
+		if strings.HasPrefix(token, auth.ServiceTokenPrefix) {
+			h.authenticateServiceToken(c, token)
+			return
+		}
+
 		claims, err := auth.VerifyToken(token)
-		if err != nil {
+		// Impersonation tokens only grant access to /api/*, never to admin routes
+		if err != nil || claims.ImpersonatedKeyID != 0 {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
@@ -69,7 +86,18 @@ func (h *Handler) APIKeyMiddleware() gin.HandlerFunc {
 			key = key[7:]
 		}
 
+		// Admin impersonation tokens are JWTs (three dot-separated parts)
+		if strings.Count(key, ".") == 2 {
+			h.impersonate(c, key)
+			return
+		}
+
 		userID, err := auth.VerifyHMACKey(key)
+		if err != nil && database.InMemory() {
+			// In-memory demo instances provision any key on first use
+			userID, _, _ = strings.Cut(key, ".")
+			err = nil
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key signature"})
 			c.Abort()
@@ -78,11 +106,26 @@ func (h *Handler) APIKeyMiddleware() gin.HandlerFunc {
 
 		// Fetch or create API key record to track usage
 		var apiKey database.APIKey
-		h.DB.Where(database.APIKey{Key: key}).FirstOrCreate(&apiKey, database.APIKey{
+		err = h.DB.Where(database.APIKey{Key: key}).Attrs(database.APIKey{
 			Key:       key,
 			Name:      userID,
 			RateLimit: 10000,
-		})
+		}).FirstOrCreate(&apiKey).Error
+		if err != nil {
+			if degradedKey(c, key, userID) {
+				c.Next()
+				return
+			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database unavailable"})
+			c.Abort()
+			return
+		}
+
+		if apiKey.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has been revoked"})
+			c.Abort()
+			return
+		}
 
 		c.Set("apiKey", &apiKey)
 		c.Set("userID", userID)
@@ -90,13 +133,153 @@ func (h *Handler) APIKeyMiddleware() gin.HandlerFunc {
 	}
 }
 
-// ScheduleJSON handles the JSON-based scheduling request
+// impersonate authenticates an API request using an admin impersonation token
+func (h *Handler) impersonate(c *gin.Context, token string) {
+	claims, err := auth.VerifyToken(token)
+	if err != nil || claims.ImpersonatedKeyID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid impersonation token"})
+		c.Abort()
+		return
+	}
+
+	var apiKey database.APIKey
+	if err := h.DB.First(&apiKey, claims.ImpersonatedKeyID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Impersonated key no longer exists"})
+		c.Abort()
+		return
+	}
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has been revoked"})
+		c.Abort()
+		return
+	}
+
+	log.Printf("audit: admin %q impersonating key %d on %s %s", claims.Username, apiKey.ID, c.Request.Method, c.Request.URL.Path)
+
+	c.Set("apiKey", &apiKey)
+	c.Set("userID", apiKey.Name)
+	c.Set("impersonatedBy", claims.Username)
+	c.Next()
+}
+
+// ScheduleJSON handles the JSON-based scheduling request. MessagePack bodies
+// (application/x-msgpack) are also accepted and answered in kind.
 func (h *Handler) ScheduleJSON(c *gin.Context) {
 	var input models.ScheduleInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindBody(c, &input); err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.applyProfile(c, &input); err != nil {
+		status, body := profileErrorStatus(err)
+		respond(c, status, body)
+		return
+	}
+
+	// Canary and capture copies must be taken before solving, since solving
+	// mutates the input
+	canary := canarySample(&input)
+	var capture *database.DebugCapture
+	if c.GetHeader("X-Debug-Capture") != "" {
+		capture = h.newCapture(c, &input)
+	}
+	var inputJSON []byte
+	// Degraded requests can't be saved; their responses have no schedule ID
+	save := input.Save && !c.GetBool("degraded")
+	if save {
+		var err error
+		if inputJSON, err = sealedInputJSON(&input); err != nil {
+			respond(c, http.StatusInternalServerError, gin.H{"error": "Could not encrypt schedule input"})
+			return
+		}
+	}
+
+	started := time.Now()
+	resp, err := buildSchedule(c.Request.Context(), &input)
+	if err != nil {
+		status, body := solveErrorStatus(err, http.StatusBadRequest)
+		respond(c, status, body)
+		return
+	}
+	if canary != nil {
+		var keyID uint
+		if apiKeyRaw, exists := c.Get("apiKey"); exists {
+			keyID = apiKeyRaw.(*database.APIKey).ID
+		}
+		go h.runCanary(keyID, canary, resp, time.Since(started))
+	}
+
+	if save {
+		if _, err := h.saveSchedule(c, eventSolve, inputJSON, &resp); err != nil {
+			status, body := saveErrorStatus(err)
+			respond(c, status, body)
+			return
+		}
+	}
+
+	// Record usage
+	h.RecordUsage(c, len(resp.AssignedShifts), len(resp.Volunteers))
+
+	if capture != nil {
+		h.saveCapture(c, capture, resp)
+	}
+
+	if input.StrictParity {
+		respond(c, http.StatusOK, parityResponse(resp))
 		return
 	}
+	respond(c, http.StatusOK, resp)
+}
+
+// buildSchedule runs the scheduler over an input and formats the response.
+// Cancelling ctx stops the run and returns ctx's error.
+func buildSchedule(ctx context.Context, input *models.ScheduleInput) (models.ScheduleResponse, error) {
+	trackNames(ctx, input.Volunteers)
+	s, err := prepareScheduler(ctx, input)
+	if err != nil {
+		return models.ScheduleResponse{}, err
+	}
+	if len(input.PreviousAssignments) > 0 {
+		s.KeepPrevious(input.PreviousAssignments)
+	}
+	if err := s.Run(ctx, input.Algorithm, input.TimeoutSeconds); err != nil {
+		if ctx.Err() != nil {
+			return models.ScheduleResponse{}, err
+		}
+		return models.ScheduleResponse{}, fmt.Errorf("%w: %q", err, input.Algorithm)
+	}
+
+	resp := formatResponse(s, input)
+	for i := 1; s.UseAlternative(i); i++ {
+		resp.Alternatives = append(resp.Alternatives, formatResponse(s, input))
+	}
+	return resp, nil
+}
+
+// solveErrorStatus maps a buildSchedule error to a response, using status
+// when the input itself was at fault
+func solveErrorStatus(err error, status int) (int, gin.H) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable, gin.H{"error": "Scheduling was cancelled"}
+	}
+	return status, gin.H{"error": err.Error()}
+}
+
+// prepareScheduler validates an input and sets up a scheduler for it, with
+// the input's current assignments prefilled
+func prepareScheduler(ctx context.Context, input *models.ScheduleInput) (*scheduler.Scheduler, error) {
+	if err := input.NormalizeTimezones(); err != nil {
+		return nil, err
+	}
+	if err := importBusyCalendars(ctx, input); err != nil {
+		return nil, err
+	}
+	if err := input.AssignedToPrefills(); err != nil {
+		return nil, err
+	}
+	if err := input.ExpandRoles(); err != nil {
+		return nil, err
+	}
 
 	volMap := make(map[string]*models.Volunteer)
 	for i := range input.Volunteers {
@@ -108,26 +291,100 @@ func (h *Handler) ScheduleJSON(c *gin.Context) {
 		shiftMap[input.UnassignedShifts[i].ID] = &input.UnassignedShifts[i]
 	}
 
+	if err := scheduler.ValidateFairnessMetric(input.FairnessMetric); err != nil {
+		return nil, fmt.Errorf("%w: %q", err, input.FairnessMetric)
+	}
+	if err := scheduler.ValidateObjective(input.Objective); err != nil {
+		return nil, fmt.Errorf("%w: %q", err, input.Objective)
+	}
+	if err := scheduler.ValidateFairnessDimensions(input.FairnessDimensions); err != nil {
+		return nil, err
+	}
+	if err := scheduler.ValidateSoftConstraints(input.SoftConstraints); err != nil {
+		return nil, err
+	}
+	if err := scheduler.ValidateMaxHoursRatio(input.MaxHoursRatio); err != nil {
+		return nil, err
+	}
+	if err := scheduler.ValidateScoreWeights(input.ScoreWeights.FillRate, input.ScoreWeights.Fairness); err != nil {
+		return nil, err
+	}
+	if err := scheduler.ValidateTravelMinutes(input.TravelMinutes); err != nil {
+		return nil, err
+	}
+	if err := scheduler.ValidateTemperature(input.Temperature); err != nil {
+		return nil, err
+	}
+	if err := scheduler.ValidateChunk(input.Chunk); err != nil {
+		return nil, fmt.Errorf("%w: %q", err, input.Chunk)
+	}
+	if input.Chunk != "" && input.Alternatives > 1 {
+		return nil, scheduler.ErrChunkedAlternatives
+	}
+	if input.Alternatives > scheduler.MaxAlternatives {
+		return nil, fmt.Errorf("alternatives may be at most %d", scheduler.MaxAlternatives)
+	}
+
 	s := scheduler.NewScheduler(volMap, shiftMap)
+	if err := s.ValidateLinks(); err != nil {
+		return nil, err
+	}
+	if err := s.ValidateCertifications(); err != nil {
+		return nil, err
+	}
+	if err := s.ValidateBusyTimes(); err != nil {
+		return nil, err
+	}
+	if err := s.ValidateTiers(); err != nil {
+		return nil, err
+	}
+	s.PreferenceWeight = input.PreferenceWeight
+	s.ConsecutiveWeight = input.ConsecutiveWeight
+	s.FairnessMetric = input.FairnessMetric
+	s.Objective = input.Objective
+	s.Chunk = input.Chunk
+	s.Temperature = input.Temperature
+	s.CategoryLimits = input.CategoryLimits
+	s.SoftConstraints = input.SoftConstraints
+	s.MaxHoursRatio = input.MaxHoursRatio
+	s.FillWeight = input.ScoreWeights.FillRate
+	s.FairnessWeight = input.ScoreWeights.Fairness
+	s.Alternatives = input.Alternatives
+	s.DetailedConflicts = input.DetailedConflicts
+	s.Hints = input.HintAssignments
+	s.TravelMinutes = input.TravelMinutes
+	if len(input.Resources) > 0 {
+		s.Resources = make(map[string]*models.Resource, len(input.Resources))
+		for i := range input.Resources {
+			s.Resources[input.Resources[i].ID] = &input.Resources[i]
+		}
+	}
+	if input.Seed != nil {
+		s.Seed(*input.Seed)
+	}
+	s.ApplyGroupHierarchy(input.GroupHierarchy)
 	s.Prefill(input.CurrentAssignments)
-	s.AssignSimple(true)
-
-	// Record usage
-	h.RecordUsage(c, len(shiftMap), len(volMap))
+	if input.StrictAssignments && s.DuplicatePrefills > 0 {
+		return nil, fmt.Errorf("current_assignments repeats %d assignments", s.DuplicatePrefills)
+	}
+	return s, nil
+}
 
+// formatResponse builds the API response from a scheduler's current state
+func formatResponse(s *scheduler.Scheduler, input *models.ScheduleInput) models.ScheduleResponse {
 	// Format response for parity with Python version
 	assignedShifts := make(map[string][]string)
 	unfilledShifts := make(map[string]bool)
-	for id, sh := range shiftMap {
+	for id, sh := range s.Shifts {
 		assignedShifts[id] = sh.Assigned
 
-		// Determine which shifts have unfilled slots
-		totalNeeded := 0
-		for _, count := range sh.RequiredGroups {
-			totalNeeded += count
-		}
-		if len(sh.Assigned) < totalNeeded {
-			unfilledShifts[id] = true
+		// Determine which shifts have unfilled slots. Counting per group keeps
+		// ideal extras in one group from masking a shortfall in another.
+		filled := s.FilledByGroup(sh)
+		for group, count := range sh.RequiredGroups {
+			if filled[group] < count {
+				unfilledShifts[id] = true
+			}
 		}
 	}
 
@@ -135,25 +392,56 @@ func (h *Handler) ScheduleJSON(c *gin.Context) {
 	for id := range unfilledShifts {
 		unfilledList = append(unfilledList, id)
 	}
+	sort.Strings(unfilledList)
 
 	volStats := make(map[string]any)
-	for id, v := range volMap {
+	for id, v := range s.Volunteers {
 		volStats[id] = gin.H{
 			"assigned_hours":  v.AssignedHours,
 			"assigned_shifts": v.AssignedShifts,
 		}
 	}
 
-	c.JSON(http.StatusOK, models.ScheduleResponse{
-		AssignedShifts: assignedShifts,
-		UnfilledShifts: unfilledList,
-		Conflicts:      s.Conflicts,
-		FairnessScore:  s.CalculateFairnessScore(),
-		Volunteers:     volStats,
-	})
+	fairnessMetric := input.FairnessMetric
+	if fairnessMetric == "" {
+		fairnessMetric = scheduler.FairnessStdDev
+	}
+
+	var churn *models.ChurnReport
+	if len(input.PreviousAssignments) > 0 {
+		report := s.Churn(input.PreviousAssignments)
+		churn = &report
+	}
+
+	softViolations, softPenalty := s.SoftViolations()
+	return models.ScheduleResponse{
+		AssignedShifts:   assignedShifts,
+		ShiftAssignments: s.ShiftAssignments(),
+		Roles:            s.RoleAssignments(),
+		UnfilledShifts:   unfilledList,
+		Conflicts:        s.Conflicts,
+		ConflictSummary:  s.ConflictSummary(),
+		FairnessScore:    s.FairnessScore(),
+		FairnessMetric:   fairnessMetric,
+		GroupFairness:    s.GroupFairness(),
+		Resources:        s.AssignedResources,
+		SoftViolations:   softViolations,
+		SoftPenalty:      softPenalty,
+		Volunteers:       volStats,
+		Compliance:       s.ComplianceReport(),
+
+		FairnessByDimension:    s.FairnessByDimension(input.FairnessDimensions),
+		DuplicateAssignments:   s.DuplicatePrefills,
+		StandbyAssignments:     s.StandbyAssignments(),
+		PreferenceSatisfaction: s.CalculatePreferenceSatisfaction(),
+		Churn:                  churn,
+		Shortfalls:             s.Shortfalls(),
+		BelowIdealShifts:       s.BelowIdeal(),
+	}
 }
 
-// RecordUsage records API usage in the database using an efficient upsert
+// RecordUsage records API usage in the database. In degraded mode, usage
+// that can't be recorded is buffered for FlushUsageBuffer instead.
 func (h *Handler) RecordUsage(c *gin.Context, shiftCount, volunteerCount int) {
 	apiKeyRaw, exists := c.Get("apiKey")
 	if !exists {
@@ -163,21 +451,64 @@ func (h *Handler) RecordUsage(c *gin.Context, shiftCount, volunteerCount int) {
 
 	today := time.Now().Format("2006-01-02")
 
+	impersonated := 0
+	if _, ok := c.Get("impersonatedBy"); ok {
+		impersonated = 1
+	}
+
+	if !c.GetBool("degraded") {
+		err := addUsage(h.DB, apiKey.ID, today, shiftCount, volunteerCount, impersonated)
+		if err == nil || !DegradedMode() {
+			return
+		}
+	}
+	err := bufferUsage(bufferedUsage{
+		KeyID:        apiKey.ID,
+		KeyHash:      usageKeyHash(apiKey.Key),
+		UserID:       c.GetString("userID"),
+		Date:         today,
+		Shifts:       shiftCount,
+		Volunteers:   volunteerCount,
+		Impersonated: impersonated,
+	})
+	if err != nil {
+		log.Printf("usage buffer: could not buffer usage for %s: %v", apiKey.Name, err)
+	}
+}
+
+// addUsage adds one request to a key's usage for the day
+func addUsage(db *gorm.DB, keyID uint, date string, shiftCount, volunteerCount, impersonated int) error {
 	// Use OnConflict for a single-query upsert (supported by both Postgres and SQLite)
-	h.DB.Clauses(clause.OnConflict{
+	return db.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "key_id"}, {Name: "date"}},
 		DoUpdates: clause.Assignments(map[string]interface{}{
-			"request_count":    gorm.Expr("request_count + ?", 1),
-			"total_shifts":     gorm.Expr("total_shifts + ?", shiftCount),
-			"total_volunteers": gorm.Expr("total_volunteers + ?", volunteerCount),
+			"request_count":      gorm.Expr("request_count + ?", 1),
+			"total_shifts":       gorm.Expr("total_shifts + ?", shiftCount),
+			"total_volunteers":   gorm.Expr("total_volunteers + ?", volunteerCount),
+			"impersonated_count": gorm.Expr("impersonated_count + ?", impersonated),
 		}),
 	}).Create(&database.APIUsage{
-		KeyID:           apiKey.ID,
-		Date:            today,
-		RequestCount:    1,
-		TotalShifts:     shiftCount,
-		TotalVolunteers: volunteerCount,
-	})
+		KeyID:             keyID,
+		Date:              date,
+		RequestCount:      1,
+		TotalShifts:       shiftCount,
+		TotalVolunteers:   volunteerCount,
+		ImpersonatedCount: impersonated,
+	}).Error
+}
+
+// parseShiftTime parses a CSV shift time. Times with an offset or Z are taken
+// as given; local times like 2026-01-01T09:00 are read in loc.
+func parseShiftTime(value string, loc *time.Location) time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc)
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
 
 // ScheduleCSV handles CSV file uploads for scheduling
@@ -191,6 +522,11 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "volunteers_file and shifts_file are required"})
 		return
 	}
+	format, err := parseExportFormat(c.PostForm("locale"), c.PostForm("time_format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse volunteers
 	vFile, err := volsFile.Open()
@@ -221,11 +557,26 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 		}
 		id := record[vCols["id"]]
 		maxHours, _ := strconv.ParseFloat(record[vCols["max_hours"]], 64)
+		var groups []string
+		if val, ok := vCols["groups"]; ok && record[val] != "" {
+			groups = strings.Split(record[val], "|")
+		}
+		var targetHours float64
+		if val, ok := vCols["target_hours"]; ok {
+			targetHours, _ = strconv.ParseFloat(record[val], 64)
+		}
+		var maxShifts int
+		if val, ok := vCols["max_shifts"]; ok {
+			maxShifts, _ = strconv.Atoi(record[val])
+		}
 		volMap[id] = &models.Volunteer{
-			ID:       id,
-			Name:     record[vCols["name"]],
-			Group:    record[vCols["group"]],
-			MaxHours: maxHours,
+			ID:          id,
+			Name:        record[vCols["name"]],
+			Group:       record[vCols["group"]],
+			Groups:      groups,
+			MaxHours:    maxHours,
+			MaxShifts:   maxShifts,
+			TargetHours: targetHours,
 		}
 	}
 
@@ -247,6 +598,9 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 		sCols[h] = i
 	}
 
+	// Times without an offset are read in the shift's timezone column, or the
+	// timezone form field, falling back to UTC
+	defaultTZ := c.DefaultPostForm("timezone", "UTC")
 	shiftMap := make(map[string]*models.Shift)
 	for {
 		record, err := sReader.Read()
@@ -254,14 +608,17 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 			break
 		}
 		id := record[sCols["id"]]
-		start, _ := time.Parse("2006-01-02T15:04:05Z", record[sCols["start"]])
-		if start.IsZero() {
-			start, _ = time.Parse("2006-01-02T15:04", record[sCols["start"]])
+		tz := defaultTZ
+		if val, ok := sCols["timezone"]; ok && record[val] != "" {
+			tz = record[val]
 		}
-		end, _ := time.Parse("2006-01-02T15:04:05Z", record[sCols["end"]])
-		if end.IsZero() {
-			end, _ = time.Parse("2006-01-02T15:04", record[sCols["end"]])
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("shift %s: unknown timezone %q", id, tz)})
+			return
 		}
+		start := parseShiftTime(record[sCols["start"]], loc)
+		end := parseShiftTime(record[sCols["end"]], loc)
 
 		// Fix for overnight shifts (e.g. 10 PM to 2 AM) or Midnight wrap (22:00 to 00:00)
 		if end.Before(start) || end.Equal(start) {
@@ -292,10 +649,12 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 			RequiredGroups: reqGroups,
 			AllowedGroups:  allowed,
 			ExcludedGroups: excluded,
+			Timezone:       tz,
 		}
 	}
 
 	s := scheduler.NewScheduler(volMap, shiftMap)
+	s.DetailedConflicts, _ = strconv.ParseBool(c.PostForm("detailed_conflicts"))
 
 	// Prefill if assignments provided
 	if assignmentsFile != nil {
@@ -313,9 +672,14 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 			if err == io.EOF {
 				break
 			}
+			locked := false
+			if val, ok := aCols["locked"]; ok {
+				locked, _ = strconv.ParseBool(record[val])
+			}
 			asgns = append(asgns, models.Assignment{
 				ShiftID:     record[aCols["shift_id"]],
 				VolunteerID: record[aCols["volunteer_id"]],
+				Locked:      locked,
 			})
 		}
 		s.Prefill(asgns)
@@ -336,24 +700,7 @@ func (h *Handler) ScheduleCSV(c *gin.Context) {
 
 	// Export CSV
 	var outCSV strings.Builder
-	writer := csv.NewWriter(&outCSV)
-	writer.Write([]string{"shift_id", "volunteer_id", "volunteer_name", "start", "end", "duration_hours"})
-
-	for _, sh := range shiftMap {
-		for _, vid := range sh.Assigned {
-			v := volMap[vid]
-			duration := sh.End.Sub(sh.Start).Hours()
-			writer.Write([]string{
-				sh.ID,
-				v.ID,
-				v.Name,
-				sh.Start.Format(time.RFC3339),
-				sh.End.Format(time.RFC3339),
-				fmt.Sprintf("%.2f", duration),
-			})
-		}
-	}
-	writer.Flush()
+	writeAssignmentsCSV(&outCSV, shiftMap, volMap, nil, format)
 
 	c.JSON(http.StatusOK, gin.H{"csv": outCSV.String()})
 }
@@ -395,6 +742,9 @@ func (h *Handler) GenerateKey(c *gin.Context) {
 	var req struct {
 		Name      string `json:"name"`
 		RateLimit int    `json:"rate_limit"`
+		// Upsert accepts an existing key with this name instead of failing,
+		// updating its rate limit if one is given. Its secret isn't returned.
+		Upsert bool `json:"upsert"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -406,44 +756,149 @@ func (h *Handler) GenerateKey(c *gin.Context) {
 		return
 	}
 
-	if req.RateLimit == 0 {
-		req.RateLimit = 10000
+	if req.RateLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rate limit"})
+		return
 	}
 
-	// Generate key using HMAC
-	key := auth.GenerateHMACKey(req.Name)
+	// Keys are derived from the name, so names are unique in practice. The
+	// lookup and insert share a transaction; a concurrent insert of the same
+	// name still surfaces as a duplicate key, which is reported like a reuse.
+	var apiKey database.APIKey
+	created := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("name = ?", req.Name).First(&apiKey).Error; err == nil {
+			if !req.Upsert {
+				return gorm.ErrDuplicatedKey
+			}
+			if apiKey.RevokedAt != nil {
+				return errKeyRevoked
+			}
+			if req.RateLimit > 0 && req.RateLimit != apiKey.RateLimit {
+				return tx.Model(&apiKey).Update("rate_limit", req.RateLimit).Error
+			}
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 
-	// Create preview (e.g., sk_...****)
-	preview := ""
-	if len(key) > 8 {
-		preview = key[:3] + "..." + key[len(key)-4:]
-	} else {
-		preview = "****"
-	}
+		rateLimit := req.RateLimit
+		if rateLimit == 0 {
+			rateLimit = 10000
+		}
 
-	apiKey := database.APIKey{
-		Key:        key,
-		Name:       req.Name,
-		KeyPreview: preview,
-		RateLimit:  req.RateLimit,
-	}
+		// Generate key using HMAC
+		key := auth.GenerateHMACKey(req.Name)
 
-	if err := h.DB.Create(&apiKey).Error; err != nil {
+		apiKey = database.APIKey{
+			Key:        key,
+			Name:       req.Name,
+			KeyPreview: keyPreview(key),
+			RateLimit:  rateLimit,
+		}
+		created = true
+		return tx.Create(&apiKey).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		var existing database.APIKey
+		h.DB.Where("name = ?", req.Name).First(&existing)
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "A key with this name already exists",
+			"id":          existing.ID,
+			"external_id": existing.ExternalID,
+			"name":        existing.Name,
+			"key_preview": existing.KeyPreview,
+			"rate_limit":  existing.RateLimit,
+			"created_at":  existing.CreatedAt,
+		})
+		return
+	}
+	if errors.Is(err, errKeyRevoked) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A revoked key has this name"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create key record"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"name": req.Name,
-		"key":  key,
-	})
+	resp := gin.H{
+		"id":          apiKey.ID,
+		"external_id": apiKey.ExternalID,
+		"name":        apiKey.Name,
+		"key_preview": apiKey.KeyPreview,
+		"rate_limit":  apiKey.RateLimit,
+		"created":     created,
+	}
+	// The secret is only handed out when it's minted
+	if created {
+		resp["key"] = apiKey.Key
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// errKeyRevoked refuses an upsert onto a revoked key
+var errKeyRevoked = errors.New("key is revoked")
+
+// keyPreview creates a masked preview of a key (e.g., sk_...****)
+func keyPreview(key string) string {
+	if len(key) > 8 {
+		return key[:3] + "..." + key[len(key)-4:]
+	}
+	return "****"
 }
 
-// ListKeys returns all API keys
+// ListKeys returns all API keys, optionally filtered by name or external_id
 func (h *Handler) ListKeys(c *gin.Context) {
+	query := h.reader(c)
+	if name := c.Query("name"); name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if externalID := c.Query("external_id"); externalID != "" {
+		query = query.Where("external_id = ?", externalID)
+	}
 	var keys []database.APIKey
-	h.DB.Find(&keys)
-	c.JSON(http.StatusOK, gin.H{"keys": keys})
+	query.Find(&keys)
+	views := make([]keyView, len(keys))
+	for i, k := range keys {
+		views[i] = newKeyView(k)
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": views})
+}
+
+// keyView is an API key as listed to admins. The key itself is shown only
+// when it is minted; listings carry a preview.
+type keyView struct {
+	ID         uint       `json:"id"`
+	ExternalID string     `json:"external_id"`
+	Name       string     `json:"name"`
+	KeyPreview string     `json:"key_preview"`
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsed   *time.Time `json:"last_used"`
+	PartnerID  *uint      `json:"partner_id,omitempty"`
+	MaxSubKeys int        `json:"max_sub_keys"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func newKeyView(k database.APIKey) keyView {
+	preview := k.KeyPreview
+	if preview == "" {
+		// Keys provisioned on first use have no stored preview
+		preview = keyPreview(k.Key)
+	}
+	return keyView{
+		ID:         k.ID,
+		ExternalID: k.ExternalID,
+		Name:       k.Name,
+		KeyPreview: preview,
+		RateLimit:  k.RateLimit,
+		CreatedAt:  k.CreatedAt,
+		LastUsed:   k.LastUsed,
+		PartnerID:  k.PartnerID,
+		MaxSubKeys: k.MaxSubKeys,
+		RevokedAt:  k.RevokedAt,
+	}
 }
 
 // RevokeKey deletes an API key
@@ -483,11 +938,41 @@ func (h *Handler) UpdateKeyLimit(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Rate limit updated successfully"})
 }
 
+// ImpersonateKey issues a short-lived token that lets an admin call /api/* as a key
+func (h *Handler) ImpersonateKey(c *gin.Context) {
+	id := c.Param("id")
+	var apiKey database.APIKey
+	if err := h.DB.First(&apiKey, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "API key has been revoked"})
+		return
+	}
+
+	username := c.GetString("username")
+	token, expiresAt, err := auth.CreateImpersonationToken(username, apiKey.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		return
+	}
+
+	log.Printf("audit: admin %q issued impersonation token for key %d", username, apiKey.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "bearer",
+		"key_id":       apiKey.ID,
+		"expires_at":   expiresAt,
+	})
+}
+
 // GetUsage returns usage stats for a key
 func (h *Handler) GetUsage(c *gin.Context) {
 	id := c.Param("id")
 	var usage []database.APIUsage
-	h.DB.Where("key_id = ?", id).Order("date desc").Limit(30).Find(&usage)
+	h.reader(c).Where("key_id = ?", id).Order("date desc").Limit(30).Find(&usage)
 	c.JSON(http.StatusOK, gin.H{"usage": usage})
 }
 
@@ -500,6 +985,10 @@ func (h *Handler) AdminInterface(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "static/index.html not found in embedded FS"})
 		return
 	}
+	if base := BasePath(); base != "" {
+		data = bytes.ReplaceAll(data, []byte(`="/static/`), []byte(`="`+base+`/static/`))
+		data = bytes.Replace(data, []byte("</head>"), []byte("<script>window.BASE_PATH = "+strconv.Quote(base)+";</script>\n</head>"), 1)
+	}
 
 	c.Data(http.StatusOK, "text/html; charset=utf-8", data)
 }
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/html"
+)
+
+// assetRule limits what an admin asset slot accepts
+type assetRule struct {
+	maxBytes int64
+	types    []string // sniffed content types accepted
+}
+
+// assetRules lists the admin UI assets that can be replaced. SVG is left out
+// of the logo types since it can carry scripts.
+var assetRules = map[string]assetRule{
+	"logo":   {maxBytes: 256 << 10, types: []string{"image/png", "image/jpeg", "image/gif", "image/webp"}},
+	"footer": {maxBytes: 16 << 10, types: []string{"text/html; charset=utf-8", "text/plain; charset=utf-8"}},
+}
+
+// GetAdminAsset serves a custom admin UI asset. It is public so the login
+// screen can show it.
+func (h *Handler) GetAdminAsset(c *gin.Context) {
+	var asset database.AdminAsset
+	if err := h.reader(c).First(&asset, "name = ?", c.Param("name")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	data := asset.Data
+	if strings.HasPrefix(asset.ContentType, "text/html") {
+		// Footers saved before uploads were sanitized are cleaned on the way out
+		data = sanitizeFooter(data)
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, asset.ContentType, data)
+}
+
+// PutAdminAsset replaces a custom admin UI asset with the uploaded "file".
+// The type is sniffed from the content, not taken from the upload.
+func (h *Handler) PutAdminAsset(c *gin.Context) {
+	name := c.Param("name")
+	rule, ok := assetRules[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown asset; expected logo or footer"})
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if file.Size > rule.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%s must be at most %d KB", name, rule.maxBytes>>10)})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, rule.maxBytes+1))
+	if err != nil || int64(len(data)) > rule.maxBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(rule.types, contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("%s cannot be %s", name, contentType)})
+		return
+	}
+	if strings.HasPrefix(contentType, "text/") {
+		if !utf8.Valid(data) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": name + " must be UTF-8 text"})
+			return
+		}
+		// The admin UI inserts the footer as HTML: plain text is escaped and
+		// HTML cut down to footerTags
+		if strings.HasPrefix(contentType, "text/plain") {
+			data = []byte(html.EscapeString(string(data)))
+		} else {
+			data = sanitizeFooter(data)
+		}
+		contentType = "text/html; charset=utf-8"
+	}
+
+	asset := database.AdminAsset{Name: name, ContentType: contentType, Data: data, UpdatedBy: c.GetString("username")}
+	if err := h.DB.Save(&asset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save asset"})
+		return
+	}
+	h.audit(c, "admin_asset.update", name, fmt.Sprintf("%s, %d bytes", contentType, len(data)))
+	c.JSON(http.StatusOK, gin.H{"asset": asset, "size": len(data)})
+}
+
+// DeleteAdminAsset restores the built-in look for an asset
+func (h *Handler) DeleteAdminAsset(c *gin.Context) {
+	result := h.DB.Delete(&database.AdminAsset{}, "name = ?", c.Param("name"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete asset"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	h.audit(c, "admin_asset.delete", c.Param("name"), "")
+	c.JSON(http.StatusOK, gin.H{"message": "Asset deleted"})
+}
+
+// footerTags are the elements a footer may use. Any other tag is dropped,
+// keeping its text, and every attribute but a safe href is removed.
+var footerTags = map[string]bool{
+	"a": true, "b": true, "br": true, "em": true, "i": true, "p": true,
+	"small": true, "span": true, "strong": true, "u": true,
+}
+
+// footerDropContent are elements whose content is dropped along with them
+var footerDropContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"noscript": true, "template": true, "textarea": true, "title": true,
+}
+
+// sanitizeFooter rewrites footer HTML so that only footerTags survive
+func sanitizeFooter(data []byte) []byte {
+	var out bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(data))
+	skip := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return out.Bytes()
+		}
+		tok := z.Token()
+		switch tt {
+		case html.TextToken:
+			if skip == 0 {
+				out.WriteString(html.EscapeString(tok.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if footerDropContent[tok.Data] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 || !footerTags[tok.Data] {
+				continue
+			}
+			out.WriteString("<" + tok.Data)
+			if tok.Data == "a" {
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" && safeHref(attr.Val) {
+						out.WriteString(` href="` + html.EscapeString(attr.Val) + `" rel="noopener noreferrer"`)
+						break
+					}
+				}
+			}
+			out.WriteString(">")
+		case html.EndTagToken:
+			if footerDropContent[tok.Data] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip == 0 && footerTags[tok.Data] && tok.Data != "br" {
+				out.WriteString("</" + tok.Data + ">")
+			}
+		}
+	}
+}
+
+// safeHref allows links to web pages, mail and phone numbers only
+func safeHref(href string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
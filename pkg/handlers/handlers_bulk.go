@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BulkKeyOp is a single create/revoke/update_limit operation in a bulk request
+type BulkKeyOp struct {
+	Op        string `json:"op"`
+	ID        uint   `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	RateLimit int    `json:"rate_limit,omitempty"`
+}
+
+// BulkKeyResult is the outcome of a single bulk operation
+type BulkKeyResult struct {
+	Index      int    `json:"index"`
+	Op         string `json:"op"`
+	ID         uint   `json:"id,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+var errBulkItemFailed = errors.New("bulk item failed")
+
+// BulkKeys applies many key operations in a single transaction. If any item
+// fails, the whole batch is rolled back and per-item results are returned.
+func (h *Handler) BulkKeys(c *gin.Context) {
+	var req struct {
+		Operations []BulkKeyOp `json:"operations"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operations are required"})
+		return
+	}
+
+	results := make([]BulkKeyResult, len(req.Operations))
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		failed := false
+		for i, op := range req.Operations {
+			results[i] = applyBulkKeyOp(tx, i, op)
+			if results[i].Error != "" {
+				failed = true
+			}
+		}
+		if failed {
+			return errBulkItemFailed
+		}
+		return nil
+	})
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errBulkItemFailed) {
+			status = http.StatusBadRequest
+		}
+		// Nothing was persisted, so don't hand out keys for rolled-back records
+		for i := range results {
+			results[i].Key = ""
+			if results[i].Op == "create" {
+				results[i].ID = 0
+				results[i].ExternalID = ""
+			}
+		}
+		c.JSON(status, gin.H{
+			"error":       "Bulk operation rolled back",
+			"rolled_back": true,
+			"results":     results,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rolled_back": false, "results": results})
+}
+
+// applyBulkKeyOp runs one operation inside the bulk transaction
+func applyBulkKeyOp(tx *gorm.DB, index int, op BulkKeyOp) BulkKeyResult {
+	res := BulkKeyResult{Index: index, Op: op.Op, ID: op.ID, Name: op.Name}
+
+	switch op.Op {
+	case "create":
+		if op.Name == "" {
+			res.Error = "name is required"
+			return res
+		}
+		if op.RateLimit == 0 {
+			op.RateLimit = 10000
+		}
+		key := auth.GenerateHMACKey(op.Name)
+		apiKey := database.APIKey{
+			Key:        key,
+			Name:       op.Name,
+			KeyPreview: keyPreview(key),
+			RateLimit:  op.RateLimit,
+		}
+		if err := tx.Create(&apiKey).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+			res.Error = "A key with this name already exists"
+			return res
+		} else if err != nil {
+			res.Error = "Could not create key record"
+			return res
+		}
+		res.ID = apiKey.ID
+		res.ExternalID = apiKey.ExternalID
+		res.Key = key
+	case "revoke":
+		if op.ID == 0 {
+			res.Error = "id is required"
+			return res
+		}
+		result := tx.Delete(&database.APIKey{}, op.ID)
+		if result.Error != nil {
+			res.Error = "Could not delete key"
+		} else if result.RowsAffected == 0 {
+			res.Error = "Key not found"
+		}
+	case "update_limit":
+		if op.ID == 0 {
+			res.Error = "id is required"
+			return res
+		}
+		if op.RateLimit <= 0 {
+			res.Error = "invalid rate limit"
+			return res
+		}
+		result := tx.Model(&database.APIKey{}).Where("id = ?", op.ID).Update("rate_limit", op.RateLimit)
+		if result.Error != nil {
+			res.Error = "Could not update key limit"
+		} else if result.RowsAffected == 0 {
+			res.Error = "Key not found"
+		}
+	default:
+		res.Error = "unknown op: " + op.Op
+	}
+	return res
+}
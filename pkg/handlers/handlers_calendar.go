@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+)
+
+const (
+	// maxCalendarSize caps the size of a fetched busy calendar
+	maxCalendarSize = 2 << 20
+	// maxBusyCalendars caps how many different calendars one input may fetch
+	maxBusyCalendars = 100
+	// calendarFetches is how many calendars are fetched at once
+	calendarFetches = 8
+	// maxRecurrences caps how many times one recurring event is expanded
+	maxRecurrences = 100000
+)
+
+// errPrivateAddress is returned when a URL a caller gave us resolves to an
+// address inside our own network
+var errPrivateAddress = errors.New("host resolves to a private address")
+
+// privateIP reports whether ip is loopback, private, link-local or
+// unspecified
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// publicTransport dials only public addresses, for requests to URLs that
+// come from callers. The check runs on the address actually dialled, so a
+// name that resolves differently later is still caught. allowPrivate, if
+// set, can lift the restriction.
+func publicTransport(allowPrivate func() bool) *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				if allowPrivate != nil && allowPrivate() {
+					return nil
+				}
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || privateIP(ip) {
+					return errPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+	}
+}
+
+// calendarClient fetches busy calendars
+var calendarClient = &http.Client{Timeout: 10 * time.Second, Transport: publicTransport(nil)}
+
+// importBusyCalendars fetches each volunteer's busy calendar and adds the
+// busy periods overlapping the input's shifts to their busy times. Each
+// distinct URL is fetched once. URLs redacted from debug captures are
+// skipped, so captures can still be replayed.
+func importBusyCalendars(ctx context.Context, input *models.ScheduleInput) error {
+	byURL := make(map[string][]*models.Volunteer)
+	for i := range input.Volunteers {
+		vol := &input.Volunteers[i]
+		if vol.BusyCalendarURL == "" || vol.BusyCalendarURL == pii.Redacted {
+			continue
+		}
+		byURL[vol.BusyCalendarURL] = append(byURL[vol.BusyCalendarURL], vol)
+	}
+	if len(byURL) == 0 {
+		return nil
+	}
+	if len(byURL) > maxBusyCalendars {
+		return fmt.Errorf("at most %d different busy calendars may be used", maxBusyCalendars)
+	}
+
+	// Only busy periods near the shifts matter, which also bounds recurrences
+	var from, to time.Time
+	for i, sh := range input.UnassignedShifts {
+		if i == 0 || sh.Start.Before(from) {
+			from = sh.Start
+		}
+		if i == 0 || sh.End.After(to) {
+			to = sh.End
+		}
+	}
+	window := models.TimeWindow{Start: from.Add(-24 * time.Hour), End: to.Add(24 * time.Hour)}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		slots    = make(chan struct{}, calendarFetches)
+	)
+	for rawURL, vols := range byURL {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			busy, err := fetchBusyCalendar(ctx, rawURL, window)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("volunteer %s: busy calendar: %w", vols[0].ID, err)
+				}
+				return
+			}
+			for _, vol := range vols {
+				vol.BusyTimes = append(vol.BusyTimes, busy...)
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// fetchBusyCalendar downloads an iCalendar feed over HTTPS and returns its
+// busy periods within window
+func fetchBusyCalendar(ctx context.Context, rawURL string, window models.TimeWindow) ([]models.TimeWindow, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("url must be an https URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+	resp, err := calendarClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch returned %d", resp.StatusCode)
+	}
+	return parseBusyCalendar(io.LimitReader(resp.Body, maxCalendarSize), window)
+}
+
+// icsProperty is one unfolded iCalendar content line
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// readICS unfolds an iCalendar stream into its content lines
+func readICS(r io.Reader) ([]icsProperty, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxCalendarSize)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	props := make([]icsProperty, 0, len(lines))
+	for _, line := range lines {
+		head, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(head, ";")
+		p := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+		for _, param := range parts[1:] {
+			k, v, _ := strings.Cut(param, "=")
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+		props = append(props, p)
+	}
+	return props, nil
+}
+
+// parseBusyCalendar reads the busy periods within window from an iCalendar
+// feed: opaque, uncancelled events, with daily and weekly recurrences
+// expanded, and the busy periods of free/busy components
+func parseBusyCalendar(r io.Reader, window models.TimeWindow) ([]models.TimeWindow, error) {
+	props, err := readICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var busy []models.TimeWindow
+	var event []icsProperty
+	inEvent := false
+	for _, p := range props {
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			inEvent, event = true, event[:0]
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			inEvent = false
+			occurrences, err := eventBusy(event, window)
+			if err != nil {
+				return nil, err
+			}
+			busy = append(busy, occurrences...)
+		case inEvent:
+			event = append(event, p)
+		case p.name == "FREEBUSY":
+			if fb := strings.ToUpper(p.params["FBTYPE"]); fb != "" && fb != "BUSY" && fb != "BUSY-UNAVAILABLE" && fb != "BUSY-TENTATIVE" {
+				continue
+			}
+			for _, period := range strings.Split(p.value, ",") {
+				w, err := parsePeriod(period)
+				if err != nil {
+					return nil, err
+				}
+				if w.Start.Before(window.End) && window.Start.Before(w.End) {
+					busy = append(busy, w)
+				}
+			}
+		}
+	}
+	return busy, nil
+}
+
+// eventBusy returns the times an event takes up within window
+func eventBusy(event []icsProperty, window models.TimeWindow) ([]models.TimeWindow, error) {
+	var start, end time.Time
+	var length time.Duration
+	var rrule string
+	exdates := make(map[int64]bool)
+	for _, p := range event {
+		var err error
+		switch p.name {
+		case "DTSTART":
+			start, err = parseICSTime(p)
+		case "DTEND":
+			end, err = parseICSTime(p)
+		case "DURATION":
+			length, err = parseICSDuration(p.value)
+		case "RRULE":
+			rrule = p.value
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				var t time.Time
+				if t, err = parseICSTime(icsProperty{params: p.params, value: v}); err == nil {
+					exdates[t.Unix()] = true
+				}
+			}
+		case "TRANSP":
+			if strings.EqualFold(p.value, "TRANSPARENT") {
+				return nil, nil
+			}
+		case "STATUS":
+			if strings.EqualFold(p.value, "CANCELLED") {
+				return nil, nil
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.ToLower(p.name), err)
+		}
+	}
+	if start.IsZero() {
+		return nil, nil
+	}
+	switch {
+	case !end.IsZero():
+		length = end.Sub(start)
+	case length == 0 && isICSDate(event):
+		// All-day events without an end last the day
+		length = 24 * time.Hour
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+
+	var busy []models.TimeWindow
+	err := occurrences(start, rrule, window.End, func(t time.Time) {
+		if !exdates[t.Unix()] && t.Before(window.End) && window.Start.Before(t.Add(length)) {
+			busy = append(busy, models.TimeWindow{Start: t, End: t.Add(length)})
+		}
+	})
+	return busy, err
+}
+
+// isICSDate reports whether an event's start is a date without a time
+func isICSDate(event []icsProperty) bool {
+	for _, p := range event {
+		if p.name == "DTSTART" {
+			return strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len("20060102")
+		}
+	}
+	return false
+}
+
+// occurrences calls fn with each start of a recurring event up to until.
+// Daily and weekly rules are expanded, with INTERVAL, COUNT, UNTIL and, for
+// weekly rules, BYDAY; anything else is treated as a single occurrence.
+func occurrences(start time.Time, rrule string, until time.Time, fn func(time.Time)) error {
+	if rrule == "" {
+		fn(start)
+		return nil
+	}
+	rule := make(map[string]string)
+	for _, part := range strings.Split(rrule, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		rule[strings.ToUpper(k)] = strings.ToUpper(v)
+	}
+
+	interval := 1
+	if v, ok := rule["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("rrule: invalid INTERVAL %q", v)
+		}
+		interval = n
+	}
+	count := maxRecurrences
+	if v, ok := rule["COUNT"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("rrule: invalid COUNT %q", v)
+		}
+		count = min(n, maxRecurrences)
+	}
+	if v, ok := rule["UNTIL"]; ok {
+		t, err := parseICSTime(icsProperty{value: v, params: map[string]string{}})
+		if err != nil {
+			return fmt.Errorf("rrule: invalid UNTIL %q", v)
+		}
+		if len(v) == len("20060102") {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		if t.Before(until) {
+			until = t
+		}
+	}
+
+	var days []time.Weekday
+	if rule["FREQ"] == "WEEKLY" {
+		for _, d := range strings.Split(rule["BYDAY"], ",") {
+			if wd, ok := icsWeekdays[d]; ok {
+				days = append(days, wd)
+			}
+		}
+	}
+
+	switch rule["FREQ"] {
+	case "DAILY":
+		for n, t := 0, start; n < count && !t.After(until); n, t = n+1, t.AddDate(0, 0, interval) {
+			fn(t)
+		}
+	case "WEEKLY":
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		// Walk week by week from the start's week, taking the listed days
+		weekStart := start.AddDate(0, 0, -int(start.Weekday()))
+		for n := 0; n < count && !weekStart.After(until); weekStart = weekStart.AddDate(0, 0, 7*interval) {
+			for wd := time.Sunday; wd <= time.Saturday && n < count; wd++ {
+				t := weekStart.AddDate(0, 0, int(wd))
+				if t.Before(start) || t.After(until) || !slices.Contains(days, wd) {
+					continue
+				}
+				fn(t)
+				n++
+			}
+		}
+	default:
+		fn(start)
+	}
+	return nil
+}
+
+// icsWeekdays maps iCalendar day codes to weekdays
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseICSTime reads a DATE or DATE-TIME value. Times in UTC end in Z; others
+// are in the TZID parameter's zone, or UTC without one.
+func parseICSTime(p icsProperty) (time.Time, error) {
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone %q", tzid)
+		}
+		loc = l
+	}
+	switch {
+	case strings.HasSuffix(p.value, "Z"):
+		return time.Parse("20060102T150405Z", p.value)
+	case len(p.value) == len("20060102"):
+		return time.ParseInLocation("20060102", p.value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", p.value, loc)
+	}
+}
+
+// parseICSDuration reads a duration such as PT1H30M or P1D. Weeks and days
+// are taken as 7 and 1 times 24 hours.
+func parseICSDuration(v string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(v, "+"), "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var d time.Duration
+	num := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T':
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("invalid duration %q", v)
+			}
+			d += time.Duration(n) * unit
+			num = ""
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	return d, nil
+}
+
+// parsePeriod reads a free/busy period: a start and either an end or a duration
+func parsePeriod(v string) (models.TimeWindow, error) {
+	startValue, endValue, ok := strings.Cut(v, "/")
+	if !ok {
+		return models.TimeWindow{}, fmt.Errorf("invalid period %q", v)
+	}
+	start, err := parseICSTime(icsProperty{value: startValue})
+	if err != nil {
+		return models.TimeWindow{}, fmt.Errorf("invalid period %q", v)
+	}
+	if strings.HasPrefix(endValue, "P") || strings.HasPrefix(endValue, "+P") {
+		d, err := parseICSDuration(endValue)
+		if err != nil {
+			return models.TimeWindow{}, err
+		}
+		return models.TimeWindow{Start: start, End: start.Add(d)}, nil
+	}
+	end, err := parseICSTime(icsProperty{value: endValue})
+	if err != nil {
+		return models.TimeWindow{}, fmt.Errorf("invalid period %q", v)
+	}
+	return models.TimeWindow{Start: start, End: end}, nil
+}
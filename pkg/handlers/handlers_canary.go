@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// canaryConfig returns the candidate strategy and the percentage of schedule
+// requests it also solves, from CANARY_ALGORITHM and CANARY_PERCENT. Either
+// one unset turns canaries off.
+func canaryConfig() (algorithm string, percent int) {
+	return os.Getenv("CANARY_ALGORITHM"), min(envInt("CANARY_PERCENT"), 100)
+}
+
+// canarySample picks requests for the candidate strategy. It returns a copy
+// of the input to solve later, since solving mutates the original, or nil.
+func canarySample(input *models.ScheduleInput) *models.ScheduleInput {
+	candidate, percent := canaryConfig()
+	if candidate == "" || percent == 0 || rand.IntN(100) >= percent {
+		return nil
+	}
+	if cmp.Or(input.Algorithm, scheduler.AlgorithmGreedy) == candidate {
+		return nil
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil
+	}
+	var canary models.ScheduleInput
+	if err := json.Unmarshal(data, &canary); err != nil {
+		return nil
+	}
+	return &canary
+}
+
+// runCanary solves a sampled request with the candidate strategy and records
+// how it compares with the response the client got. The candidate's schedule
+// itself is discarded. It runs after the request is answered, so it doesn't
+// share the request's context.
+func (h *Handler) runCanary(keyID uint, input *models.ScheduleInput, baseline models.ScheduleResponse, baselineTime time.Duration) {
+	candidate, _ := canaryConfig()
+	run := database.CanaryRun{
+		KeyID:     keyID,
+		Algorithm: cmp.Or(input.Algorithm, scheduler.AlgorithmGreedy),
+		Candidate: candidate,
+	}
+
+	required := requiredSlots(input)
+	canary := *input
+	canary.Algorithm = candidate
+	canary.Save = false
+	canary.Alternatives = 0
+	started := time.Now()
+	resp, err := buildSchedule(context.Background(), &canary)
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.FillDelta = fillRate(resp, required) - fillRate(baseline, required)
+		run.FairnessDelta = resp.FairnessScore - baseline.FairnessScore
+		run.DurationDeltaMs = (time.Since(started) - baselineTime).Milliseconds()
+	}
+	if err := h.DB.Create(&run).Error; err != nil {
+		log.Printf("canary: could not record run: %v", err)
+	}
+}
+
+// requiredSlots counts the slots an input asks to fill
+func requiredSlots(input *models.ScheduleInput) int {
+	total := 0
+	for _, sh := range input.UnassignedShifts {
+		for _, count := range sh.RequiredGroups {
+			total += count
+		}
+	}
+	return total
+}
+
+// fillRate returns the fraction of required slots a response fills
+func fillRate(resp models.ScheduleResponse, required int) float64 {
+	if required == 0 {
+		return 1
+	}
+	filled := 0
+	for _, assigned := range resp.AssignedShifts {
+		filled += len(assigned)
+	}
+	return float64(filled) / float64(required)
+}
+
+// CanaryReport summarises canary runs for each candidate and baseline pair.
+// Deltas are the candidate minus the baseline, averaged over runs that didn't fail.
+func (h *Handler) CanaryReport(c *gin.Context) {
+	type row struct {
+		Candidate       string  `json:"candidate"`
+		Algorithm       string  `json:"algorithm"`
+		Runs            int64   `json:"runs"`
+		Errors          int64   `json:"errors"`
+		Better          int64   `json:"better"`
+		Worse           int64   `json:"worse"`
+		FillDelta       float64 `json:"fill_delta"`
+		FairnessDelta   float64 `json:"fairness_delta"`
+		DurationDeltaMs float64 `json:"duration_delta_ms"`
+	}
+	var rows []row
+	err := h.reader(c).Model(&database.CanaryRun{}).
+		Select(`candidate, algorithm, COUNT(*) AS runs,
+			SUM(CASE WHEN error <> '' THEN 1 ELSE 0 END) AS errors,
+			SUM(CASE WHEN error = '' AND fill_delta > 0 THEN 1 ELSE 0 END) AS better,
+			SUM(CASE WHEN error = '' AND fill_delta < 0 THEN 1 ELSE 0 END) AS worse,
+			COALESCE(AVG(CASE WHEN error = '' THEN fill_delta END), 0) AS fill_delta,
+			COALESCE(AVG(CASE WHEN error = '' THEN fairness_delta END), 0) AS fairness_delta,
+			COALESCE(AVG(CASE WHEN error = '' THEN duration_delta_ms END), 0) AS duration_delta_ms`).
+		Group("candidate, algorithm").
+		Order("candidate, algorithm").
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load canary runs"})
+		return
+	}
+
+	candidate, percent := canaryConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"candidate":   candidate,
+		"percent":     percent,
+		"comparisons": rows,
+	})
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+)
+
+// newCapture snapshots a scheduling request for later replay,
+// with personal fields masked when redaction is enabled. Names and contact
+// details never affect assignments, so redacted captures still replay faithfully.
+func (h *Handler) newCapture(c *gin.Context, input *models.ScheduleInput) *database.DebugCapture {
+	if redactionEnabled() {
+		redacted := *input
+		redacted.Volunteers = append([]models.Volunteer(nil), input.Volunteers...)
+		pii.RedactVolunteers(redacted.Volunteers)
+		input = &redacted
+	}
+	body, err := sealedInputJSON(input)
+	if err != nil {
+		return nil
+	}
+	capture := &database.DebugCapture{
+		Endpoint: c.FullPath(),
+		Request:  string(body),
+	}
+	if apiKeyRaw, exists := c.Get("apiKey"); exists {
+		capture.KeyID = apiKeyRaw.(*database.APIKey).ID
+	}
+	return capture
+}
+
+// saveCapture stores a capture alongside its response and exposes its ID as a header
+func (h *Handler) saveCapture(c *gin.Context, capture *database.DebugCapture, resp models.ScheduleResponse) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	capture.Response = string(body)
+	if err := h.DB.Create(capture).Error; err != nil {
+		return
+	}
+	c.Header("X-Debug-Capture-ID", strconv.FormatUint(uint64(capture.ID), 10))
+}
+
+// ShiftDiff describes how one shift's assignments changed between two runs
+type ShiftDiff struct {
+	ShiftID string   `json:"shift_id"`
+	Before  []string `json:"before"`
+	After   []string `json:"after"`
+}
+
+// ReplayCapture re-executes a captured scheduling request against the current
+// code as a dry run (no usage recorded) and diffs the outputs
+func (h *Handler) ReplayCapture(c *gin.Context) {
+	id := c.Param("id")
+	var capture database.DebugCapture
+	if err := h.DB.First(&capture, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Capture not found"})
+		return
+	}
+
+	var input models.ScheduleInput
+	if err := json.Unmarshal([]byte(capture.Request), &input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Captured request is not valid: " + err.Error()})
+		return
+	}
+	if err := pii.OpenVolunteers(input.Volunteers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not decrypt captured request"})
+		return
+	}
+
+	var original models.ScheduleResponse
+	if err := json.Unmarshal([]byte(capture.Response), &original); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Captured response is not valid: " + err.Error()})
+		return
+	}
+
+	replayed, err := buildSchedule(c.Request.Context(), &input)
+	if err != nil {
+		c.JSON(solveErrorStatus(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"capture_id": capture.ID,
+		"original":   original,
+		"replayed":   replayed,
+		"diff":       diffSchedules(original, replayed),
+	})
+}
+
+// diffSchedules compares the assignments, unfilled shifts and fairness of two responses
+func diffSchedules(before, after models.ScheduleResponse) gin.H {
+	changed := make([]ShiftDiff, 0)
+	seen := make(map[string]bool)
+	for id := range before.AssignedShifts {
+		seen[id] = true
+	}
+	for id := range after.AssignedShifts {
+		seen[id] = true
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		b := slices.Sorted(slices.Values(before.AssignedShifts[id]))
+		a := slices.Sorted(slices.Values(after.AssignedShifts[id]))
+		if !slices.Equal(b, a) {
+			changed = append(changed, ShiftDiff{ShiftID: id, Before: b, After: a})
+		}
+	}
+
+	wasUnfilled := make(map[string]bool)
+	for _, id := range before.UnfilledShifts {
+		wasUnfilled[id] = true
+	}
+	isUnfilled := make(map[string]bool)
+	for _, id := range after.UnfilledShifts {
+		isUnfilled[id] = true
+	}
+	newlyUnfilled := make([]string, 0)
+	newlyFilled := make([]string, 0)
+	for _, id := range ids {
+		if isUnfilled[id] && !wasUnfilled[id] {
+			newlyUnfilled = append(newlyUnfilled, id)
+		}
+		if wasUnfilled[id] && !isUnfilled[id] {
+			newlyFilled = append(newlyFilled, id)
+		}
+	}
+
+	return gin.H{
+		"identical":      len(changed) == 0,
+		"changed_shifts": changed,
+		"newly_unfilled": newlyUnfilled,
+		"newly_filled":   newlyFilled,
+		"fairness_delta": after.FairnessScore - before.FairnessScore,
+	}
+}
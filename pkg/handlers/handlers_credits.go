@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCreditHours caps how long a burst credit can last
+const maxCreditHours = 30 * 24
+
+// activeCredits returns the credits on a key that haven't expired yet
+func activeCredits(db *gorm.DB, keyID uint, now time.Time) []database.BurstCredit {
+	var credits []database.BurstCredit
+	db.Where("key_id = ? AND expires_at > ?", keyID, now).Order("expires_at").Find(&credits)
+	return credits
+}
+
+// creditTotal sums the requests a set of credits adds to the base limit
+func creditTotal(credits []database.BurstCredit) int {
+	total := 0
+	for _, cr := range credits {
+		total += cr.Requests
+	}
+	return total
+}
+
+// GrantBurstCredit raises a key's rate limit by a number of requests for a
+// limited time, e.g. for an event weekend
+func (h *Handler) GrantBurstCredit(c *gin.Context) {
+	var apiKey database.APIKey
+	if err := h.DB.First(&apiKey, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+
+	var req struct {
+		Requests int    `json:"requests" binding:"required"`
+		Hours    int    `json:"hours" binding:"required"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "requests and hours are required"})
+		return
+	}
+	if req.Requests <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "requests must be positive"})
+		return
+	}
+	if req.Hours <= 0 || req.Hours > maxCreditHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("hours must be between 1 and %d", maxCreditHours)})
+		return
+	}
+
+	credit := database.BurstCredit{
+		KeyID:     apiKey.ID,
+		Requests:  req.Requests,
+		Reason:    req.Reason,
+		GrantedBy: c.GetString("username"),
+		ExpiresAt: time.Now().Add(time.Duration(req.Hours) * time.Hour),
+	}
+	if err := h.DB.Create(&credit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save burst credit"})
+		return
+	}
+	h.audit(c, "burst_credit.grant", apiKey.Name, fmt.Sprintf("+%d requests until %s", credit.Requests, credit.ExpiresAt.UTC().Format(time.RFC3339)))
+	c.JSON(http.StatusCreated, credit)
+}
+
+// ListBurstCredits returns a key's unexpired credits and the limit they add
+// up to
+func (h *Handler) ListBurstCredits(c *gin.Context) {
+	var apiKey database.APIKey
+	if err := h.reader(c).First(&apiKey, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+
+	credits := activeCredits(h.reader(c), apiKey.ID, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"key_id":               apiKey.ID,
+		"rate_limit":           apiKey.RateLimit,
+		"effective_rate_limit": apiKey.RateLimit + creditTotal(credits),
+		"credits":              credits,
+	})
+}
+
+// RevokeBurstCredit withdraws a credit before it expires
+func (h *Handler) RevokeBurstCredit(c *gin.Context) {
+	var credit database.BurstCredit
+	if err := h.DB.Where("id = ? AND key_id = ?", c.Param("creditId"), c.Param("id")).First(&credit).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Burst credit not found"})
+		return
+	}
+	if err := h.DB.Delete(&credit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete burst credit"})
+		return
+	}
+
+	var apiKey database.APIKey
+	h.DB.First(&apiKey, credit.KeyID)
+	h.audit(c, "burst_credit.revoke", apiKey.Name, fmt.Sprintf("-%d requests", credit.Requests))
+	c.JSON(http.StatusOK, gin.H{"message": "Burst credit revoked"})
+}
+
+// ExpireBurstCredits records an audit entry for each credit that has run out
+// since the last pass, returning how many did. Expired credits already stop
+// counting towards the limit; this only makes the expiry visible.
+func (h *Handler) ExpireBurstCredits(now time.Time) int {
+	var credits []database.BurstCredit
+	h.DB.Where("expired = ? AND expires_at <= ?", false, now).Find(&credits)
+	for _, credit := range credits {
+		var apiKey database.APIKey
+		h.DB.First(&apiKey, credit.KeyID)
+		h.DB.Create(&database.AuditLog{
+			KeyID:   credit.KeyID,
+			Actor:   "system",
+			Action:  "burst_credit.expire",
+			Subject: apiKey.Name,
+			Detail:  fmt.Sprintf("-%d requests", credit.Requests),
+		})
+		h.DB.Model(&credit).Update("expired", true)
+	}
+	return len(credits)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// volunteerKeys identifies a volunteer across schedules: by ID, and by email
+// when they have one, since each program may number its volunteers differently
+func volunteerKeys(vol *models.Volunteer) []string {
+	keys := []string{"id:" + vol.ID}
+	if vol.Email != "" {
+		keys = append(keys, "email:"+strings.ToLower(vol.Email))
+	}
+	return keys
+}
+
+// bookedShift is one shift a volunteer works in the schedule being checked
+type bookedShift struct {
+	volunteerID string
+	shift       *models.Shift
+}
+
+// GetDoubleBookings checks a saved schedule against the calling key's other
+// saved schedules and lists every volunteer booked into overlapping shifts
+// across them
+func (h *Handler) GetDoubleBookings(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+	input, result, err := decodeSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return
+	}
+
+	booked := make(map[string][]bookedShift)
+	shiftMap, volMap := scheduleMaps(input, result)
+	for _, shift := range shiftMap {
+		for _, volID := range shift.Assigned {
+			vol, ok := volMap[volID]
+			if !ok {
+				continue
+			}
+			for _, key := range volunteerKeys(vol) {
+				booked[key] = append(booked[key], bookedShift{volID, shift})
+			}
+		}
+	}
+
+	var others []database.Schedule
+	if err := h.reader(c).Where("key_id = ? AND id <> ?", schedule.KeyID, schedule.ID).Find(&others).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load schedules"})
+		return
+	}
+
+	bookings := []models.DoubleBooking{}
+	skipped := []string{}
+	for i := range others {
+		otherInput, otherResult, err := decodeSchedule(&others[i])
+		if err != nil {
+			skipped = append(skipped, others[i].ID)
+			continue
+		}
+		otherShifts, otherVols := scheduleMaps(otherInput, otherResult)
+		for _, other := range otherShifts {
+			for _, otherVolID := range other.Assigned {
+				vol, ok := otherVols[otherVolID]
+				if !ok {
+					continue
+				}
+				// A volunteer matched by both ID and email is only reported once
+				seen := make(map[bookedShift]bool)
+				for _, key := range volunteerKeys(vol) {
+					for _, b := range booked[key] {
+						if seen[b] || !b.shift.Start.Before(other.End) || !other.Start.Before(b.shift.End) {
+							continue
+						}
+						seen[b] = true
+						bookings = append(bookings, models.DoubleBooking{
+							VolunteerID:      b.volunteerID,
+							ShiftID:          b.shift.ID,
+							Start:            b.shift.Start,
+							End:              b.shift.End,
+							OtherScheduleID:  others[i].ID,
+							OtherVolunteerID: otherVolID,
+							OtherShiftID:     other.ID,
+							OtherStart:       other.Start,
+							OtherEnd:         other.End,
+						})
+					}
+				}
+			}
+		}
+	}
+	slices.SortFunc(bookings, func(a, b models.DoubleBooking) int {
+		return cmp.Or(
+			cmp.Compare(a.VolunteerID, b.VolunteerID),
+			a.Start.Compare(b.Start),
+			cmp.Compare(a.ShiftID, b.ShiftID),
+			cmp.Compare(a.OtherScheduleID, b.OtherScheduleID),
+			cmp.Compare(a.OtherShiftID, b.OtherShiftID),
+		)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id":       schedule.ID,
+		"schedules_checked": len(others) - len(skipped),
+		"skipped":           skipped,
+		"double_bookings":   bookings,
+	})
+}
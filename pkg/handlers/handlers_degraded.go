@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// DegradedMode reports whether scheduling should carry on while the
+// database is down, set by DEGRADED_MODE=on. Solving needs nothing from the
+// database, so only metering and saving are lost, and usage is buffered.
+func DegradedMode() bool {
+	switch strings.ToLower(os.Getenv("DEGRADED_MODE")) {
+	case "on", "true", "1":
+		return true
+	}
+	return false
+}
+
+// degradedRoutes are the routes that keep working in degraded mode
+var degradedRoutes = map[string]bool{
+	"/api/schedule":  true,
+	"/schedule/json": true,
+}
+
+// degradedKey lets a request with a validly signed key through without its
+// database record. The key has no ID, so nothing is looked up or saved
+// under it, and the response carries an X-Degraded header.
+func degradedKey(c *gin.Context, key, userID string) bool {
+	if !DegradedMode() || !degradedRoutes[c.FullPath()] {
+		return false
+	}
+	c.Set("apiKey", &database.APIKey{Key: key, Name: userID, RateLimit: 10000})
+	c.Set("userID", userID)
+	c.Set("degraded", true)
+	c.Header("X-Degraded", "true")
+	return true
+}
+
+// usageBufferPath is where usage recorded in degraded mode waits to be
+// flushed, from USAGE_BUFFER_PATH. It defaults to the temp directory, since
+// the working directory may not be writable.
+func usageBufferPath() string {
+	return cmp.Or(os.Getenv("USAGE_BUFFER_PATH"), filepath.Join(os.TempDir(), "scheduler-usage-buffer.jsonl"))
+}
+
+// bufferedUsage is one request's usage, one JSON line of the buffer. Keys
+// are never written out: a key whose record may not exist yet is stored as
+// its user ID and a hash, and rebuilt from the user ID when flushed.
+type bufferedUsage struct {
+	KeyID        uint   `json:"key_id,omitempty"`
+	KeyHash      string `json:"key_hash"`
+	UserID       string `json:"user_id"`
+	Date         string `json:"date"`
+	Shifts       int    `json:"shifts"`
+	Volunteers   int    `json:"volunteers"`
+	Impersonated int    `json:"impersonated,omitempty"`
+}
+
+// usageKeyHash identifies a key in the usage buffer
+func usageKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// usageBufferMu serialises access to the buffer file
+var usageBufferMu sync.Mutex
+
+// bufferUsage appends usage to the buffer file
+func bufferUsage(u bufferedUsage) error {
+	line, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	usageBufferMu.Lock()
+	defer usageBufferMu.Unlock()
+	f, err := os.OpenFile(usageBufferPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// FlushUsageBuffer records buffered usage in the database and returns how
+// many requests it recorded. It stops at the first database error, keeping
+// the rest for next time.
+func (h *Handler) FlushUsageBuffer() (int, error) {
+	usageBufferMu.Lock()
+	defer usageBufferMu.Unlock()
+
+	path := usageBufferPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			lines = append(lines, bytes.Clone(scanner.Bytes()))
+		}
+	}
+
+	done, flushed := 0, 0
+	var flushErr error
+	for _, line := range lines {
+		var u bufferedUsage
+		if err := json.Unmarshal(line, &u); err != nil {
+			log.Printf("usage buffer: skipping unreadable line: %v", err)
+			done++
+			continue
+		}
+		keyID := u.KeyID
+		var err error
+		if keyID == 0 {
+			// A key that no longer hashes the same was signed with a
+			// rotated secret and can't be rebuilt
+			key := auth.GenerateHMACKey(u.UserID)
+			if usageKeyHash(key) != u.KeyHash {
+				log.Printf("usage buffer: skipping usage of %s, whose key can't be rebuilt", u.UserID)
+				done++
+				continue
+			}
+			var apiKey database.APIKey
+			err = h.DB.Where(database.APIKey{Key: key}).Attrs(database.APIKey{
+				Key:       key,
+				Name:      u.UserID,
+				RateLimit: 10000,
+			}).FirstOrCreate(&apiKey).Error
+			keyID = apiKey.ID
+		}
+		if err == nil {
+			err = addUsage(h.DB, keyID, u.Date, u.Shifts, u.Volunteers, u.Impersonated)
+		}
+		if err != nil {
+			flushErr = err
+			break
+		}
+		done++
+		flushed++
+	}
+
+	rest := bytes.Join(lines[done:], []byte("\n"))
+	if len(rest) == 0 {
+		err = os.Remove(path)
+	} else {
+		err = os.WriteFile(path, append(rest, '\n'), 0o600)
+	}
+	return flushed, cmp.Or(flushErr, err)
+}
+
+// RunUsageFlusher flushes the usage buffer every interval until the process
+// exits
+func (h *Handler) RunUsageFlusher(interval time.Duration) {
+	h.runEvery("usage_flush", interval)
+}
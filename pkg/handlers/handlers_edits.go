@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errVersionConflict aborts a schedule write whose version check matched no row
+var errVersionConflict = errors.New("schedule version conflict")
+
+// scheduleETag formats a schedule version as an HTTP entity tag
+func scheduleETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// expectedVersion reads the version an edit was based on, from If-Match or
+// the body. It returns 0 when neither is given.
+func expectedVersion(c *gin.Context, bodyVersion int) (int, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		return bodyVersion, nil
+	}
+	v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("If-Match must be a schedule version such as %s", scheduleETag(1))
+	}
+	return v, nil
+}
+
+// ScheduleEdit is a manual change to a saved schedule's assignments
+type ScheduleEdit struct {
+	Version int                 `json:"version"`
+	Add     []models.Assignment `json:"add"`
+	Remove  []models.Assignment `json:"remove"`
+	// Notes replace the note and flags of existing assignments; an entry
+	// with neither clears them
+	Notes []models.AssignmentNote `json:"notes,omitempty"`
+	// Force applies added assignments even if they break a constraint
+	Force bool `json:"force"`
+}
+
+// scheduleFromResult rebuilds a scheduler holding a stored result's assignments.
+// Locked assignments in the input stay locked.
+func scheduleFromResult(input *models.ScheduleInput, assigned map[string][]string) *scheduler.Scheduler {
+	// Roles were validated when the schedule was first solved
+	input.ExpandRoles()
+	shiftMap := make(map[string]*models.Shift, len(input.UnassignedShifts))
+	for i := range input.UnassignedShifts {
+		input.UnassignedShifts[i].Assigned = nil
+		shiftMap[input.UnassignedShifts[i].ID] = &input.UnassignedShifts[i]
+	}
+	volMap := make(map[string]*models.Volunteer, len(input.Volunteers))
+	for i := range input.Volunteers {
+		input.Volunteers[i].AssignedHours = 0
+		input.Volunteers[i].AssignedShifts = nil
+		volMap[input.Volunteers[i].ID] = &input.Volunteers[i]
+	}
+
+	locked := make(map[models.Assignment]bool)
+	for _, a := range input.CurrentAssignments {
+		if a.Locked {
+			locked[models.Assignment{ShiftID: a.ShiftID, VolunteerID: a.VolunteerID}] = true
+		}
+	}
+
+	shiftIDs := make([]string, 0, len(assigned))
+	for id := range assigned {
+		shiftIDs = append(shiftIDs, id)
+	}
+	slices.Sort(shiftIDs)
+	var assignments []models.Assignment
+	for _, shiftID := range shiftIDs {
+		for _, volID := range assigned[shiftID] {
+			a := models.Assignment{ShiftID: shiftID, VolunteerID: volID}
+			a.Locked = locked[a]
+			assignments = append(assignments, a)
+		}
+	}
+
+	s := scheduler.NewScheduler(volMap, shiftMap)
+	s.PreferenceWeight = input.PreferenceWeight
+	s.ConsecutiveWeight = input.ConsecutiveWeight
+	s.FairnessMetric = input.FairnessMetric
+	s.Objective = input.Objective
+	s.CategoryLimits = input.CategoryLimits
+	s.SoftConstraints = input.SoftConstraints
+	s.TravelMinutes = input.TravelMinutes
+	s.MaxHoursRatio = input.MaxHoursRatio
+	s.ApplyGroupHierarchy(input.GroupHierarchy)
+	s.Prefill(assignments)
+	return s
+}
+
+// applyEdit removes and then adds assignments on a stored schedule, then sets
+// notes. It returns the new result and the constraint violations caused by
+// the additions, or an error if the edit names assignments that don't exist.
+func applyEdit(input *models.ScheduleInput, result *models.ScheduleResponse, edit *ScheduleEdit) (models.ScheduleResponse, []gin.H, error) {
+	// Apply removals to the stored assignments, then rebuild and validate additions
+	assigned := make(map[string][]string, len(result.AssignedShifts))
+	for shiftID, vols := range result.AssignedShifts {
+		assigned[shiftID] = slices.Clone(vols)
+	}
+	for _, a := range edit.Remove {
+		i := slices.Index(assigned[a.ShiftID], a.VolunteerID)
+		if i < 0 {
+			return models.ScheduleResponse{}, nil, fmt.Errorf("%s is not assigned to %s", a.VolunteerID, a.ShiftID)
+		}
+		assigned[a.ShiftID] = slices.Delete(assigned[a.ShiftID], i, i+1)
+	}
+
+	s := scheduleFromResult(input, assigned)
+	violations := make([]gin.H, 0)
+	for _, a := range edit.Add {
+		vol, okVol := s.Volunteers[a.VolunteerID]
+		shift, okShift := s.Shifts[a.ShiftID]
+		if !okVol || !okShift {
+			return models.ScheduleResponse{}, nil, fmt.Errorf("unknown shift %q or volunteer %q", a.ShiftID, a.VolunteerID)
+		}
+		if slices.Contains(shift.Assigned, vol.ID) {
+			continue
+		}
+		if e := s.CheckEligibility(vol, shift, s.DurationHours(shift.Start, shift.End)); !e.OK() {
+			violations = append(violations, gin.H{"shift_id": shift.ID, "volunteer_id": vol.ID, "failed": e.Failed()})
+		}
+		s.Prefill([]models.Assignment{{ShiftID: shift.ID, VolunteerID: vol.ID}})
+	}
+
+	resp := formatResponse(s, input)
+	resp.ScheduleID = result.ScheduleID
+	resp.Resources = result.Resources
+	notes, err := mergeNotes(resp.AssignedShifts, result.Notes, edit.Notes)
+	if err != nil {
+		return models.ScheduleResponse{}, nil, err
+	}
+	resp.Notes = notes
+	return resp, violations, nil
+}
+
+// mergeNotes applies note changes on top of a schedule's notes. Notes on
+// assignments that no longer exist are dropped; changes to them are an error.
+func mergeNotes(assigned map[string][]string, current, changes []models.AssignmentNote) ([]models.AssignmentNote, error) {
+	byAssignment := noteIndex(current)
+	for _, n := range changes {
+		if !slices.Contains(assigned[n.ShiftID], n.VolunteerID) {
+			return nil, fmt.Errorf("%s is not assigned to %s", n.VolunteerID, n.ShiftID)
+		}
+		byAssignment[models.Assignment{ShiftID: n.ShiftID, VolunteerID: n.VolunteerID}] = n
+	}
+
+	var notes []models.AssignmentNote
+	for a, n := range byAssignment {
+		if (n.Note != "" || len(n.Flags) > 0) && slices.Contains(assigned[a.ShiftID], a.VolunteerID) {
+			notes = append(notes, n)
+		}
+	}
+	slices.SortFunc(notes, func(a, b models.AssignmentNote) int {
+		if c := strings.Compare(a.ShiftID, b.ShiftID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.VolunteerID, b.VolunteerID)
+	})
+	return notes, nil
+}
+
+// noteIndex maps each assignment to its note
+func noteIndex(notes []models.AssignmentNote) map[models.Assignment]models.AssignmentNote {
+	byAssignment := make(map[models.Assignment]models.AssignmentNote, len(notes))
+	for _, n := range notes {
+		byAssignment[models.Assignment{ShiftID: n.ShiftID, VolunteerID: n.VolunteerID}] = n
+	}
+	return byAssignment
+}
+
+// commitChange stores a schedule's new result as the next version and records
+// it in the history, replacing the stored input too unless inputJSON is nil.
+// It returns errVersionConflict if the schedule has moved on.
+func (h *Handler) commitChange(c *gin.Context, schedule *database.Schedule, version int, kind string, detail any, resp *models.ScheduleResponse, inputJSON []byte) error {
+	resultJSON, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	// An updated schedule starts its retention period again, so any expiry
+	// warning sent for it no longer applies
+	updates := map[string]any{"result": string(resultJSON), "version": gorm.Expr("version + 1"), "warned_at": nil}
+	if inputJSON != nil {
+		updates["input"] = string(inputJSON)
+	}
+	// The version check in the WHERE clause makes the write atomic against concurrent edits
+	return h.DB.Transaction(func(tx *gorm.DB) error {
+		update := tx.Model(&database.Schedule{}).
+			Where("id = ? AND version = ?", schedule.ID, version).
+			Updates(updates)
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		if err := storeAssignments(tx, schedule.ID, resp); err != nil {
+			return err
+		}
+		return recordEvent(tx, c, schedule.ID, version+1, kind, detail, string(resultJSON))
+	})
+}
+
+// lockedSchedule loads a schedule for a change based on the given version,
+// writing 428 if no version was sent and 409 if it is stale
+func (h *Handler) lockedSchedule(c *gin.Context, bodyVersion int) (*database.Schedule, bool) {
+	version, err := expectedVersion(c, bodyVersion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if version == 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Send the schedule version you edited in If-Match or the version field"})
+		return nil, false
+	}
+
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return nil, false
+	}
+	if schedule.Version != version {
+		c.Header("ETag", scheduleETag(schedule.Version))
+		c.JSON(http.StatusConflict, gin.H{"error": "Schedule was changed by someone else", "version": schedule.Version})
+		return nil, false
+	}
+	return schedule, true
+}
+
+// EditSchedule applies manual assignment changes to a saved schedule. The
+// caller must name the version they edited, via If-Match or the body; if the
+// schedule has changed since, the edit is rejected with 409.
+func (h *Handler) EditSchedule(c *gin.Context) {
+	var edit ScheduleEdit
+	if err := c.ShouldBindJSON(&edit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule, ok := h.lockedSchedule(c, edit.Version)
+	if !ok {
+		return
+	}
+	input, result, err := decodeSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return
+	}
+
+	resp, violations, err := applyEdit(&input, &result, &edit)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if len(violations) > 0 && !edit.Force {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Edit breaks scheduling constraints; resend with force to apply anyway", "violations": violations})
+		return
+	}
+
+	edit.Version = schedule.Version
+	h.finishChange(c, schedule, eventEdit, &edit, &resp, violations, nil)
+}
+
+// finishChange commits a change and writes the response
+func (h *Handler) finishChange(c *gin.Context, schedule *database.Schedule, kind string, detail any, resp *models.ScheduleResponse, violations []gin.H, inputJSON []byte) {
+	err := h.commitChange(c, schedule, schedule.Version, kind, detail, resp, inputJSON)
+	if errors.Is(err, errVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Schedule was changed by someone else"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save schedule"})
+		return
+	}
+
+	c.Header("ETag", scheduleETag(schedule.Version+1))
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id": schedule.ID,
+		"version":     schedule.Version + 1,
+		"result":      resp,
+		"violations":  violations,
+	})
+}
+
+// RegenerateRequest re-solves a saved schedule. Without an input, the stored
+// input is solved again.
+type RegenerateRequest struct {
+	Version int                   `json:"version"`
+	Input   *models.ScheduleInput `json:"input"`
+}
+
+// RegenerateSchedule solves a saved schedule again, typically after its
+// volunteers or shifts changed, and stores the result as the next version.
+// Earlier versions stay in the history, so GET /api/schedules/:id/diff can
+// show what the regeneration changed.
+func (h *Handler) RegenerateSchedule(c *gin.Context) {
+	var req RegenerateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	schedule, ok := h.lockedSchedule(c, req.Version)
+	if !ok {
+		return
+	}
+
+	var input models.ScheduleInput
+	if req.Input != nil {
+		input = *req.Input
+		if err := h.applyProfile(c, &input); err != nil {
+			status, body := profileErrorStatus(err)
+			c.JSON(status, body)
+			return
+		}
+	} else {
+		var err error
+		if input, _, err = decodeSchedule(schedule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+			return
+		}
+	}
+	// Sealed before solving, since solving mutates the input
+	inputJSON, err := sealedInputJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not encrypt schedule input"})
+		return
+	}
+
+	resp, err := buildSchedule(c.Request.Context(), &input)
+	if err != nil {
+		status, body := solveErrorStatus(err, http.StatusBadRequest)
+		c.JSON(status, body)
+		return
+	}
+	resp.ScheduleID = schedule.ID
+	h.RecordUsage(c, len(resp.AssignedShifts), len(resp.Volunteers))
+
+	detail := gin.H{"of": schedule.Version, "new_input": req.Input != nil}
+	h.finishChange(c, schedule, eventRegenerate, detail, &resp, nil, inputJSON)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+)
+
+// isMsgPack checks if a Content-Type or Accept header names MessagePack
+func isMsgPack(mime string) bool {
+	return strings.Contains(mime, binding.MIMEMSGPACK) || strings.Contains(mime, binding.MIMEMSGPACK2)
+}
+
+// bindBody decodes the request body as MessagePack or JSON based on Content-Type
+func bindBody(c *gin.Context, obj any) error {
+	if isMsgPack(c.ContentType()) {
+		return c.ShouldBindWith(obj, binding.MsgPack)
+	}
+	return c.ShouldBindJSON(obj)
+}
+
+// respond writes obj as MessagePack when the client accepts it, or sent
+// MessagePack without asking for JSON, and as JSON otherwise
+func respond(c *gin.Context, status int, obj any) {
+	accept := c.GetHeader("Accept")
+	if isMsgPack(accept) || (!strings.Contains(accept, binding.MIMEJSON) && isMsgPack(c.ContentType())) {
+		c.Render(status, render.MsgPack{Data: obj})
+		return
+	}
+	c.JSON(status, obj)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// ExplainRequest asks how every volunteer fares for one slot. The slot is
+// judged against a saved schedule's assignments when schedule_id is set,
+// otherwise against the inline input's current assignments.
+type ExplainRequest struct {
+	models.ScheduleInput
+	ScheduleID string `json:"schedule_id,omitempty"`
+	ShiftID    string `json:"shift_id" binding:"required"`
+	Group      string `json:"group" binding:"required"`
+}
+
+// Explain returns every volunteer's pass/fail per constraint and ranking for
+// a shift and group, to answer questions like "why wasn't Alice picked?"
+func (h *Handler) Explain(c *gin.Context) {
+	var req ExplainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, ok := h.requestScheduler(c, req.ScheduleID, &req.ScheduleInput)
+	if !ok {
+		return
+	}
+
+	candidates, err := s.Explain(req.ShiftID, req.Group)
+	if errors.Is(err, scheduler.ErrUnknownShift) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shift not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"shift_id":   req.ShiftID,
+		"group":      req.Group,
+		"candidates": candidates,
+	})
+}
+
+// requestScheduler sets up a scheduler holding a saved schedule's
+// assignments when scheduleID is set, or the inline input's current
+// assignments otherwise. It responds with the error itself on failure.
+func (h *Handler) requestScheduler(c *gin.Context, scheduleID string, input *models.ScheduleInput) (*scheduler.Scheduler, bool) {
+	if scheduleID == "" {
+		if err := h.applyProfile(c, input); err != nil {
+			c.JSON(profileErrorStatus(err))
+			return nil, false
+		}
+		s, err := prepareScheduler(c.Request.Context(), input)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return s, true
+	}
+
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+	var schedule database.Schedule
+	if err := h.reader(c).Where("id = ? AND key_id = ?", scheduleID, apiKey.ID).First(&schedule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return nil, false
+	}
+	stored, result, err := decodeSchedule(&schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return nil, false
+	}
+	*input = stored
+	return scheduleFromResult(input, result.AssignedShifts), true
+}
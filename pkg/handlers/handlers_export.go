@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// exportFormat controls how times and numbers are written in exports. The
+// zero value writes RFC 3339 times and decimal points.
+type exportFormat struct {
+	dateLayout   string
+	timeLayout   string
+	decimalComma bool
+}
+
+// Date orders and decimal commas by language, with regional overrides.
+// Languages missing here use ISO dates and decimal points.
+var (
+	localeDates = map[string]string{
+		"en": "02/01/2006", "en-US": "01/02/2006", "en-CA": "2006-01-02",
+		"fr": "02/01/2006", "es": "02/01/2006", "it": "02/01/2006", "pt": "02/01/2006",
+		"nl": "02-01-2006", "de": "02.01.2006", "pl": "02.01.2006", "ru": "02.01.2006",
+		"da": "02.01.2006", "nb": "02.01.2006", "fi": "2.1.2006", "sv": "2006-01-02",
+	}
+	decimalCommaLanguages = []string{"fr", "es", "it", "pt", "nl", "de", "pl", "ru", "da", "nb", "fi", "sv"}
+)
+
+// parseExportFormat builds an export format from a BCP 47 locale such as
+// "de-DE" and a time format of "12h" or "24h". US English defaults to 12h
+// clocks, everyone else to 24h. Both empty keeps the default format.
+func parseExportFormat(locale, timeFormat string) (exportFormat, error) {
+	if locale == "" && timeFormat == "" {
+		return exportFormat{}, nil
+	}
+	lang, region, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	lang = strings.ToLower(lang)
+	if locale = lang; region != "" {
+		locale += "-" + strings.ToUpper(region)
+	}
+
+	f := exportFormat{dateLayout: "2006-01-02", timeLayout: "15:04"}
+	if layout, ok := localeDates[locale]; ok {
+		f.dateLayout = layout
+	} else if layout, ok := localeDates[lang]; ok {
+		f.dateLayout = layout
+	}
+	f.decimalComma = slices.Contains(decimalCommaLanguages, lang)
+	if locale == "en-US" {
+		f.timeLayout = "3:04 PM"
+	}
+
+	switch timeFormat {
+	case "":
+	case "12h":
+		f.timeLayout = "3:04 PM"
+	case "24h":
+		f.timeLayout = "15:04"
+	default:
+		return exportFormat{}, fmt.Errorf("unknown time_format %q, expected 12h or 24h", timeFormat)
+	}
+	return f, nil
+}
+
+// time formats a time in its own location
+func (f exportFormat) time(t time.Time) string {
+	if f.dateLayout == "" {
+		return t.Format(time.RFC3339)
+	}
+	return t.Format(f.dateLayout + " " + f.timeLayout)
+}
+
+// number formats a number with two decimals
+func (f exportFormat) number(v float64) string {
+	out := fmt.Sprintf("%.2f", v)
+	if f.decimalComma {
+		out = strings.Replace(out, ".", ",", 1)
+	}
+	return out
+}
+
+// writeAssignmentsCSV writes one row per volunteer assignment, with its note
+// and |-separated flags if any. With a decimal comma, fields are separated by
+// semicolons, as spreadsheets in those locales expect.
+func writeAssignmentsCSV(w io.Writer, shiftMap map[string]*models.Shift, volMap map[string]*models.Volunteer, notes []models.AssignmentNote, format exportFormat) error {
+	byAssignment := noteIndex(notes)
+	writer := csv.NewWriter(w)
+	if format.decimalComma {
+		writer.Comma = ';'
+	}
+	writer.Write([]string{"shift_id", "volunteer_id", "volunteer_name", "start", "end", "duration_hours", "note", "flags"})
+
+	for _, sh := range shiftMap {
+		for _, vid := range sh.Assigned {
+			v, ok := volMap[vid]
+			if !ok {
+				continue
+			}
+			duration := sh.End.Sub(sh.Start).Hours()
+			note := byAssignment[models.Assignment{ShiftID: sh.ID, VolunteerID: v.ID}]
+			writer.Write([]string{
+				sh.ID,
+				v.ID,
+				v.Name,
+				format.time(sh.Start),
+				format.time(sh.End),
+				format.number(duration),
+				note.Note,
+				strings.Join(note.Flags, "|"),
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// icsEscape escapes text values for iCalendar content lines
+func icsEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// writeScheduleICS writes an iCalendar file with one event per shift listing
+// its volunteers and any notes on their assignments
+func writeScheduleICS(w io.Writer, shiftMap map[string]*models.Shift, volMap map[string]*models.Volunteer, notes []models.AssignmentNote) error {
+	byAssignment := noteIndex(notes)
+	ids := make([]string, 0, len(shiftMap))
+	for id := range shiftMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	const stamp = "20060102T150405Z"
+	now := time.Now().UTC().Format(stamp)
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Shift Scheduler API//EN\r\n")
+	for _, id := range ids {
+		sh := shiftMap[id]
+		names := make([]string, 0, len(sh.Assigned))
+		for _, vid := range sh.Assigned {
+			name := vid
+			if v, ok := volMap[vid]; ok && v.Name != "" {
+				name = v.Name
+			}
+			note := byAssignment[models.Assignment{ShiftID: sh.ID, VolunteerID: vid}]
+			extra := append([]string(nil), note.Flags...)
+			if note.Note != "" {
+				extra = append(extra, note.Note)
+			}
+			if len(extra) > 0 {
+				name += " (" + strings.Join(extra, "; ") + ")"
+			}
+			names = append(names, name)
+		}
+		b.WriteString("BEGIN:VEVENT\r\n")
+		b.WriteString("UID:" + icsEscape(sh.ID) + "@shift-scheduler\r\n")
+		b.WriteString("DTSTAMP:" + now + "\r\n")
+		b.WriteString("DTSTART:" + sh.Start.UTC().Format(stamp) + "\r\n")
+		b.WriteString("DTEND:" + sh.End.UTC().Format(stamp) + "\r\n")
+		b.WriteString("SUMMARY:Shift " + icsEscape(sh.ID) + "\r\n")
+		b.WriteString("DESCRIPTION:" + icsEscape("Volunteers: "+strings.Join(names, ", ")) + "\r\n")
+		b.WriteString("END:VEVENT\r\n")
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
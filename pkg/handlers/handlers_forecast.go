@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Forecast reports, per shift and group, how many volunteers could fill the
+// open slots against how many are needed, without scheduling anyone, so
+// coordinators can see recruitment gaps early
+func (h *Handler) Forecast(c *gin.Context) {
+	var input models.ScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, ok := h.requestScheduler(c, "", &input)
+	if !ok {
+		return
+	}
+
+	forecast := s.Forecast()
+	short, totalGap := 0, 0
+	for _, f := range forecast {
+		if f.Gap > 0 {
+			short++
+			totalGap += f.Gap
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"forecast":     forecast,
+		"groups_short": short,
+		"total_gap":    totalGap,
+	})
+}
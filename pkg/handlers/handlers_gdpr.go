@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// actor describes who is making a request, for audit and history entries
+func actor(c *gin.Context) string {
+	var who string
+	if apiKeyRaw, exists := c.Get("apiKey"); exists {
+		who = "key:" + apiKeyRaw.(*database.APIKey).Name
+	}
+	if token, ok := c.Get("serviceToken"); ok {
+		who = "token:" + token.(*database.ServiceToken).Name
+	} else if admin, ok := c.Get("impersonatedBy"); ok {
+		who = fmt.Sprintf("admin:%v (impersonating %s)", admin, who)
+	} else if username := c.GetString("username"); username != "" {
+		who = "admin:" + username
+	}
+	return who
+}
+
+// audit records an entry in the audit log. Failures are logged rather than
+// returned, so auditing never blocks the request it describes.
+func (h *Handler) audit(c *gin.Context, action, subject, detail string) {
+	entry := database.AuditLog{Actor: actor(c), Action: action, Subject: subject, Detail: detail}
+	if apiKeyRaw, exists := c.Get("apiKey"); exists {
+		entry.KeyID = apiKeyRaw.(*database.APIKey).ID
+	}
+	if err := h.DB.Create(&entry).Error; err != nil {
+		c.Error(err)
+	}
+}
+
+// findVolunteer returns the volunteer with the given ID from a list
+func findVolunteer(volunteers []models.Volunteer, id string) *models.Volunteer {
+	for i := range volunteers {
+		if volunteers[i].ID == id {
+			return &volunteers[i]
+		}
+	}
+	return nil
+}
+
+// ExportVolunteerData returns everything stored about a volunteer across the
+// calling key's saved schedules, draft problems, debug captures and async
+// jobs
+func (h *Handler) ExportVolunteerData(c *gin.Context) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+	volID := c.Param("id")
+
+	schedules := make([]gin.H, 0)
+	var stored []database.Schedule
+	if err := h.reader(c).Where("key_id = ?", apiKey.ID).Find(&stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load schedules"})
+		return
+	}
+	for i := range stored {
+		input, result, err := decodeSchedule(&stored[i])
+		if err != nil {
+			continue
+		}
+		vol := findVolunteer(input.Volunteers, volID)
+		if vol == nil {
+			continue
+		}
+		shifts := volunteerShifts(result.AssignedShifts, volID)
+		notes := make([]models.AssignmentNote, 0)
+		for _, n := range result.Notes {
+			if n.VolunteerID == volID {
+				notes = append(notes, n)
+			}
+		}
+		schedules = append(schedules, gin.H{
+			"schedule_id":     stored[i].ID,
+			"created_at":      stored[i].CreatedAt,
+			"volunteer":       vol,
+			"assigned_shifts": shifts,
+			"notes":           notes,
+		})
+	}
+
+	drafts := make([]gin.H, 0)
+	var items []database.DraftItem
+	if err := h.reader(c).Joins("JOIN draft_problems ON draft_problems.id = draft_items.problem_id").
+		Where("draft_problems.key_id = ? AND draft_items.kind = ? AND draft_items.item_id = ?", apiKey.ID, draftVolunteer, volID).
+		Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load drafts"})
+		return
+	}
+	for _, item := range items {
+		vols := make([]models.Volunteer, 1)
+		if json.Unmarshal([]byte(item.Data), &vols[0]) != nil || pii.OpenVolunteers(vols) != nil {
+			continue
+		}
+		drafts = append(drafts, gin.H{"problem_id": item.ProblemID, "volunteer": vols[0]})
+	}
+
+	captures := make([]gin.H, 0)
+	var caps []database.DebugCapture
+	if err := h.reader(c).Where("key_id = ?", apiKey.ID).Find(&caps).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load captures"})
+		return
+	}
+	for _, capture := range caps {
+		var input models.ScheduleInput
+		if json.Unmarshal([]byte(capture.Request), &input) != nil || pii.OpenVolunteers(input.Volunteers) != nil {
+			continue
+		}
+		if vol := findVolunteer(input.Volunteers, volID); vol != nil {
+			captures = append(captures, gin.H{"capture_id": capture.ID, "created_at": capture.CreatedAt, "volunteer": vol})
+		}
+	}
+
+	jobs := make([]gin.H, 0)
+	var storedJobs []database.Job
+	if err := h.reader(c).Where("key_id = ?", apiKey.ID).Find(&storedJobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load jobs"})
+		return
+	}
+	for i := range storedJobs {
+		input, ok := jobVolunteerInput(&storedJobs[i], volID)
+		if !ok {
+			continue
+		}
+		var result models.ScheduleResponse
+		json.Unmarshal([]byte(storedJobs[i].Result), &result)
+		jobs = append(jobs, gin.H{
+			"job_id":          storedJobs[i].ID,
+			"status":          storedJobs[i].Status,
+			"created_at":      storedJobs[i].CreatedAt,
+			"volunteer":       findVolunteer(input.Volunteers, volID),
+			"assigned_shifts": volunteerShifts(result.AssignedShifts, volID),
+		})
+	}
+
+	h.audit(c, "volunteer.export", volID, fmt.Sprintf("%d schedules, %d drafts, %d captures, %d jobs", len(schedules), len(drafts), len(captures), len(jobs)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"volunteer_id": volID,
+		"schedules":    schedules,
+		"drafts":       drafts,
+		"captures":     captures,
+		"jobs":         jobs,
+	})
+}
+
+// volunteerShifts lists the shifts a volunteer is assigned to
+func volunteerShifts(assignedShifts map[string][]string, volID string) []string {
+	shifts := make([]string, 0)
+	for shiftID, assigned := range assignedShifts {
+		if slices.Contains(assigned, volID) {
+			shifts = append(shifts, shiftID)
+		}
+	}
+	slices.Sort(shifts)
+	return shifts
+}
+
+// jobVolunteerInput decodes a job's input, reporting whether it includes
+// the volunteer
+func jobVolunteerInput(job *database.Job, volID string) (models.ScheduleInput, bool) {
+	input, err := decodeStoredInput(job.Input)
+	if err != nil {
+		return input, false
+	}
+	return input, findVolunteer(input.Volunteers, volID) != nil
+}
+
+// decodeStoredInput decodes a stored schedule input, opening its sealed
+// volunteer data
+func decodeStoredInput(data string) (models.ScheduleInput, error) {
+	var input models.ScheduleInput
+	if err := json.Unmarshal([]byte(data), &input); err != nil {
+		return input, err
+	}
+	return input, pii.OpenVolunteers(input.Volunteers)
+}
+
+// unreadableRows lists the stored rows an erasure couldn't decode, by table.
+// Erasure refuses to report success while any of them may still hold the
+// volunteer's data.
+type unreadableRows map[string][]string
+
+func (u unreadableRows) Error() string {
+	return "stored data could not be decoded"
+}
+
+// anonymizeInput replaces a volunteer's ID and personal data in a schedule
+// input, including where other volunteers name them as a pairing
+func anonymizeInput(input *models.ScheduleInput, volID, alias string) bool {
+	vol := findVolunteer(input.Volunteers, volID)
+	if vol == nil {
+		return false
+	}
+	vol.ID = alias
+	vol.Name, vol.Email, vol.Phone = "", "", ""
+	vol.BusyCalendarURL, vol.BusyTimes = "", nil
+	for i := range input.Volunteers {
+		anonymizePairings(&input.Volunteers[i], volID, alias)
+	}
+	for _, list := range [][]models.Assignment{input.CurrentAssignments, input.PreviousAssignments, input.HintAssignments} {
+		for i := range list {
+			if list[i].VolunteerID == volID {
+				list[i].VolunteerID = alias
+			}
+		}
+	}
+	for i := range input.UnassignedShifts {
+		replaceID(input.UnassignedShifts[i].Assigned, volID, alias)
+	}
+	return true
+}
+
+// anonymizePairings replaces a volunteer's ID in another volunteer's
+// must_work_with and cannot_work_with lists, reporting whether it was there
+func anonymizePairings(v *models.Volunteer, volID, alias string) bool {
+	found := slices.Contains(v.MustWorkWith, volID) || slices.Contains(v.CannotWorkWith, volID)
+	replaceID(v.MustWorkWith, volID, alias)
+	replaceID(v.CannotWorkWith, volID, alias)
+	return found
+}
+
+// anonymizeResult replaces a volunteer's ID in a schedule result
+func anonymizeResult(result *models.ScheduleResponse, volID, alias string) {
+	for _, assigned := range result.AssignedShifts {
+		replaceID(assigned, volID, alias)
+	}
+	for _, list := range result.ShiftAssignments {
+		for i := range list {
+			if list[i].VolunteerID == volID {
+				list[i].VolunteerID = alias
+			}
+		}
+	}
+	for _, roles := range result.Roles {
+		for _, vols := range roles {
+			replaceID(vols, volID, alias)
+		}
+	}
+	if stats, ok := result.Volunteers[volID]; ok {
+		delete(result.Volunteers, volID)
+		result.Volunteers[alias] = stats
+	}
+	anonymizeNotes(result.Notes, volID, alias)
+}
+
+// anonymizeNotes replaces a volunteer's ID in assignment notes and clears
+// the free text, which may mention them; flags are kept
+func anonymizeNotes(notes []models.AssignmentNote, volID, alias string) {
+	for i := range notes {
+		if notes[i].VolunteerID == volID {
+			notes[i].VolunteerID = alias
+			notes[i].Note = ""
+		}
+	}
+}
+
+// anonymizeHistory replaces a volunteer's ID in the stored results and change
+// details of a schedule's history
+func anonymizeHistory(tx *gorm.DB, scheduleID, volID, alias string) error {
+	var events []database.ScheduleEvent
+	if err := tx.Where("schedule_id = ?", scheduleID).Find(&events).Error; err != nil {
+		return err
+	}
+	for _, e := range events {
+		updates := map[string]any{}
+		var result models.ScheduleResponse
+		if json.Unmarshal([]byte(e.Result), &result) == nil {
+			anonymizeResult(&result, volID, alias)
+			data, err := json.Marshal(&result)
+			if err != nil {
+				return err
+			}
+			updates["result"] = string(data)
+		}
+		// Edits, undos and redos list the assignments and notes they changed
+		var detail map[string]json.RawMessage
+		if json.Unmarshal([]byte(e.Detail), &detail) == nil {
+			for _, key := range []string{"add", "remove"} {
+				var list []models.Assignment
+				if json.Unmarshal(detail[key], &list) != nil {
+					continue
+				}
+				for i := range list {
+					if list[i].VolunteerID == volID {
+						list[i].VolunteerID = alias
+					}
+				}
+				data, err := json.Marshal(list)
+				if err != nil {
+					return err
+				}
+				detail[key] = data
+			}
+			var notes []models.AssignmentNote
+			if json.Unmarshal(detail["notes"], &notes) == nil {
+				anonymizeNotes(notes, volID, alias)
+				data, err := json.Marshal(notes)
+				if err != nil {
+					return err
+				}
+				detail["notes"] = data
+			}
+			data, err := json.Marshal(detail)
+			if err != nil {
+				return err
+			}
+			updates["detail"] = string(data)
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Model(&e).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceID swaps one ID for another in place
+func replaceID(ids []string, from, to string) {
+	for i := range ids {
+		if ids[i] == from {
+			ids[i] = to
+		}
+	}
+}
+
+// EraseVolunteerData anonymizes a volunteer across the calling key's saved
+// schedules, draft problems and debug captures. The volunteer's ID is
+// replaced with a random alias and their personal fields are cleared. Async
+// jobs that include the volunteer are deleted; a schedule a job saved is
+// anonymized with the rest. If any row can't be decoded, nothing is erased
+// and the rows are listed, since they may still hold the volunteer's data.
+func (h *Handler) EraseVolunteerData(c *gin.Context) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+	volID := c.Param("id")
+
+	suffix, err := newID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create alias"})
+		return
+	}
+	alias := "erased-" + suffix[:12]
+
+	counts := map[string]int{"schedules": 0, "drafts": 0, "captures": 0, "jobs": 0}
+	var erasedJobs []string
+	unreadable := unreadableRows{}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var stored []database.Schedule
+		if err := tx.Where("key_id = ?", apiKey.ID).Find(&stored).Error; err != nil {
+			return err
+		}
+		for i := range stored {
+			input, result, err := decodeSchedule(&stored[i])
+			if err != nil {
+				unreadable["schedules"] = append(unreadable["schedules"], stored[i].ID)
+				continue
+			}
+			if !anonymizeInput(&input, volID, alias) {
+				continue
+			}
+			anonymizeResult(&result, volID, alias)
+			inputJSON, err := sealedInputJSON(&input)
+			if err != nil {
+				return err
+			}
+			resultJSON, err := json.Marshal(&result)
+			if err != nil {
+				return err
+			}
+			if err := anonymizeHistory(tx, stored[i].ID, volID, alias); err != nil {
+				return err
+			}
+			if err := tx.Model(&stored[i]).Updates(map[string]any{"input": string(inputJSON), "result": string(resultJSON), "version": gorm.Expr("version + 1")}).Error; err != nil {
+				return err
+			}
+			if err := storeAssignments(tx, stored[i].ID, &result); err != nil {
+				return err
+			}
+			if err := recordEvent(tx, c, stored[i].ID, stored[i].Version+1, eventErase, gin.H{"alias": alias}, string(resultJSON)); err != nil {
+				return err
+			}
+			counts["schedules"]++
+		}
+
+		var problemIDs []string
+		if err := tx.Model(&database.DraftProblem{}).Where("key_id = ?", apiKey.ID).Pluck("id", &problemIDs).Error; err != nil {
+			return err
+		}
+		if len(problemIDs) > 0 {
+			var items []database.DraftItem
+			if err := tx.Where("problem_id IN ? AND ((kind = ? AND item_id = ?) OR (kind = ? AND item_id LIKE ?))",
+				problemIDs, draftVolunteer, volID, draftAssignment, "%/"+volID).Find(&items).Error; err != nil {
+				return err
+			}
+			for _, item := range items {
+				updates := map[string]any{}
+				switch item.Kind {
+				case draftVolunteer:
+					data, err := json.Marshal(models.Volunteer{ID: alias})
+					if err != nil {
+						return err
+					}
+					updates["item_id"], updates["data"] = alias, string(data)
+					counts["drafts"]++
+				case draftAssignment:
+					var a models.Assignment
+					if json.Unmarshal([]byte(item.Data), &a) != nil {
+						unreadable["drafts"] = append(unreadable["drafts"], item.ProblemID+"/"+item.ItemID)
+						continue
+					}
+					if a.VolunteerID != volID {
+						continue
+					}
+					a.VolunteerID = alias
+					data, err := json.Marshal(&a)
+					if err != nil {
+						return err
+					}
+					updates["item_id"], updates["data"] = strings.TrimSuffix(item.ItemID, volID)+alias, string(data)
+				}
+				if err := tx.Model(&item).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+
+			// Other volunteers may name them as a pairing. IDs aren't
+			// sealed, so the data is matched and rewritten as it's stored.
+			var paired []database.DraftItem
+			if err := tx.Where("problem_id IN ? AND kind = ? AND item_id <> ? AND data LIKE ?",
+				problemIDs, draftVolunteer, volID, "%"+volID+"%").Find(&paired).Error; err != nil {
+				return err
+			}
+			for _, item := range paired {
+				var v models.Volunteer
+				if json.Unmarshal([]byte(item.Data), &v) != nil {
+					unreadable["drafts"] = append(unreadable["drafts"], item.ProblemID+"/"+item.ItemID)
+					continue
+				}
+				if !anonymizePairings(&v, volID, alias) {
+					continue
+				}
+				data, err := json.Marshal(&v)
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&item).Update("data", string(data)).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var caps []database.DebugCapture
+		if err := tx.Where("key_id = ?", apiKey.ID).Find(&caps).Error; err != nil {
+			return err
+		}
+		for i := range caps {
+			var result models.ScheduleResponse
+			input, err := decodeStoredInput(caps[i].Request)
+			if err != nil {
+				unreadable["captures"] = append(unreadable["captures"], strconv.FormatUint(uint64(caps[i].ID), 10))
+				continue
+			}
+			if !anonymizeInput(&input, volID, alias) {
+				continue
+			}
+			if json.Unmarshal([]byte(caps[i].Response), &result) == nil {
+				anonymizeResult(&result, volID, alias)
+			}
+			request, err := sealedInputJSON(&input)
+			if err != nil {
+				return err
+			}
+			response, err := json.Marshal(&result)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&caps[i]).Updates(map[string]any{"request": string(request), "response": string(response)}).Error; err != nil {
+				return err
+			}
+			counts["captures"]++
+		}
+
+		var jobs []database.Job
+		if err := tx.Where("key_id = ?", apiKey.ID).Find(&jobs).Error; err != nil {
+			return err
+		}
+		for i := range jobs {
+			input, err := decodeStoredInput(jobs[i].Input)
+			if err != nil {
+				unreadable["jobs"] = append(unreadable["jobs"], jobs[i].ID)
+				continue
+			}
+			if findVolunteer(input.Volunteers, volID) == nil {
+				continue
+			}
+			if err := tx.Delete(&jobs[i]).Error; err != nil {
+				return err
+			}
+			erasedJobs = append(erasedJobs, jobs[i].ID)
+		}
+		counts["jobs"] = len(erasedJobs)
+		if len(unreadable) > 0 {
+			return unreadable
+		}
+
+		// Exports name the volunteer as the audit entry's subject
+		return tx.Model(&database.AuditLog{}).
+			Where("key_id = ? AND action = ? AND subject = ?", apiKey.ID, "volunteer.export", volID).
+			Update("subject", alias).Error
+	})
+	var rows unreadableRows
+	if errors.As(err, &rows) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Some stored data could not be decoded, so nothing was erased",
+			"unreadable": rows,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not erase volunteer data"})
+		return
+	}
+	// A deleted job can't start, and one solving in this process is stopped
+	for _, id := range erasedJobs {
+		h.jobs.remove(id)
+	}
+
+	// The audit entry deliberately records only the alias, not the erased ID
+	h.audit(c, "volunteer.erase", alias, fmt.Sprintf("%d schedules, %d drafts, %d captures, %d jobs", counts["schedules"], counts["drafts"], counts["captures"], counts["jobs"]))
+
+	c.JSON(http.StatusOK, gin.H{
+		"alias":   alias,
+		"updated": counts,
+	})
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Kinds of schedule change recorded in the history
+const (
+	eventSolve      = "solve"
+	eventImport     = "import"
+	eventEdit       = "edit"
+	eventUndo       = "undo"
+	eventRedo       = "redo"
+	eventErase      = "erase"
+	eventRegenerate = "regenerate"
+)
+
+// recordEvent appends a change to a schedule's history. detail may be nil.
+func recordEvent(tx *gorm.DB, c *gin.Context, scheduleID string, version int, kind string, detail any, result string) error {
+	event := database.ScheduleEvent{
+		ScheduleID: scheduleID,
+		Version:    version,
+		Kind:       kind,
+		Actor:      actor(c),
+		Result:     result,
+	}
+	if detail != nil {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		event.Detail = string(data)
+	}
+	return tx.Create(&event).Error
+}
+
+// GetScheduleHistory lists every change made to a saved schedule, oldest first
+func (h *Handler) GetScheduleHistory(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+
+	var events []database.ScheduleEvent
+	if err := h.reader(c).Where("schedule_id = ?", schedule.ID).Order("version, id").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load history"})
+		return
+	}
+
+	history := make([]gin.H, len(events))
+	for i, e := range events {
+		entry := gin.H{
+			"version":    e.Version,
+			"kind":       e.Kind,
+			"actor":      e.Actor,
+			"created_at": e.CreatedAt,
+		}
+		if e.Detail != "" {
+			entry["detail"] = json.RawMessage(e.Detail)
+		}
+		history[i] = entry
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id": schedule.ID,
+		"version":     schedule.Version,
+		"events":      history,
+	})
+}
+
+// assignmentMove is a volunteer taken off one shift and put on another
+type assignmentMove struct {
+	VolunteerID string `json:"volunteer_id"`
+	FromShiftID string `json:"from_shift_id"`
+	ToShiftID   string `json:"to_shift_id"`
+}
+
+// assignmentChanges is assignmentDiff with each volunteer's removals paired
+// off against their additions, in shift order, as moves
+func assignmentChanges(from, to map[string][]string) (added, removed []models.Assignment, moved []assignmentMove) {
+	add, remove := assignmentDiff(from, to)
+	adds := make(map[string][]string)
+	for _, a := range add {
+		adds[a.VolunteerID] = append(adds[a.VolunteerID], a.ShiftID)
+	}
+	added, removed, moved = []models.Assignment{}, []models.Assignment{}, []assignmentMove{}
+	for _, r := range remove {
+		if shifts := adds[r.VolunteerID]; len(shifts) > 0 {
+			moved = append(moved, assignmentMove{VolunteerID: r.VolunteerID, FromShiftID: r.ShiftID, ToShiftID: shifts[0]})
+			adds[r.VolunteerID] = shifts[1:]
+		} else {
+			removed = append(removed, r)
+		}
+	}
+	for _, a := range add {
+		if slices.Contains(adds[a.VolunteerID], a.ShiftID) {
+			added = append(added, a)
+		}
+	}
+	return added, removed, moved
+}
+
+// parseVersion reads a schedule version given as 3 or v3
+func parseVersion(s string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid version %q; use a version such as v1", s)
+	}
+	return v, nil
+}
+
+// DiffSchedule compares two versions of a saved schedule, listing the
+// assignments added and removed between them and the volunteers moved from
+// one shift to another. to defaults to the current version and from to the
+// one before it.
+func (h *Handler) DiffSchedule(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+
+	to, from := schedule.Version, schedule.Version-1
+	var err error
+	if s := c.Query("to"); s != "" {
+		if to, err = parseVersion(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		from = to - 1
+	}
+	if s := c.Query("from"); s != "" {
+		if from, err = parseVersion(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if from == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Version 1 has no earlier version; pass from"})
+		return
+	}
+
+	var events []database.ScheduleEvent
+	if err := h.reader(c).Where("schedule_id = ? AND version IN ?", schedule.ID, []int{from, to}).Order("id").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load history"})
+		return
+	}
+	results := make(map[int]*models.ScheduleResponse, 2)
+	for _, e := range events {
+		var result models.ScheduleResponse
+		if e.Result != "" && json.Unmarshal([]byte(e.Result), &result) == nil {
+			results[e.Version] = &result
+		}
+	}
+	for _, v := range []int{from, to} {
+		if results[v] == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No history is stored for version %d", v)})
+			return
+		}
+	}
+
+	added, removed, moved := assignmentChanges(results[from].AssignedShifts, results[to].AssignedShifts)
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id": schedule.ID,
+		"from":        from,
+		"to":          to,
+		"added":       added,
+		"removed":     removed,
+		"moved":       moved,
+	})
+}
+
+// UndoRequest names the version an undo or redo is based on
+type UndoRequest struct {
+	Version int `json:"version"`
+	// Force applies the step even if the restored assignments now break a constraint
+	Force bool `json:"force"`
+}
+
+// historyStep is the detail recorded for an undo or redo
+type historyStep struct {
+	Of     int                     `json:"of"`
+	Add    []models.Assignment     `json:"add"`
+	Remove []models.Assignment     `json:"remove"`
+	Notes  []models.AssignmentNote `json:"notes,omitempty"`
+}
+
+// editStacks replays a schedule's history into the versions of the manual
+// edits that can be undone and the undone edits that can be redone, most
+// recent last. A new edit clears the redo stack, and regenerating the
+// schedule clears both.
+func editStacks(events []database.ScheduleEvent) (undo, redo []int) {
+	for _, e := range events {
+		switch e.Kind {
+		case eventRegenerate:
+			undo, redo = nil, nil
+		case eventEdit:
+			undo = append(undo, e.Version)
+			redo = nil
+		case eventUndo:
+			if n := len(undo); n > 0 {
+				redo = append(redo, undo[n-1])
+				undo = undo[:n-1]
+			}
+		case eventRedo:
+			if n := len(redo); n > 0 {
+				undo = append(undo, redo[n-1])
+				redo = redo[:n-1]
+			}
+		}
+	}
+	return undo, redo
+}
+
+// assignmentDiff lists the assignments to add and remove to turn from into to
+func assignmentDiff(from, to map[string][]string) (add, remove []models.Assignment) {
+	shiftIDs := make([]string, 0, len(from)+len(to))
+	for id := range from {
+		shiftIDs = append(shiftIDs, id)
+	}
+	for id := range to {
+		if _, ok := from[id]; !ok {
+			shiftIDs = append(shiftIDs, id)
+		}
+	}
+	slices.Sort(shiftIDs)
+	for _, id := range shiftIDs {
+		for _, vol := range from[id] {
+			if !slices.Contains(to[id], vol) {
+				remove = append(remove, models.Assignment{ShiftID: id, VolunteerID: vol})
+			}
+		}
+		for _, vol := range to[id] {
+			if !slices.Contains(from[id], vol) {
+				add = append(add, models.Assignment{ShiftID: id, VolunteerID: vol})
+			}
+		}
+	}
+	return add, remove
+}
+
+// noteDiff lists the note changes that turn from into to. Notes on
+// assignments missing from to are left out, since removing the assignment
+// drops them anyway.
+func noteDiff(from, to []models.AssignmentNote, assigned map[string][]string) []models.AssignmentNote {
+	want := noteIndex(to)
+	var changes []models.AssignmentNote
+	for _, n := range from {
+		a := models.Assignment{ShiftID: n.ShiftID, VolunteerID: n.VolunteerID}
+		if _, ok := want[a]; !ok && slices.Contains(assigned[a.ShiftID], a.VolunteerID) {
+			changes = append(changes, models.AssignmentNote{ShiftID: a.ShiftID, VolunteerID: a.VolunteerID})
+		}
+	}
+	have := noteIndex(from)
+	for _, n := range to {
+		old, ok := have[models.Assignment{ShiftID: n.ShiftID, VolunteerID: n.VolunteerID}]
+		if !ok || old.Note != n.Note || !slices.Equal(old.Flags, n.Flags) {
+			changes = append(changes, n)
+		}
+	}
+	return changes
+}
+
+// UndoSchedule reverts the most recent manual edit of a saved schedule
+func (h *Handler) UndoSchedule(c *gin.Context) {
+	h.stepHistory(c, eventUndo)
+}
+
+// RedoSchedule reapplies the most recently undone edit of a saved schedule
+func (h *Handler) RedoSchedule(c *gin.Context) {
+	h.stepHistory(c, eventRedo)
+}
+
+// stepHistory restores the assignments from before an edit (undo) or after
+// it (redo). The restored assignments are re-validated like a manual edit,
+// and the step is recorded as a new version.
+func (h *Handler) stepHistory(c *gin.Context, kind string) {
+	var req UndoRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	schedule, ok := h.lockedSchedule(c, req.Version)
+	if !ok {
+		return
+	}
+
+	var events []database.ScheduleEvent
+	if err := h.DB.Where("schedule_id = ?", schedule.ID).Order("version, id").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load history"})
+		return
+	}
+	undo, redo := editStacks(events)
+	stack, restore := undo, -1
+	if kind == eventRedo {
+		stack, restore = redo, 0
+	}
+	if len(stack) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Nothing to " + kind})
+		return
+	}
+	of := stack[len(stack)-1]
+
+	var snapshot models.ScheduleResponse
+	found := false
+	for _, e := range events {
+		if e.Version == of+restore {
+			found = json.Unmarshal([]byte(e.Result), &snapshot) == nil
+		}
+	}
+	if !found {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No history is stored for that version"})
+		return
+	}
+
+	input, result, err := decodeSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return
+	}
+	step := historyStep{Of: of}
+	step.Add, step.Remove = assignmentDiff(result.AssignedShifts, snapshot.AssignedShifts)
+	step.Notes = noteDiff(result.Notes, snapshot.Notes, snapshot.AssignedShifts)
+	resp, violations, err := applyEdit(&input, &result, &ScheduleEdit{Add: step.Add, Remove: step.Remove, Notes: step.Notes})
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if len(violations) > 0 && !req.Force {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Restored assignments break scheduling constraints; resend with force to apply anyway", "violations": violations})
+		return
+	}
+
+	h.finishChange(c, schedule, kind, &step, &resp, violations, nil)
+}
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Job statuses. Queued and running jobs are unfinished; the rest are final.
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+)
+
+// unfinishedJobs lists the statuses a job can still leave
+var unfinishedJobs = []string{jobQueued, jobRunning}
+
+// defaultJobWorkers is how many jobs solve at once unless ASYNC_JOB_WORKERS
+// says otherwise. The optimal search already uses every CPU for one job.
+const defaultJobWorkers = 2
+
+// Each queued or running job holds its decoded input in memory, so this
+// process takes at most defaultMaxPendingJobs of them, and
+// defaultMaxPendingJobsPerKey per key, unless ASYNC_JOB_MAX_PENDING and
+// ASYNC_JOB_MAX_PENDING_PER_KEY say otherwise
+const (
+	defaultMaxPendingJobs       = 100
+	defaultMaxPendingJobsPerKey = 10
+)
+
+// Errors from jobQueue.admit
+var (
+	errJobQueueFull = errors.New("job queue is full")
+	errKeyJobsFull  = errors.New("too many pending jobs for this key")
+)
+
+// jobLease is how long a job stays claimed by its instance without being
+// renewed, and jobLeaseRenewal how often a live instance renews it
+const (
+	jobLease        = 2 * time.Minute
+	jobLeaseRenewal = 30 * time.Second
+)
+
+// instanceID identifies this process as the owner of the jobs it runs
+var instanceID, _ = newID()
+
+// jobQueue tracks the jobs this process is running so they can be
+// cancelled, and limits how many of them solve at once
+type jobQueue struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	slots   chan struct{}
+	pending map[uint]int // unfinished jobs by key
+	total   int
+}
+
+// admit reserves room for one more of a key's jobs, or says why there's none.
+// The reservation is given back with leave.
+func (q *jobQueue) admit(keyID uint) error {
+	maxTotal := cmp.Or(envInt("ASYNC_JOB_MAX_PENDING"), defaultMaxPendingJobs)
+	maxPerKey := cmp.Or(envInt("ASYNC_JOB_MAX_PENDING_PER_KEY"), defaultMaxPendingJobsPerKey)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.total >= maxTotal {
+		return errJobQueueFull
+	}
+	if q.pending[keyID] >= maxPerKey {
+		return errKeyJobsFull
+	}
+	if q.pending == nil {
+		q.pending = make(map[uint]int)
+	}
+	q.pending[keyID]++
+	q.total++
+	return nil
+}
+
+// leave gives back a reservation taken by admit
+func (q *jobQueue) leave(keyID uint) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[keyID]--; q.pending[keyID] <= 0 {
+		delete(q.pending, keyID)
+	}
+	q.total--
+}
+
+// add registers a job's cancel function
+func (q *jobQueue) add(id string, cancel context.CancelFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.cancels == nil {
+		q.cancels = make(map[string]context.CancelFunc)
+	}
+	q.cancels[id] = cancel
+}
+
+// remove forgets a job, releasing its context
+func (q *jobQueue) remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cancel, ok := q.cancels[id]; ok {
+		cancel()
+		delete(q.cancels, id)
+	}
+}
+
+// acquire waits for a free worker slot, returning false if ctx ends first
+func (q *jobQueue) acquire(ctx context.Context) bool {
+	q.mu.Lock()
+	if q.slots == nil {
+		workers := envInt("ASYNC_JOB_WORKERS")
+		if workers == 0 {
+			workers = defaultJobWorkers
+		}
+		q.slots = make(chan struct{}, workers)
+	}
+	slots := q.slots
+	q.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot taken by acquire
+func (q *jobQueue) release() {
+	<-q.slots
+}
+
+// ScheduleAsync queues a scheduling request and answers straight away with
+// the job's ID, for runs that would outlast the client's HTTP timeout. The
+// body is the same as for /api/schedule; poll GET /api/jobs/:id for the
+// result, or pass ?webhook_url= to be told when the job finishes.
+func (h *Handler) ScheduleAsync(c *gin.Context) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+	webhookURL := c.Query("webhook_url")
+	if webhookURL != "" {
+		if err := h.checkJobWebhook(apiKey.ID, webhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Room is reserved before the body is read, and handed to the job
+	// once it's queued
+	switch err := h.jobs.admit(apiKey.ID); {
+	case errors.Is(err, errKeyJobsFull):
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many unfinished jobs for this key; wait for some to finish"})
+		return
+	case errors.Is(err, errJobQueueFull):
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The job queue is full; try again later"})
+		return
+	}
+	queued := false
+	defer func() {
+		if !queued {
+			h.jobs.leave(apiKey.ID)
+		}
+	}()
+
+	var input models.ScheduleInput
+	if err := bindBody(c, &input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.applyProfile(c, &input); err != nil {
+		status, body := profileErrorStatus(err)
+		c.JSON(status, body)
+		return
+	}
+	inputJSON, err := sealedInputJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not encrypt schedule input"})
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create job"})
+		return
+	}
+
+	leaseUntil := time.Now().Add(jobLease)
+	job := database.Job{
+		ID:         id,
+		KeyID:      apiKey.ID,
+		Status:     jobQueued,
+		Input:      string(inputJSON),
+		WebhookURL: webhookURL,
+		Instance:   instanceID,
+		LeaseUntil: &leaseUntil,
+	}
+	if err := h.DB.Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create job"})
+		return
+	}
+
+	// The job outlives the request, so it gets its own context and a copy of
+	// the gin context for saving and usage
+	ctx, cancel := context.WithCancel(context.Background())
+	h.jobs.add(id, cancel)
+	jobCtx := c.Copy()
+	queued = true
+	go func() {
+		defer h.jobs.leave(apiKey.ID)
+		h.runJob(ctx, jobCtx, id, &input, inputJSON)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": id,
+		"status": jobQueued,
+		"url":    externalURL(c, "/api/jobs/"+id),
+	})
+}
+
+// runJob solves a queued job and records the outcome. A job cancelled
+// meanwhile keeps its cancelled status; whatever it produced is dropped.
+func (h *Handler) runJob(ctx context.Context, c *gin.Context, id string, input *models.ScheduleInput, inputJSON []byte) {
+	untrack := trackJobNames(input.Volunteers)
+	defer h.jobs.remove(id)
+	stop := make(chan struct{})
+	defer close(stop)
+	go h.renewJobLease(id, stop)
+	if !h.jobs.acquire(ctx) {
+		untrack()
+		return
+	}
+	defer h.jobs.release()
+	// Delivery may wait out retries, so it doesn't hold the worker slot.
+	// The names stay masked until it's done.
+	defer func() {
+		go func() {
+			h.notifyJob(c, id)
+			untrack()
+		}()
+	}()
+
+	now := time.Now()
+	if !h.updateJob(id, jobQueued, map[string]any{"status": jobRunning, "started_at": now}) {
+		return
+	}
+
+	resp, err := buildSchedule(ctx, input)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		h.failJob(id, err.Error())
+		return
+	}
+	if input.Save {
+		// A job cancelled or erased through another instance is still
+		// solving here, but mustn't save
+		var running int64
+		if h.DB.Model(&database.Job{}).Where("id = ? AND status = ?", id, jobRunning).Count(&running); running == 0 {
+			return
+		}
+		if _, err := h.saveSchedule(c, eventSolve, inputJSON, &resp); err != nil {
+			_, body := saveErrorStatus(err)
+			h.failJob(id, body["error"].(string))
+			return
+		}
+	}
+	h.RecordUsage(c, len(resp.AssignedShifts), len(resp.Volunteers))
+
+	var result []byte
+	if input.StrictParity {
+		result, err = json.Marshal(parityResponse(resp))
+	} else {
+		result, err = json.Marshal(resp)
+	}
+	if err != nil {
+		h.failJob(id, "Could not encode result")
+		return
+	}
+	h.updateJob(id, jobRunning, map[string]any{
+		"status":      jobSucceeded,
+		"result":      string(result),
+		"schedule_id": resp.ScheduleID,
+		"finished_at": time.Now(),
+	})
+}
+
+// updateJob applies updates to a job if it still has status from, reporting
+// whether it did. Checking the status keeps a cancellation from being
+// overwritten, even one made through another instance.
+func (h *Handler) updateJob(id, from string, updates map[string]any) bool {
+	result := h.DB.Model(&database.Job{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if result.Error != nil {
+		log.Printf("jobs: could not update job %s: %v", id, result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// failJob marks a running job failed
+func (h *Handler) failJob(id, message string) {
+	h.updateJob(id, jobRunning, map[string]any{"status": jobFailed, "error": message, "finished_at": time.Now()})
+}
+
+// renewJobLease keeps a job's lease current until stop is closed
+func (h *Handler) renewJobLease(id string, stop <-chan struct{}) {
+	ticker := time.NewTicker(jobLeaseRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			err := h.DB.Model(&database.Job{}).Where("id = ? AND status IN ?", id, unfinishedJobs).
+				Update("lease_until", now.Add(jobLease)).Error
+			if err != nil {
+				log.Printf("jobs: could not renew lease of job %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// FailInterruptedJobs marks queued or running jobs as failed when the
+// process running them has stopped renewing their lease, since nothing will
+// finish them. Jobs from before leases were recorded have none and count as
+// interrupted. It returns how many jobs it failed.
+func (h *Handler) FailInterruptedJobs(now time.Time) int {
+	result := h.DB.Model(&database.Job{}).
+		Where("status IN ? AND COALESCE(instance, '') <> ? AND (lease_until IS NULL OR lease_until < ?)", unfinishedJobs, instanceID, now).
+		Updates(map[string]any{"status": jobFailed, "error": "Interrupted by a server restart", "finished_at": now})
+	if result.Error != nil {
+		log.Printf("jobs: could not fail interrupted jobs: %v", result.Error)
+		return 0
+	}
+	return int(result.RowsAffected)
+}
+
+// defaultJobRetentionDays is how long finished jobs are kept unless
+// JOB_RETENTION_DAYS says otherwise
+const defaultJobRetentionDays = 7
+
+// ReapJobs deletes jobs that finished longer ago than the retention period
+// and returns how many it deleted. Schedules they saved are kept.
+func (h *Handler) ReapJobs(now time.Time) int {
+	days := envInt("JOB_RETENTION_DAYS")
+	if days == 0 {
+		days = defaultJobRetentionDays
+	}
+	result := h.DB.Where("finished_at < ?", now.AddDate(0, 0, -days)).Delete(&database.Job{})
+	if result.Error != nil {
+		log.Printf("reaper: could not delete jobs: %v", result.Error)
+		return 0
+	}
+	return int(result.RowsAffected)
+}
+
+// loadJob fetches a job owned by the calling key, writing a 404 if it
+// doesn't exist. It reads from the primary, since the job's own updates
+// don't count as the caller's writes.
+func (h *Handler) loadJob(c *gin.Context) (*database.Job, bool) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+
+	var job database.Job
+	if err := h.DB.Where("id = ? AND key_id = ?", c.Param("id"), apiKey.ID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return nil, false
+	}
+	return &job, true
+}
+
+// jobJSON describes a job, with its result once it has succeeded
+func jobJSON(c *gin.Context, job *database.Job) gin.H {
+	body := gin.H{
+		"job_id":      job.ID,
+		"status":      job.Status,
+		"created_at":  job.CreatedAt,
+		"started_at":  job.StartedAt,
+		"finished_at": job.FinishedAt,
+	}
+	if job.Error != "" {
+		body["error"] = job.Error
+	}
+	if job.WebhookURL != "" {
+		body["webhook_url"] = job.WebhookURL
+		body["webhook_status"] = job.WebhookStatus
+		body["webhook_attempts"] = job.WebhookAttempts
+	}
+	if job.ScheduleID != "" {
+		body["schedule_id"] = job.ScheduleID
+		body["schedule_url"] = externalURL(c, "/api/schedules/"+job.ScheduleID)
+	}
+	if job.Status == jobSucceeded {
+		body["result"] = json.RawMessage(job.Result)
+	}
+	return body
+}
+
+// GetJob returns a job's status, and its result once it has succeeded
+func (h *Handler) GetJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, jobJSON(c, job))
+}
+
+// CancelJob cancels a queued or running job. Finished jobs can't be
+// cancelled.
+func (h *Handler) CancelJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	result := h.DB.Model(&database.Job{}).Where("id = ? AND status IN ?", job.ID, unfinishedJobs).
+		Updates(map[string]any{"status": jobCancelled, "finished_at": time.Now()})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not cancel job"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		h.DB.First(job, "id = ?", job.ID)
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already finished", "status": job.Status})
+		return
+	}
+	h.jobs.remove(job.ID)
+
+	h.DB.First(job, "id = ?", job.ID)
+	c.JSON(http.StatusOK, jobJSON(c, job))
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// parityResponse cuts a schedule response down to the Python version's
+// fields. Empty lists are sent as [] rather than null, as Python does.
+func parityResponse(resp models.ScheduleResponse) models.ParityResponse {
+	out := models.ParityResponse{
+		AssignedShifts: make(map[string][]string, len(resp.AssignedShifts)),
+		UnfilledShifts: resp.UnfilledShifts,
+		FairnessScore:  resp.FairnessScore,
+		Conflicts:      make([]models.ParityConflict, 0, len(resp.Conflicts)),
+		Volunteers:     make(map[string]models.ParityVolunteer, len(resp.Volunteers)),
+	}
+	if out.UnfilledShifts == nil {
+		out.UnfilledShifts = []string{}
+	}
+	for id, vols := range resp.AssignedShifts {
+		out.AssignedShifts[id] = nonNil(vols)
+	}
+	for _, conflict := range resp.Conflicts {
+		out.Conflicts = append(out.Conflicts, models.ParityConflict{
+			ShiftID: conflict.ShiftID,
+			Group:   conflict.Group,
+			Reasons: nonNil(conflict.Reasons),
+		})
+	}
+
+	// Volunteer summaries are loosely typed, so read them back through JSON
+	data, _ := json.Marshal(resp.Volunteers)
+	_ = json.Unmarshal(data, &out.Volunteers)
+	for id, vol := range out.Volunteers {
+		vol.AssignedShifts = nonNil(vol.AssignedShifts)
+		out.Volunteers[id] = vol
+	}
+	return out
+}
+
+// nonNil returns an empty list in place of nil
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
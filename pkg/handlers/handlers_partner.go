@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errSubKeyLimit is returned when a partner has used up its sub-key allowance
+var errSubKeyLimit = errors.New("sub-key limit reached")
+
+// errSubKeyRateLimit is returned when a sub-key's rate limit doesn't fit in
+// what's left of the partner's
+var errSubKeyRateLimit = errors.New("sub-key rate limit exceeds the partner's")
+
+// errSubKeyExists is returned when a sub-key's name is taken, including by a
+// revoked key, whose name would otherwise mint the same key again
+var errSubKeyExists = errors.New("sub-key name taken")
+
+// SetPartner approves a key as a partner allowed to mint up to max_sub_keys
+// sub-keys, or revokes that approval with zero
+func (h *Handler) SetPartner(c *gin.Context) {
+	var apiKey database.APIKey
+	if err := h.DB.First(&apiKey, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	if apiKey.PartnerID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sub-keys can't be partners"})
+		return
+	}
+
+	var req struct {
+		MaxSubKeys *int `json:"max_sub_keys" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_sub_keys is required"})
+		return
+	}
+	if *req.MaxSubKeys < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_sub_keys must not be negative"})
+		return
+	}
+
+	if err := h.DB.Model(&apiKey).Update("max_sub_keys", *req.MaxSubKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update key"})
+		return
+	}
+	h.audit(c, "partner.update", apiKey.Name, fmt.Sprintf("max %d sub-keys", *req.MaxSubKeys))
+	c.JSON(http.StatusOK, gin.H{"id": apiKey.ID, "max_sub_keys": *req.MaxSubKeys})
+}
+
+// PartnerMiddleware only lets approved partner keys through
+func (h *Handler) PartnerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.MustGet("apiKey").(*database.APIKey)
+		if apiKey.MaxSubKeys <= 0 || apiKey.PartnerID != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Key is not an approved partner"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CreateSubKey mints a key under the calling partner. Sub-keys' rate limits
+// are carved out of the partner's own, so together they can't exceed it;
+// without a rate_limit a sub-key gets an even share of it.
+func (h *Handler) CreateSubKey(c *gin.Context) {
+	partner := c.MustGet("apiKey").(*database.APIKey)
+
+	var req struct {
+		Name      string `json:"name" binding:"required"`
+		RateLimit int    `json:"rate_limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if req.RateLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_limit must not be negative"})
+		return
+	}
+
+	var subKey database.APIKey
+	var remaining int
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the partner's row so concurrent creates see each other's keys
+		var locked database.APIKey
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, partner.ID).Error; err != nil {
+			return err
+		}
+		var live struct {
+			Count     int64
+			Allocated int
+		}
+		if err := tx.Model(&database.APIKey{}).Select("COUNT(*) AS count, COALESCE(SUM(rate_limit), 0) AS allocated").
+			Where("partner_id = ? AND revoked_at IS NULL", partner.ID).Scan(&live).Error; err != nil {
+			return err
+		}
+		if live.Count >= int64(locked.MaxSubKeys) {
+			return errSubKeyLimit
+		}
+		remaining = locked.RateLimit - live.Allocated
+		rateLimit := req.RateLimit
+		if rateLimit == 0 {
+			rateLimit = min(max(locked.RateLimit/max(locked.MaxSubKeys, 1), 1), remaining)
+		}
+		if rateLimit <= 0 || rateLimit > remaining {
+			return errSubKeyRateLimit
+		}
+
+		// Prefix the name so sub-keys of different partners can't collide
+		name := fmt.Sprintf("%s/%s", partner.Name, req.Name)
+		key := auth.GenerateHMACKey(name)
+		if err := tx.Where(database.APIKey{Key: key}).First(&database.APIKey{}).Error; err == nil {
+			return errSubKeyExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		subKey = database.APIKey{
+			Key:        key,
+			Name:       name,
+			KeyPreview: keyPreview(key),
+			RateLimit:  rateLimit,
+			PartnerID:  &partner.ID,
+		}
+		return tx.Create(&subKey).Error
+	})
+	if errors.Is(err, errSubKeyLimit) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Partner is limited to %d sub-keys", partner.MaxSubKeys)})
+		return
+	}
+	if errors.Is(err, errSubKeyRateLimit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rate_limit must be between 1 and the %d left of the partner's limit", max(remaining, 0))})
+		return
+	}
+	if errors.Is(err, errSubKeyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A key with this name exists or was revoked; choose another name"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create key record"})
+		return
+	}
+
+	h.audit(c, "partner.sub_key.create", subKey.Name, fmt.Sprintf("rate limit %d", subKey.RateLimit))
+	c.JSON(http.StatusCreated, gin.H{
+		"id":          subKey.ID,
+		"external_id": subKey.ExternalID,
+		"name":        subKey.Name,
+		"key":         subKey.Key,
+		"rate_limit":  subKey.RateLimit,
+	})
+}
+
+// ListSubKeys returns the calling partner's sub-keys with their usage rolled
+// up into partner-wide totals
+func (h *Handler) ListSubKeys(c *gin.Context) {
+	partner := c.MustGet("apiKey").(*database.APIKey)
+
+	var subKeys []database.APIKey
+	if err := h.reader(c).Where("partner_id = ? AND revoked_at IS NULL", partner.ID).Order("id").Find(&subKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch keys"})
+		return
+	}
+
+	type keyUsage struct {
+		KeyID    uint
+		Requests int64
+		Shifts   int64
+	}
+	var usage []keyUsage
+	if err := h.reader(c).Model(&database.APIUsage{}).
+		Select("api_usages.key_id, SUM(api_usages.request_count) AS requests, SUM(api_usages.total_shifts) AS shifts").
+		Joins("JOIN api_keys ON api_keys.id = api_usages.key_id").
+		Where("api_keys.partner_id = ?", partner.ID).
+		Group("api_usages.key_id").
+		Scan(&usage).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch usage details"})
+		return
+	}
+	byKey := make(map[uint]keyUsage, len(usage))
+	var totalRequests, totalShifts int64
+	for _, u := range usage {
+		byKey[u.KeyID] = u
+		totalRequests += u.Requests
+		totalShifts += u.Shifts
+	}
+
+	keys := make([]gin.H, 0, len(subKeys))
+	for _, k := range subKeys {
+		keys = append(keys, gin.H{
+			"id":          k.ID,
+			"external_id": k.ExternalID,
+			"name":        k.Name,
+			"key_preview": k.KeyPreview,
+			"rate_limit":  k.RateLimit,
+			"created_at":  k.CreatedAt,
+			"last_used":   k.LastUsed,
+			"requests":    byKey[k.ID].Requests,
+			"shifts":      byKey[k.ID].Shifts,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys":         keys,
+		"max_sub_keys": partner.MaxSubKeys,
+		"totals": gin.H{
+			"requests": totalRequests,
+			"shifts":   totalShifts,
+		},
+	})
+}
+
+// RevokeSubKey revokes one of the calling partner's sub-keys. The key is
+// kept as revoked rather than deleted, and its usage stays in the totals.
+func (h *Handler) RevokeSubKey(c *gin.Context) {
+	partner := c.MustGet("apiKey").(*database.APIKey)
+
+	var subKey database.APIKey
+	if err := h.DB.Where("id = ? AND partner_id = ? AND revoked_at IS NULL", c.Param("id"), partner.ID).First(&subKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	if err := h.DB.Model(&subKey).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke key"})
+		return
+	}
+	h.audit(c, "partner.sub_key.revoke", subKey.Name, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Key revoked"})
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of items stored on a draft problem
+const (
+	draftVolunteer  = "volunteer"
+	draftShift      = "shift"
+	draftAssignment = "assignment"
+)
+
+// CreateProblem starts a new draft problem that volunteers, shifts and
+// assignments can be uploaded to in separate chunks
+func (h *Handler) CreateProblem(c *gin.Context) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+
+	id, err := newID()
+	if err != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not create problem ID"})
+		return
+	}
+
+	problem := database.DraftProblem{ID: id, KeyID: apiKey.ID}
+	if err := h.DB.Create(&problem).Error; err != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not create problem"})
+		return
+	}
+	respond(c, http.StatusCreated, gin.H{"problem_id": problem.ID})
+}
+
+// newID returns a random 128-bit hex identifier
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// loadProblem fetches a draft owned by the calling key, writing a 404 if it doesn't exist
+func (h *Handler) loadProblem(c *gin.Context) (*database.DraftProblem, bool) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+
+	var problem database.DraftProblem
+	if err := h.reader(c).Where("id = ? AND key_id = ?", c.Param("id"), apiKey.ID).First(&problem).Error; err != nil {
+		respond(c, http.StatusNotFound, gin.H{"error": "Problem not found"})
+		return nil, false
+	}
+	return &problem, true
+}
+
+// AddProblemVolunteers uploads a chunk of volunteers to a draft problem
+func (h *Handler) AddProblemVolunteers(c *gin.Context) {
+	addDraftItems(h, c, draftVolunteer, func(v *models.Volunteer) string { return v.ID })
+}
+
+// AddProblemShifts uploads a chunk of shifts to a draft problem
+func (h *Handler) AddProblemShifts(c *gin.Context) {
+	addDraftItems(h, c, draftShift, func(s *models.Shift) string { return s.ID })
+}
+
+// AddProblemAssignments uploads a chunk of existing assignments to a draft problem
+func (h *Handler) AddProblemAssignments(c *gin.Context) {
+	addDraftItems(h, c, draftAssignment, func(a *models.Assignment) string {
+		return a.ShiftID + "/" + a.VolunteerID
+	})
+}
+
+// addDraftItems stores a chunk of items on a draft problem. Items are keyed by
+// ID, so re-uploading an item replaces the previous copy, but a chunk can't
+// hold the same ID twice.
+func addDraftItems[T any](h *Handler, c *gin.Context, kind string, idOf func(*T) string) {
+	problem, ok := h.loadProblem(c)
+	if !ok {
+		return
+	}
+
+	var chunk []T
+	if err := bindBody(c, &chunk); err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(chunk) == 0 {
+		respond(c, http.StatusBadRequest, gin.H{"error": "At least one " + kind + " is required"})
+		return
+	}
+
+	items := make([]database.DraftItem, 0, len(chunk))
+	seen := make(map[string]bool, len(chunk))
+	for i := range chunk {
+		itemID := idOf(&chunk[i])
+		if seen[itemID] {
+			respond(c, http.StatusBadRequest, gin.H{"error": "Duplicate " + kind + " in chunk: " + itemID})
+			return
+		}
+		seen[itemID] = true
+
+		var item any = &chunk[i]
+		// Volunteer personal data is encrypted at rest when configured
+		if v, ok := item.(*models.Volunteer); ok {
+			sealed := []models.Volunteer{*v}
+			if err := pii.SealVolunteers(sealed); err != nil {
+				respond(c, http.StatusInternalServerError, gin.H{"error": "Could not encrypt volunteer"})
+				return
+			}
+			item = &sealed[0]
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		items = append(items, database.DraftItem{
+			ProblemID: problem.ID,
+			Kind:      kind,
+			ItemID:    itemID,
+			Data:      string(data),
+		})
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "problem_id"}, {Name: "kind"}, {Name: "item_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"data"}),
+		}).CreateInBatches(items, 500).Error; err != nil {
+			return err
+		}
+		// Touch the draft so UpdatedAt tracks the last edit
+		return tx.Model(problem).Update("updated_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+	})
+	if err != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not store " + kind + "s"})
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"problem_id": problem.ID,
+		"added":      len(items),
+		"totals":     h.problemTotals(problem.ID),
+	})
+}
+
+// problemTotals counts the items stored on a draft by kind
+func (h *Handler) problemTotals(problemID string) gin.H {
+	var rows []struct {
+		Kind  string
+		Count int64
+	}
+	h.DB.Model(&database.DraftItem{}).Select("kind, count(*) as count").
+		Where("problem_id = ?", problemID).Group("kind").Scan(&rows)
+
+	totals := gin.H{"volunteers": int64(0), "shifts": int64(0), "assignments": int64(0)}
+	for _, r := range rows {
+		totals[r.Kind+"s"] = r.Count
+	}
+	return totals
+}
+
+// loadProblemInput assembles the stored items of a draft into a ScheduleInput
+func (h *Handler) loadProblemInput(problemID string, input *models.ScheduleInput) error {
+	var items []database.DraftItem
+	if err := h.DB.Where("problem_id = ?", problemID).Order("id").Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var err error
+		switch item.Kind {
+		case draftVolunteer:
+			var v models.Volunteer
+			if err = json.Unmarshal([]byte(item.Data), &v); err == nil {
+				input.Volunteers = append(input.Volunteers, v)
+				err = pii.OpenVolunteers(input.Volunteers[len(input.Volunteers)-1:])
+			}
+		case draftShift:
+			var s models.Shift
+			if err = json.Unmarshal([]byte(item.Data), &s); err == nil {
+				input.UnassignedShifts = append(input.UnassignedShifts, s)
+			}
+		case draftAssignment:
+			var a models.Assignment
+			if err = json.Unmarshal([]byte(item.Data), &a); err == nil {
+				input.CurrentAssignments = append(input.CurrentAssignments, a)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProblem returns the current contents of a draft problem
+func (h *Handler) GetProblem(c *gin.Context) {
+	problem, ok := h.loadProblem(c)
+	if !ok {
+		return
+	}
+
+	var input models.ScheduleInput
+	if err := h.loadProblemInput(problem.ID, &input); err != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not load problem"})
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"problem_id": problem.ID,
+		"created_at": problem.CreatedAt,
+		"updated_at": problem.UpdatedAt,
+		"totals":     h.problemTotals(problem.ID),
+		"problem":    input,
+	})
+}
+
+// RemoveProblemVolunteer removes a volunteer from a draft problem. Assignments
+// that reference it are left in place and ignored when solving.
+func (h *Handler) RemoveProblemVolunteer(c *gin.Context) {
+	h.removeDraftItem(c, draftVolunteer, c.Param("itemId"))
+}
+
+// RemoveProblemShift removes a shift from a draft problem
+func (h *Handler) RemoveProblemShift(c *gin.Context) {
+	h.removeDraftItem(c, draftShift, c.Param("itemId"))
+}
+
+// RemoveProblemAssignment removes an existing assignment from a draft problem
+func (h *Handler) RemoveProblemAssignment(c *gin.Context) {
+	shiftID, volunteerID := c.Query("shift_id"), c.Query("volunteer_id")
+	if shiftID == "" || volunteerID == "" {
+		respond(c, http.StatusBadRequest, gin.H{"error": "shift_id and volunteer_id are required"})
+		return
+	}
+	h.removeDraftItem(c, draftAssignment, shiftID+"/"+volunteerID)
+}
+
+// removeDraftItem deletes one stored item from a draft problem
+func (h *Handler) removeDraftItem(c *gin.Context, kind, itemID string) {
+	problem, ok := h.loadProblem(c)
+	if !ok {
+		return
+	}
+
+	result := h.DB.Where("problem_id = ? AND kind = ? AND item_id = ?", problem.ID, kind, itemID).Delete(&database.DraftItem{})
+	if result.Error != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not remove " + kind})
+		return
+	}
+	if result.RowsAffected == 0 {
+		respond(c, http.StatusNotFound, gin.H{"error": kind + " not found in problem"})
+		return
+	}
+	h.DB.Model(problem).Update("updated_at", gorm.Expr("CURRENT_TIMESTAMP"))
+
+	respond(c, http.StatusOK, gin.H{
+		"problem_id": problem.ID,
+		"removed":    itemID,
+		"totals":     h.problemTotals(problem.ID),
+	})
+}
+
+// DeleteProblem discards a draft problem and everything stored on it
+func (h *Handler) DeleteProblem(c *gin.Context) {
+	problem, ok := h.loadProblem(c)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", problem.ID).Delete(&database.DraftItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(problem).Error
+	})
+	if err != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not delete problem"})
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "Problem deleted"})
+}
+
+// defaultDraftRetentionDays is how long a draft is kept after its last edit
+// unless DRAFT_RETENTION_DAYS says otherwise
+const defaultDraftRetentionDays = 7
+
+// ReapDrafts deletes drafts that haven't been edited within the retention
+// period, with their items, and returns how many it deleted
+func (h *Handler) ReapDrafts(now time.Time) int {
+	days := envInt("DRAFT_RETENTION_DAYS")
+	if days == 0 {
+		days = defaultDraftRetentionDays
+	}
+	cutoff := now.AddDate(0, 0, -days).UTC()
+
+	var deleted int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Model(&database.DraftProblem{}).Select("id").Where("updated_at < ?", cutoff)
+		if err := tx.Where("problem_id IN (?)", stale).Delete(&database.DraftItem{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("updated_at < ?", cutoff).Delete(&database.DraftProblem{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Printf("reaper: could not delete drafts: %v", err)
+		return 0
+	}
+	return int(deleted)
+}
+
+// SolveProblem schedules a draft problem. An optional ScheduleInput body
+// supplies solver options; any items in it are added to the stored ones.
+func (h *Handler) SolveProblem(c *gin.Context) {
+	problem, ok := h.loadProblem(c)
+	if !ok {
+		return
+	}
+
+	var input models.ScheduleInput
+	if c.Request.ContentLength != 0 {
+		if err := bindBody(c, &input); err != nil {
+			respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.loadProblemInput(problem.ID, &input); err != nil {
+		respond(c, http.StatusInternalServerError, gin.H{"error": "Could not load problem"})
+		return
+	}
+	if err := h.applyProfile(c, &input); err != nil {
+		status, body := profileErrorStatus(err)
+		respond(c, status, body)
+		return
+	}
+	if len(input.Volunteers) == 0 || len(input.UnassignedShifts) == 0 {
+		respond(c, http.StatusBadRequest, gin.H{"error": "Problem needs at least one volunteer and one shift"})
+		return
+	}
+
+	var inputJSON []byte
+	if input.Save {
+		var err error
+		if inputJSON, err = sealedInputJSON(&input); err != nil {
+			respond(c, http.StatusInternalServerError, gin.H{"error": "Could not encrypt schedule input"})
+			return
+		}
+	}
+
+	resp, err := buildSchedule(c.Request.Context(), &input)
+	if err != nil {
+		status, body := solveErrorStatus(err, http.StatusBadRequest)
+		respond(c, status, body)
+		return
+	}
+	if input.Save {
+		if _, err := h.saveSchedule(c, eventSolve, inputJSON, &resp); err != nil {
+			status, body := saveErrorStatus(err)
+			respond(c, status, body)
+			return
+		}
+	}
+	h.RecordUsage(c, len(resp.AssignedShifts), len(resp.Volunteers))
+
+	respond(c, http.StatusOK, resp)
+}
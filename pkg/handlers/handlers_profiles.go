@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errUnknownProfile is returned when a request names a profile that doesn't exist
+var errUnknownProfile = errors.New("unknown solver profile")
+
+// validateSettings checks a solver profile's settings
+func validateSettings(st models.SolverSettings) error {
+	if err := scheduler.ValidateAlgorithm(st.Algorithm); err != nil {
+		return fmt.Errorf("%w: %q", err, st.Algorithm)
+	}
+	if st.TimeoutSeconds < 0 || st.TimeoutSeconds > scheduler.MaxTimeoutSeconds {
+		return fmt.Errorf("timeout_seconds must be between 0 and %d", scheduler.MaxTimeoutSeconds)
+	}
+	if err := scheduler.ValidateFairnessMetric(st.FairnessMetric); err != nil {
+		return fmt.Errorf("%w: %q", err, st.FairnessMetric)
+	}
+	if err := scheduler.ValidateSoftConstraints(st.SoftConstraints); err != nil {
+		return err
+	}
+	if err := scheduler.ValidateMaxHoursRatio(st.MaxHoursRatio); err != nil {
+		return err
+	}
+	return scheduler.ValidateScoreWeights(st.ScoreWeights.FillRate, st.ScoreWeights.Fairness)
+}
+
+// profileJSON is a solver profile as the API shows it
+func profileJSON(p database.SolverProfile) gin.H {
+	var settings models.SolverSettings
+	json.Unmarshal([]byte(p.Settings), &settings)
+	return gin.H{
+		"name":       p.Name,
+		"settings":   settings,
+		"updated_by": p.UpdatedBy,
+		"updated_at": p.UpdatedAt,
+	}
+}
+
+// applyProfile fills in the settings of the profile an input names
+func (h *Handler) applyProfile(c *gin.Context, input *models.ScheduleInput) error {
+	if input.Profile == "" {
+		return nil
+	}
+	var profile database.SolverProfile
+	if err := h.reader(c).First(&profile, "name = ?", input.Profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %q", errUnknownProfile, input.Profile)
+		}
+		return err
+	}
+	var settings models.SolverSettings
+	if err := json.Unmarshal([]byte(profile.Settings), &settings); err != nil {
+		return err
+	}
+	input.ApplySettings(settings)
+	return nil
+}
+
+// profileErrorStatus maps an applyProfile error to a response
+func profileErrorStatus(err error) (int, gin.H) {
+	if errors.Is(err, errUnknownProfile) {
+		return http.StatusBadRequest, gin.H{"error": err.Error()}
+	}
+	return http.StatusInternalServerError, gin.H{"error": "Could not load solver profile"}
+}
+
+// ListProfiles returns every solver profile
+func (h *Handler) ListProfiles(c *gin.Context) {
+	var profiles []database.SolverProfile
+	h.reader(c).Order("name").Find(&profiles)
+	out := make([]gin.H, len(profiles))
+	for i, p := range profiles {
+		out[i] = profileJSON(p)
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": out})
+}
+
+// PutProfile creates or replaces a named solver profile
+func (h *Handler) PutProfile(c *gin.Context) {
+	var settings models.SolverSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not encode profile"})
+		return
+	}
+
+	profile := database.SolverProfile{
+		Name:      c.Param("name"),
+		Settings:  string(data),
+		UpdatedBy: c.GetString("username"),
+	}
+	if err := h.DB.Save(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save profile"})
+		return
+	}
+	h.audit(c, "solver_profile.update", profile.Name, string(data))
+	c.JSON(http.StatusOK, gin.H{"profile": profileJSON(profile)})
+}
+
+// DeleteProfile removes a solver profile. Requests naming it fail afterwards.
+func (h *Handler) DeleteProfile(c *gin.Context) {
+	result := h.DB.Delete(&database.SolverProfile{}, "name = ?", c.Param("name"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete profile"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+	h.audit(c, "solver_profile.delete", c.Param("name"), "")
+	c.JSON(http.StatusOK, gin.H{"message": "Profile deleted"})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+)
+
+// redactionEnabled reports whether PII should be masked in logs, debug
+// captures and error reports. PII_REDACTION=off/on overrides the default,
+// which is on in release mode.
+func redactionEnabled() bool {
+	switch strings.ToLower(os.Getenv("PII_REDACTION")) {
+	case "on", "true", "1":
+		return true
+	case "off", "false", "0":
+		return false
+	}
+	return gin.Mode() == gin.ReleaseMode
+}
+
+// ConfigureRedaction routes the standard logger and gin's request and
+// recovery logs through the PII redactor when redaction is enabled. It must
+// run before the router and its middleware are created.
+func ConfigureRedaction() {
+	if !redactionEnabled() {
+		return
+	}
+	gin.DefaultWriter = pii.NewRedactingWriter(gin.DefaultWriter)
+	gin.DefaultErrorWriter = pii.NewRedactingWriter(gin.DefaultErrorWriter)
+	log.SetOutput(pii.NewRedactingWriter(log.Writer()))
+}
+
+// trackNames has the redacted logs mask the volunteers' names until ctx is
+// done, which for a request is once its response has been written. Contexts
+// that never end (the canary's) aren't tracked; their names would pile up.
+func trackNames(ctx context.Context, volunteers []models.Volunteer) {
+	if !redactionEnabled() || ctx.Done() == nil {
+		return
+	}
+	context.AfterFunc(ctx, pii.TrackNames(volunteers))
+}
+
+// trackJobNames has the redacted logs mask a job's volunteer names until
+// untrack is called, once the job and its webhook delivery are over
+func trackJobNames(volunteers []models.Volunteer) (untrack func()) {
+	if !redactionEnabled() {
+		return func() {}
+	}
+	return pii.TrackNames(volunteers)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// RepairRequest is a complete schedule, given as current assignments, and
+// the volunteers and shifts that have since dropped out
+type RepairRequest struct {
+	models.ScheduleInput
+	DroppedVolunteers []string `json:"dropped_volunteers"`
+	CancelledShifts   []string `json:"cancelled_shifts"`
+}
+
+// RepairResponse is the patched schedule and the assignments that changed
+type RepairResponse struct {
+	models.ScheduleResponse
+	Removed []models.Assignment `json:"removed"`
+	Added   []models.Assignment `json:"added"`
+}
+
+// RepairSchedule refills the slots left by dropped volunteers without
+// touching any other assignment
+func (h *Handler) RepairSchedule(c *gin.Context) {
+	var req RepairRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.DroppedVolunteers) == 0 && len(req.CancelledShifts) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to repair: list dropped_volunteers or cancelled_shifts"})
+		return
+	}
+
+	if err := h.applyProfile(c, &req.ScheduleInput); err != nil {
+		c.JSON(profileErrorStatus(err))
+		return
+	}
+	s, err := prepareScheduler(c.Request.Context(), &req.ScheduleInput)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	removed, added, err := s.Repair(req.DroppedVolunteers, req.CancelledShifts)
+	if errors.Is(err, scheduler.ErrUnknownShift) || errors.Is(err, scheduler.ErrUnknownVolunteer) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := formatResponse(s, &req.ScheduleInput)
+	h.RecordUsage(c, len(resp.AssignedShifts), len(resp.Volunteers))
+	c.JSON(http.StatusOK, RepairResponse{ScheduleResponse: resp, Removed: removed, Added: added})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// stickyPrimaryWindow is how long reads stay on the primary after a caller
+// writes, comfortably longer than normal replication lag
+const stickyPrimaryWindow = 10 * time.Second
+
+// recentWrites remembers when each caller last wrote through this instance.
+// It saves looking up the caller's database record on every read.
+type recentWrites struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func (w *recentWrites) mark(caller string, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.last == nil {
+		w.last = make(map[string]time.Time)
+	}
+	// Drop expired entries now and then so the map stays small
+	if len(w.last) > 1000 {
+		for k, t := range w.last {
+			if now.Sub(t) > stickyPrimaryWindow {
+				delete(w.last, k)
+			}
+		}
+	}
+	w.last[caller] = now
+}
+
+func (w *recentWrites) since(caller string, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t, ok := w.last[caller]
+	return ok && now.Sub(t) < stickyPrimaryWindow
+}
+
+// caller identifies who made a request: the API key, or the admin or service token
+func caller(c *gin.Context) string {
+	if apiKeyRaw, exists := c.Get("apiKey"); exists {
+		return "key:" + strconv.FormatUint(uint64(apiKeyRaw.(*database.APIKey).ID), 10)
+	}
+	if username := c.GetString("username"); username != "" {
+		return "admin:" + username
+	}
+	return ""
+}
+
+// isRead reports whether a request method never writes
+func isRead(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+}
+
+// reader returns the database to read from. Reads go to the replica unless
+// the request itself writes or the caller wrote within stickyPrimaryWindow,
+// through any instance, so callers always see their own writes.
+func (h *Handler) reader(c *gin.Context) *gorm.DB {
+	if h.Replica == nil || !isRead(c) {
+		return h.DB
+	}
+	who := caller(c)
+	if who == "" {
+		return h.DB
+	}
+	now := time.Now()
+	if h.writes.since(who, now) {
+		return h.DB
+	}
+	// Another instance may have served the write. The record is read from
+	// the primary, since the replica may not have it yet.
+	var last database.CallerWrite
+	err := h.DB.Where("caller = ?", who).Limit(1).Find(&last).Error
+	if err != nil || now.Sub(last.WrittenAt) < stickyPrimaryWindow {
+		return h.DB
+	}
+	return h.Replica
+}
+
+// TrackWrites marks callers as having written after each non-read request,
+// pinning their next reads to the primary. It is a no-op without a replica.
+// The write is recorded in the database as well as in memory, so it holds
+// on every instance.
+func (h *Handler) TrackWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if h.Replica == nil || isRead(c) {
+			return
+		}
+		if who := caller(c); who != "" {
+			now := time.Now()
+			h.writes.mark(who, now)
+			err := h.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "caller"}},
+				DoUpdates: clause.AssignmentColumns([]string{"written_at"}),
+			}).Create(&database.CallerWrite{Caller: who, WrittenAt: now}).Error
+			if err != nil {
+				log.Printf("replica: could not record a write by %s: %v", who, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sealedInputJSON marshals a schedule input for storage, encrypting volunteer
+// personal data when PII encryption is configured. The input is not modified.
+func sealedInputJSON(input *models.ScheduleInput) ([]byte, error) {
+	sealed := *input
+	sealed.Volunteers = append([]models.Volunteer(nil), input.Volunteers...)
+	if err := pii.SealVolunteers(sealed.Volunteers); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&sealed)
+}
+
+// saveSchedule stores a solved schedule for the calling key and returns its ID.
+// kind is recorded as the first event in the schedule's history.
+func (h *Handler) saveSchedule(c *gin.Context, kind string, inputJSON []byte, resp *models.ScheduleResponse) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	resp.ScheduleID = id
+
+	result, err := json.Marshal(resp)
+	if err != nil {
+		return "", err
+	}
+	// Not stored, since the external URL may change
+	resp.ScheduleURL = externalURL(c, "/api/schedules/"+id)
+
+	keyID := c.MustGet("apiKey").(*database.APIKey).ID
+	schedule := database.Schedule{
+		ID:      id,
+		KeyID:   keyID,
+		Input:   string(inputJSON),
+		Result:  string(result),
+		Version: 1,
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkQuota(tx, keyID, len(inputJSON)+len(result)); err != nil {
+			return err
+		}
+		if err := tx.Create(&schedule).Error; err != nil {
+			return err
+		}
+		if err := storeAssignments(tx, id, resp); err != nil {
+			return err
+		}
+		return recordEvent(tx, c, id, 1, kind, nil, schedule.Result)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// loadSchedule fetches a stored schedule owned by the calling key, writing a 404 if it doesn't exist
+func (h *Handler) loadSchedule(c *gin.Context) (*database.Schedule, bool) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+
+	var schedule database.Schedule
+	if err := h.reader(c).Where("id = ? AND key_id = ?", c.Param("id"), apiKey.ID).First(&schedule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return nil, false
+	}
+	return &schedule, true
+}
+
+// decodeSchedule unmarshals a stored schedule's input and result, decrypting volunteer personal data
+func decodeSchedule(schedule *database.Schedule) (models.ScheduleInput, models.ScheduleResponse, error) {
+	var input models.ScheduleInput
+	var result models.ScheduleResponse
+	if err := json.Unmarshal([]byte(schedule.Input), &input); err != nil {
+		return input, result, err
+	}
+	if err := pii.OpenVolunteers(input.Volunteers); err != nil {
+		return input, result, err
+	}
+	if err := input.NormalizeTimezones(); err != nil {
+		return input, result, err
+	}
+	err := json.Unmarshal([]byte(schedule.Result), &result)
+	return input, result, err
+}
+
+// scheduleMaps rebuilds shift and volunteer maps with the stored result's assignments
+func scheduleMaps(input models.ScheduleInput, result models.ScheduleResponse) (map[string]*models.Shift, map[string]*models.Volunteer) {
+	shiftMap := make(map[string]*models.Shift, len(input.UnassignedShifts))
+	for i := range input.UnassignedShifts {
+		sh := &input.UnassignedShifts[i]
+		sh.Assigned = result.AssignedShifts[sh.ID]
+		shiftMap[sh.ID] = sh
+	}
+	volMap := make(map[string]*models.Volunteer, len(input.Volunteers))
+	for i := range input.Volunteers {
+		volMap[input.Volunteers[i].ID] = &input.Volunteers[i]
+	}
+	return shiftMap, volMap
+}
+
+// GetSchedule returns a stored schedule's input and result
+func (h *Handler) GetSchedule(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+	input, result, err := decodeSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return
+	}
+	c.Header("ETag", scheduleETag(schedule.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id": schedule.ID,
+		"version":     schedule.Version,
+		"created_at":  schedule.CreatedAt,
+		"updated_at":  schedule.UpdatedAt,
+		"input":       input,
+		"result":      result,
+	})
+}
+
+// GetScheduleCompliance returns the compliance report of a saved schedule,
+// for audits. Schedules saved before reports were recorded have theirs
+// rebuilt from the stored input and assignments.
+func (h *Handler) GetScheduleCompliance(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+	input, result, err := decodeSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return
+	}
+	report := result.Compliance
+	if report.Evaluated == nil {
+		report = scheduleFromResult(&input, result.AssignedShifts).ComplianceReport()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id": schedule.ID,
+		"version":     schedule.Version,
+		"compliance":  report,
+	})
+}
+
+// storeAssignments replaces a schedule's rows in the assignments table with
+// the assignments of its result
+func storeAssignments(tx *gorm.DB, scheduleID string, result *models.ScheduleResponse) error {
+	if err := tx.Where("schedule_id = ?", scheduleID).Delete(&database.Assignment{}).Error; err != nil {
+		return err
+	}
+	var rows []database.Assignment
+	for _, shiftID := range slices.Sorted(maps.Keys(result.AssignedShifts)) {
+		for _, volID := range result.AssignedShifts[shiftID] {
+			row := database.Assignment{ScheduleID: scheduleID, ShiftID: shiftID, VolunteerID: volID}
+			for _, a := range result.ShiftAssignments[shiftID] {
+				if a.VolunteerID == volID {
+					row.Group, row.Role = a.Group, a.Role
+					break
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+func init() {
+	database.AddBackfill(backfillAssignments)
+}
+
+// backfillAssignments fills the assignments table for schedules saved before
+// it existed. It runs with the database migration.
+func backfillAssignments(db *gorm.DB) error {
+	var schedules []database.Schedule
+	err := db.Select("id", "result").
+		Where("id NOT IN (?)", db.Model(&database.Assignment{}).Distinct().Select("schedule_id")).
+		Find(&schedules).Error
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		var result models.ScheduleResponse
+		if err := json.Unmarshal([]byte(schedule.Result), &result); err != nil {
+			continue
+		}
+		if err := storeAssignments(db, schedule.ID, &result); err != nil {
+			return fmt.Errorf("backfill assignments of schedule %s: %w", schedule.ID, err)
+		}
+	}
+	return nil
+}
+
+// maxScheduleListLimit caps how many schedules ListSchedules returns at once
+const maxScheduleListLimit = 200
+
+// ListSchedules lists the calling key's saved schedules, newest first,
+// without their input or result. volunteer_id and shift_id narrow the list
+// to schedules with those assignments; limit (default 50) and offset page
+// through it.
+func (h *Handler) ListSchedules(c *gin.Context) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+
+	limit, offset := 50, 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxScheduleListLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxScheduleListLimit)})
+			return
+		}
+		limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		offset = n
+	}
+
+	db := h.reader(c)
+	query := db.Model(&database.Schedule{}).Where("key_id = ?", apiKey.ID)
+	for _, column := range []string{"volunteer_id", "shift_id"} {
+		if v := c.Query(column); v != "" {
+			query = query.Where("id IN (?)", db.Model(&database.Assignment{}).Select("schedule_id").Where(column+" = ?", v))
+		}
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	var schedules []database.Schedule
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list schedules"})
+		return
+	}
+	err := query.Select("id", "version", "created_at", "updated_at").
+		Order("created_at DESC, id").Limit(limit).Offset(offset).Find(&schedules).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list schedules"})
+		return
+	}
+
+	ids := make([]string, len(schedules))
+	for i, s := range schedules {
+		ids[i] = s.ID
+	}
+	var counts []struct {
+		ScheduleID string
+		Count      int
+	}
+	db.Model(&database.Assignment{}).Select("schedule_id, COUNT(*) AS count").
+		Where("schedule_id IN ?", ids).Group("schedule_id").Find(&counts)
+	assigned := make(map[string]int, len(counts))
+	for _, row := range counts {
+		assigned[row.ScheduleID] = row.Count
+	}
+
+	list := make([]gin.H, len(schedules))
+	for i, s := range schedules {
+		list[i] = gin.H{
+			"schedule_id": s.ID,
+			"version":     s.Version,
+			"created_at":  s.CreatedAt,
+			"updated_at":  s.UpdatedAt,
+			"assignments": assigned[s.ID],
+			"url":         externalURL(c, "/api/schedules/"+s.ID),
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": list, "total": total, "limit": limit, "offset": offset})
+}
+
+// DeleteSchedule deletes a saved schedule with its history and assignments
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&database.ScheduleEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&database.Assignment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(schedule).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete schedule"})
+		return
+	}
+	h.audit(c, "schedule.delete", schedule.ID, "")
+	c.Status(http.StatusNoContent)
+}
+
+// GetScheduleBundle exports a stored schedule as a zip of its input, solution,
+// CSV, ICS calendar and conflict report, for archival and support tickets
+func (h *Handler) GetScheduleBundle(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+	input, result, err := decodeSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored schedule is corrupt"})
+		return
+	}
+
+	format, err := parseExportFormat(c.Query("locale"), c.Query("time_format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeBundle(&buf, schedule, input, result, format); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build bundle"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="schedule-`+schedule.ID+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// writeBundle writes the bundle zip for a stored schedule
+func writeBundle(buf *bytes.Buffer, schedule *database.Schedule, input models.ScheduleInput, result models.ScheduleResponse, format exportFormat) error {
+	zw := zip.NewWriter(buf)
+
+	// Bundles carry plaintext so they can be imported into a deployment with a different key
+	inputJSON, err := json.Marshal(&input)
+	if err != nil {
+		return err
+	}
+
+	conflicts, err := json.MarshalIndent(gin.H{
+		"schedule_id":     schedule.ID,
+		"unfilled_shifts": result.UnfilledShifts,
+		"conflicts":       result.Conflicts,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"input.json", inputJSON},
+		{"solution.json", []byte(schedule.Result)},
+		{"conflicts.json", conflicts},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return err
+		}
+	}
+
+	shiftMap, volMap := scheduleMaps(input, result)
+	w, err := zw.Create("schedule.csv")
+	if err != nil {
+		return err
+	}
+	if err := writeAssignmentsCSV(w, shiftMap, volMap, result.Notes, format); err != nil {
+		return err
+	}
+	w, err = zw.Create("schedule.ics")
+	if err != nil {
+		return err
+	}
+	if err := writeScheduleICS(w, shiftMap, volMap, result.Notes); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// maxBundleSize caps the size of an uploaded bundle zip, and of each file in it
+const maxBundleSize = 32 << 20
+
+// errBundleTooLarge is returned by readBundle for anything over maxBundleSize
+var errBundleTooLarge = fmt.Errorf("bundle must be at most %d MB", maxBundleSize>>20)
+
+// readBundle reads r whole, refusing to truncate it at maxBundleSize
+func readBundle(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBundleSize+1))
+	if err == nil && len(data) > maxBundleSize {
+		return nil, errBundleTooLarge
+	}
+	return data, err
+}
+
+// ImportScheduleBundle restores a schedule from a bundle produced by
+// GetScheduleBundle, storing it under a new ID for the calling key. The zip is
+// accepted as a "bundle" form file or as a raw application/zip body.
+func (h *Handler) ImportScheduleBundle(c *gin.Context) {
+	var data []byte
+	if fh, err := c.FormFile("bundle"); err == nil {
+		if fh.Size > maxBundleSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errBundleTooLarge.Error()})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to open bundle"})
+			return
+		}
+		defer f.Close()
+		data, err = readBundle(f)
+		if errors.Is(err, errBundleTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read bundle"})
+			return
+		}
+	} else {
+		data, err = readBundle(c.Request.Body)
+		if errors.Is(err, errBundleTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil || len(data) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bundle zip is required"})
+			return
+		}
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bundle is not a valid zip"})
+		return
+	}
+
+	inputJSON, err := readZipFile(zr, "input.json")
+	if err != nil {
+		c.JSON(bundleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	resultJSON, err := readZipFile(zr, "solution.json")
+	if err != nil {
+		c.JSON(bundleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var input models.ScheduleInput
+	if err := json.Unmarshal(inputJSON, &input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "input.json is invalid: " + err.Error()})
+		return
+	}
+	var result models.ScheduleResponse
+	if err := json.Unmarshal(resultJSON, &result); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "solution.json is invalid: " + err.Error()})
+		return
+	}
+
+	sealed, err := sealedInputJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not encrypt schedule input"})
+		return
+	}
+
+	importedFrom := result.ScheduleID
+	id, err := h.saveSchedule(c, eventImport, sealed, &result)
+	if err != nil {
+		c.JSON(saveErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"schedule_id":   id,
+		"imported_from": importedFrom,
+	})
+}
+
+// readZipFile reads a named file from a bundle zip
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("bundle is missing %s", name)
+	}
+	defer f.Close()
+	data, err := readBundle(f)
+	if errors.Is(err, errBundleTooLarge) {
+		return nil, fmt.Errorf("%w; %s is larger unpacked", err, name)
+	}
+	return data, err
+}
+
+// bundleErrorStatus maps a readZipFile error to a response status
+func bundleErrorStatus(err error) int {
+	if errors.Is(err, errBundleTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
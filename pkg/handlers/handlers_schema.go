@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/schema"
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ../../cmd/schemagen -o schema.json
+
+// schemaJSON is SchemaDocument as generated at build time. A test fails when
+// it no longer matches the models, so run go generate after changing them.
+//
+//go:embed schema.json
+var schemaJSON []byte
+
+// SchemaDocument builds the JSON Schemas for ScheduleInput and
+// ScheduleResponse from the model structs
+func SchemaDocument() map[string]any {
+	return map[string]any{
+		"schedule_input":    schema.Generate(models.ScheduleInput{}, "ScheduleInput"),
+		"schedule_response": schema.Generate(models.ScheduleResponse{}, "ScheduleResponse"),
+	}
+}
+
+// GetSchema returns JSON Schemas for ScheduleInput and ScheduleResponse,
+// generated from the model structs so they never drift from the server
+func (h *Handler) GetSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", schemaJSON)
+}
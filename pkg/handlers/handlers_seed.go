@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// seedFile is the layout of a seed file. Everything in it is matched by
+// name or ID, so applying the same file twice leaves the database as it was.
+type seedFile struct {
+	Admins   []seedAdmin   `json:"admins"`
+	Keys     []seedKey     `json:"keys"`
+	Profiles []seedProfile `json:"profiles"`
+}
+
+// seedAdmin is an admin login. The password can be read from an environment
+// variable so it needn't be committed with the file.
+type seedAdmin struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	PasswordEnv string `json:"password_env"`
+}
+
+// seedKey is an API key and the sample problems stored under it. Keys are
+// derived from their names, so the same file always produces the same keys.
+type seedKey struct {
+	Name       string        `json:"name"`
+	RateLimit  int           `json:"rate_limit"`
+	MaxSubKeys int           `json:"max_sub_keys"`
+	Problems   []seedProblem `json:"problems"`
+}
+
+// seedProblem is a draft problem of sample volunteers and shifts, ready to be
+// solved through /api/problems/:id/solve
+type seedProblem struct {
+	ID          string              `json:"id"`
+	Volunteers  []models.Volunteer  `json:"volunteers"`
+	Shifts      []models.Shift      `json:"shifts"`
+	Assignments []models.Assignment `json:"assignments"`
+}
+
+type seedProfile struct {
+	Name     string                `json:"name"`
+	Settings models.SolverSettings `json:"settings"`
+}
+
+// SeedSummary lists what a seed file created or updated. Keys are returned
+// whole so the caller can hand them out.
+type SeedSummary struct {
+	Admins   []string    `json:"admins"`
+	Keys     []SeededKey `json:"keys"`
+	Profiles []string    `json:"profiles"`
+	Problems []string    `json:"problems"`
+}
+
+// SeededKey is an API key written by Seed
+type SeededKey struct {
+	Name    string `json:"name"`
+	Key     string `json:"key"`
+	Created bool   `json:"created"`
+}
+
+// parseSeed reads a seed file, in YAML or JSON. Unknown fields are refused so
+// a misspelt section isn't silently skipped.
+func parseSeed(data []byte) (*seedFile, error) {
+	// YAML is converted to JSON so the models' JSON field names apply
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid seed file: %w", err)
+	}
+	if doc == nil {
+		return nil, errors.New("seed file is empty")
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var file seedFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid seed file: %w", err)
+	}
+
+	for _, a := range file.Admins {
+		if a.Username == "" {
+			return nil, errors.New("every admin needs a username")
+		}
+		if (a.Password == "") == (a.PasswordEnv == "") {
+			return nil, fmt.Errorf("admin %s needs exactly one of password and password_env", a.Username)
+		}
+	}
+	for _, k := range file.Keys {
+		if k.Name == "" {
+			return nil, errors.New("every key needs a name")
+		}
+		if k.RateLimit < 0 || k.MaxSubKeys < 0 {
+			return nil, fmt.Errorf("key %s has a negative limit", k.Name)
+		}
+		for _, p := range k.Problems {
+			if p.ID == "" {
+				return nil, fmt.Errorf("every problem of key %s needs an id", k.Name)
+			}
+		}
+	}
+	for _, p := range file.Profiles {
+		if p.Name == "" {
+			return nil, errors.New("every profile needs a name")
+		}
+		if err := validateSettings(p.Settings); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	return &file, nil
+}
+
+// Seed applies a seed file: it creates or updates the admins, keys and solver
+// profiles it lists, and replaces the contents of its sample problems. It
+// runs in one transaction, so a file that fails part way changes nothing.
+func Seed(db *gorm.DB, data []byte) (*SeedSummary, error) {
+	file, err := parseSeed(data)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &SeedSummary{Admins: []string{}, Keys: []SeededKey{}, Profiles: []string{}, Problems: []string{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, a := range file.Admins {
+			password := a.Password
+			if a.PasswordEnv != "" {
+				if password = os.Getenv(a.PasswordEnv); password == "" {
+					return fmt.Errorf("admin %s: %s is not set", a.Username, a.PasswordEnv)
+				}
+			}
+			hash, err := auth.HashPassword(password)
+			if err != nil {
+				return err
+			}
+			var user database.MasterUser
+			if err := tx.Where(database.MasterUser{Username: a.Username}).
+				Assign(database.MasterUser{PasswordHash: hash}).FirstOrCreate(&user).Error; err != nil {
+				return fmt.Errorf("admin %s: %w", a.Username, err)
+			}
+			summary.Admins = append(summary.Admins, a.Username)
+		}
+
+		for _, k := range file.Keys {
+			apiKey, created, err := seedAPIKey(tx, k)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", k.Name, err)
+			}
+			summary.Keys = append(summary.Keys, SeededKey{Name: k.Name, Key: apiKey.Key, Created: created})
+			for _, p := range k.Problems {
+				if err := seedDraft(tx, apiKey.ID, p); err != nil {
+					return fmt.Errorf("problem %s: %w", p.ID, err)
+				}
+				summary.Problems = append(summary.Problems, p.ID)
+			}
+		}
+
+		for _, p := range file.Profiles {
+			settings, err := json.Marshal(p.Settings)
+			if err != nil {
+				return err
+			}
+			profile := database.SolverProfile{Name: p.Name, Settings: string(settings), UpdatedBy: "seed"}
+			if err := tx.Save(&profile).Error; err != nil {
+				return fmt.Errorf("profile %s: %w", p.Name, err)
+			}
+			summary.Profiles = append(summary.Profiles, p.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// seedAPIKey creates a key, or brings an existing key of the same name up to
+// the file's limits. It reports whether the key was created.
+func seedAPIKey(tx *gorm.DB, k seedKey) (*database.APIKey, bool, error) {
+	rateLimit := k.RateLimit
+	if rateLimit == 0 {
+		rateLimit = 10000
+	}
+
+	var apiKey database.APIKey
+	err := tx.Where("name = ?", k.Name).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		key := auth.GenerateHMACKey(k.Name)
+		apiKey = database.APIKey{
+			Key:        key,
+			Name:       k.Name,
+			KeyPreview: keyPreview(key),
+			RateLimit:  rateLimit,
+			MaxSubKeys: k.MaxSubKeys,
+		}
+		return &apiKey, true, tx.Create(&apiKey).Error
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	err = tx.Model(&apiKey).Updates(map[string]any{"rate_limit": rateLimit, "max_sub_keys": k.MaxSubKeys}).Error
+	return &apiKey, false, err
+}
+
+// seedDraft stores a sample problem under a key, replacing whatever the draft
+// held before
+func seedDraft(tx *gorm.DB, keyID uint, p seedProblem) error {
+	var problem database.DraftProblem
+	if err := tx.Where(database.DraftProblem{ID: p.ID}).
+		Attrs(database.DraftProblem{KeyID: keyID}).FirstOrCreate(&problem).Error; err != nil {
+		return err
+	}
+	if problem.KeyID != keyID {
+		return errors.New("already belongs to another key")
+	}
+	if err := tx.Where("problem_id = ?", p.ID).Delete(&database.DraftItem{}).Error; err != nil {
+		return err
+	}
+
+	volunteers := append([]models.Volunteer(nil), p.Volunteers...)
+	if err := pii.SealVolunteers(volunteers); err != nil {
+		return err
+	}
+	var items []database.DraftItem
+	add := func(kind, itemID string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		items = append(items, database.DraftItem{ProblemID: p.ID, Kind: kind, ItemID: itemID, Data: string(data)})
+		return nil
+	}
+	for i := range volunteers {
+		if err := add(draftVolunteer, volunteers[i].ID, &volunteers[i]); err != nil {
+			return err
+		}
+	}
+	for i := range p.Shifts {
+		if err := add(draftShift, p.Shifts[i].ID, &p.Shifts[i]); err != nil {
+			return err
+		}
+	}
+	for i := range p.Assignments {
+		a := &p.Assignments[i]
+		if err := add(draftAssignment, a.ShiftID+"/"+a.VolunteerID, a); err != nil {
+			return err
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(items, 500).Error
+}
+
+// SeedData applies the seed file in the request body, in YAML or JSON. It is
+// only served in dev mode (GIN_MODE=debug); production uses cmd/seed.
+func (h *Handler) SeedData(c *gin.Context) {
+	if gin.Mode() != gin.DebugMode {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Seeding over HTTP is only available in dev mode"})
+		return
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	summary, err := Seed(h.DB, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit(c, "seed.apply", "", fmt.Sprintf("%d admins, %d keys, %d profiles, %d problems",
+		len(summary.Admins), len(summary.Keys), len(summary.Profiles), len(summary.Problems)))
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Size of the self-test scheduling problem
+const (
+	selfTestVolunteers = 60
+	selfTestShifts     = 120
+	selfTestTimeout    = 1 // seconds per search-based algorithm
+)
+
+// selfTestProblem builds the fixed scheduling problem the self-test solves:
+// overlapping two-hour shifts across a week, each needing two of three groups
+func selfTestProblem() (map[string]*models.Volunteer, map[string]*models.Shift) {
+	groups := []string{"A", "B", "C"}
+	volunteers := make(map[string]*models.Volunteer, selfTestVolunteers)
+	for i := range selfTestVolunteers {
+		id := fmt.Sprintf("v%d", i)
+		volunteers[id] = &models.Volunteer{ID: id, Name: id, Group: groups[i%len(groups)], MaxHours: 12}
+	}
+	start := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	shifts := make(map[string]*models.Shift, selfTestShifts)
+	for i := range selfTestShifts {
+		id := fmt.Sprintf("s%d", i)
+		begin := start.Add(time.Duration(i/6)*24*time.Hour/3 + time.Duration(i%6)*time.Hour)
+		shifts[id] = &models.Shift{
+			ID:    id,
+			Start: begin,
+			End:   begin.Add(2 * time.Hour),
+			RequiredGroups: map[string]int{
+				groups[i%len(groups)]:     1,
+				groups[(i+1)%len(groups)]: 1,
+			},
+		}
+	}
+	return volunteers, shifts
+}
+
+// pingMillis times a trivial query against a database
+func pingMillis(ctx context.Context, db *gorm.DB) (float64, error) {
+	began := time.Now()
+	var one int
+	if err := db.WithContext(ctx).Raw("SELECT 1").Scan(&one).Error; err != nil {
+		return 0, err
+	}
+	return millis(time.Since(began)), nil
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// SelfTest solves a small fixed problem with every algorithm and times a
+// database round trip, for sizing up a new deployment before it takes traffic
+func (h *Handler) SelfTest(c *gin.Context) {
+	ctx := c.Request.Context()
+	began := time.Now()
+
+	var solvers []gin.H
+	for _, algorithm := range []string{
+		scheduler.AlgorithmGreedy,
+		scheduler.AlgorithmOptimal,
+		scheduler.AlgorithmBranchAndBound,
+		scheduler.AlgorithmAnneal,
+	} {
+		volunteers, shifts := selfTestProblem()
+		s := scheduler.NewScheduler(volunteers, shifts)
+		s.Seed(1)
+		solveBegan := time.Now()
+		if err := s.Run(ctx, algorithm, selfTestTimeout); err != nil {
+			status, body := solveErrorStatus(err, http.StatusInternalServerError)
+			c.JSON(status, body)
+			return
+		}
+		solvers = append(solvers, gin.H{
+			"algorithm":      algorithm,
+			"duration_ms":    millis(time.Since(solveBegan)),
+			"fill_rate":      s.FillRate(),
+			"fairness_score": s.FairnessScore(),
+		})
+	}
+
+	db := gin.H{}
+	if ms, err := pingMillis(ctx, h.DB); err != nil {
+		db["primary_error"] = err.Error()
+	} else {
+		db["primary_ms"] = ms
+	}
+	if h.Replica != nil {
+		if ms, err := pingMillis(ctx, h.Replica); err != nil {
+			db["replica_error"] = err.Error()
+		} else {
+			db["replica_ms"] = ms
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"go_version": runtime.Version(),
+		"cpus":       runtime.GOMAXPROCS(0),
+		"tuning":     scheduler.CurrentTuning(),
+		"problem":    gin.H{"volunteers": selfTestVolunteers, "shifts": selfTestShifts},
+		"solvers":    solvers,
+		"database":   db,
+		"total_ms":   millis(time.Since(began)),
+	})
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultWarnDays is how long before deletion a webhook is warned when the
+// policy sets a webhook but no warning period
+const defaultWarnDays = 3
+
+// ErrStorageQuota is returned by saveSchedule when a key is out of storage
+var ErrStorageQuota = errors.New("storage quota exceeded")
+
+// webhookClient posts to webhooks. Their URLs come from callers, so it only
+// reaches public addresses unless WEBHOOK_ALLOW_PRIVATE is set.
+var webhookClient = &http.Client{Timeout: 10 * time.Second, Transport: publicTransport(allowPrivateWebhooks)}
+
+// allowPrivateWebhooks reports whether webhooks may point inside our own
+// network, set by WEBHOOK_ALLOW_PRIVATE=on for receivers on the same host
+// or network
+func allowPrivateWebhooks() bool {
+	switch strings.ToLower(os.Getenv("WEBHOOK_ALLOW_PRIVATE")) {
+	case "on", "true", "1":
+		return true
+	}
+	return false
+}
+
+// envInt reads a non-negative integer from the environment, or 0
+func envInt(name string) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// storagePolicy returns a key's storage policy with defaults applied.
+// SCHEDULE_RETENTION_DAYS and SCHEDULE_QUOTA set the defaults; zero means unlimited.
+func storagePolicy(db *gorm.DB, keyID uint) database.StoragePolicy {
+	policy := database.StoragePolicy{KeyID: keyID}
+	db.Where("key_id = ?", keyID).Limit(1).Find(&policy)
+	if policy.RetentionDays == 0 {
+		policy.RetentionDays = envInt("SCHEDULE_RETENTION_DAYS")
+	}
+	if policy.MaxSchedules == 0 {
+		policy.MaxSchedules = envInt("SCHEDULE_QUOTA")
+	}
+	if policy.WarningWebhook != "" && policy.WarnDays == 0 {
+		policy.WarnDays = defaultWarnDays
+	}
+	return policy
+}
+
+// storageUsage returns how many schedules a key has saved and their size in bytes
+func storageUsage(db *gorm.DB, keyID uint) (count int64, size int64) {
+	var row struct {
+		Count int64
+		Size  int64
+	}
+	db.Model(&database.Schedule{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(LENGTH(input) + LENGTH(result)), 0) AS size").
+		Where("key_id = ?", keyID).Scan(&row)
+	return row.Count, row.Size
+}
+
+// checkQuota reports ErrStorageQuota if saving newBytes more would exceed the
+// key's quota. Call it in the transaction that saves the schedule: it locks
+// the key's row, so concurrent saves for the key can't both pass the check.
+func checkQuota(tx *gorm.DB, keyID uint, newBytes int) error {
+	policy := storagePolicy(tx, keyID)
+	if policy.MaxSchedules == 0 && policy.MaxBytes == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&database.APIKey{}, keyID).Error; err != nil {
+		return err
+	}
+	count, size := storageUsage(tx, keyID)
+	if policy.MaxSchedules > 0 && count >= int64(policy.MaxSchedules) {
+		return ErrStorageQuota
+	}
+	if policy.MaxBytes > 0 && size+int64(newBytes) > policy.MaxBytes {
+		return ErrStorageQuota
+	}
+	return nil
+}
+
+// saveErrorStatus maps a saveSchedule error to a response
+func saveErrorStatus(err error) (int, gin.H) {
+	if errors.Is(err, ErrStorageQuota) {
+		return http.StatusForbidden, gin.H{"error": "Storage quota exceeded; delete saved schedules or ask an admin to raise the quota"}
+	}
+	return http.StatusInternalServerError, gin.H{"error": "Could not save schedule"}
+}
+
+// GetStoragePolicy returns a key's effective storage policy and current usage
+func (h *Handler) GetStoragePolicy(c *gin.Context) {
+	var apiKey database.APIKey
+	if err := h.DB.First(&apiKey, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	count, size := storageUsage(h.DB, apiKey.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"policy": storagePolicy(h.DB, apiKey.ID),
+		"usage":  gin.H{"schedules": count, "bytes": size},
+	})
+}
+
+// UpdateStoragePolicy sets a key's retention, quota and warning webhook
+func (h *Handler) UpdateStoragePolicy(c *gin.Context) {
+	var apiKey database.APIKey
+	if err := h.DB.First(&apiKey, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+
+	var policy database.StoragePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if policy.RetentionDays < 0 || policy.MaxSchedules < 0 || policy.MaxBytes < 0 || policy.WarnDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limits must not be negative"})
+		return
+	}
+	policy.KeyID = apiKey.ID
+
+	if err := h.DB.Save(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save storage policy"})
+		return
+	}
+	h.audit(c, "storage_policy.update", apiKey.Name, fmt.Sprintf("retention %dd, max %d schedules, max %d bytes", policy.RetentionDays, policy.MaxSchedules, policy.MaxBytes))
+	c.JSON(http.StatusOK, gin.H{"policy": storagePolicy(h.DB, apiKey.ID)})
+}
+
+// ReapSummary describes one reaper pass
+type ReapSummary struct {
+	Warned         int `json:"warned"`
+	Deleted        int `json:"deleted"`
+	ExpiredCredits int `json:"expired_credits"`
+	DeletedJobs    int `json:"deleted_jobs"`
+	DeletedDrafts  int `json:"deleted_drafts"`
+	// InterruptedJobs were failed because their instance stopped
+	InterruptedJobs int `json:"interrupted_jobs"`
+}
+
+// ReapSchedules deletes saved schedules that have not been updated within
+// their key's retention period. When the key has a warning webhook, each
+// schedule is only deleted once the webhook was told it would expire at
+// least WarnDays ago, however late the warning went out.
+func (h *Handler) ReapSchedules(now time.Time) ReapSummary {
+	var summary ReapSummary
+
+	var keyIDs []uint
+	h.DB.Model(&database.Schedule{}).Distinct().Pluck("key_id", &keyIDs)
+	for _, keyID := range keyIDs {
+		policy := storagePolicy(h.DB, keyID)
+		if policy.RetentionDays == 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -policy.RetentionDays)
+
+		if policy.WarningWebhook != "" {
+			warnCutoff := cutoff.AddDate(0, 0, policy.WarnDays)
+			var expiring []database.Schedule
+			h.DB.Select("id", "updated_at").
+				Where("key_id = ? AND updated_at < ? AND warned_at IS NULL", keyID, warnCutoff).
+				Find(&expiring)
+			if len(expiring) > 0 && h.sendExpiryWarning(policy, expiring, now) {
+				ids := make([]string, len(expiring))
+				for i, s := range expiring {
+					ids[i] = s.ID
+				}
+				h.DB.Model(&database.Schedule{}).Where("id IN ?", ids).UpdateColumn("warned_at", now)
+				summary.Warned += len(expiring)
+			}
+		}
+
+		query := h.DB.Where("key_id = ? AND updated_at < ?", keyID, cutoff)
+		if policy.WarningWebhook != "" {
+			query = query.Where("warned_at <= ?", now.AddDate(0, 0, -policy.WarnDays))
+		}
+		result := query.Delete(&database.Schedule{})
+		if result.Error != nil {
+			log.Printf("reaper: could not delete schedules for key %d: %v", keyID, result.Error)
+			continue
+		}
+		if result.RowsAffected > 0 {
+			summary.Deleted += int(result.RowsAffected)
+			h.DB.Create(&database.AuditLog{
+				KeyID:   keyID,
+				Actor:   "reaper",
+				Action:  "schedule.reap",
+				Subject: strconv.FormatUint(uint64(keyID), 10),
+				Detail:  fmt.Sprintf("%d schedules older than %d days", result.RowsAffected, policy.RetentionDays),
+			})
+		}
+	}
+	if summary.Deleted > 0 {
+		h.DB.Where("schedule_id NOT IN (?)", h.DB.Model(&database.Schedule{}).Select("id")).Delete(&database.ScheduleEvent{})
+		h.DB.Where("schedule_id NOT IN (?)", h.DB.Model(&database.Schedule{}).Select("id")).Delete(&database.Assignment{})
+	}
+	return summary
+}
+
+// sendExpiryWarning posts the schedules about to expire to the key's webhook.
+// A schedule warned late still gets WarnDays before it's deleted.
+func (h *Handler) sendExpiryWarning(policy database.StoragePolicy, expiring []database.Schedule, now time.Time) bool {
+	type warning struct {
+		ScheduleID string    `json:"schedule_id"`
+		URL        string    `json:"url,omitempty"`
+		ExpiresAt  time.Time `json:"expires_at"`
+	}
+	warnings := make([]warning, len(expiring))
+	earliest := now.AddDate(0, 0, policy.WarnDays)
+	for i, s := range expiring {
+		expires := s.UpdatedAt.AddDate(0, 0, policy.RetentionDays)
+		if expires.Before(earliest) {
+			expires = earliest
+		}
+		warnings[i] = warning{
+			ScheduleID: s.ID,
+			URL:        externalURL(nil, "/api/schedules/"+s.ID),
+			ExpiresAt:  expires,
+		}
+	}
+	body, err := json.Marshal(gin.H{"event": "schedules.expiring", "key_id": policy.KeyID, "schedules": warnings})
+	if err != nil {
+		return false
+	}
+
+	req, err := h.newWebhookRequest(policy.KeyID, policy.WarningWebhook, body)
+	if err != nil {
+		log.Printf("reaper: could not build warning webhook for key %d: %v", policy.KeyID, err)
+		return false
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		log.Printf("reaper: warning webhook for key %d failed: %v", policy.KeyID, err)
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("reaper: warning webhook for key %d returned %d", policy.KeyID, resp.StatusCode)
+		return false
+	}
+	return true
+}
+
+// reap runs one reaper pass over schedules, burst credits, jobs and drafts
+func (h *Handler) reap(now time.Time) ReapSummary {
+	summary := h.ReapSchedules(now)
+	summary.ExpiredCredits = h.ExpireBurstCredits(now)
+	summary.DeletedJobs = h.ReapJobs(now)
+	summary.InterruptedJobs = h.FailInterruptedJobs(now)
+	summary.DeletedDrafts = h.ReapDrafts(now)
+	return summary
+}
+
+// RunReaper reaps expired schedules every interval until the process exits
+func (h *Handler) RunReaper(interval time.Duration) {
+	h.runEvery("reaper", interval)
+}
+
+// RunReaperNow runs a single reaper pass, for deployments without a
+// long-running process (e.g. triggered by a cron job)
+func (h *Handler) RunReaperNow(c *gin.Context) {
+	task, _ := findTask("reaper")
+	_, summary, err := h.runTask(task, triggerManual, actor(c))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reaper is already running"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// SwapRequest names a shift a volunteer wants to drop, in a saved schedule
+// when schedule_id is set or in the inline input otherwise
+type SwapRequest struct {
+	models.ScheduleInput
+	ScheduleID  string `json:"schedule_id,omitempty"`
+	ShiftID     string `json:"shift_id" binding:"required"`
+	VolunteerID string `json:"volunteer_id" binding:"required"`
+	// TwoWay also looks for volunteers who would trade one of their own shifts
+	TwoWay bool `json:"two_way"`
+}
+
+// SuggestSwaps lists the volunteers who could take over a shift without
+// breaking any constraint
+func (h *Handler) SuggestSwaps(c *gin.Context) {
+	var req SwapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s, ok := h.requestScheduler(c, req.ScheduleID, &req.ScheduleInput)
+	if !ok {
+		return
+	}
+
+	direct, swaps, err := s.Swaps(req.ShiftID, req.VolunteerID, req.TwoWay)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownShift), errors.Is(err, scheduler.ErrUnknownVolunteer):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, scheduler.ErrNotAssigned):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"shift_id":     req.ShiftID,
+		"volunteer_id": req.VolunteerID,
+		"candidates":   direct,
+		"swaps":        swaps,
+	})
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// How a task run was started
+const (
+	triggerSchedule = "schedule"
+	triggerManual   = "manual"
+)
+
+// taskRunHistory is how many runs of each task are kept
+const taskRunHistory = 100
+
+// taskFailureHistory is how many recent failures ListTasks shows per task
+const taskFailureHistory = 10
+
+// errTaskRunning is returned when a task is triggered while it runs
+var errTaskRunning = errors.New("task is already running")
+
+// backgroundTask is a housekeeping job the server runs on a timer, which
+// admins can also trigger. run returns a summary of what it did.
+type backgroundTask struct {
+	name        string
+	description string
+	run         func(h *Handler, now time.Time) (any, error)
+}
+
+// backgroundTasks lists every background task
+var backgroundTasks = []backgroundTask{
+	{
+		name:        "reaper",
+		description: "Deletes expired schedules and old finished jobs, fails interrupted jobs, and audits expired burst credits",
+		run: func(h *Handler, now time.Time) (any, error) {
+			summary := h.reap(now)
+			if summary.Deleted > 0 || summary.Warned > 0 {
+				log.Printf("reaper: warned about %d and deleted %d schedules", summary.Warned, summary.Deleted)
+			}
+			if summary.ExpiredCredits > 0 {
+				log.Printf("reaper: %d burst credits expired", summary.ExpiredCredits)
+			}
+			if summary.DeletedJobs > 0 {
+				log.Printf("reaper: deleted %d finished jobs", summary.DeletedJobs)
+			}
+			if summary.InterruptedJobs > 0 {
+				log.Printf("reaper: %d jobs were interrupted", summary.InterruptedJobs)
+			}
+			return summary, nil
+		},
+	},
+	{
+		name:        "usage_flush",
+		description: "Records usage buffered while the database was unavailable in degraded mode",
+		run: func(h *Handler, now time.Time) (any, error) {
+			n, err := h.FlushUsageBuffer()
+			if n > 0 {
+				log.Printf("usage buffer: recorded %d buffered requests", n)
+			}
+			return gin.H{"flushed": n}, err
+		},
+	},
+}
+
+// findTask looks a background task up by name
+func findTask(name string) (backgroundTask, bool) {
+	i := slices.IndexFunc(backgroundTasks, func(t backgroundTask) bool { return t.name == name })
+	if i < 0 {
+		return backgroundTask{}, false
+	}
+	return backgroundTasks[i], true
+}
+
+// taskStates tracks which tasks this process runs on a timer, and which are
+// running right now
+type taskStates struct {
+	mu        sync.Mutex
+	intervals map[string]time.Duration
+	running   map[string]bool
+}
+
+func (t *taskStates) schedule(name string, interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.intervals == nil {
+		t.intervals = make(map[string]time.Duration)
+	}
+	t.intervals[name] = interval
+}
+
+// start marks a task running, or returns false if it already was
+func (t *taskStates) start(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running == nil {
+		t.running = make(map[string]bool)
+	}
+	if t.running[name] {
+		return false
+	}
+	t.running[name] = true
+	return true
+}
+
+func (t *taskStates) finish(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.running, name)
+}
+
+func (t *taskStates) status(name string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.intervals[name], t.running[name]
+}
+
+// runTask runs a task once and records the run. A task that panics is
+// recorded as failed rather than taking the process down.
+func (h *Handler) runTask(task backgroundTask, trigger, actor string) (database.TaskRun, any, error) {
+	if !h.tasks.start(task.name) {
+		return database.TaskRun{}, nil, errTaskRunning
+	}
+	defer h.tasks.finish(task.name)
+
+	run := database.TaskRun{Task: task.name, Trigger: trigger, Actor: actor, StartedAt: time.Now()}
+	summary, err := func() (summary any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return task.run(h, run.StartedAt)
+	}()
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+		log.Printf("%s: %v", task.name, err)
+	}
+	if data, err := json.Marshal(summary); err == nil {
+		run.Summary = string(data)
+	}
+
+	if err := h.DB.Create(&run).Error; err != nil {
+		log.Printf("%s: could not record run: %v", task.name, err)
+		return run, summary, nil
+	}
+	keep := h.DB.Model(&database.TaskRun{}).Select("id").Where("task = ?", task.name).Order("id DESC").Limit(taskRunHistory)
+	h.DB.Where("task = ? AND id NOT IN (?)", task.name, keep).Delete(&database.TaskRun{})
+	return run, summary, nil
+}
+
+// runEvery runs a task every interval until the process exits. A run due
+// while an admin-triggered one is still going is skipped.
+func (h *Handler) runEvery(name string, interval time.Duration) {
+	task, ok := findTask(name)
+	if !ok {
+		return
+	}
+	h.tasks.schedule(name, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.runTask(task, triggerSchedule, "")
+	}
+}
+
+// taskRunView is a task run with its summary decoded
+type taskRunView struct {
+	database.TaskRun
+	Summary json.RawMessage `json:"summary,omitempty"`
+}
+
+func newTaskRunView(run database.TaskRun) *taskRunView {
+	view := &taskRunView{TaskRun: run}
+	if run.Summary != "" {
+		view.Summary = json.RawMessage(run.Summary)
+	}
+	return view
+}
+
+// ListTasks lists the background tasks with how often this process runs
+// them, whether they are running, their last run and last success, and
+// their recent failures
+func (h *Handler) ListTasks(c *gin.Context) {
+	type taskStatus struct {
+		Name            string         `json:"name"`
+		Description     string         `json:"description"`
+		IntervalSeconds int            `json:"interval_seconds,omitempty"` // unset when this process doesn't run it on a timer
+		Running         bool           `json:"running"`
+		Runs            int64          `json:"runs"`
+		LastRun         *taskRunView   `json:"last_run"`
+		LastSuccess     *time.Time     `json:"last_success"`
+		Failures        []*taskRunView `json:"failures"`
+	}
+
+	db := h.reader(c)
+	tasks := make([]taskStatus, 0, len(backgroundTasks))
+	for _, task := range backgroundTasks {
+		interval, running := h.tasks.status(task.name)
+		status := taskStatus{
+			Name:            task.name,
+			Description:     task.description,
+			IntervalSeconds: int(interval.Seconds()),
+			Running:         running,
+			Failures:        []*taskRunView{},
+		}
+		db.Model(&database.TaskRun{}).Where("task = ?", task.name).Count(&status.Runs)
+
+		var runs []database.TaskRun
+		db.Where("task = ?", task.name).Order("id DESC").Limit(1).Find(&runs)
+		if len(runs) > 0 {
+			status.LastRun = newTaskRunView(runs[0])
+		}
+		runs = nil
+		db.Where("task = ? AND error = ''", task.name).Order("id DESC").Limit(1).Find(&runs)
+		if len(runs) > 0 {
+			status.LastSuccess = &runs[0].StartedAt
+		}
+		runs = nil
+		db.Where("task = ? AND error <> ''", task.name).Order("id DESC").Limit(taskFailureHistory).Find(&runs)
+		for _, run := range runs {
+			status.Failures = append(status.Failures, newTaskRunView(run))
+		}
+		tasks = append(tasks, status)
+	}
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
+}
+
+// TriggerTask runs the background task named in the body now and returns
+// the run. The request waits for the task to finish.
+func (h *Handler) TriggerTask(c *gin.Context) {
+	var req struct {
+		Task string `json:"task" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	task, ok := findTask(req.Task)
+	if !ok {
+		names := make([]string, len(backgroundTasks))
+		for i, t := range backgroundTasks {
+			names[i] = t.name
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown task; expected one of: " + strings.Join(names, ", ")})
+		return
+	}
+
+	run, _, err := h.runTask(task, triggerManual, actor(c))
+	if errors.Is(err, errTaskRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is already running"})
+		return
+	}
+	h.audit(c, "task.run", task.name, run.Error)
+	c.JSON(http.StatusOK, newTaskRunView(run))
+}
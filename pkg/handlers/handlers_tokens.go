@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// Service token roles
+const (
+	RoleReader     = "reader"
+	RoleKeyManager = "key_manager"
+)
+
+// rolePermissions lists the admin routes each service token role may call.
+// Anything not listed, including token management itself, needs a human admin.
+var rolePermissions = map[string][]string{
+	RoleReader: {
+		"GET /admin/keys/:id/impact",
+		"GET /admin/keys/:id/storage",
+		"GET /admin/keys/:id/credits",
+		"GET /admin/usage/:id",
+		"GET /admin/profiles",
+		"GET /admin/canary",
+		"GET /admin/tasks",
+	},
+	RoleKeyManager: {
+		"GET /admin/keys",
+		"GET /admin/keys/:id/impact",
+		"GET /admin/keys/:id/storage",
+		"PUT /admin/keys/:id/storage",
+		"GET /admin/keys/:id/credits",
+		"POST /admin/keys/:id/credits",
+		"DELETE /admin/keys/:id/credits/:creditId",
+		"GET /admin/usage/:id",
+		"POST /admin/keys",
+		"POST /admin/keys/bulk",
+		"PUT /admin/keys/:id",
+		"DELETE /admin/keys/:id",
+	},
+}
+
+// authenticateServiceToken admits a service token to the admin route being
+// called if its role allows it
+func (h *Handler) authenticateServiceToken(c *gin.Context, token string) {
+	var st database.ServiceToken
+	if err := h.DB.Where("token_hash = ?", auth.HashServiceToken(token)).First(&st).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	if !slices.Contains(rolePermissions[st.Role], c.Request.Method+" "+c.FullPath()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token role " + st.Role + " cannot access this route"})
+		c.Abort()
+		return
+	}
+
+	now := time.Now()
+	h.DB.Model(&st).Update("last_used", &now)
+
+	c.Set("username", "token:"+st.Name)
+	c.Set("serviceToken", &st)
+	c.Next()
+}
+
+// CreateServiceToken issues a new service token. The token is only shown once.
+func (h *Handler) CreateServiceToken(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if _, ok := rolePermissions[req.Role]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of: " + RoleReader + ", " + RoleKeyManager})
+		return
+	}
+
+	token, err := auth.GenerateServiceToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		return
+	}
+
+	st := database.ServiceToken{
+		Name:         req.Name,
+		TokenHash:    auth.HashServiceToken(token),
+		TokenPreview: keyPreview(token),
+		Role:         req.Role,
+		CreatedBy:    c.GetString("username"),
+	}
+	if err := h.DB.Create(&st).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token record"})
+		return
+	}
+	h.audit(c, "service_token.create", st.Name, "role "+st.Role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":    st.ID,
+		"name":  st.Name,
+		"role":  st.Role,
+		"token": token,
+	})
+}
+
+// ListServiceTokens returns all service tokens, without their secrets
+func (h *Handler) ListServiceTokens(c *gin.Context) {
+	var tokens []database.ServiceToken
+	h.reader(c).Order("id").Find(&tokens)
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// RevokeServiceToken deletes a service token
+func (h *Handler) RevokeServiceToken(c *gin.Context) {
+	var st database.ServiceToken
+	if err := h.DB.First(&st, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	if err := h.DB.Delete(&st).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke token"})
+		return
+	}
+	h.audit(c, "service_token.revoke", st.Name, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
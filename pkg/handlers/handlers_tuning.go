@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/scheduler"
+)
+
+// Autotune sizes the solver for the CPUs this process may actually use,
+// counting a container's cgroup quota, which Go doesn't on its own. It sets
+// GOMAXPROCS unless that was set explicitly, and SOLVER_WORKERS and
+// SOLVER_DEFAULT_TIMEOUT override the suggested tuning. Call it once at
+// startup.
+func Autotune() scheduler.Tuning {
+	return autotune(true)
+}
+
+// AutotuneServerless is Autotune for serverless functions, which run on a
+// single small CPU under a hard duration limit. The default timeout isn't
+// stretched for small machines there, so a request that takes the default
+// still finishes within the function's limit.
+func AutotuneServerless() scheduler.Tuning {
+	return autotune(false)
+}
+
+func autotune(stretch bool) scheduler.Tuning {
+	cpus := availableCPUs()
+	if os.Getenv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(cpus)
+	} else {
+		cpus = runtime.GOMAXPROCS(0)
+	}
+
+	t := scheduler.TuneFor(cpus)
+	if !stretch {
+		t.DefaultTimeoutSeconds = min(t.DefaultTimeoutSeconds, scheduler.DefaultTimeoutSeconds)
+	}
+	if n := envInt("SOLVER_WORKERS"); n > 0 {
+		t.Workers = n
+	}
+	if n := envInt("SOLVER_DEFAULT_TIMEOUT"); n > 0 {
+		t.DefaultTimeoutSeconds = min(n, scheduler.MaxTimeoutSeconds)
+	}
+	scheduler.SetTuning(t)
+	log.Printf("solver: %d CPUs, %d optimal workers, %ds default timeout", cpus, t.Workers, t.DefaultTimeoutSeconds)
+	return t
+}
+
+// availableCPUs returns the CPUs the process may run on, capped by its
+// cgroup's CPU quota
+func availableCPUs() int {
+	n := runtime.NumCPU()
+	if quota := cgroupCPUs(); quota > 0 && quota < n {
+		n = quota
+	}
+	return n
+}
+
+// cgroupCPUs returns the process's cgroup CPU quota in whole CPUs, rounded
+// up, or 0 without one. Containers see their own cgroup at the root of
+// /sys/fs/cgroup, for both cgroup v2 and v1.
+func cgroupCPUs() int {
+	if data, err := os.ReadFile("/sys/fs/cgroup/cpu.max"); err == nil {
+		quota, period, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+		return quotaCPUs(quota, period)
+	}
+	quota, err := os.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	if err != nil {
+		return 0
+	}
+	period, err := os.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if err != nil {
+		return 0
+	}
+	return quotaCPUs(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+// quotaCPUs converts a CFS quota and period to CPUs. An unlimited quota,
+// "max" in v2 and -1 in v1, gives 0.
+func quotaCPUs(quota, period string) int {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return int(math.Ceil(q / p))
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BasePath is the path prefix the API is served under, from BASE_PATH, e.g.
+// "/scheduler". It is empty when the API is served at the root.
+func BasePath() string {
+	p := strings.Trim(os.Getenv("BASE_PATH"), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// WithBasePath strips BasePath from request paths before routing. Requests
+// without the prefix pass through unchanged, for gateways that strip it
+// themselves.
+func WithBasePath(next http.Handler) http.Handler {
+	base := BasePath()
+	if base == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, base)
+		if !ok || (rest != "" && rest[0] != '/') {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// externalURL is the absolute URL clients reach path at, path being rooted
+// at the API, e.g. "/api/schedules/abc". PUBLIC_BASE_URL, such as
+// "https://gateway.example.org/scheduler", is used when set; otherwise the
+// URL is built from the request's host and BasePath. Without either, as in
+// background tasks, it is empty.
+func externalURL(c *gin.Context, path string) string {
+	if base := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"); base != "" {
+		return base + path
+	}
+	if c == nil {
+		return ""
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + BasePath() + path
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/arnavshah/scheduler-api-go/pkg/database"
 	"github.com/gin-gonic/gin"
@@ -17,7 +19,7 @@ func (h *Handler) GetMyUsage(c *gin.Context) {
 	apiKey := apiKeyRaw.(*database.APIKey)
 
 	var usage []database.APIUsage
-	if err := h.DB.Where("key_id = ?", apiKey.ID).Order("date desc").Limit(30).Find(&usage).Error; err != nil {
+	if err := h.reader(c).Where("key_id = ?", apiKey.ID).Order("date desc").Limit(30).Find(&usage).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch usage details"})
 		return
 	}
@@ -30,10 +32,14 @@ func (h *Handler) GetMyUsage(c *gin.Context) {
 		totalVolunteers += int64(u.TotalVolunteers)
 	}
 
+	credits := activeCredits(h.reader(c), apiKey.ID, time.Now())
+
 	c.JSON(http.StatusOK, gin.H{
-		"key_name":      apiKey.Name,
-		"rate_limit":    apiKey.RateLimit,
-		"usage_history": usage,
+		"key_name":             apiKey.Name,
+		"rate_limit":           apiKey.RateLimit,
+		"effective_rate_limit": apiKey.RateLimit + creditTotal(credits),
+		"burst_credits":        credits,
+		"usage_history":        usage,
 		"totals": gin.H{
 			"requests":   totalRequests,
 			"shifts":     totalShifts,
@@ -41,3 +47,47 @@ func (h *Handler) GetMyUsage(c *gin.Context) {
 		},
 	})
 }
+
+// KeyLimitImpact previews how many of a key's recent days would have exceeded
+// a proposed rate limit, without applying it
+func (h *Handler) KeyLimitImpact(c *gin.Context) {
+	id := c.Param("id")
+	proposed, err := strconv.Atoi(c.Query("rate_limit"))
+	if err != nil || proposed <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_limit must be a positive integer"})
+		return
+	}
+
+	var apiKey database.APIKey
+	if err := h.reader(c).First(&apiKey, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+
+	var usage []database.APIUsage
+	if err := h.reader(c).Where("key_id = ?", apiKey.ID).Order("date desc").Limit(30).Find(&usage).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch usage details"})
+		return
+	}
+
+	exceededDays := make([]string, 0)
+	peak := 0
+	for _, u := range usage {
+		if u.RequestCount > proposed {
+			exceededDays = append(exceededDays, u.Date)
+		}
+		if u.RequestCount > peak {
+			peak = u.RequestCount
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"key_id":             apiKey.ID,
+		"current_rate_limit": apiKey.RateLimit,
+		"proposed_limit":     proposed,
+		"days_evaluated":     len(usage),
+		"days_exceeded":      len(exceededDays),
+		"exceeded_dates":     exceededDays,
+		"peak_requests":      peak,
+	})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/gin-gonic/gin"
+)
+
+// Warmup readies a fresh instance for traffic: it connects to the database
+// and fills gorm's schema cache. Schedulers can call it to keep instances
+// warm; it needs no credentials and does no work once warm.
+func (h *Handler) Warmup(c *gin.Context) {
+	started := time.Now()
+	sqlDB, err := h.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(c.Request.Context())
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database unavailable"})
+		return
+	}
+	if err := database.ParseSchemas(h.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load schema"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "warm",
+		"database_ms": time.Since(started).Milliseconds(),
+	})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/gin-gonic/gin"
+)
+
+// minWebhookSecretLength is the shortest secret a key may choose
+const minWebhookSecretLength = 24
+
+// PutWebhookSecret sets or rotates the secret the calling key's webhooks are
+// signed with. Without a body, a random secret is generated. The secret is
+// only ever shown in this response.
+func (h *Handler) PutWebhookSecret(c *gin.Context) {
+	apiKey := c.MustGet("apiKey").(*database.APIKey)
+	var req struct {
+		Secret string `json:"secret"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate secret"})
+			return
+		}
+		req.Secret = "whsec_" + hex.EncodeToString(b)
+	}
+	if len(req.Secret) < minWebhookSecretLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "secret must be at least " + strconv.Itoa(minWebhookSecretLength) + " characters"})
+		return
+	}
+
+	sealed, err := pii.Encrypt(req.Secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not encrypt secret"})
+		return
+	}
+	secret := database.WebhookSecret{KeyID: apiKey.ID, Secret: sealed}
+	if err := h.DB.Save(&secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save secret"})
+		return
+	}
+	h.audit(c, "webhook_secret.rotate", apiKey.Name, "")
+	c.JSON(http.StatusOK, gin.H{"secret": req.Secret, "rotated_at": secret.RotatedAt})
+}
+
+// newWebhookRequest builds a webhook POST, signed with the key's secret if
+// it has one. The X-Webhook-Signature header is "sha256=" and the hex
+// HMAC-SHA256 of the X-Webhook-Timestamp value, a dot, and the body, so
+// receivers can reject both forged and replayed deliveries.
+func (h *Handler) newWebhookRequest(keyID uint, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var secret database.WebhookSecret
+	if h.DB.Where("key_id = ?", keyID).Limit(1).Find(&secret); secret.Secret == "" {
+		return req, nil
+	}
+	plain, err := pii.Decrypt(secret.Secret)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(plain))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req, nil
+}
+
+// Job webhooks are retried on network errors, 5xx and 429, waiting
+// jobWebhookBackoff and doubling it after each attempt
+var (
+	jobWebhookAttempts = 5
+	jobWebhookBackoff  = time.Second
+)
+
+// checkJobWebhook checks a job's webhook URL, and that the key has a secret
+// to sign deliveries with
+func (h *Handler) checkJobWebhook(keyID uint, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook_url must be an absolute http or https URL")
+	}
+	if !allowPrivateWebhooks() {
+		if err := checkPublicHost(u.Hostname()); err != nil {
+			return fmt.Errorf("webhook_url: %w", err)
+		}
+	}
+	var count int64
+	h.DB.Model(&database.WebhookSecret{}).Where("key_id = ?", keyID).Count(&count)
+	if count == 0 {
+		return errors.New("set a webhook secret with PUT /api/webhook-secret before using webhook_url")
+	}
+	return nil
+}
+
+// checkPublicHost refuses a host that is, or resolves to, a private address.
+// webhookClient checks again when it dials, in case the name's addresses
+// change in between.
+func checkPublicHost(host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if privateIP(ip) {
+			return errPrivateAddress
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if privateIP(addr.IP) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// notifyJob posts a finished job's outcome to its webhook, if it has one.
+// The body links to the result rather than carrying it, since results can
+// be large. Cancelled jobs aren't announced; whoever cancelled them knows.
+func (h *Handler) notifyJob(c *gin.Context, id string) {
+	var job database.Job
+	if err := h.DB.First(&job, "id = ?", id).Error; err != nil || job.WebhookURL == "" {
+		return
+	}
+	if job.Status != jobSucceeded && job.Status != jobFailed {
+		return
+	}
+	body, err := json.Marshal(gin.H{
+		"event":       "job.finished",
+		"job_id":      job.ID,
+		"status":      job.Status,
+		"error":       job.Error,
+		"schedule_id": job.ScheduleID,
+		"result_url":  externalURL(c, "/api/jobs/"+job.ID),
+	})
+	if err != nil {
+		return
+	}
+
+	status := "failed"
+	attempts := 0
+	wait := jobWebhookBackoff
+	for attempts < jobWebhookAttempts {
+		attempts++
+		retry, err := h.deliverWebhook(job.KeyID, job.WebhookURL, body)
+		if err == nil {
+			status = "delivered"
+			break
+		}
+		log.Printf("jobs: webhook for job %s failed (attempt %d): %v", job.ID, attempts, err)
+		if !retry || attempts == jobWebhookAttempts {
+			break
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+	h.DB.Model(&database.Job{}).Where("id = ?", job.ID).
+		Updates(map[string]any{"webhook_status": status, "webhook_attempts": attempts})
+}
+
+// deliverWebhook makes one signed delivery, reporting whether a failure is
+// worth retrying
+func (h *Handler) deliverWebhook(keyID uint, url string, body []byte) (retry bool, err error) {
+	req, err := h.newWebhookRequest(keyID, url, body)
+	if err != nil {
+		return false, err
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return false, nil
+}
@@ -0,0 +1,260 @@
+package handlers_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestScheduleAsync queues a job, polls it to completion, and checks that
+// its result matches what /api/schedule would have answered
+func TestScheduleAsync(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule/async", h.APIKeyMiddleware(), h.ScheduleAsync)
+	srv.Engine.GET("/api/jobs/:id", h.APIKeyMiddleware(), h.GetJob)
+	srv.Engine.DELETE("/api/jobs/:id", h.APIKeyMiddleware(), h.CancelJob)
+	key := srv.APIKey(t, "async")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	w := srv.Do(t, http.MethodPost, "/api/schedule/async", key.Key, input)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var queued struct {
+		JobID  string `json:"job_id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &queued); err != nil || queued.JobID == "" {
+		t.Fatalf("Expected a job ID, got %s", w.Body.String())
+	}
+
+	var job struct {
+		Status string                  `json:"status"`
+		Error  string                  `json:"error"`
+		Result models.ScheduleResponse `json:"result"`
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status != "succeeded" {
+		if time.Now().After(deadline) {
+			t.Fatalf("Job did not finish, last status %q", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		w = srv.Do(t, http.MethodGet, "/api/jobs/"+queued.JobID, key.Key, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+			t.Fatalf("decode job: %v", err)
+		}
+		if job.Status == "failed" || job.Status == "cancelled" {
+			t.Fatalf("Expected the job to succeed, got %q: %s", job.Status, job.Error)
+		}
+	}
+	if got := job.Result.AssignedShifts["shift_101"]; len(got) != 1 || got[0] != "vol_1" {
+		t.Errorf("Expected vol_1 on shift_101, got %v", got)
+	}
+
+	if w := srv.Do(t, http.MethodDelete, "/api/jobs/"+queued.JobID, key.Key, nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected a finished job not to be cancellable, got %d", w.Code)
+	}
+	other := srv.APIKey(t, "other")
+	if w := srv.Do(t, http.MethodGet, "/api/jobs/"+queued.JobID, other.Key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key not to see the job, got %d", w.Code)
+	}
+}
+
+// TestScheduleAsync_Webhook checks a finished job is posted to its webhook,
+// signed with the key's secret, after retrying a failed delivery, and that
+// webhooks on private addresses are refused unless allowed
+func TestScheduleAsync_Webhook(t *testing.T) {
+	type delivery struct {
+		timestamp, signature string
+		body                 []byte
+	}
+	deliveries := make(chan delivery, 2)
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{r.Header.Get("X-Webhook-Timestamp"), r.Header.Get("X-Webhook-Signature"), body}
+	}))
+	defer receiver.Close()
+
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule/async", h.APIKeyMiddleware(), h.ScheduleAsync)
+	srv.Engine.PUT("/api/webhook-secret", h.APIKeyMiddleware(), h.PutWebhookSecret)
+	key := srv.APIKey(t, "webhooks")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	path := "/api/schedule/async?webhook_url=" + url.QueryEscape(receiver.URL)
+	if w := srv.Do(t, http.MethodPost, path, key.Key, input); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a webhook without a secret to be refused, got %d", w.Code)
+	}
+
+	w := srv.Do(t, http.MethodPut, "/api/webhook-secret", key.Key, nil)
+	var secret struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &secret); err != nil || secret.Secret == "" {
+		t.Fatalf("Expected a webhook secret, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodPost, path, key.Key, input); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a webhook on a loopback address to be refused, got %d", w.Code)
+	}
+
+	// The receiver listens on loopback
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "on")
+	if w := srv.Do(t, http.MethodPost, path, key.Key, input); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var got delivery
+	select {
+	case got = <-deliveries:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the webhook to be retried and delivered")
+	}
+	mac := hmac.New(sha256.New, []byte(secret.Secret))
+	mac.Write([]byte(got.timestamp + "."))
+	mac.Write(got.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); got.signature != want {
+		t.Errorf("Expected signature %s, got %s", want, got.signature)
+	}
+	var payload struct {
+		Event     string `json:"event"`
+		Status    string `json:"status"`
+		ResultURL string `json:"result_url"`
+	}
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("decode webhook body: %v", err)
+	}
+	if payload.Event != "job.finished" || payload.Status != "succeeded" || payload.ResultURL == "" {
+		t.Errorf("Unexpected webhook body %s", got.body)
+	}
+}
+
+// TestFailInterruptedJobs checks that only jobs whose instance stopped
+// renewing their lease are failed, not those another instance is running
+func TestFailInterruptedJobs(t *testing.T) {
+	srv := testutil.NewServer(t)
+	now := time.Now()
+	live, lapsed := now.Add(time.Minute), now.Add(-time.Minute)
+	jobs := []database.Job{
+		{ID: "live", Status: "running", Instance: "other", LeaseUntil: &live},
+		{ID: "lapsed", Status: "running", Instance: "other", LeaseUntil: &lapsed},
+		{ID: "unleased", Status: "queued"},
+		{ID: "done", Status: "succeeded", Instance: "other", LeaseUntil: &lapsed},
+	}
+	if err := srv.DB.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := srv.Handler.FailInterruptedJobs(now); n != 2 {
+		t.Errorf("Expected 2 interrupted jobs, got %d", n)
+	}
+	want := map[string]string{"live": "running", "lapsed": "failed", "unleased": "failed", "done": "succeeded"}
+	for id, status := range want {
+		var job database.Job
+		if err := srv.DB.First(&job, "id = ?", id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != status {
+			t.Errorf("Expected job %s to be %s, got %s", id, status, job.Status)
+		}
+	}
+}
+
+// TestScheduleAsync_PendingCap checks unfinished jobs are capped per key and
+// per process, and that a cancelled job gives its room back
+func TestScheduleAsync_PendingCap(t *testing.T) {
+	t.Setenv("ASYNC_JOB_MAX_PENDING", "2")
+	t.Setenv("ASYNC_JOB_MAX_PENDING_PER_KEY", "1")
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule/async", h.APIKeyMiddleware(), h.ScheduleAsync)
+	srv.Engine.DELETE("/api/jobs/:id", h.APIKeyMiddleware(), h.CancelJob)
+	a, b, c := srv.APIKey(t, "a"), srv.APIKey(t, "b"), srv.APIKey(t, "c")
+
+	// A branch and bound search over overlapping shifts runs until its
+	// timeout, which keeps the job unfinished
+	slow := models.ScheduleInput{Algorithm: "branch_and_bound", TimeoutSeconds: 30}
+	for i := range 40 {
+		slow.Volunteers = append(slow.Volunteers, models.Volunteer{ID: fmt.Sprintf("v%d", i), Group: "G", MaxHours: 40})
+	}
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 60 {
+		st := start.Add(time.Duration(i*3) * time.Hour)
+		slow.UnassignedShifts = append(slow.UnassignedShifts, models.Shift{
+			ID: fmt.Sprintf("s%d", i), Start: st, End: st.Add(5 * time.Hour), RequiredGroups: map[string]int{"G": 3},
+		})
+	}
+	submit := func(key string) (int, string) {
+		w := srv.Do(t, http.MethodPost, "/api/schedule/async", key, slow)
+		var queued struct {
+			JobID string `json:"job_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &queued)
+		return w.Code, queued.JobID
+	}
+	var cancel []func()
+	defer func() {
+		for _, f := range cancel {
+			f()
+		}
+	}()
+	accept := func(key string) string {
+		t.Helper()
+		code, id := submit(key)
+		if code != http.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d", code)
+		}
+		cancel = append(cancel, func() { srv.Do(t, http.MethodDelete, "/api/jobs/"+id, key, nil) })
+		return id
+	}
+
+	first := accept(a.Key)
+	if code, _ := submit(a.Key); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a key's second job to get 429, got %d", code)
+	}
+	accept(b.Key)
+	if code, _ := submit(c.Key); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected a full queue to answer 503, got %d", code)
+	}
+
+	if w := srv.Do(t, http.MethodDelete, "/api/jobs/"+first, a.Key, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected the job to be cancelled, got %d: %s", w.Code, w.Body.String())
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		code, id := submit(a.Key)
+		if code == http.StatusAccepted {
+			cancel = append(cancel, func() { srv.Do(t, http.MethodDelete, "/api/jobs/"+id, a.Key, nil) })
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the cancelled job's room back, still got %d", code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestListKeys checks key listings carry a preview rather than the key, and
+// that read-only service tokens can't list keys at all
+func TestListKeys(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.GET("/admin/keys", h.AuthMiddleware(), h.ListKeys)
+	srv.Engine.POST("/admin/tokens", h.AuthMiddleware(), h.CreateServiceToken)
+	admin := srv.AdminToken(t)
+	key := srv.APIKey(t, "listed")
+
+	w := srv.Do(t, http.MethodGet, "/admin/keys", admin, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), key.Key) || !strings.Contains(w.Body.String(), key.KeyPreview) {
+		t.Errorf("Expected the listing to show only the key preview, got %s", w.Body.String())
+	}
+
+	w = srv.Do(t, http.MethodPost, "/admin/tokens", admin, map[string]string{"name": "dashboards", "role": "reader"})
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil || token.Token == "" {
+		t.Fatalf("Expected a service token, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/admin/keys", token.Token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a reader token not to list keys, got %d", w.Code)
+	}
+}
+
+// TestGenerateKey_Upsert checks upserting an existing name never hands out
+// its secret, and that a revoked key isn't upserted at all
+func TestGenerateKey_Upsert(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/admin/keys", h.AuthMiddleware(), h.GenerateKey)
+	admin := srv.AdminToken(t)
+
+	w := srv.Do(t, http.MethodPost, "/admin/keys", admin, map[string]any{"name": "upserted"})
+	var created struct {
+		Key     string `json:"key"`
+		Created bool   `json:"created"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || !created.Created || created.Key == "" {
+		t.Fatalf("Expected a new key, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = srv.Do(t, http.MethodPost, "/admin/keys", admin, map[string]any{"name": "upserted", "upsert": true, "rate_limit": 50})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var upserted map[string]any
+	json.Unmarshal(w.Body.Bytes(), &upserted)
+	if strings.Contains(w.Body.String(), created.Key) || upserted["key"] != nil {
+		t.Errorf("Expected the upsert not to return the secret, got %s", w.Body.String())
+	}
+	if upserted["created"] != false || upserted["key_preview"] == "" || upserted["rate_limit"] != float64(50) {
+		t.Errorf("Expected the existing key's preview and new rate limit, got %s", w.Body.String())
+	}
+
+	srv.DB.Model(&database.APIKey{}).Where("name = ?", "upserted").Update("revoked_at", time.Now())
+	w = srv.Do(t, http.MethodPost, "/admin/keys", admin, map[string]any{"name": "upserted", "upsert": true})
+	if w.Code != http.StatusConflict || strings.Contains(w.Body.String(), created.Key) {
+		t.Errorf("Expected a revoked key to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestImpersonateKey_Revoked checks a revoked key can neither be issued an
+// impersonation token nor be acted as with one issued before it was revoked
+func TestImpersonateKey_Revoked(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/admin/keys/:id/impersonate", h.AuthMiddleware(), h.ImpersonateKey)
+	srv.Engine.GET("/api/usage", h.APIKeyMiddleware(), h.GetMyUsage)
+	admin := srv.AdminToken(t)
+	key := srv.APIKey(t, "impersonated")
+	path := "/admin/keys/" + strconv.Itoa(int(key.ID)) + "/impersonate"
+
+	w := srv.Do(t, http.MethodPost, path, admin, nil)
+	var issued struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &issued); err != nil || issued.AccessToken == "" {
+		t.Fatalf("Expected an impersonation token, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/usage", issued.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected the token to act as the key, got %d: %s", w.Code, w.Body.String())
+	}
+
+	srv.DB.Model(key).Update("revoked_at", time.Now())
+	if w := srv.Do(t, http.MethodGet, "/api/usage", issued.AccessToken, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the token refused once the key was revoked, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodPost, path, admin, nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected no token issued for a revoked key, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,59 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestStrictParity runs each fixture in testdata/parity through
+// /schedule/json in strict parity mode and checks the response matches its
+// expected output exactly, field names included. Fixtures are plain request
+// and response JSON, so the Python version's suite can run the same files.
+func TestStrictParity(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "parity", "*"))
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("no parity fixtures found: %v", err)
+	}
+
+	srv := testutil.NewServer(t)
+	srv.Engine.POST("/schedule/json", srv.Handler.APIKeyMiddleware(), srv.Handler.ScheduleJSON)
+	key := srv.APIKey(t, "parity")
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			var input map[string]any
+			readJSON(t, filepath.Join(dir, "input.json"), &input)
+			input["strict_parity"] = true
+
+			w := srv.Do(t, http.MethodPost, "/schedule/json", key.Key, input)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var got, want any
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			readJSON(t, filepath.Join(dir, "expected.json"), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Response differs from expected.json\ngot:  %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestRevokeSubKey checks a revoked sub-key stays revoked: it is refused on
+// its next use rather than provisioned again, and its name can't be reused
+// to mint the same key
+func TestRevokeSubKey(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	partnerRoutes := srv.Engine.Group("/partner", h.APIKeyMiddleware(), h.PartnerMiddleware())
+	partnerRoutes.POST("/keys", h.CreateSubKey)
+	partnerRoutes.DELETE("/keys/:id", h.RevokeSubKey)
+	srv.Engine.GET("/api/usage", h.APIKeyMiddleware(), h.GetMyUsage)
+
+	partner := srv.APIKey(t, "partner")
+	srv.DB.Model(partner).Update("max_sub_keys", 1)
+
+	w := srv.Do(t, http.MethodPost, "/partner/keys", partner.Key, map[string]string{"name": "venue"})
+	var sub struct {
+		ID  uint   `json:"id"`
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &sub); err != nil || sub.Key == "" {
+		t.Fatalf("Expected a sub-key, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/usage", sub.Key, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected the sub-key to work, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := srv.Do(t, http.MethodDelete, "/partner/keys/"+strconv.Itoa(int(sub.ID)), partner.Key, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/usage", sub.Key, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked sub-key to be refused, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodPost, "/partner/keys", partner.Key, map[string]string{"name": "venue"}); w.Code != http.StatusConflict {
+		t.Errorf("Expected the revoked name not to be reusable, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodPost, "/partner/keys", partner.Key, map[string]string{"name": "arena"}); w.Code != http.StatusCreated {
+		t.Errorf("Expected the revoked key not to count towards the limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestCreateSubKey_Limits checks sub-keys' rate limits are carved out of the
+// partner's, and that concurrent creates can't exceed max_sub_keys
+func TestCreateSubKey_Limits(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/partner/keys", h.APIKeyMiddleware(), h.PartnerMiddleware(), h.CreateSubKey)
+	partner := srv.APIKey(t, "partner")
+	srv.DB.Model(partner).Updates(map[string]any{"max_sub_keys": 2, "rate_limit": 100})
+
+	create := func(name string, rateLimit int) *httptest.ResponseRecorder {
+		return srv.Do(t, http.MethodPost, "/partner/keys", partner.Key, map[string]any{"name": name, "rate_limit": rateLimit})
+	}
+	if w := create("main", 80); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := create("side", 30); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a limit over what's left of the partner's refused, got %d: %s", w.Code, w.Body.String())
+	}
+	w := create("side", 0)
+	var sub struct {
+		RateLimit int `json:"rate_limit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &sub); err != nil || w.Code != http.StatusCreated || sub.RateLimit != 20 {
+		t.Errorf("Expected a default limit of the 20 left, got %d: %s", w.Code, w.Body.String())
+	}
+
+	srv.DB.Model(partner).Updates(map[string]any{"max_sub_keys": 5, "rate_limit": 1000})
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			create("venue-"+strconv.Itoa(i), 1)
+		}()
+	}
+	wg.Wait()
+	var live int64
+	srv.DB.Model(&database.APIKey{}).Where("partner_id = ?", partner.ID).Count(&live)
+	if live != 5 {
+		t.Errorf("Expected concurrent creates held to 5 sub-keys, got %d", live)
+	}
+}
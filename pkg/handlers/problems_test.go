@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// problemRoutes registers the draft problem endpoints on srv
+func problemRoutes(srv *testutil.Server) {
+	h := srv.Handler
+	api := srv.Engine.Group("/api", h.APIKeyMiddleware())
+	api.POST("/problems", h.CreateProblem)
+	api.POST("/problems/:id/volunteers", h.AddProblemVolunteers)
+	api.POST("/problems/:id/shifts", h.AddProblemShifts)
+	api.POST("/problems/:id/assignments", h.AddProblemAssignments)
+	api.POST("/problems/:id/solve", h.SolveProblem)
+	api.GET("/problems/:id", h.GetProblem)
+	api.DELETE("/problems/:id", h.DeleteProblem)
+	api.DELETE("/problems/:id/volunteers/:itemId", h.RemoveProblemVolunteer)
+	api.DELETE("/problems/:id/shifts/:itemId", h.RemoveProblemShift)
+	api.DELETE("/problems/:id/assignments", h.RemoveProblemAssignment)
+}
+
+// createProblem starts a draft for key and returns its ID
+func createProblem(t *testing.T, srv *testutil.Server, key string) string {
+	t.Helper()
+	w := srv.Do(t, http.MethodPost, "/api/problems", key, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ProblemID string `json:"problem_id"`
+	}
+	decode(t, w.Body.String(), &created)
+	return created.ProblemID
+}
+
+// TestProblems_ChunkedSolve uploads a problem in chunks, edits it, and
+// checks the solve only sees what's left
+func TestProblems_ChunkedSolve(t *testing.T) {
+	srv := testutil.NewServer(t)
+	problemRoutes(srv)
+	key := srv.APIKey(t, "drafts").Key
+	id := createProblem(t, srv, key)
+
+	var input models.ScheduleInput
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	for _, chunk := range [][]models.Volunteer{input.Volunteers[:1], input.Volunteers[1:]} {
+		if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/volunteers", key, chunk); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/shifts", key, input.UnassignedShifts); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// Re-uploading an item replaces it rather than adding a second copy
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/volunteers", key, input.Volunteers[:1]); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var got struct {
+		Totals  map[string]int       `json:"totals"`
+		Problem models.ScheduleInput `json:"problem"`
+	}
+	w := srv.Do(t, http.MethodGet, "/api/problems/"+id, key, nil)
+	decode(t, w.Body.String(), &got)
+	if got.Totals["volunteers"] != 3 || got.Totals["shifts"] != 2 || len(got.Problem.Volunteers) != 3 {
+		t.Fatalf("Expected 3 volunteers and 2 shifts, got %s", w.Body.String())
+	}
+
+	if w := srv.Do(t, http.MethodDelete, "/api/problems/"+id+"/volunteers/vol_3", key, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodDelete, "/api/problems/"+id+"/volunteers/vol_3", key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected removing a missing volunteer to 404, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodDelete, "/api/problems/"+id+"/assignments", key, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected removing an assignment without IDs to 400, got %d", w.Code)
+	}
+
+	resp := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/problems/"+id+"/solve", key, nil))
+	if got := resp.AssignedShifts["shift_101"]; len(got) != 1 || got[0] != "vol_1" {
+		t.Errorf("Expected vol_1 on shift_101, got %v", got)
+	}
+	if got := resp.AssignedShifts["shift_102"]; len(got) != 1 || got[0] != "vol_2" {
+		t.Errorf("Expected only vol_2 on shift_102 once vol_3 was removed, got %v", got)
+	}
+
+	other := srv.APIKey(t, "other").Key
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/solve", other, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key not to see the draft, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodDelete, "/api/problems/"+id, key, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var left int64
+	srv.DB.Model(&database.DraftItem{}).Where("problem_id = ?", id).Count(&left)
+	if left != 0 {
+		t.Errorf("Expected a deleted draft to leave no items, got %d", left)
+	}
+}
+
+// TestProblems_DuplicateInChunk checks a chunk repeating an ID is refused
+// whole, instead of failing inside the upsert
+func TestProblems_DuplicateInChunk(t *testing.T) {
+	srv := testutil.NewServer(t)
+	problemRoutes(srv)
+	key := srv.APIKey(t, "drafts").Key
+	id := createProblem(t, srv, key)
+
+	volunteers := []models.Volunteer{
+		{ID: "vol_1", Group: "Lifeguards", MaxHours: 40},
+		{ID: "vol_2", Group: "Medics", MaxHours: 40},
+		{ID: "vol_1", Group: "Drivers", MaxHours: 40},
+	}
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/volunteers", key, volunteers); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	assignments := []models.Assignment{
+		{ShiftID: "shift_101", VolunteerID: "vol_1"},
+		{ShiftID: "shift_101", VolunteerID: "vol_1"},
+	}
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/assignments", key, assignments); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored int64
+	srv.DB.Model(&database.DraftItem{}).Where("problem_id = ?", id).Count(&stored)
+	if stored != 0 {
+		t.Errorf("Expected nothing stored from a refused chunk, got %d items", stored)
+	}
+}
+
+// TestReapDrafts checks drafts are deleted with their items once they go
+// unedited past the retention period
+func TestReapDrafts(t *testing.T) {
+	t.Setenv("DRAFT_RETENTION_DAYS", "2")
+	srv := testutil.NewServer(t)
+	problemRoutes(srv)
+	key := srv.APIKey(t, "drafts").Key
+	id := createProblem(t, srv, key)
+	volunteers := []models.Volunteer{{ID: "vol_1", Group: "Lifeguards", MaxHours: 40}}
+	if w := srv.Do(t, http.MethodPost, "/api/problems/"+id+"/volunteers", key, volunteers); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	now := time.Now()
+	if n := srv.Handler.ReapDrafts(now.AddDate(0, 0, 1)); n != 0 {
+		t.Fatalf("Expected a recent draft to be kept, deleted %d", n)
+	}
+	if n := srv.Handler.ReapDrafts(now.AddDate(0, 0, 3)); n != 1 {
+		t.Fatalf("Expected the stale draft to be deleted, deleted %d", n)
+	}
+	var items int64
+	srv.DB.Model(&database.DraftItem{}).Where("problem_id = ?", id).Count(&items)
+	if items != 0 {
+		t.Errorf("Expected the draft's items to be deleted, got %d", items)
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/problems/"+id, key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the reaped draft to 404, got %d", w.Code)
+	}
+}
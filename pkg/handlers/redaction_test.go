@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// TestRedaction_MasksVolunteerNames logs a line naming volunteers while their
+// schedule is being served and checks the names don't reach the log, then
+// that they're logged again once the request is over
+func TestRedaction_MasksVolunteerNames(t *testing.T) {
+	t.Setenv("PII_REDACTION", "on")
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+
+	// Servers cancel a request's context once it's answered; httptest doesn't
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	logger := log.New(pii.NewRedactingWriter(&buf), "", 0)
+	logAfter := func(c *gin.Context) {
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		logger.Printf("assigned Alice and Bob, not Alicia; ask cara@example.com")
+	}
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), logAfter, h.ScheduleJSON)
+	key := srv.APIKey(t, "redaction")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	if w := srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	line := buf.String()
+	for _, name := range []string{"Alice", "Bob", "cara@"} {
+		if strings.Contains(line, name) {
+			t.Errorf("Expected %q to be redacted, got %q", name, line)
+		}
+	}
+	if !strings.Contains(line, "Alicia") {
+		t.Errorf("Expected names that only start like a volunteer's to be kept, got %q", line)
+	}
+
+	cancel()
+	buf.Reset()
+	// The names are released by a context.AfterFunc, which runs on its own goroutine
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "Alice") && time.Now().Before(deadline) {
+		buf.Reset()
+		logger.Printf("Alice")
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "Alice") {
+		t.Errorf("Expected names to be released once the request was over, got %q", buf.String())
+	}
+}
+
+// TestRedaction_MasksJobNames logs a line naming a job's volunteers while its
+// webhook is being delivered, after the request that queued it has ended
+func TestRedaction_MasksJobNames(t *testing.T) {
+	t.Setenv("PII_REDACTION", "on")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "on")
+	var buf bytes.Buffer
+	logger := log.New(pii.NewRedactingWriter(&buf), "", 0)
+	delivered := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Printf("delivering Cara's schedule")
+		close(delivered)
+	}))
+	defer receiver.Close()
+
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule/async", h.APIKeyMiddleware(), h.ScheduleAsync)
+	srv.Engine.PUT("/api/webhook-secret", h.APIKeyMiddleware(), h.PutWebhookSecret)
+	key := srv.APIKey(t, "redacted-jobs")
+	srv.Do(t, http.MethodPut, "/api/webhook-secret", key.Key, nil)
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	path := "/api/schedule/async?webhook_url=" + url.QueryEscape(receiver.URL)
+	if w := srv.Do(t, http.MethodPost, path, key.Key, input); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	select {
+	case <-delivered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the webhook to be delivered")
+	}
+	if strings.Contains(buf.String(), "Cara") {
+		t.Errorf("Expected the job's volunteer names to be redacted, got %q", buf.String())
+	}
+}
@@ -0,0 +1,48 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// TestReader_StickyAcrossInstances creates a key through one instance and
+// lists keys through another, which must read the primary to see it
+func TestReader_StickyAcrossInstances(t *testing.T) {
+	srv := testutil.NewServer(t)
+	replica, err := database.OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := replica.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	first := srv.Handler
+	first.Replica = replica
+	srv.Engine.POST("/admin/keys", first.AuthMiddleware(), first.GenerateKey)
+
+	second := &handlers.Handler{DB: srv.DB, Replica: replica}
+	other := &testutil.Server{Engine: gin.New(), Handler: second, DB: srv.DB}
+	other.Engine.Use(second.TrackWrites())
+	other.Engine.GET("/admin/keys", second.AuthMiddleware(), second.ListKeys)
+	admin := srv.AdminToken(t)
+
+	srv.APIKey(t, "existing")
+	if w := other.Do(t, http.MethodGet, "/admin/keys", admin, nil); strings.Contains(w.Body.String(), "existing") {
+		t.Fatalf("Expected a caller who hasn't written to read the replica, got %s", w.Body.String())
+	}
+
+	if w := srv.Do(t, http.MethodPost, "/admin/keys", admin, map[string]any{"name": "sticky"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := other.Do(t, http.MethodGet, "/admin/keys", admin, nil); !strings.Contains(w.Body.String(), "sticky") {
+		t.Errorf("Expected the other instance to list the new key, got %s", w.Body.String())
+	}
+}
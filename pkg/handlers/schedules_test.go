@@ -0,0 +1,383 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestSchedules saves two schedules, lists and filters them, and deletes one
+func TestSchedules(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.GET("/api/schedules", h.APIKeyMiddleware(), h.ListSchedules)
+	srv.Engine.GET("/api/schedules/:id", h.APIKeyMiddleware(), h.GetSchedule)
+	srv.Engine.DELETE("/api/schedules/:id", h.APIKeyMiddleware(), h.DeleteSchedule)
+	key := srv.APIKey(t, "schedules")
+
+	var ids []string
+	for _, fixture := range []string{"basic", "max_hours"} {
+		var input map[string]any
+		readJSON(t, filepath.Join("testdata", "parity", fixture, "input.json"), &input)
+		input["save"] = true
+		resp := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input))
+		ids = append(ids, resp.ScheduleID)
+	}
+
+	type list struct {
+		Schedules []struct {
+			ScheduleID  string `json:"schedule_id"`
+			Assignments int    `json:"assignments"`
+		} `json:"schedules"`
+		Total int `json:"total"`
+	}
+	get := func(path string) list {
+		t.Helper()
+		w := srv.Do(t, http.MethodGet, path, key.Key, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var l list
+		if err := json.Unmarshal(w.Body.Bytes(), &l); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		return l
+	}
+
+	if l := get("/api/schedules"); l.Total != 2 || len(l.Schedules) != 2 {
+		t.Fatalf("Expected both schedules listed, got %+v", l)
+	}
+	l := get("/api/schedules?shift_id=shift_102")
+	if l.Total != 1 || l.Schedules[0].ScheduleID != ids[0] || l.Schedules[0].Assignments != 3 {
+		t.Errorf("Expected only the basic schedule, with 3 assignments, got %+v", l)
+	}
+	if l := get("/api/schedules?limit=1&offset=1"); l.Total != 2 || len(l.Schedules) != 1 {
+		t.Errorf("Expected one schedule of two on the second page, got %+v", l)
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/schedules?limit=0", key.Key, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected limit=0 to be rejected, got %d", w.Code)
+	}
+
+	if w := srv.Do(t, http.MethodDelete, "/api/schedules/"+ids[0], key.Key, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/api/schedules/"+ids[0], key.Key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted schedule to be gone, got %d", w.Code)
+	}
+	if l := get("/api/schedules?shift_id=shift_102"); l.Total != 0 {
+		t.Errorf("Expected the deleted schedule's assignments to be gone, got %+v", l)
+	}
+}
+
+// TestScheduleDiff regenerates a saved schedule from a new roster and checks
+// the diff between the two versions
+func TestScheduleDiff(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.POST("/api/schedules/:id/regenerate", h.APIKeyMiddleware(), h.RegenerateSchedule)
+	srv.Engine.POST("/api/schedules/:id/undo", h.APIKeyMiddleware(), h.UndoSchedule)
+	srv.Engine.GET("/api/schedules/:id/diff", h.APIKeyMiddleware(), h.DiffSchedule)
+	key := srv.APIKey(t, "diff")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	id := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input)).ScheduleID
+	path := "/api/schedules/" + id
+
+	// Alice leaves, Cara retrains as a lifeguard and Dev joins as a driver
+	input["volunteers"] = []map[string]any{
+		{"id": "vol_2", "name": "Bob", "group": "Medics", "max_hours": 40},
+		{"id": "vol_3", "name": "Cara", "group": "Lifeguards", "max_hours": 40},
+		{"id": "vol_4", "name": "Dev", "group": "Drivers", "max_hours": 40},
+	}
+	if w := srv.Do(t, http.MethodPost, path+"/regenerate", key.Key, map[string]any{"input": input}); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected regenerating without a version to be refused, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodPost, path+"/regenerate", key.Key, map[string]any{"version": 1, "input": input}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := srv.Do(t, http.MethodGet, path+"/diff?from=v1&to=v2", key.Key, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var diff struct {
+		Added   []map[string]string `json:"added"`
+		Removed []map[string]string `json:"removed"`
+		Moved   []map[string]string `json:"moved"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0]["volunteer_id"] != "vol_4" || diff.Added[0]["shift_id"] != "shift_102" {
+		t.Errorf("Expected vol_4 added to shift_102, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0]["volunteer_id"] != "vol_1" || diff.Removed[0]["shift_id"] != "shift_101" {
+		t.Errorf("Expected vol_1 removed from shift_101, got %v", diff.Removed)
+	}
+	want := map[string]string{"volunteer_id": "vol_3", "from_shift_id": "shift_102", "to_shift_id": "shift_101"}
+	if len(diff.Moved) != 1 || !maps.Equal(diff.Moved[0], want) {
+		t.Errorf("Expected vol_3 moved from shift_102 to shift_101, got %v", diff.Moved)
+	}
+
+	if w := srv.Do(t, http.MethodGet, path+"/diff", key.Key, nil); !strings.Contains(w.Body.String(), `"from":1,"moved"`) {
+		t.Errorf("Expected the diff to default to the last two versions, got %s", w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, path+"/diff?to=v3", key.Key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown version to be a 404, got %d", w.Code)
+	}
+	if w := srv.Do(t, http.MethodPost, path+"/undo", key.Key, map[string]any{"version": 2}); w.Code != http.StatusConflict {
+		t.Errorf("Expected nothing to undo after regenerating, got %d", w.Code)
+	}
+}
+
+// TestGetScheduleCompliance checks a saved schedule's compliance report
+// lists the busy times and travel times it was solved under
+func TestGetScheduleCompliance(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.GET("/api/schedules/:id/compliance", h.APIKeyMiddleware(), h.GetScheduleCompliance)
+	key := srv.APIKey(t, "compliance")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	input["travel_minutes"] = map[string]any{"north": map[string]any{"south": 30}}
+	input["volunteers"].([]any)[0].(map[string]any)["busy_times"] = []map[string]any{
+		{"start": "2026-05-02T09:00:00Z", "end": "2026-05-02T17:00:00Z"},
+	}
+	id := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input)).ScheduleID
+
+	check := func(when string) {
+		t.Helper()
+		w := srv.Do(t, http.MethodGet, "/api/schedules/"+id+"/compliance", key.Key, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var body struct {
+			Compliance struct {
+				Evaluated []string `json:"evaluated"`
+			} `json:"compliance"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode compliance: %v", err)
+		}
+		for _, policy := range []string{"no_overlap", "busy_times", "travel_minutes"} {
+			if !slices.Contains(body.Compliance.Evaluated, policy) {
+				t.Errorf("%s: expected %s evaluated, got %v", when, policy, body.Compliance.Evaluated)
+			}
+		}
+	}
+	check("stored report")
+
+	// Schedules saved before reports were recorded have theirs rebuilt
+	var schedule database.Schedule
+	srv.DB.First(&schedule, "id = ?", id)
+	var result map[string]any
+	if err := json.Unmarshal([]byte(schedule.Result), &result); err != nil {
+		t.Fatal(err)
+	}
+	delete(result, "compliance")
+	data, _ := json.Marshal(result)
+	srv.DB.Model(&schedule).Update("result", string(data))
+	check("rebuilt report")
+
+	if w := srv.Do(t, http.MethodGet, "/api/schedules/missing/compliance", key.Key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown schedule to be a 404, got %d", w.Code)
+	}
+}
+
+// TestStorageQuota checks the schedule quota is enforced on save, and that
+// changing a schedule clears an expiry warning sent for it
+func TestStorageQuota(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.POST("/api/schedules/:id/regenerate", h.APIKeyMiddleware(), h.RegenerateSchedule)
+	key := srv.APIKey(t, "quota")
+	if err := srv.DB.Create(&database.StoragePolicy{KeyID: key.ID, MaxSchedules: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	id := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input)).ScheduleID
+	if w := srv.Do(t, http.MethodPost, "/api/schedule", key.Key, input); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a second schedule to exceed the quota, got %d: %s", w.Code, w.Body.String())
+	}
+
+	srv.DB.Model(&database.Schedule{}).Where("id = ?", id).Update("warned_at", time.Now())
+	if w := srv.Do(t, http.MethodPost, "/api/schedules/"+id+"/regenerate", key.Key, map[string]any{"version": 1, "input": input}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var schedule database.Schedule
+	srv.DB.First(&schedule, "id = ?", id)
+	if schedule.WarnedAt != nil {
+		t.Errorf("Expected regenerating to clear the expiry warning, got %v", schedule.WarnedAt)
+	}
+}
+
+// TestImportScheduleBundle exports a schedule and imports it under another
+// key, both as a raw zip and as a form upload, and checks broken bundles
+// are refused
+func TestImportScheduleBundle(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.GET("/api/schedules/:id", h.APIKeyMiddleware(), h.GetSchedule)
+	srv.Engine.GET("/api/schedules/:id/bundle", h.APIKeyMiddleware(), h.GetScheduleBundle)
+	srv.Engine.POST("/api/schedules/import", h.APIKeyMiddleware(), h.ImportScheduleBundle)
+	from, to := srv.APIKey(t, "exporter"), srv.APIKey(t, "importer")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	original := testutil.DecodeSchedule(t, srv.Do(t, http.MethodPost, "/api/schedule", from.Key, input))
+	w := srv.Do(t, http.MethodGet, "/api/schedules/"+original.ScheduleID+"/bundle", from.Key, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	bundle := w.Body.Bytes()
+
+	importAs := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/schedules/import", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+to.Key)
+		w := httptest.NewRecorder()
+		srv.Engine.ServeHTTP(w, req)
+		return w
+	}
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, err := mw.CreateFormFile("bundle", "schedule.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bundle)
+	mw.Close()
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"raw":  importAs("application/zip", bundle),
+		"form": importAs(mw.FormDataContentType(), form.Bytes()),
+	} {
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: Expected status 201, got %d: %s", name, w.Code, w.Body.String())
+		}
+		var imported struct {
+			ScheduleID   string `json:"schedule_id"`
+			ImportedFrom string `json:"imported_from"`
+		}
+		decode(t, w.Body.String(), &imported)
+		if imported.ImportedFrom != original.ScheduleID || imported.ScheduleID == original.ScheduleID {
+			t.Errorf("%s: Expected a new schedule imported from %s, got %+v", name, original.ScheduleID, imported)
+		}
+		var stored struct {
+			Result struct {
+				AssignedShifts map[string][]string `json:"assigned_shifts"`
+			} `json:"result"`
+		}
+		decode(t, srv.Do(t, http.MethodGet, "/api/schedules/"+imported.ScheduleID, to.Key, nil).Body.String(), &stored)
+		if !maps.EqualFunc(stored.Result.AssignedShifts, original.AssignedShifts, slices.Equal) {
+			t.Errorf("%s: Expected the imported assignments %v, got %v", name, original.AssignedShifts, stored.Result.AssignedShifts)
+		}
+	}
+
+	// zipOf builds a bundle holding the named files
+	zipOf := func(files map[string][]byte) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, data := range files {
+			f, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write(data)
+		}
+		zw.Close()
+		return buf.Bytes()
+	}
+	inputJSON, _ := json.Marshal(input)
+	for name, tc := range map[string]struct {
+		body   []byte
+		status int
+	}{
+		"empty":            {nil, http.StatusBadRequest},
+		"not a zip":        {[]byte("not a zip"), http.StatusBadRequest},
+		"missing solution": {zipOf(map[string][]byte{"input.json": inputJSON}), http.StatusBadRequest},
+		"invalid solution": {zipOf(map[string][]byte{"input.json": inputJSON, "solution.json": []byte("{")}), http.StatusBadRequest},
+		"large unpacked":   {zipOf(map[string][]byte{"input.json": make([]byte, 32<<20+1), "solution.json": []byte("{}")}), http.StatusRequestEntityTooLarge},
+	} {
+		if w := importAs("application/zip", tc.body); w.Code != tc.status {
+			t.Errorf("%s: Expected status %d, got %d: %s", name, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestImportScheduleBundle_TooLarge checks an oversized bundle is refused
+// rather than truncated into an invalid zip
+func TestImportScheduleBundle_TooLarge(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedules/import", h.APIKeyMiddleware(), h.ImportScheduleBundle)
+	key := srv.APIKey(t, "import")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/schedules/import", bytes.NewReader(make([]byte, 32<<20+1)))
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("Authorization", "Bearer "+key.Key)
+	w := httptest.NewRecorder()
+	srv.Engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "at most 32 MB") {
+		t.Fatalf("Expected status 413 naming the limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestReapSchedules_WarnsBeforeDeleting reaps a schedule already past its
+// retention but never warned: it must be warned first and kept for WarnDays
+func TestReapSchedules_WarnsBeforeDeleting(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "on")
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	var warnings atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		warnings.Add(1)
+	}))
+	defer receiver.Close()
+
+	key := srv.APIKey(t, "reaped")
+	srv.DB.Create(&database.StoragePolicy{KeyID: key.ID, RetentionDays: 30, WarningWebhook: receiver.URL, WarnDays: 3})
+	now := time.Now()
+	srv.DB.Create(&database.Schedule{ID: "stale", KeyID: key.ID, Input: "{}", Result: "{}"})
+	srv.DB.Model(&database.Schedule{}).Where("id = ?", "stale").UpdateColumn("updated_at", now.AddDate(0, 0, -60))
+
+	if summary := h.ReapSchedules(now); summary.Warned != 1 || summary.Deleted != 0 {
+		t.Fatalf("Expected the schedule warned and kept, got %+v", summary)
+	}
+	if summary := h.ReapSchedules(now.AddDate(0, 0, 2)); summary.Warned != 0 || summary.Deleted != 0 {
+		t.Fatalf("Expected the schedule kept until the warning is 3 days old, got %+v", summary)
+	}
+	if summary := h.ReapSchedules(now.AddDate(0, 0, 3)); summary.Deleted != 1 {
+		t.Errorf("Expected the schedule deleted 3 days after the warning, got %+v", summary)
+	}
+	if n := warnings.Load(); n != 1 {
+		t.Errorf("Expected one warning sent, got %d", n)
+	}
+}
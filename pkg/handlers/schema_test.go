@@ -0,0 +1,113 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+	"github.com/arnavshah/scheduler-api-go/pkg/schema"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// getSchema fetches /api/schema and checks it still matches the models
+func getSchema(t *testing.T, srv *testutil.Server) map[string]any {
+	t.Helper()
+	w := srv.Do(t, http.MethodGet, "/api/schema", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var served, want map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	data, err := json.Marshal(handlers.SchemaDocument())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(served, want) {
+		t.Fatal("schema.json is out of date; run go generate ./pkg/handlers")
+	}
+	return served
+}
+
+// TestGetSchema checks that the parity fixtures and a solved schedule are
+// valid against the published schemas, so clients validating their requests
+// with it accept what the server does
+func TestGetSchema(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Engine.GET("/api/schema", srv.Handler.GetSchema)
+	srv.Engine.POST("/schedule/json", srv.Handler.APIKeyMiddleware(), srv.Handler.ScheduleJSON)
+	key := srv.APIKey(t, "schema")
+
+	doc := getSchema(t, srv)
+	inputSchema := doc["schedule_input"].(map[string]any)
+	responseSchema := doc["schedule_response"].(map[string]any)
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "parity", "*", "input.json"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no parity fixtures found: %v", err)
+	}
+	for _, path := range inputs {
+		var input any
+		readJSON(t, path, &input)
+		if err := schema.Validate(inputSchema, input); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+
+	var tiered any
+	decode(t, `{
+		"volunteers": [
+			{"id": "v1", "name": "Alice", "group": "Medics", "max_hours": 8},
+			{"id": "v2", "name": "Bob", "group": "Medics", "max_hours": 8}
+		],
+		"unassigned_shifts": [
+			{"id": "s1", "start": "2026-05-01T09:00:00Z", "end": "2026-05-01T13:00:00Z",
+			 "required_groups": {"Medics": {"min": 1, "ideal": 2}}},
+			{"id": "s2", "start": "2026-05-01T14:00:00Z", "end": "2026-05-01T16:00:00Z",
+			 "roles": [{"name": "lead", "group": "Medics", "count": 1}]}
+		],
+		"current_assignments": []
+	}`, &tiered)
+	if err := schema.Validate(inputSchema, tiered); err != nil {
+		t.Errorf("Tiered and role-only shifts rejected: %v", err)
+	}
+
+	w := srv.Do(t, http.MethodPost, "/schedule/json", key.Key, tiered)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if err := schema.Validate(responseSchema, resp); err != nil {
+		t.Errorf("Response doesn't match its schema: %v", err)
+	}
+
+	var bad any
+	decode(t, `{
+		"volunteers": [],
+		"unassigned_shifts": [
+			{"id": "s1", "start": "2026-05-01T09:00:00Z", "end": "2026-05-01T13:00:00Z",
+			 "required_groups": {"Medics": "two"}}
+		],
+		"current_assignments": []
+	}`, &bad)
+	if err := schema.Validate(inputSchema, bad); err == nil {
+		t.Error("Expected a string headcount to be rejected")
+	}
+}
+
+func decode(t *testing.T, data string, v any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		t.Fatal(err)
+	}
+}
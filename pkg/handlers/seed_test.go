@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+const seedYAML = `
+admins:
+  - username: staging
+    password_env: SEED_TEST_PASSWORD
+keys:
+  - name: demo-league
+    rate_limit: 500
+    problems:
+      - id: demo-weekend
+        volunteers:
+          - {id: vol_1, name: Ana, group: Lifeguards, max_hours: 40}
+          - {id: vol_2, name: Ben, group: Medics, max_hours: 40}
+        shifts:
+          - id: shift_1
+            start: 2026-05-01T09:00:00Z
+            end: 2026-05-01T17:00:00Z
+            required_groups: {Lifeguards: 1}
+profiles:
+  - name: quick
+    settings: {algorithm: greedy}
+`
+
+// TestSeed applies a seed file twice and checks the second run changes
+// nothing, then solves the seeded sample problem with the seeded key
+func TestSeed(t *testing.T) {
+	t.Setenv("SEED_TEST_PASSWORD", "correct horse")
+	srv := testutil.NewServer(t)
+	srv.Engine.POST("/api/problems/:id/solve", srv.Handler.APIKeyMiddleware(), srv.Handler.SolveProblem)
+
+	first, err := handlers.Seed(srv.DB, []byte(seedYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := handlers.Seed(srv.DB, []byte(seedYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Keys) != 1 || !first.Keys[0].Created || second.Keys[0].Created || first.Keys[0].Key != second.Keys[0].Key {
+		t.Fatalf("Expected the key to be created once and kept, got %+v then %+v", first.Keys, second.Keys)
+	}
+
+	var users, items int64
+	srv.DB.Model(&database.MasterUser{}).Where("username = ?", "staging").Count(&users)
+	srv.DB.Model(&database.DraftItem{}).Where("problem_id = ?", "demo-weekend").Count(&items)
+	if users != 1 || items != 3 {
+		t.Errorf("Expected 1 admin and 3 draft items after reseeding, got %d and %d", users, items)
+	}
+	var profile database.SolverProfile
+	if err := srv.DB.First(&profile, "name = ?", "quick").Error; err != nil {
+		t.Errorf("Expected the quick profile: %v", err)
+	}
+
+	w := srv.Do(t, http.MethodPost, "/api/problems/demo-weekend/solve", first.Keys[0].Key, nil)
+	testutil.AssertGolden(t, "seed_demo_weekend", testutil.DecodeSchedule(t, w))
+
+	for _, bad := range []string{"orgs: [{name: acme}]", "keys: [{rate_limit: 5}]", "profiles: [{name: x, settings: {algorithm: nope}}]"} {
+		if _, err := handlers.Seed(srv.DB, []byte(bad)); err == nil {
+			t.Errorf("Expected %q to be refused", bad)
+		}
+	}
+}
+
+// TestSeedData checks seeding over HTTP is only served in dev mode
+func TestSeedData(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Engine.POST("/admin/seed", srv.Handler.AuthMiddleware(), srv.Handler.SeedData)
+	token := srv.AdminToken(t)
+	body := map[string]any{"keys": []map[string]any{{"name": "over-http"}}}
+
+	if w := srv.Do(t, http.MethodPost, "/admin/seed", token, body); w.Code != http.StatusNotFound {
+		t.Errorf("Expected seeding to be unavailable outside dev mode, got %d", w.Code)
+	}
+
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+	w := srv.Do(t, http.MethodPost, "/admin/seed", token, body)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"over-http"`) {
+		t.Errorf("Expected the key to be seeded, got %d: %s", w.Code, w.Body.String())
+	}
+}
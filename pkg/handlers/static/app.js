@@ -1,5 +1,8 @@
 // app.js
 
+// Path prefix the API is served under, set by the server when there is one
+const BASE_PATH = window.BASE_PATH || '';
+
 // State
 let authToken = localStorage.getItem('authToken');
 let currentKeys = [];
@@ -19,8 +22,37 @@ document.addEventListener('DOMContentLoaded', () => {
     document.getElementById('createKeyForm').addEventListener('submit', handleCreateKey);
     document.getElementById('editLimitForm').addEventListener('submit', handleEditLimit);
     document.getElementById('searchKeys').addEventListener('input', handleSearch);
+
+    loadBranding();
 });
 
+// Branding: swap in the deployment's custom logo and footer, if uploaded
+async function loadBranding() {
+    try {
+        const logo = await fetch(BASE_PATH + '/admin/assets/logo');
+        if (logo.ok) {
+            const url = URL.createObjectURL(await logo.blob());
+            document.querySelectorAll('.logo').forEach(el => {
+                el.classList.add('logo-custom');
+                el.innerHTML = '';
+                const img = document.createElement('img');
+                img.src = url;
+                img.alt = 'Logo';
+                el.appendChild(img);
+            });
+        }
+
+        const footer = await fetch(BASE_PATH + '/admin/assets/footer');
+        if (footer.ok) {
+            const el = document.getElementById('customFooter');
+            el.innerHTML = await footer.text();
+            el.hidden = false;
+        }
+    } catch (error) {
+        console.error('Error loading branding:', error);
+    }
+}
+
 // Authentication
 async function handleLogin(e) {
     e.preventDefault();
@@ -30,7 +62,7 @@ async function handleLogin(e) {
     const errorEl = document.getElementById('loginError');
 
     try {
-        const response = await fetch('/admin/login', {
+        const response = await fetch(BASE_PATH + '/admin/login', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ username, password })
@@ -73,7 +105,7 @@ function showDashboard() {
 // API Key Management
 async function loadKeys() {
     try {
-        const response = await fetch('/admin/keys', {
+        const response = await fetch(BASE_PATH + '/admin/keys', {
             headers: { 'Authorization': `Bearer ${authToken}` }
         });
 
@@ -156,7 +188,7 @@ async function handleCreateKey(e) {
     const errorEl = document.getElementById('createKeyError');
 
     try {
-        const response = await fetch('/admin/keys', {
+        const response = await fetch(BASE_PATH + '/admin/keys', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -229,7 +261,7 @@ async function handleEditLimit(e) {
     const errorEl = document.getElementById('editLimitError');
 
     try {
-        const response = await fetch(`/admin/keys/${keyId}?rate_limit=${newLimit}`, {
+        const response = await fetch(`${BASE_PATH}/admin/keys/${keyId}?rate_limit=${newLimit}`, {
             method: 'PUT',
             headers: { 'Authorization': `Bearer ${authToken}` }
         });
@@ -266,7 +298,7 @@ async function confirmDeleteKey() {
     const keyId = document.getElementById('deleteKeyId').value;
 
     try {
-        const response = await fetch(`/admin/keys/${keyId}`, {
+        const response = await fetch(`${BASE_PATH}/admin/keys/${keyId}`, {
             method: 'DELETE',
             headers: { 'Authorization': `Bearer ${authToken}` }
         });
@@ -294,7 +326,7 @@ async function viewUsage(keyId, keyName) {
     document.getElementById('usageTableBody').innerHTML = '<tr><td colspan="4" style="text-align: center; padding: 2rem;">Loading usage data...</td></tr>';
 
     try {
-        const response = await fetch(`/admin/usage/${keyId}`, {
+        const response = await fetch(`${BASE_PATH}/admin/usage/${keyId}`, {
             headers: { 'Authorization': `Bearer ${authToken}` }
         });
 
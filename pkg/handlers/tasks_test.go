@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestTasks triggers the reaper through /admin/tasks and checks the run is
+// listed as its last run and success
+func TestTasks(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.GET("/admin/tasks", h.AuthMiddleware(), h.ListTasks)
+	srv.Engine.POST("/admin/tasks", h.AuthMiddleware(), h.TriggerTask)
+	token := srv.AdminToken(t)
+
+	if w := srv.Do(t, http.MethodPost, "/admin/tasks", token, map[string]string{"task": "nope"}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown task to be a 404, got %d", w.Code)
+	}
+	w := srv.Do(t, http.MethodPost, "/admin/tasks", token, map[string]string{"task": "reaper"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = srv.Do(t, http.MethodGet, "/admin/tasks", token, nil)
+	var list struct {
+		Tasks []struct {
+			Name    string `json:"name"`
+			Runs    int    `json:"runs"`
+			LastRun *struct {
+				Trigger string         `json:"trigger"`
+				Actor   string         `json:"actor"`
+				Summary map[string]int `json:"summary"`
+			} `json:"last_run"`
+			LastSuccess *string `json:"last_success"`
+			Failures    []any   `json:"failures"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode tasks: %v", err)
+	}
+	for _, task := range list.Tasks {
+		switch task.Name {
+		case "reaper":
+			if task.Runs != 1 || task.LastRun == nil || task.LastSuccess == nil || len(task.Failures) != 0 {
+				t.Fatalf("Expected one successful reaper run, got %s", w.Body.String())
+			}
+			if task.LastRun.Trigger != "manual" || task.LastRun.Actor != "admin:admin" {
+				t.Errorf("Expected a manual run by admin:admin, got %+v", task.LastRun)
+			}
+			if _, ok := task.LastRun.Summary["deleted"]; !ok {
+				t.Errorf("Expected the reaper's summary, got %v", task.LastRun.Summary)
+			}
+		case "usage_flush":
+			if task.Runs != 0 || task.LastRun != nil {
+				t.Errorf("Expected usage_flush not to have run, got %d runs", task.Runs)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+)
+
+// TestServiceTokens issues tokens of both roles and checks each reaches only
+// its own routes, never token management, and stops working once revoked
+func TestServiceTokens(t *testing.T) {
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	admin := srv.Engine.Group("/admin", h.AuthMiddleware())
+	admin.GET("/keys", h.ListKeys)
+	admin.POST("/keys", h.GenerateKey)
+	admin.GET("/usage/:id", h.GetUsage)
+	admin.POST("/keys/:id/credits", h.GrantBurstCredit)
+	admin.POST("/tokens", h.CreateServiceToken)
+	admin.GET("/tokens", h.ListServiceTokens)
+	admin.DELETE("/tokens/:id", h.RevokeServiceToken)
+	adminToken := srv.AdminToken(t)
+	key := srv.APIKey(t, "managed")
+
+	for _, bad := range []map[string]string{{"role": "reader"}, {"name": "root", "role": "admin"}} {
+		if w := srv.Do(t, http.MethodPost, "/admin/tokens", adminToken, bad); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected %v to be refused, got %d", bad, w.Code)
+		}
+	}
+
+	type issued struct {
+		ID    uint   `json:"id"`
+		Token string `json:"token"`
+	}
+	issue := func(name, role string) issued {
+		t.Helper()
+		w := srv.Do(t, http.MethodPost, "/admin/tokens", adminToken, map[string]string{"name": name, "role": role})
+		var tok issued
+		decode(t, w.Body.String(), &tok)
+		if w.Code != http.StatusOK || !strings.HasPrefix(tok.Token, "sst_") {
+			t.Fatalf("Expected a service token, got %d: %s", w.Code, w.Body.String())
+		}
+		return tok
+	}
+	reader, manager := issue("dashboards", "reader"), issue("ci", "key_manager")
+
+	w := srv.Do(t, http.MethodGet, "/admin/tokens", adminToken, nil)
+	if strings.Contains(w.Body.String(), reader.Token) || strings.Contains(w.Body.String(), manager.Token) {
+		t.Errorf("Expected the token listing to leave out the tokens, got %s", w.Body.String())
+	}
+	var stored int64
+	srv.DB.Model(&database.ServiceToken{}).Where("token_hash IN ?", []string{reader.Token, manager.Token}).Count(&stored)
+	if stored != 0 {
+		t.Errorf("Expected tokens to be stored hashed")
+	}
+
+	usage := fmt.Sprintf("/admin/usage/%d", key.ID)
+	credits := fmt.Sprintf("/admin/keys/%d/credits", key.ID)
+	for _, tc := range []struct {
+		token, method, path string
+		body                any
+		status              int
+	}{
+		{reader.Token, http.MethodGet, usage, nil, http.StatusOK},
+		{reader.Token, http.MethodGet, "/admin/keys", nil, http.StatusForbidden},
+		{reader.Token, http.MethodPost, "/admin/keys", map[string]any{"name": "sneaky"}, http.StatusForbidden},
+		{manager.Token, http.MethodGet, "/admin/keys", nil, http.StatusOK},
+		{manager.Token, http.MethodPost, "/admin/keys", map[string]any{"name": "from-ci"}, http.StatusOK},
+		{manager.Token, http.MethodPost, credits, map[string]any{"requests": 100, "hours": 1}, http.StatusCreated},
+		{manager.Token, http.MethodPost, "/admin/tokens", map[string]string{"name": "escalate", "role": "key_manager"}, http.StatusForbidden},
+		{manager.Token, http.MethodGet, "/admin/tokens", nil, http.StatusForbidden},
+		{"sst_unknown", http.MethodGet, usage, nil, http.StatusUnauthorized},
+	} {
+		if w := srv.Do(t, tc.method, tc.path, tc.token, tc.body); w.Code != tc.status {
+			t.Errorf("%s %s: Expected status %d, got %d: %s", tc.method, tc.path, tc.status, w.Code, w.Body.String())
+		}
+	}
+
+	var credit database.BurstCredit
+	srv.DB.Where("key_id = ?", key.ID).First(&credit)
+	if credit.GrantedBy != "token:ci" {
+		t.Errorf("Expected the credit to name the token that granted it, got %q", credit.GrantedBy)
+	}
+	var used database.ServiceToken
+	srv.DB.First(&used, manager.ID)
+	if used.LastUsed == nil || used.CreatedBy != "admin" {
+		t.Errorf("Expected the token's use and creator to be recorded, got %+v", used)
+	}
+
+	if w := srv.Do(t, http.MethodDelete, fmt.Sprintf("/admin/tokens/%d", manager.ID), adminToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := srv.Do(t, http.MethodGet, "/admin/keys", manager.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked token to be refused, got %d", w.Code)
+	}
+}
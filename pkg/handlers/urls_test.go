@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+	"github.com/arnavshah/scheduler-api-go/pkg/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// TestWithBasePath checks requests are routed with or without the base
+// path, and that saved schedules and the admin page link back through it
+func TestWithBasePath(t *testing.T) {
+	t.Setenv("BASE_PATH", "/scheduler/")
+	srv := testutil.NewServer(t)
+	h := srv.Handler
+	srv.Engine.POST("/api/schedule", h.APIKeyMiddleware(), h.ScheduleJSON)
+	srv.Engine.GET("/admin", h.AdminInterface)
+	srv.Engine.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "root") })
+	served := handlers.WithBasePath(srv.Engine)
+	key := srv.APIKey(t, "gateway")
+
+	var input map[string]any
+	readJSON(t, filepath.Join("testdata", "parity", "basic", "input.json"), &input)
+	input["save"] = true
+	body, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(path string, header http.Header) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Host = "example.org"
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key.Key)
+		w := httptest.NewRecorder()
+		served.ServeHTTP(w, req)
+		return testutil.DecodeSchedule(t, w).ScheduleURL
+	}
+
+	got := save("/scheduler/api/schedule", http.Header{"X-Forwarded-Proto": {"https"}})
+	if !strings.HasPrefix(got, "https://example.org/scheduler/api/schedules/") {
+		t.Errorf("Expected the schedule URL under the base path, got %q", got)
+	}
+	// A gateway may strip the prefix itself
+	if got := save("/api/schedule", nil); !strings.HasPrefix(got, "http://example.org/scheduler/api/schedules/") {
+		t.Errorf("Expected the schedule URL under the base path, got %q", got)
+	}
+	t.Setenv("PUBLIC_BASE_URL", "https://gateway.example.net/scheduler/")
+	if got := save("/scheduler/api/schedule", nil); !strings.HasPrefix(got, "https://gateway.example.net/scheduler/api/schedules/") {
+		t.Errorf("Expected the schedule URL under PUBLIC_BASE_URL, got %q", got)
+	}
+
+	for path, status := range map[string]int{
+		"/scheduler":         http.StatusOK,
+		"/scheduler/":        http.StatusOK,
+		"/schedulerx/admin":  http.StatusNotFound,
+		"/other/api/whoever": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		served.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != status {
+			t.Errorf("%s: Expected status %d, got %d", path, status, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	served.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scheduler/admin", nil))
+	page := w.Body.String()
+	if !strings.Contains(page, `href="/scheduler/static/styles.css"`) || !strings.Contains(page, `window.BASE_PATH = "/scheduler"`) {
+		t.Errorf("Expected the admin page to load assets and call the API under the base path")
+	}
+}
@@ -1,15 +1,81 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	// Embedded zone data, for serverless hosts without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// TimeWindow represents a start/end time range
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// TimeOfDayRange is a daily "HH:MM"-"HH:MM" range. An End before Start wraps past midnight.
+type TimeOfDayRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
 
 // Volunteer represents a person available for shifts
 type Volunteer struct {
-	ID            string  `json:"id"`
-	Name          string  `json:"name"`
-	Group         string  `json:"group,omitempty"`
-	MaxHours      float64 `json:"max_hours"`
-	AssignedHours float64 `json:"assigned_hours"`
-	AssignedShifts []string `json:"assigned_shifts"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Group string `json:"group,omitempty"`
+	// Groups lists additional groups for multi-skill volunteers
+	Groups []string `json:"groups,omitempty"`
+	// Tier is "core", "regular" (the default) or "standby". Standby
+	// volunteers are only called in when nobody else can fill a slot.
+	Tier string `json:"tier,omitempty"`
+	// MaxHours caps the volunteer's hours. Zero allows none, unless MaxShifts
+	// is set, in which case only the shift count is capped.
+	MaxHours float64 `json:"max_hours"`
+	// MaxShifts caps how many shifts the volunteer works; ignored when zero
+	MaxShifts    int     `json:"max_shifts,omitempty"`
+	MinRestHours float64 `json:"min_rest_hours,omitempty"`
+	// MaxHoursPerWeek caps hours in any rolling 7-day window; ignored when zero
+	MaxHoursPerWeek float64 `json:"max_hours_per_week,omitempty"`
+	// MinHours and MinShifts are minimums the scheduler works to meet; shortfalls are reported
+	MinHours  float64 `json:"min_hours,omitempty"`
+	MinShifts int     `json:"min_shifts,omitempty"`
+	// TargetHours steers selection towards this many hours; zero means as few as possible
+	TargetHours float64 `json:"target_hours,omitempty"`
+	// MaxShiftsPerDay and MaxConsecutiveDays are ignored when zero
+	MaxShiftsPerDay    int `json:"max_shifts_per_day,omitempty"`
+	MaxConsecutiveDays int `json:"max_consecutive_days,omitempty"`
+	// MustWorkWith lists volunteers of whom at least one must share every shift
+	// (e.g. a trainee's mentors); CannotWorkWith lists volunteers who must never
+	MustWorkWith   []string `json:"must_work_with,omitempty"`
+	CannotWorkWith []string `json:"cannot_work_with,omitempty"`
+	// CategoryLimits caps how many shifts of each category the volunteer
+	// works, overriding the input's defaults
+	CategoryLimits map[string]int `json:"category_limits,omitempty"`
+	// CategoryHistory counts shifts per category worked in earlier schedules,
+	// so unpopular shifts rotate to whoever has done the fewest
+	CategoryHistory map[string]int `json:"category_history,omitempty"`
+	// HasEquipment lists equipment and licences the volunteer brings, e.g. "radio"
+	HasEquipment []string `json:"has_equipment,omitempty"`
+	// Certifications the volunteer holds, checked against shifts' required certifications
+	Certifications []Certification `json:"certifications,omitempty"`
+	// Soft preferences, used to break ties between otherwise equal candidates
+	PreferredShifts []string         `json:"preferred_shifts,omitempty"`
+	PreferredTimes  []TimeOfDayRange `json:"preferred_times,omitempty"`
+	Availability    []TimeWindow     `json:"availability,omitempty"` // empty means always available
+	// BusyTimes are commitments outside this schedule, such as a day job,
+	// which no shift may overlap
+	BusyTimes []TimeWindow `json:"busy_times,omitempty"`
+	// BusyCalendarURL is an iCalendar feed whose events and free/busy periods
+	// are added to BusyTimes before scheduling
+	BusyCalendarURL string   `json:"busy_calendar_url,omitempty"`
+	AssignedHours   float64  `json:"assigned_hours" schema:"readonly"`
+	AssignedShifts  []string `json:"assigned_shifts" schema:"readonly"`
 }
 
 // Shift represents a time slot that needs filling
@@ -17,16 +83,161 @@ type Shift struct {
 	ID             string         `json:"id"`
 	Start          time.Time      `json:"start"`
 	End            time.Time      `json:"end"`
-	RequiredGroups map[string]int `json:"required_groups"`
+	RequiredGroups map[string]int `json:"required_groups" schema:"optional"`
+	// IdealGroups is the headcount per group worth filling once every shift
+	// has its minimum. Groups must also appear in RequiredGroups.
+	IdealGroups    map[string]int `json:"ideal_groups,omitempty"`
 	AllowedGroups  []string       `json:"allowed_groups,omitempty"`
 	ExcludedGroups []string       `json:"excluded_groups,omitempty"`
-	Assigned       []string       `json:"assigned"`
+	// SupervisorRatios require supervisors on the shift in proportion to the supervised
+	SupervisorRatios []SupervisorRatio `json:"supervisor_ratios,omitempty"`
+	// RequiredEquipment is how many assigned volunteers must bring each item,
+	// independent of their groups
+	RequiredEquipment map[string]int `json:"required_equipment,omitempty"`
+	// RequiredResources is how many resources of each type the shift needs
+	RequiredResources map[string]int `json:"required_resources,omitempty"`
+	// RequiredCertifications must all be held, and current on the day of the
+	// shift, by every volunteer on it
+	RequiredCertifications []string `json:"required_certifications,omitempty"`
+	// Category groups unpopular shifts such as "night" or "weekend" for category limits
+	Category string `json:"category,omitempty"`
+	// Location is where the shift takes place, for travel times between shifts
+	Location string `json:"location,omitempty"`
+	// Timezone is the IANA zone the shift takes place in, e.g. "Europe/London".
+	// It overrides the input's default timezone.
+	Timezone string `json:"timezone,omitempty"`
+	// LinkedShifts must be worked by the same volunteers as this shift, e.g. a
+	// setup and its teardown: whoever works one works all of them, or none
+	LinkedShifts []string `json:"linked_shifts,omitempty"`
+	// Roles name the positions on the shift, e.g. "lead" and "helper". When
+	// set they stand in for RequiredGroups, which is derived from them.
+	Roles    []ShiftRole `json:"roles,omitempty"`
+	Assigned []string    `json:"assigned" schema:"readonly"`
+}
+
+// ShiftRole is a named position on a shift, filled by Count volunteers from Group
+type ShiftRole struct {
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	Count int    `json:"count"`
+}
+
+// Certification is a qualification such as first aid. Expires is the last
+// day it is valid, as YYYY-MM-DD; empty means it doesn't expire.
+type Certification struct {
+	Name    string `json:"name"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// SupervisorRatio requires at least one volunteer from Supervisor per Per
+// volunteers from Supervised on the same shift
+type SupervisorRatio struct {
+	Supervisor string `json:"supervisor"`
+	Supervised string `json:"supervised"`
+	Per        int    `json:"per"`
+}
+
+// JSONSchemaProperties describes required_groups as UnmarshalJSON reads it,
+// each entry a count or a {"min", "ideal"} pair
+func (Shift) JSONSchemaProperties() map[string]any {
+	count := map[string]any{"type": "integer"}
+	tiers := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"min": count, "ideal": count},
+	}
+	return map[string]any{
+		"required_groups": map[string]any{
+			"type":                 "object",
+			"additionalProperties": map[string]any{"oneOf": []any{count, tiers}},
+		},
+	}
+}
+
+// UnmarshalJSON accepts each required_groups entry either as a plain minimum
+// or as {"min": n, "ideal": m}, the latter also filling IdealGroups
+func (s *Shift) UnmarshalJSON(data []byte) error {
+	type plain Shift
+	aux := struct {
+		*plain
+		RequiredGroups map[string]json.RawMessage `json:"required_groups"`
+	}{plain: (*plain)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.RequiredGroups == nil {
+		return nil
+	}
+
+	s.RequiredGroups = make(map[string]int, len(aux.RequiredGroups))
+	for group, raw := range aux.RequiredGroups {
+		var count int
+		if err := json.Unmarshal(raw, &count); err == nil {
+			s.RequiredGroups[group] = count
+			continue
+		}
+		var tiers struct {
+			Min   int `json:"min"`
+			Ideal int `json:"ideal"`
+		}
+		if err := json.Unmarshal(raw, &tiers); err != nil {
+			return err
+		}
+		s.RequiredGroups[group] = tiers.Min
+		if tiers.Ideal > 0 {
+			if s.IdealGroups == nil {
+				s.IdealGroups = make(map[string]int)
+			}
+			s.IdealGroups[group] = tiers.Ideal
+		}
+	}
+	return nil
 }
 
 // Assignment represents a volunteer-shift pairing
 type Assignment struct {
 	ShiftID     string `json:"shift_id"`
 	VolunteerID string `json:"volunteer_id"`
+	// Locked assignments are never removed by re-runs
+	Locked bool `json:"locked,omitempty"`
+}
+
+// ShiftAssignment is one volunteer on a shift and the requirement they fill
+type ShiftAssignment struct {
+	VolunteerID string `json:"volunteer_id"`
+	Group       string `json:"group,omitempty"`
+	Role        string `json:"role,omitempty"`
+}
+
+// Resource is something other than a person that shifts can require, such
+// as a vehicle or a room. It can serve one shift at a time.
+type Resource struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name,omitempty"`
+	Type         string       `json:"type"`
+	Availability []TimeWindow `json:"availability,omitempty"` // empty means always available
+}
+
+// AssignmentNote attaches a free-text note and flags, such as
+// "needs_parking_pass", to one assignment of a saved schedule
+type AssignmentNote struct {
+	ShiftID     string   `json:"shift_id"`
+	VolunteerID string   `json:"volunteer_id"`
+	Note        string   `json:"note,omitempty"`
+	Flags       []string `json:"flags,omitempty"`
+}
+
+// DoubleBooking is a volunteer whose shift in one saved schedule overlaps a
+// shift they were given in another
+type DoubleBooking struct {
+	VolunteerID      string    `json:"volunteer_id"`
+	ShiftID          string    `json:"shift_id"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	OtherScheduleID  string    `json:"other_schedule_id"`
+	OtherVolunteerID string    `json:"other_volunteer_id"`
+	OtherShiftID     string    `json:"other_shift_id"`
+	OtherStart       time.Time `json:"other_start"`
+	OtherEnd         time.Time `json:"other_end"`
 }
 
 // ConflictReason represents why a shift could not be filled
@@ -34,15 +245,193 @@ type ConflictReason struct {
 	ShiftID string   `json:"shift_id"`
 	Group   string   `json:"group"`
 	Reasons []string `json:"reasons"`
+	// Cause names what went wrong: for unfilled slots, the constraint that
+	// ruled out the most volunteers
+	Cause string `json:"cause,omitempty"`
+	// Details lists each rejected volunteer, when detailed conflicts are requested
+	Details []VolunteerRejection `json:"details,omitempty"`
+	// Relaxations are the smallest constraint changes that would fill the slot
+	Relaxations []Relaxation `json:"relaxations,omitempty"`
+}
+
+// ConflictCause aggregates the conflicts that share a cause and group
+type ConflictCause struct {
+	Cause   string   `json:"cause"`
+	Group   string   `json:"group,omitempty"`
+	Count   int      `json:"count"` // conflicts with this cause, one per unfilled slot
+	Shifts  []string `json:"shifts"`
+	Summary string   `json:"summary"` // e.g. "14 slots unfilled because group \"driver\" has only 2 volunteers"
+}
+
+// Relaxation lists the changes that would let one volunteer fill a slot,
+// e.g. "raise max_hours from 10 to 12"
+type Relaxation struct {
+	VolunteerID string   `json:"volunteer_id"`
+	Changes     []string `json:"changes"`
+}
+
+// VolunteerRejection names the constraints a volunteer failed for a slot
+type VolunteerRejection struct {
+	VolunteerID string   `json:"volunteer_id"`
+	Failed      []string `json:"failed"`
+}
+
+// ComplianceReport lists which policies were evaluated while building a schedule,
+// for audit purposes
+type ComplianceReport struct {
+	Evaluated []string `json:"evaluated"`
+	Relaxed   []string `json:"relaxed"`
+	Waivers   []string `json:"waivers"`
+}
+
+// SoftViolation is one broken soft constraint and what it cost
+type SoftViolation struct {
+	Constraint  string  `json:"constraint"`
+	ShiftID     string  `json:"shift_id"`
+	VolunteerID string  `json:"volunteer_id"`
+	Penalty     float64 `json:"penalty"`
+}
+
+// StaffingForecast compares the volunteers who could fill a shift's group
+// with how many it needs
+type StaffingForecast struct {
+	ShiftID  string    `json:"shift_id"`
+	Group    string    `json:"group"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Required int       `json:"required"`
+	// Assigned counts the slots already filled by current assignments
+	Assigned int `json:"assigned"`
+	// Eligible counts the other group members who could take an open slot
+	Eligible int `json:"eligible"`
+	// Gap is how many slots no one can fill, however the rest are scheduled
+	Gap int `json:"gap"`
+	// ExcludedBy counts the group members ruled out by each constraint
+	ExcludedBy map[string]int `json:"excluded_by"`
+}
+
+// CandidateExplanation shows how one volunteer fared for a slot on a shift
+type CandidateExplanation struct {
+	VolunteerID string `json:"volunteer_id"`
+	Name        string `json:"name,omitempty"`
+	// Rank is 1 for the volunteer the scheduler would pick; 0 when ineligible
+	Rank            int             `json:"rank,omitempty"`
+	Eligible        bool            `json:"eligible"`
+	AlreadyAssigned bool            `json:"already_assigned,omitempty"`
+	Checks          map[string]bool `json:"checks"` // constraint name -> passed
+	SoftViolations  []string        `json:"soft_violations,omitempty"`
+	// Score is hours from target plus soft penalties, less any preference
+	// bonus; lower is better after the rotation count
+	Score         float64 `json:"score"`
+	RotationCount int     `json:"rotation_count"`
+	Prefers       bool    `json:"prefers"`
+}
+
+// SwapCandidate is a volunteer who could take over a shift
+type SwapCandidate struct {
+	VolunteerID string `json:"volunteer_id"`
+	Name        string `json:"name,omitempty"`
+	// GivesShiftID is the candidate's own shift handed back in a two-way swap
+	GivesShiftID string `json:"gives_shift_id,omitempty"`
+}
+
+// ChurnReport describes how much a re-run changed a previous schedule
+type ChurnReport struct {
+	Kept         int     `json:"kept"`
+	Removed      int     `json:"removed"`
+	Added        int     `json:"added"`
+	ChurnPercent float64 `json:"churn_percent"` // share of previous assignments that were removed
+}
+
+// Shortfall reports a volunteer who ended up below their minimum hours or shifts
+type Shortfall struct {
+	VolunteerID    string  `json:"volunteer_id"`
+	MinHours       float64 `json:"min_hours,omitempty"`
+	AssignedHours  float64 `json:"assigned_hours"`
+	MinShifts      int     `json:"min_shifts,omitempty"`
+	AssignedShifts int     `json:"assigned_shifts"`
 }
 
 // ScheduleResponse is the data structure for the scheduling result
 type ScheduleResponse struct {
 	AssignedShifts map[string][]string `json:"assigned_shifts"`
-	UnfilledShifts []string            `json:"unfilled_shifts"` // shift IDs that have ANY unfilled slots
-	Conflicts      []ConflictReason    `json:"conflicts,omitempty"`
-	FairnessScore  float64             `json:"fairness_score"`
-	Volunteers     map[string]any       `json:"volunteers"` // ID -> {assigned_hours, assigned_shifts}
+	// ShiftAssignments is AssignedShifts with the group and role each volunteer fills
+	ShiftAssignments map[string][]ShiftAssignment `json:"shift_assignments"`
+	// Roles maps shift IDs to the volunteers filling each role, for shifts with roles
+	Roles          map[string]map[string][]string `json:"roles,omitempty"`
+	UnfilledShifts []string                       `json:"unfilled_shifts"` // shift IDs that have ANY unfilled slots
+	// BelowIdealShifts lists shifts that met every minimum but not every ideal headcount
+	BelowIdealShifts []string         `json:"below_ideal_shifts,omitempty"`
+	Conflicts        []ConflictReason `json:"conflicts,omitempty"`
+	// ConflictSummary groups Conflicts by cause, largest first
+	ConflictSummary []ConflictCause `json:"conflict_summary,omitempty"`
+	FairnessScore   float64         `json:"fairness_score"`
+	FairnessMetric  string          `json:"fairness_metric"`
+	// GroupFairness is the stddev-based fairness score within each volunteer group
+	GroupFairness map[string]float64 `json:"group_fairness,omitempty"`
+	// FairnessByDimension scores each requested fairness dimension with the selected metric
+	FairnessByDimension map[string]float64 `json:"fairness_by_dimension,omitempty"`
+	// Resources maps shift IDs to the resources booked for them
+	Resources map[string][]string `json:"resources,omitempty"`
+	// SoftViolations lists the soft constraints the schedule breaks; SoftPenalty is their total cost
+	SoftViolations []SoftViolation `json:"soft_violations,omitempty"`
+	SoftPenalty    float64         `json:"soft_penalty,omitempty"`
+	// StandbyAssignments counts the assignments that had to go to standby volunteers
+	StandbyAssignments int `json:"standby_assignments,omitempty"`
+	// DuplicateAssignments counts repeated current assignments that were dropped
+	DuplicateAssignments int `json:"duplicate_assignments,omitempty"`
+	// Alternatives are the runner-up schedules, best first, when more than one was asked for
+	Alternatives []ScheduleResponse `json:"alternatives,omitempty"`
+	// PreferenceSatisfaction is the percentage of assignments that matched a volunteer preference
+	PreferenceSatisfaction float64          `json:"preference_satisfaction"`
+	Volunteers             map[string]any   `json:"volunteers"` // ID -> {assigned_hours, assigned_shifts}
+	Compliance             ComplianceReport `json:"compliance"`
+	Churn                  *ChurnReport     `json:"churn,omitempty"`        // only set for incremental re-schedules
+	Shortfalls             []Shortfall      `json:"shortfalls,omitempty"`   // volunteers below their minimums
+	Notes                  []AssignmentNote `json:"notes,omitempty"`        // per-assignment notes on saved schedules
+	ScheduleID             string           `json:"schedule_id,omitempty"`  // set when the request asked to save the result
+	ScheduleURL            string           `json:"schedule_url,omitempty"` // absolute URL of the saved schedule
+}
+
+// ScoreWeights weigh fill rate against fairness when the optimal search
+// compares schedules
+type ScoreWeights struct {
+	FillRate float64 `json:"fill_rate,omitempty"`
+	Fairness float64 `json:"fairness,omitempty"`
+}
+
+// ParityResponse is a schedule response in the Python version's exact
+// shape, returned in strict parity mode
+type ParityResponse struct {
+	AssignedShifts map[string][]string        `json:"assigned_shifts"`
+	UnfilledShifts []string                   `json:"unfilled_shifts"`
+	FairnessScore  float64                    `json:"fairness_score"`
+	Conflicts      []ParityConflict           `json:"conflicts"`
+	Volunteers     map[string]ParityVolunteer `json:"volunteers"`
+}
+
+// ParityConflict is a conflict as the Python version reports it
+type ParityConflict struct {
+	ShiftID string   `json:"shift_id"`
+	Group   string   `json:"group"`
+	Reasons []string `json:"reasons"`
+}
+
+// ParityVolunteer is a volunteer's summary as the Python version reports it
+type ParityVolunteer struct {
+	AssignedHours  float64  `json:"assigned_hours"`
+	AssignedShifts []string `json:"assigned_shifts"`
+}
+
+// SolverSettings are the tuning options a solver profile can set
+type SolverSettings struct {
+	Algorithm        string             `json:"algorithm,omitempty"`
+	TimeoutSeconds   int                `json:"timeout_seconds,omitempty"`
+	FairnessMetric   string             `json:"fairness_metric,omitempty"`
+	PreferenceWeight float64            `json:"preference_weight,omitempty"`
+	SoftConstraints  map[string]float64 `json:"soft_constraints,omitempty"`
+	MaxHoursRatio    float64            `json:"max_hours_ratio,omitempty"`
+	ScoreWeights     ScoreWeights       `json:"score_weights,omitempty"`
 }
 
 // ScheduleInput is the data structure for the scheduling endpoint
@@ -50,4 +439,187 @@ type ScheduleInput struct {
 	Volunteers         []Volunteer  `json:"volunteers"`
 	UnassignedShifts   []Shift      `json:"unassigned_shifts"`
 	CurrentAssignments []Assignment `json:"current_assignments"`
+	// StrictAssignments rejects repeated current assignments instead of dropping them
+	StrictAssignments bool `json:"strict_assignments,omitempty"`
+	// SoftConstraints turns constraints such as "min_rest_hours" or
+	// "preferences" into penalties, weighted in hours of imbalance
+	SoftConstraints map[string]float64 `json:"soft_constraints,omitempty"`
+	// Resources are the vehicles, rooms and so on that shifts can require
+	Resources []Resource `json:"resources,omitempty"`
+	// PreferenceWeight is how many hours of imbalance a preferred shift can outweigh
+	PreferenceWeight float64 `json:"preference_weight,omitempty"`
+	// ConsecutiveWeight is how many hours of imbalance giving someone a shift
+	// right before or after one of theirs at the same location that day can
+	// outweigh, so they come in once for a block
+	ConsecutiveWeight float64 `json:"consecutive_weight,omitempty"`
+	// PreviousAssignments enables incremental mode: these are kept wherever still
+	// valid, unlike CurrentAssignments which are always applied
+	PreviousAssignments []Assignment `json:"previous_assignments,omitempty"`
+	// HintAssignments are a suggested schedule to start from, e.g. the last
+	// result before a small input change. Solvers keep the hints that still
+	// fit but, unlike CurrentAssignments, may change them. The optimal
+	// algorithm only improves on them, so feeding a seeded result back in
+	// never does worse.
+	HintAssignments []Assignment `json:"hint_assignments,omitempty"`
+	// Profile names an admin-defined solver profile; settings sent in the
+	// request take precedence over the profile's
+	Profile string `json:"profile,omitempty"`
+	// Algorithm selects the solver: "greedy" (default), "optimal", "branch_and_bound" or "anneal"
+	Algorithm      string `json:"algorithm,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	// FairnessMetric selects the fairness score to report and optimize:
+	// "stddev" (default), "min_max", "gini" or "per_group"
+	FairnessMetric string `json:"fairness_metric,omitempty"`
+	// ScoreWeights makes the optimal search weigh fairness against fill
+	// rate, e.g. {"fill_rate": 0.7, "fairness": 0.3}. By default fill rate
+	// decides and fairness only breaks ties.
+	ScoreWeights ScoreWeights `json:"score_weights,omitempty"`
+	// FairnessDimensions adds a fairness score for each listed attribute:
+	// "hours", "shifts", "night" or "weekend"
+	FairnessDimensions []string `json:"fairness_dimensions,omitempty"`
+	// Alternatives asks the optimal search for up to this many distinct
+	// schedules, returned best first after the main one
+	Alternatives int `json:"alternatives,omitempty"`
+	// Seed makes the random shuffle reproducible, so identical inputs give identical outputs
+	Seed *int64 `json:"seed,omitempty"`
+	// Save stores the input and result server-side and returns a schedule_id
+	Save bool `json:"save,omitempty"`
+	// GroupHierarchy maps a group to the groups it can stand in for,
+	// e.g. {"senior_medic": ["medic"]}. Substitution is transitive.
+	GroupHierarchy map[string][]string `json:"group_hierarchy,omitempty"`
+	// CategoryLimits caps how many shifts of each category anyone works,
+	// e.g. {"night": 2}; volunteers can override it
+	CategoryLimits map[string]int `json:"category_limits,omitempty"`
+	// Timezone is the default IANA zone for shifts that don't name their own
+	Timezone string `json:"timezone,omitempty"`
+	// MaxHoursRatio is a hard fairness cap: nobody may work more than this
+	// multiple of the mean hours, e.g. 1.5
+	MaxHoursRatio float64 `json:"max_hours_ratio,omitempty"`
+	// DetailedConflicts lists every rejected volunteer and why on each conflict
+	DetailedConflicts bool `json:"detailed_conflicts,omitempty"`
+	// StrictParity answers with only the Python version's fields, in its
+	// exact shape, for clients migrating from it
+	StrictParity bool `json:"strict_parity,omitempty"`
+	// TravelMinutes is the time to travel between shift locations, e.g.
+	// {"north_hall": {"riverside": 40}}. Either direction may be given.
+	TravelMinutes map[string]map[string]int `json:"travel_minutes,omitempty"`
+	// Objective is "maximize_participation" (default), spreading shifts over
+	// as many volunteers as possible, or "minimize_headcount", giving them to
+	// as few as possible
+	Objective string `json:"objective,omitempty"`
+	// Chunk solves long horizons one "day" or "week" at a time, carrying
+	// volunteer hours forward, to bound the time and memory each search takes
+	Chunk string `json:"chunk,omitempty"`
+	// Temperature, in hours, randomizes picks among near-equal volunteers so
+	// the same least-busy person isn't always first: a volunteer this many
+	// hours further from target is picked e times less often. 0 (default)
+	// always picks the best.
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// ApplySettings fills in solver settings the input leaves unset
+func (in *ScheduleInput) ApplySettings(st SolverSettings) {
+	if in.Algorithm == "" {
+		in.Algorithm = st.Algorithm
+	}
+	if in.TimeoutSeconds == 0 {
+		in.TimeoutSeconds = st.TimeoutSeconds
+	}
+	if in.FairnessMetric == "" {
+		in.FairnessMetric = st.FairnessMetric
+	}
+	if in.PreferenceWeight == 0 {
+		in.PreferenceWeight = st.PreferenceWeight
+	}
+	if in.SoftConstraints == nil {
+		in.SoftConstraints = st.SoftConstraints
+	}
+	if in.MaxHoursRatio == 0 {
+		in.MaxHoursRatio = st.MaxHoursRatio
+	}
+	if in.ScoreWeights == (ScoreWeights{}) {
+		in.ScoreWeights = st.ScoreWeights
+	}
+}
+
+// AssignedToPrefills moves the volunteers listed in each shift's Assigned
+// into CurrentAssignments, so they are prefilled with their hours counted
+// like any other current assignment. It fails on volunteers that aren't in
+// the input.
+func (in *ScheduleInput) AssignedToPrefills() error {
+	known := make(map[string]bool, len(in.Volunteers))
+	for _, v := range in.Volunteers {
+		known[v.ID] = true
+	}
+	seen := make(map[Assignment]bool, len(in.CurrentAssignments))
+	for _, a := range in.CurrentAssignments {
+		seen[Assignment{ShiftID: a.ShiftID, VolunteerID: a.VolunteerID}] = true
+	}
+	for i := range in.UnassignedShifts {
+		sh := &in.UnassignedShifts[i]
+		for _, volID := range sh.Assigned {
+			if !known[volID] {
+				return fmt.Errorf("shift %s: assigned volunteer %q is not in volunteers", sh.ID, volID)
+			}
+			a := Assignment{ShiftID: sh.ID, VolunteerID: volID}
+			if !seen[a] {
+				seen[a] = true
+				in.CurrentAssignments = append(in.CurrentAssignments, a)
+			}
+		}
+		sh.Assigned = nil
+	}
+	return nil
+}
+
+// ExpandRoles derives each shift's RequiredGroups from its roles. It fails on
+// malformed roles, and on shifts whose required_groups disagree with them.
+func (in *ScheduleInput) ExpandRoles() error {
+	for i := range in.UnassignedShifts {
+		sh := &in.UnassignedShifts[i]
+		if len(sh.Roles) == 0 {
+			continue
+		}
+		required := make(map[string]int)
+		names := make(map[string]bool, len(sh.Roles))
+		for _, role := range sh.Roles {
+			switch {
+			case role.Name == "" || role.Group == "":
+				return fmt.Errorf("shift %s: roles need a name and a group", sh.ID)
+			case role.Count <= 0:
+				return fmt.Errorf("shift %s: role %q needs a positive count", sh.ID, role.Name)
+			case names[role.Name]:
+				return fmt.Errorf("shift %s: duplicate role %q", sh.ID, role.Name)
+			}
+			names[role.Name] = true
+			required[role.Group] += role.Count
+		}
+		if len(sh.RequiredGroups) > 0 && !maps.Equal(sh.RequiredGroups, required) {
+			return fmt.Errorf("shift %s: required_groups can't be combined with roles", sh.ID)
+		}
+		sh.RequiredGroups = required
+	}
+	return nil
+}
+
+// NormalizeTimezones converts each shift's times into its own timezone, or the
+// input's default, so day boundaries and preferred times are judged in local
+// time. Shifts with neither keep the offset they were sent with.
+func (in *ScheduleInput) NormalizeTimezones() error {
+	for i := range in.UnassignedShifts {
+		sh := &in.UnassignedShifts[i]
+		name := sh.Timezone
+		if name == "" {
+			name = in.Timezone
+		}
+		if name == "" {
+			continue
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return fmt.Errorf("shift %s: unknown timezone %q", sh.ID, name)
+		}
+		sh.Start, sh.End = sh.Start.In(loc), sh.End.In(loc)
+	}
+	return nil
 }
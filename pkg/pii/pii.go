@@ -0,0 +1,148 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// prefix marks an encrypted value, so plaintext written before encryption
+// was enabled still reads back transparently
+const prefix = "enc:v1:"
+
+// escaped marks plaintext that itself starts with prefix or escaped, so it
+// isn't read back as ciphertext
+const escaped = "enc:raw:"
+
+var (
+	aeadOnce sync.Once
+	aead     cipher.AEAD
+	aeadErr  error
+)
+
+// ErrNoKey is returned when decrypting a value without PII_ENCRYPTION_KEY set
+var ErrNoKey = errors.New("PII_ENCRYPTION_KEY is not set")
+
+// loadKey builds the AES-GCM cipher from PII_ENCRYPTION_KEY, a base64-encoded 32-byte key
+func loadKey() (cipher.AEAD, error) {
+	aeadOnce.Do(func() {
+		raw := os.Getenv("PII_ENCRYPTION_KEY")
+		if raw == "" {
+			return
+		}
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(key) != 32 {
+			aeadErr = errors.New("PII_ENCRYPTION_KEY must be a base64-encoded 32-byte key")
+			return
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			aeadErr = err
+			return
+		}
+		aead, aeadErr = cipher.NewGCM(block)
+	})
+	return aead, aeadErr
+}
+
+// Enabled reports whether PII encryption at rest is configured
+func Enabled() bool {
+	a, _ := loadKey()
+	return a != nil
+}
+
+// Encrypt seals a value with AES-GCM. Empty values, and all values when
+// encryption is not configured, are returned unchanged, unless they look
+// like a sealed value and are escaped.
+func Encrypt(plain string) (string, error) {
+	a, err := loadKey()
+	if err != nil {
+		return "", err
+	}
+	if plain == "" {
+		return plain, nil
+	}
+	if a == nil {
+		if strings.HasPrefix(plain, prefix) || strings.HasPrefix(plain, escaped) {
+			return escaped + plain, nil
+		}
+		return plain, nil
+	}
+
+	nonce := make([]byte, a.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := a.Seal(nonce, nonce, []byte(plain), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt. Values without the encryption
+// prefix are returned unchanged.
+func Decrypt(value string) (string, error) {
+	if plain, ok := strings.CutPrefix(value, escaped); ok {
+		return plain, nil
+	}
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	a, err := loadKey()
+	if err != nil {
+		return "", err
+	}
+	if a == nil {
+		return "", ErrNoKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil || len(sealed) < a.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:a.NonceSize()], sealed[a.NonceSize():]
+	plain, err := a.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// piiFields lists the volunteer fields that hold personal data. Busy
+// calendar URLs usually carry an access token, so they count too.
+func piiFields(v *models.Volunteer) []*string {
+	return []*string{&v.Name, &v.Email, &v.Phone, &v.BusyCalendarURL}
+}
+
+// SealVolunteers encrypts the personal fields of each volunteer in place
+func SealVolunteers(volunteers []models.Volunteer) error {
+	for i := range volunteers {
+		for _, f := range piiFields(&volunteers[i]) {
+			enc, err := Encrypt(*f)
+			if err != nil {
+				return err
+			}
+			*f = enc
+		}
+	}
+	return nil
+}
+
+// OpenVolunteers decrypts the personal fields of each volunteer in place
+func OpenVolunteers(volunteers []models.Volunteer) error {
+	for i := range volunteers {
+		for _, f := range piiFields(&volunteers[i]) {
+			dec, err := Decrypt(*f)
+			if err != nil {
+				return err
+			}
+			*f = dec
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package pii
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// useKey sets PII_ENCRYPTION_KEY for the test, dropping the cached cipher
+// now and when the test ends so the key is loaded afresh
+func useKey(t *testing.T, key string) {
+	t.Helper()
+	reset := func() { aeadOnce, aead, aeadErr = sync.Once{}, nil, nil }
+	reset()
+	t.Cleanup(reset)
+	t.Setenv("PII_ENCRYPTION_KEY", key)
+}
+
+// newKey returns a base64-encoded 32-byte key filled with b
+func newKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	useKey(t, newKey('a'))
+	for _, plain := range []string{"", "Alice", "alice@example.com", "enc:v1:abc", "enc:raw:x"} {
+		sealed, err := Encrypt(plain)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plain, err)
+		}
+		if plain != "" && (sealed == plain || !strings.HasPrefix(sealed, prefix)) {
+			t.Errorf("Expected %q to be sealed, got %q", plain, sealed)
+		}
+		if got, err := Decrypt(sealed); err != nil || got != plain {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plain, got, err)
+		}
+	}
+}
+
+func TestEncrypt_PrefixCollision(t *testing.T) {
+	useKey(t, "")
+	for _, plain := range []string{"Alice", "enc:v1:abc", "enc:raw:abc", "enc:raw:enc:v1:abc"} {
+		stored, err := Encrypt(plain)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plain, err)
+		}
+		if got, err := Decrypt(stored); err != nil || got != plain {
+			t.Errorf("Expected %q to read back unchanged, got %q, %v", plain, got, err)
+		}
+	}
+	if stored, _ := Encrypt("Alice"); stored != "Alice" {
+		t.Errorf("Expected ordinary plaintext stored as is, got %q", stored)
+	}
+
+	volunteers := []models.Volunteer{{ID: "vol_1", Name: "enc:v1:abc", Email: "a@example.com"}}
+	if err := SealVolunteers(volunteers); err != nil {
+		t.Fatal(err)
+	}
+	if err := OpenVolunteers(volunteers); err != nil || volunteers[0].Name != "enc:v1:abc" {
+		t.Errorf("Expected the name to survive sealing, got %q, %v", volunteers[0].Name, err)
+	}
+}
+
+func TestDecrypt_WrongKey(t *testing.T) {
+	useKey(t, newKey('a'))
+	sealed, err := Encrypt("Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useKey(t, newKey('b'))
+	if _, err := Decrypt(sealed); err == nil {
+		t.Error("Expected a value sealed with another key to fail to open")
+	}
+	useKey(t, "")
+	if _, err := Decrypt(sealed); err != ErrNoKey {
+		t.Errorf("Expected ErrNoKey without a key, got %v", err)
+	}
+}
+
+func TestDecrypt_Tampered(t *testing.T) {
+	useKey(t, newKey('a'))
+	sealed, err := Encrypt("Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, prefix))
+	raw[len(raw)-1] ^= 1
+	tampered := prefix + base64.StdEncoding.EncodeToString(raw)
+	if _, err := Decrypt(tampered); err == nil {
+		t.Error("Expected tampered ciphertext to fail to open")
+	}
+	if _, err := Decrypt(prefix + "not base64!"); err == nil {
+		t.Error("Expected malformed ciphertext to fail to open")
+	}
+}
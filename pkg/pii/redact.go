@@ -0,0 +1,189 @@
+package pii
+
+import (
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Redacted replaces personal values in logs, captures and error reports
+const Redacted = "[redacted]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`(\+\d{1,3}[\s.-]?)?\(?\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}`)
+)
+
+// names holds the volunteer names of the requests in flight, so RedactText
+// can mask them too. Names can't be recognised by their shape, unlike emails.
+// They're indexed by their first word, so lines are scanned a word at a time
+// and tracking a name only touches its own entry.
+var names struct {
+	sync.RWMutex
+	counts map[string]int
+	byWord map[string][]trackedName // longest first
+}
+
+// trackedName is a name and the length of anything before its first word
+type trackedName struct {
+	name   string
+	offset int
+}
+
+// TrackNames has RedactText mask the volunteers' names until release is
+// called, e.g. once the request carrying them has been handled
+func TrackNames(volunteers []models.Volunteer) (release func()) {
+	var tracked []string
+	names.Lock()
+	for _, v := range volunteers {
+		name := strings.TrimSpace(v.Name)
+		if name == "" || name == Redacted {
+			continue
+		}
+		word, offset, ok := firstWord(name)
+		if !ok {
+			continue
+		}
+		if names.counts == nil {
+			names.counts = make(map[string]int)
+			names.byWord = make(map[string][]trackedName)
+		}
+		if names.counts[name]++; names.counts[name] == 1 {
+			list := append(names.byWord[word], trackedName{name, offset})
+			slices.SortFunc(list, func(a, b trackedName) int { return len(b.name) - len(a.name) })
+			names.byWord[word] = list
+		}
+		tracked = append(tracked, name)
+	}
+	names.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			names.Lock()
+			defer names.Unlock()
+			for _, name := range tracked {
+				if names.counts[name]--; names.counts[name] > 0 {
+					continue
+				}
+				delete(names.counts, name)
+				word, _, _ := firstWord(name)
+				list := slices.DeleteFunc(names.byWord[word], func(t trackedName) bool { return t.name == name })
+				if len(list) == 0 {
+					delete(names.byWord, word)
+				} else {
+					names.byWord[word] = list
+				}
+			}
+		})
+	}
+}
+
+// firstWord returns the first run of word characters in s and its offset
+func firstWord(s string) (word string, offset int, ok bool) {
+	start := strings.IndexFunc(s, isWordRune)
+	if start < 0 {
+		return "", 0, false
+	}
+	end := strings.IndexFunc(s[start:], func(r rune) bool { return !isWordRune(r) })
+	if end < 0 {
+		return s[start:], start, true
+	}
+	return s[start : start+end], start, true
+}
+
+// redactNames masks tracked names standing as whole words, so "Al" is
+// masked in "Al was late" but not in "Alice"
+func redactNames(s string) string {
+	names.RLock()
+	defer names.RUnlock()
+	if len(names.byWord) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isWordRune(r) {
+			i += size
+			continue
+		}
+		end := i + size
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if !isWordRune(r) {
+				break
+			}
+			end += size
+		}
+		for _, t := range names.byWord[s[i:end]] {
+			start := i - t.offset
+			stop := start + len(t.name)
+			if start < last || !strings.HasPrefix(s[start:], t.name) {
+				continue
+			}
+			before, _ := utf8.DecodeLastRuneInString(s[:start])
+			after, _ := utf8.DecodeRuneInString(s[stop:])
+			if isWordRune(before) || isWordRune(after) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(Redacted)
+			last, end = stop, stop
+			break
+		}
+		i = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// RedactText masks email addresses, phone numbers and the names of tracked
+// volunteers in free text
+func RedactText(s string) string {
+	s = emailPattern.ReplaceAllString(s, Redacted)
+	s = phonePattern.ReplaceAllString(s, Redacted)
+	return redactNames(s)
+}
+
+// RedactVolunteers masks the personal fields of each volunteer in place
+func RedactVolunteers(volunteers []models.Volunteer) {
+	for i := range volunteers {
+		for _, f := range piiFields(&volunteers[i]) {
+			if *f != "" {
+				*f = Redacted
+			}
+		}
+	}
+}
+
+// redactingWriter masks personal data in everything written through it
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter wraps w so emails, phone numbers and tracked names
+// never reach it
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return redactingWriter{w: w}
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, RedactText(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,52 @@
+package pii_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/arnavshah/scheduler-api-go/pkg/pii"
+)
+
+func TestRedactText_TrackedNames(t *testing.T) {
+	release := pii.TrackNames([]models.Volunteer{{Name: "Al"}, {Name: "Al Smith"}, {Name: "Zoë"}, {Name: " "}})
+	second := pii.TrackNames([]models.Volunteer{{Name: "Al"}})
+
+	cases := map[string]string{
+		"Al Smith is late":    "[redacted] is late",
+		"ask Al, not Alice":   "ask [redacted], not Alice",
+		"Zoë and Al":          "[redacted] and [redacted]",
+		"Zoëy, Hal, Al_1":     "Zoëy, Hal, Al_1",
+		"mail al@example.com": "mail [redacted]",
+		"nothing to see":      "nothing to see",
+		"(Al)":                "([redacted])",
+	}
+	for in, want := range cases {
+		if got := pii.RedactText(in); got != want {
+			t.Errorf("RedactText(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	release()
+	release()
+	if got := pii.RedactText("Al Smith and Al"); got != "[redacted] Smith and [redacted]" {
+		t.Errorf("Expected Al to stay tracked by the second request, got %q", got)
+	}
+	second()
+	if got := pii.RedactText("Al and Zoë"); got != "Al and Zoë" {
+		t.Errorf("Expected names released, got %q", got)
+	}
+}
+
+func BenchmarkRedactText(b *testing.B) {
+	volunteers := make([]models.Volunteer, 20000)
+	for i := range volunteers {
+		volunteers[i].Name = fmt.Sprintf("Volunteer%d Surname%d", i, i)
+	}
+	defer pii.TrackNames(volunteers)()
+	line := "[GIN] 2026/10/16 - 14:43:37 | 200 | 1.2ms | 127.0.0.1 | POST /api/schedule assigned Volunteer12 Surname12 to shift_101"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pii.RedactText(line)
+	}
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MaxAlternatives caps how many schedules one request can ask for
+const MaxAlternatives = 5
+
+// ErrAlternativesNeedOptimal is returned by Run when alternatives are asked
+// of an algorithm that only finds one schedule
+var ErrAlternativesNeedOptimal = errors.New("alternatives need the optimal algorithm")
+
+// alternative is one distinct schedule found by the optimal search
+type alternative struct {
+	score, penalty, fairness float64
+	standby                  int
+	// headcount is only set when minimizing it
+	headcount int
+	key       string
+	state     assignmentState
+}
+
+// better ranks alternatives by score, then fewest standby assignments, then
+// headcount, then soft penalty, then fairness
+func (a alternative) better(b alternative) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.standby != b.standby {
+		return a.standby < b.standby
+	}
+	if a.headcount != b.headcount {
+		return a.headcount < b.headcount
+	}
+	if a.penalty != b.penalty {
+		return a.penalty < b.penalty
+	}
+	return a.fairness > b.fairness
+}
+
+// keepAlternative records the current assignments, scored as pass, if they
+// are new and rank among the best s.Alternatives found so far
+func (s *Scheduler) keepAlternative(pass alternative) {
+	shiftIDs := slices.Sorted(maps.Keys(s.Shifts))
+	var key strings.Builder
+	for _, id := range shiftIDs {
+		key.WriteString(id)
+		key.WriteByte('=')
+		key.WriteString(strings.Join(slices.Sorted(slices.Values(s.Shifts[id].Assigned)), ","))
+		key.WriteByte(';')
+	}
+	alt := pass
+	alt.key = key.String()
+	if !s.wantsAlternative(alt) {
+		return
+	}
+	alt.state = s.snapshot()
+	s.insertAlternative(alt)
+}
+
+// wantsAlternative reports whether a schedule is new and ranks among the
+// best s.Alternatives found so far
+func (s *Scheduler) wantsAlternative(alt alternative) bool {
+	if slices.ContainsFunc(s.alternatives, func(a alternative) bool { return a.key == alt.key }) {
+		return false
+	}
+	return len(s.alternatives) < s.Alternatives || alt.better(s.alternatives[len(s.alternatives)-1])
+}
+
+// insertAlternative adds a schedule in rank order, dropping the worst if
+// there are more than s.Alternatives
+func (s *Scheduler) insertAlternative(alt alternative) {
+	// Ties go after the schedules found earlier, as they do for Run's pick
+	i, _ := slices.BinarySearchFunc(s.alternatives, alt, func(kept, alt alternative) int {
+		if alt.better(kept) {
+			return 1
+		}
+		return -1
+	})
+	s.alternatives = slices.Insert(s.alternatives, i, alt)
+	if len(s.alternatives) > s.Alternatives {
+		s.alternatives = s.alternatives[:s.Alternatives]
+	}
+}
+
+// UseAlternative switches to the i-th best schedule found by Run, 0 being
+// the one Run settled on, and finishes it the same way. It reports false
+// when fewer distinct schedules were found.
+func (s *Scheduler) UseAlternative(i int) bool {
+	if i >= len(s.alternatives) {
+		return false
+	}
+	s.restore(s.alternatives[i].state)
+	s.AssignedResources = cloneBookings(s.baseResources)
+	s.finish()
+	return true
+}
+
+// cloneBookings copies resource bookings
+func cloneBookings(bookings map[string][]string) map[string][]string {
+	if bookings == nil {
+		return nil
+	}
+	out := make(map[string][]string, len(bookings))
+	for id, booked := range bookings {
+		out[id] = slices.Clone(booked)
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// AlgorithmAnneal selects simulated annealing
+const AlgorithmAnneal = "anneal"
+
+// Annealing schedule. FairnessWeight makes a fairness score of 0 (for stddev,
+// a coefficient of variation of 1.0) cost as much as one unfilled slot.
+const (
+	annealMaxIterations  = 200000
+	annealStartTemp      = 2.0
+	annealEndTemp        = 0.001
+	annealFairnessWeight = 1.0
+	// annealPenaltyWeight converts soft constraint penalties, counted in
+	// hours, to energy; ten hours cost as much as one unfilled slot
+	annealPenaltyWeight = 0.1
+	// annealStandbyWeight is the energy of one standby assignment, less than
+	// an unfilled slot so standby volunteers still fill what nobody else can
+	annealStandbyWeight = 0.5
+	// annealHeadcountWeight is the energy of each volunteer working, when
+	// minimizing headcount; it replaces fairness
+	annealHeadcountWeight = 0.25
+)
+
+// annealState tracks the running totals the energy function needs, so each
+// move can be scored without rescanning every volunteer
+type annealState struct {
+	s             *Scheduler
+	totalRequired int
+	filled        int
+	sumHours      float64
+	sqHours       float64
+	standby       int
+}
+
+// energy scores the current state; lower is better
+func (a *annealState) energy() float64 {
+	unfilled := float64(a.totalRequired-a.filled) + annealStandbyWeight*float64(a.standby)
+	if len(a.s.SoftConstraints) > 0 {
+		_, penalty := a.s.SoftViolations()
+		unfilled += annealPenaltyWeight * penalty
+	}
+	if a.s.minimizeHeadcount() {
+		return unfilled + annealHeadcountWeight*float64(a.s.Headcount())
+	}
+	if a.s.FairnessMetric != "" && a.s.FairnessMetric != FairnessStdDev {
+		// Other metrics need a full rescan of volunteer hours
+		return unfilled + annealFairnessWeight*(100.0-a.s.FairnessScore())/100.0
+	}
+	n := float64(len(a.s.Volunteers))
+	if n == 0 || a.sumHours == 0 {
+		return unfilled
+	}
+	mean := a.sumHours / n
+	variance := a.sqHours/n - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+	return unfilled + annealFairnessWeight*math.Sqrt(variance)/mean
+}
+
+// add assigns a volunteer and updates the running totals
+func (a *annealState) add(vol *models.Volunteer, shift *models.Shift, duration float64) {
+	before := vol.AssignedHours
+	a.s.assign(vol, shift, duration)
+	a.filled++
+	if vol.Tier == TierStandby {
+		a.standby++
+	}
+	a.sumHours += duration
+	a.sqHours += vol.AssignedHours*vol.AssignedHours - before*before
+}
+
+// remove unassigns a volunteer and updates the running totals
+func (a *annealState) remove(vol *models.Volunteer, shift *models.Shift, duration float64) {
+	before := vol.AssignedHours
+	a.s.removeAssignment(vol, shift, duration)
+	a.filled--
+	if vol.Tier == TierStandby {
+		a.standby--
+	}
+	a.sumHours -= duration
+	a.sqHours += vol.AssignedHours*vol.AssignedHours - before*before
+}
+
+// AssignAnneal starts from the greedy solution and improves fill rate and
+// fairness jointly with simulated annealing, using fill, replace and drop
+// moves. Prefilled assignments are never touched. Cancelling ctx stops the
+// search early with the best solution so far.
+func (s *Scheduler) AssignAnneal(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	rng := s.random()
+	volsByGroup := s.GroupByGroup()
+
+	fixed := make(map[models.Assignment]bool)
+	for id, sh := range s.Shifts {
+		for _, volID := range sh.Assigned {
+			fixed[models.Assignment{ShiftID: id, VolunteerID: volID}] = true
+		}
+	}
+
+	s.warmStart(true, volsByGroup)
+
+	shiftList := make([]*models.Shift, 0, len(s.Shifts))
+	for _, sh := range s.Shifts {
+		shiftList = append(shiftList, sh)
+	}
+	slices.SortFunc(shiftList, func(x, y *models.Shift) int { return strings.Compare(x.ID, y.ID) })
+	if len(shiftList) == 0 {
+		return
+	}
+
+	a := &annealState{s: s, filled: s.filledSlots(), sqHours: s.squaredHours(), standby: s.StandbyAssignments()}
+	for _, sh := range shiftList {
+		for _, count := range sh.RequiredGroups {
+			a.totalRequired += count
+		}
+	}
+	for _, v := range s.Volunteers {
+		a.sumHours += v.AssignedHours
+	}
+
+	current := a.energy()
+	bestEnergy := current
+	best := s.snapshot()
+
+	for iter := 0; iter < annealMaxIterations; iter++ {
+		if iter%256 == 0 && ctx.Err() != nil {
+			break
+		}
+		temp := annealStartTemp * math.Pow(annealEndTemp/annealStartTemp, float64(iter)/annealMaxIterations)
+
+		shift := shiftList[rng.Intn(len(shiftList))]
+		duration := s.DurationHours(shift.Start, shift.End)
+
+		// Pick the volunteer to take off the shift, if any (replace/drop move)
+		var removed *models.Volunteer
+		if len(shift.Assigned) > 0 && rng.Intn(2) == 0 {
+			volID := shift.Assigned[rng.Intn(len(shift.Assigned))]
+			if fixed[models.Assignment{ShiftID: shift.ID, VolunteerID: volID}] {
+				continue
+			}
+			removed = s.Volunteers[volID]
+			a.remove(removed, shift, duration)
+		}
+
+		// Pick a volunteer to put on the shift in an open group (fill/replace move).
+		// Drops skip this half the time so the search can free volunteers up.
+		var added *models.Volunteer
+		if removed == nil || rng.Intn(2) == 0 {
+			added = s.randomCandidate(rng, shift, duration, volsByGroup, removed)
+			if added != nil {
+				a.add(added, shift, duration)
+			}
+		}
+
+		if removed == nil && added == nil {
+			continue
+		}
+
+		next := a.energy()
+		delta := next - current
+		if delta <= 0 || rng.Float64() < math.Exp(-delta/temp) {
+			current = next
+			if current < bestEnergy-1e-9 {
+				bestEnergy = current
+				best = s.snapshot()
+			}
+			continue
+		}
+
+		// Reject: undo the move
+		if added != nil {
+			a.remove(added, shift, duration)
+		}
+		if removed != nil {
+			a.add(removed, shift, duration)
+		}
+	}
+
+	// Restore the best state, then let a final greedy pass record conflicts
+	s.restore(best)
+	s.Conflicts = nil
+	s.AssignSimpleWithGroups(false, volsByGroup)
+}
+
+// randomCandidate picks a random eligible volunteer for one of a shift's open
+// groups, never returning the excluded volunteer. It returns nil if none is found.
+func (s *Scheduler) randomCandidate(rng *rand.Rand, shift *models.Shift, duration float64, volsByGroup map[string][]*models.Volunteer, exclude *models.Volunteer) *models.Volunteer {
+	filled := s.FilledByGroup(shift)
+	var open []string
+	for _, g := range sortedGroups(shift) {
+		if filled[g] < shift.RequiredGroups[g] {
+			open = append(open, g)
+		}
+	}
+	if len(open) == 0 {
+		return nil
+	}
+	pool := volsByGroup[open[rng.Intn(len(open))]]
+	if len(pool) == 0 {
+		return nil
+	}
+
+	// Probe a few random volunteers rather than scanning the whole group
+	for tries := 0; tries < 8; tries++ {
+		vol := pool[rng.Intn(len(pool))]
+		if vol == exclude || slices.Contains(shift.Assigned, vol.ID) {
+			continue
+		}
+		if s.CheckEligibility(vol, shift, duration).OK() {
+			return vol
+		}
+	}
+	return nil
+}
+
+// removeAssignment takes a volunteer off a shift and updates their totals
+func (s *Scheduler) removeAssignment(volunteer *models.Volunteer, shift *models.Shift, duration float64) {
+	if i := slices.Index(shift.Assigned, volunteer.ID); i >= 0 {
+		shift.Assigned = slices.Delete(shift.Assigned, i, i+1)
+	}
+	if i := slices.Index(volunteer.AssignedShifts, shift.ID); i >= 0 {
+		volunteer.AssignedShifts = slices.Delete(volunteer.AssignedShifts, i, i+1)
+		s.trackUnassign(volunteer, shift)
+	}
+	volunteer.AssignedHours -= duration
+}
@@ -0,0 +1,19 @@
+package scheduler
+
+import "github.com/arnavshah/scheduler-api-go/pkg/models"
+
+// extendsBlock checks if a shift starts as another of the volunteer's shifts
+// at the same location on the same day ends, or ends as one starts, so they
+// would work it in one visit
+func (s *Scheduler) extendsBlock(volunteer *models.Volunteer, shift *models.Shift) bool {
+	for _, shiftID := range volunteer.AssignedShifts {
+		sh := s.Shifts[shiftID]
+		if sh == nil || sh.ID == shift.ID || sh.Location != shift.Location || !dayOf(sh.Start).Equal(dayOf(shift.Start)) {
+			continue
+		}
+		if sh.End.Equal(shift.Start) || shift.End.Equal(sh.Start) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// certDate is the layout of certification expiry dates
+const certDate = "2006-01-02"
+
+// ValidateCertifications checks that every certification has a name and a
+// valid expiry date
+func (s *Scheduler) ValidateCertifications() error {
+	for id, vol := range s.Volunteers {
+		for _, cert := range vol.Certifications {
+			if cert.Name == "" {
+				return fmt.Errorf("volunteer %s has a certification without a name", id)
+			}
+			if cert.Expires == "" {
+				continue
+			}
+			if _, err := time.Parse(certDate, cert.Expires); err != nil {
+				return fmt.Errorf("volunteer %s: certification %q expires must be YYYY-MM-DD", id, cert.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// certification returns the volunteer's certification with the given name
+func certification(vol *models.Volunteer, name string) (models.Certification, bool) {
+	for _, cert := range vol.Certifications {
+		if cert.Name == name {
+			return cert, true
+		}
+	}
+	return models.Certification{}, false
+}
+
+// HasCertifications checks that the volunteer holds every certification the
+// shift requires, current or not
+func (s *Scheduler) HasCertifications(vol *models.Volunteer, shift *models.Shift) bool {
+	for _, name := range shift.RequiredCertifications {
+		if _, ok := certification(vol, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CertificationsCurrent checks that none of the volunteer's required
+// certifications expires before the day the shift starts. Certifications
+// they don't hold are left to HasCertifications.
+func (s *Scheduler) CertificationsCurrent(vol *models.Volunteer, shift *models.Shift) bool {
+	return len(s.expiredCertifications(vol, shift)) == 0
+}
+
+// expiredCertifications names the required certifications the volunteer
+// holds that will have expired by the shift. Dates compare as strings since
+// both are YYYY-MM-DD.
+func (s *Scheduler) expiredCertifications(vol *models.Volunteer, shift *models.Shift) []string {
+	var expired []string
+	day := shift.Start.Format(certDate)
+	for _, name := range shift.RequiredCertifications {
+		if cert, ok := certification(vol, name); ok && cert.Expires != "" && cert.Expires < day {
+			expired = append(expired, name)
+		}
+	}
+	return expired
+}
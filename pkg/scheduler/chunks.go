@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Chunk sizes for solving long horizons piece by piece
+const (
+	ChunkDay  = "day"
+	ChunkWeek = "week"
+)
+
+// ErrUnknownChunk is returned for a chunk size that doesn't exist
+var ErrUnknownChunk = errors.New("chunk must be day or week")
+
+// ErrChunkedAlternatives is returned when alternatives are asked of a chunked run
+var ErrChunkedAlternatives = errors.New("alternatives can't be combined with chunk")
+
+// ValidateChunk checks a chunk size. An empty size means no chunking.
+func ValidateChunk(chunk string) error {
+	switch chunk {
+	case "", ChunkDay, ChunkWeek:
+		return nil
+	}
+	return ErrUnknownChunk
+}
+
+// chunkStart is the start of the day or week (from Monday) a time falls in
+func chunkStart(chunk string, t time.Time) time.Time {
+	day := dayOf(t)
+	if chunk == ChunkWeek {
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// runChunked solves the shifts one day or week at a time, in order, so each
+// search only sees a bounded number of open slots. Volunteers carry their
+// hours and shifts from one chunk to the next; linked shifts are solved with
+// the earliest of their unit. The time budget is shared out evenly between
+// the chunks still to run.
+func (s *Scheduler) runChunked(ctx context.Context, algorithm string, timeoutSeconds int) error {
+	// The fairness cap depends on the whole horizon, so settle it up front
+	s.HoursCap()
+
+	chunks := make(map[time.Time][]string)
+	placed := make(map[string]bool)
+	for _, id := range slices.Sorted(maps.Keys(s.Shifts)) {
+		if placed[id] {
+			continue
+		}
+		unit := append([]string{id}, s.linked(id)...)
+		start := s.Shifts[id].Start
+		for _, other := range unit {
+			if s.Shifts[other].Start.Before(start) {
+				start = s.Shifts[other].Start
+			}
+		}
+		key := chunkStart(s.Chunk, start)
+		for _, other := range unit {
+			placed[other] = true
+			chunks[key] = append(chunks[key], other)
+		}
+	}
+	keys := slices.SortedFunc(maps.Keys(chunks), time.Time.Compare)
+
+	// Only assigned shifts within the lookback of a chunk are carried into
+	// it; those further back are tallied into pastCategories as they leave
+	lookback := s.chunkLookback()
+	s.pastCategories = make(map[string]map[string]int)
+	defer func() { s.pastCategories = nil }()
+
+	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+	var window []string
+	var conflicts []models.ConflictReason
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cutoff := key.Add(-lookback)
+		window = slices.DeleteFunc(window, func(id string) bool {
+			if s.Shifts[id].End.After(cutoff) {
+				return false
+			}
+			s.leaveWindow(s.Shifts[id])
+			return true
+		})
+
+		chunkCtx, cancel := context.WithDeadline(ctx, time.Now().Add(time.Until(deadline)/time.Duration(len(keys)-i)))
+		sub := s.chunkScheduler(chunks[key], window)
+		err := sub.solve(chunkCtx, algorithm, timeoutSeconds)
+		cancel()
+		if err != nil {
+			return err
+		}
+		conflicts = append(conflicts, sub.Conflicts...)
+		for _, id := range chunks[key] {
+			if len(s.Shifts[id].Assigned) > 0 || len(s.AssignedResources[id]) > 0 {
+				window = append(window, id)
+			}
+		}
+	}
+
+	s.Conflicts = append(s.Conflicts, conflicts...)
+	s.forgetBusyTimes(nil)
+	return nil
+}
+
+// chunkLookback is how far back a chunk needs to see the shifts volunteers
+// already work: the rolling week of max_hours_per_week, the longest run of
+// consecutive days, rest gap or travel time, plus a day for time zones
+func (s *Scheduler) chunkLookback() time.Duration {
+	const day = 24 * time.Hour
+	lookback := 7 * day
+	for _, v := range s.Volunteers {
+		lookback = max(lookback, time.Duration(v.MaxConsecutiveDays+1)*day,
+			time.Duration(v.MinRestHours*float64(time.Hour)))
+	}
+	for _, row := range s.TravelMinutes {
+		for _, minutes := range row {
+			lookback = max(lookback, time.Duration(minutes)*time.Minute)
+		}
+	}
+	return lookback + day
+}
+
+// leaveWindow counts a shift dropped from the chunk window towards its
+// volunteers' category totals
+func (s *Scheduler) leaveWindow(shift *models.Shift) {
+	if shift.Category == "" {
+		return
+	}
+	for _, volID := range shift.Assigned {
+		if s.pastCategories[volID] == nil {
+			s.pastCategories[volID] = make(map[string]int)
+		}
+		s.pastCategories[volID][shift.Category]++
+	}
+}
+
+// chunkScheduler returns a scheduler over one chunk's shifts, which it
+// assigns in place. The earlier shifts in the window are included as copies
+// needing no one, so overlap, rest and limit checks still see them; checks
+// over a volunteer's shifts skip the ones left out.
+func (s *Scheduler) chunkScheduler(ids, window []string) *Scheduler {
+	sub := *s
+	sub.Shifts = make(map[string]*models.Shift, len(ids)+len(window))
+	for _, id := range ids {
+		sub.Shifts[id] = s.Shifts[id]
+	}
+	for _, id := range window {
+		past := *s.Shifts[id]
+		past.RequiredGroups, past.IdealGroups, past.LinkedShifts = nil, nil, nil
+		sub.Shifts[id] = &past
+	}
+	sub.Conflicts = nil
+	sub.alternatives = nil
+	sub.busy = nil
+	sub.links = nil
+	return &sub
+}
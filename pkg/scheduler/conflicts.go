@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// CauseGroupSize is the cause of slots left open because every volunteer in
+// the group was already working at the time
+const CauseGroupSize = "group_size"
+
+// causeReasons describes why most of a group's volunteers were ruled out,
+// by the constraint that did it
+var causeReasons = map[string]string{
+	"max_hours":            "were at max hours",
+	"no_overlap":           "were already booked at the same time",
+	"group_rules":          "were disallowed by group rules",
+	"availability":         "were outside their availability windows",
+	"min_rest_hours":       "needed more rest between shifts",
+	"day_limits":           "were at their daily or consecutive-day limits",
+	"max_hours_per_week":   "were at their weekly hour cap",
+	"category_limits":      "were at their category limits",
+	"pairing":              "were ruled out by pairing rules",
+	"supervisor_ratios":    "would have exceeded the supervisor ratio",
+	"equipment":            "lacked equipment the shift still needed",
+	"max_hours_ratio":      "were at the fairness cap",
+	"max_shifts":           "were at their max shifts",
+	"certifications":       "lacked a required certification",
+	"certification_expiry": "had a required certification expire before the shift",
+}
+
+// mainCause returns the constraint that ruled out the most of a slot's
+// rejected candidates, earlier constraints winning ties. Candidates already
+// on the shift aren't rejected, so none at all also means the group is too
+// small.
+func mainCause(rejected []rejection) string {
+	busy := true
+	for _, r := range rejected {
+		busy = busy && !r.NoOverlap
+	}
+	if busy {
+		return CauseGroupSize
+	}
+	counts := make(map[string]int)
+	cause := ""
+	for _, r := range rejected {
+		for _, name := range r.Failed() {
+			counts[name]++
+		}
+	}
+	for _, c := range rejected[0].results() {
+		if counts[c.name] > counts[cause] {
+			cause = c.name
+		}
+	}
+	return cause
+}
+
+// ConflictSummary groups the schedule's conflicts by cause and group,
+// largest first, so a long conflict list can be read at a glance
+func (s *Scheduler) ConflictSummary() []models.ConflictCause {
+	type key struct{ cause, group string }
+	byKey := make(map[key]*models.ConflictCause)
+	var order []key
+	for _, c := range s.Conflicts {
+		k := key{c.Cause, c.Group}
+		entry, ok := byKey[k]
+		if !ok {
+			entry = &models.ConflictCause{Cause: c.Cause, Group: c.Group, Shifts: []string{}}
+			byKey[k] = entry
+			order = append(order, k)
+		}
+		entry.Count++
+		if !slices.Contains(entry.Shifts, c.ShiftID) {
+			entry.Shifts = append(entry.Shifts, c.ShiftID)
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	groupSizes := make(map[string]int)
+	for _, vol := range s.Volunteers {
+		for _, g := range VolunteerGroups(vol) {
+			groupSizes[g]++
+		}
+	}
+
+	summary := make([]models.ConflictCause, 0, len(order))
+	for _, k := range order {
+		entry := byKey[k]
+		slices.Sort(entry.Shifts)
+		entry.Summary = causeSummary(entry, groupSizes[entry.Group])
+		summary = append(summary, *entry)
+	}
+	slices.SortStableFunc(summary, func(a, b models.ConflictCause) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return summary
+}
+
+// causeSummary describes one entry of the conflict summary
+func causeSummary(entry *models.ConflictCause, groupSize int) string {
+	switch entry.Cause {
+	case CauseGroupSize:
+		if groupSize == 0 {
+			return fmt.Sprintf("%d slots unfilled because group %q has no volunteers", entry.Count, entry.Group)
+		}
+		return fmt.Sprintf("%d slots unfilled because group %q has only %d volunteers", entry.Count, entry.Group, groupSize)
+	case "missing_partner":
+		return fmt.Sprintf("%d %s volunteers removed because none of their required partners were on the shift", entry.Count, entry.Group)
+	case "unlinked":
+		return fmt.Sprintf("%d %s volunteers removed because they couldn't also work linked shifts", entry.Count, entry.Group)
+	case "supervisor_shortage":
+		return fmt.Sprintf("%d shifts short of %s supervisors", len(entry.Shifts), entry.Group)
+	case "missing_equipment":
+		return fmt.Sprintf("%d shifts missing required equipment", len(entry.Shifts))
+	case "missing_resources":
+		return fmt.Sprintf("%d shifts missing required resources", len(entry.Shifts))
+	}
+	return fmt.Sprintf("%d slots unfilled in group %q (%d volunteers): most %s", entry.Count, entry.Group, groupSize, causeReasons[entry.Cause])
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// openSlots counts a shift's unfilled minimum headcount
+func openSlots(shift *models.Shift) int {
+	total := 0
+	for _, count := range shift.RequiredGroups {
+		total += count
+	}
+	return max(total-len(shift.Assigned), 0)
+}
+
+// equipmentHeld counts the volunteers carrying an item, on the shift and
+// among everyone else who could still join it
+func (s *Scheduler) equipmentHeld(shift *models.Shift, item string) (held, spare int) {
+	for _, vol := range s.Volunteers {
+		if !slices.Contains(vol.HasEquipment, item) {
+			continue
+		}
+		if slices.Contains(shift.Assigned, vol.ID) {
+			held++
+		} else {
+			spare++
+		}
+	}
+	return held, spare
+}
+
+// EquipmentOK checks that adding a volunteer to a shift leaves enough open
+// slots for the equipment the shift still needs. Volunteers carrying an
+// item can always take a slot as far as that item is concerned, and slots
+// are only held back for items someone could still bring.
+func (s *Scheduler) EquipmentOK(volunteer *models.Volunteer, shift *models.Shift) bool {
+	for item, count := range shift.RequiredEquipment {
+		if slices.Contains(volunteer.HasEquipment, item) {
+			continue
+		}
+		held, spare := s.equipmentHeld(shift, item)
+		if need := min(count-held, spare); need > 0 && openSlots(shift)-1 < need {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckEquipment records a conflict for every shift that ends up short of
+// required equipment
+func (s *Scheduler) CheckEquipment() {
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		if len(shift.RequiredEquipment) > 0 {
+			shiftKeys = append(shiftKeys, id)
+		}
+	}
+	slices.Sort(shiftKeys)
+
+	for _, id := range shiftKeys {
+		shift := s.Shifts[id]
+		items := make([]string, 0, len(shift.RequiredEquipment))
+		for item := range shift.RequiredEquipment {
+			items = append(items, item)
+		}
+		slices.Sort(items)
+
+		var reasons []string
+		for _, item := range items {
+			if held, _ := s.equipmentHeld(shift, item); held < shift.RequiredEquipment[item] {
+				reasons = append(reasons, fmt.Sprintf("needs %d volunteers with %s: has %d", shift.RequiredEquipment[item], item, held))
+			}
+		}
+		if len(reasons) > 0 {
+			s.Conflicts = append(s.Conflicts, models.ConflictReason{ShiftID: id, Reasons: reasons, Cause: "missing_equipment"})
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ErrUnknownShift is returned by Explain for a shift that isn't in the schedule
+var ErrUnknownShift = errors.New("unknown shift")
+
+// Explain evaluates every volunteer for one slot of a shift in a group,
+// against the current assignments. Volunteers already on the shift are
+// checked as if they were being placed now. Eligible volunteers are ranked
+// in the order the greedy scheduler would pick them.
+func (s *Scheduler) Explain(shiftID, group string) ([]models.CandidateExplanation, error) {
+	shift, ok := s.Shifts[shiftID]
+	if !ok {
+		return nil, ErrUnknownShift
+	}
+
+	type ranked struct {
+		explanation models.CandidateExplanation
+		rank        Rank
+	}
+	all := make([]ranked, 0, len(s.Volunteers))
+	for _, vol := range s.Volunteers {
+		var e Eligibility
+		s.withoutAssignment(vol, shift, func(duration float64) {
+			e = s.CheckEligibility(vol, shift, duration)
+		})
+		checks := e.Checks()
+		checks["group"] = InGroup(vol, group)
+		x := models.CandidateExplanation{
+			VolunteerID:     vol.ID,
+			Name:            vol.Name,
+			Eligible:        e.OK() && checks["group"],
+			AlreadyAssigned: slices.Contains(shift.Assigned, vol.ID),
+			Checks:          checks,
+			SoftViolations:  e.Soft,
+		}
+		r := s.RankCandidate(vol, shift, e)
+		x.Score, x.RotationCount, x.Prefers = r.Score, r.Rotation, r.Prefers
+		all = append(all, ranked{x, r})
+	}
+
+	slices.SortFunc(all, func(a, b ranked) int {
+		if a.explanation.Eligible != b.explanation.Eligible {
+			if a.explanation.Eligible {
+				return -1
+			}
+			return 1
+		}
+		if a.explanation.Eligible {
+			if a.rank.Before(b.rank) {
+				return -1
+			}
+			if b.rank.Before(a.rank) {
+				return 1
+			}
+		}
+		return strings.Compare(a.explanation.VolunteerID, b.explanation.VolunteerID)
+	})
+
+	explanations := make([]models.CandidateExplanation, len(all))
+	for i, r := range all {
+		explanations[i] = r.explanation
+		if r.explanation.Eligible {
+			explanations[i].Rank = i + 1
+		}
+	}
+	return explanations, nil
+}
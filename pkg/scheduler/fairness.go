@@ -0,0 +1,288 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Fairness metrics selectable via the schedule request's "fairness_metric" field
+const (
+	FairnessStdDev   = "stddev"
+	FairnessMinMax   = "min_max"
+	FairnessGini     = "gini"
+	FairnessPerGroup = "per_group"
+)
+
+// ErrUnknownFairnessMetric is returned for an unsupported fairness metric name
+var ErrUnknownFairnessMetric = errors.New("unknown fairness metric")
+
+// Fairness dimensions selectable via the schedule request's "fairness_dimensions" field
+const (
+	DimensionHours   = "hours"
+	DimensionShifts  = "shifts"
+	DimensionNight   = "night"
+	DimensionWeekend = "weekend"
+)
+
+// ErrUnknownFairnessDimension is returned for an unsupported fairness dimension name
+var ErrUnknownFairnessDimension = errors.New("unknown fairness dimension")
+
+// ErrInvalidHoursRatio is returned for a max hours ratio below 1
+var ErrInvalidHoursRatio = errors.New("max_hours_ratio must be at least 1")
+
+// ErrInvalidScoreWeights is returned for negative score weights
+var ErrInvalidScoreWeights = errors.New("score_weights must not be negative")
+
+// ValidateFairnessMetric checks a metric name. An empty name means stddev.
+func ValidateFairnessMetric(metric string) error {
+	switch metric {
+	case "", FairnessStdDev, FairnessMinMax, FairnessGini, FairnessPerGroup:
+		return nil
+	}
+	return ErrUnknownFairnessMetric
+}
+
+// ValidateFairnessDimensions checks fairness dimension names
+func ValidateFairnessDimensions(dimensions []string) error {
+	for _, d := range dimensions {
+		switch d {
+		case DimensionHours, DimensionShifts, DimensionNight, DimensionWeekend:
+		default:
+			return fmt.Errorf("%w: %q", ErrUnknownFairnessDimension, d)
+		}
+	}
+	return nil
+}
+
+// ValidateMaxHoursRatio checks a fairness cap. Zero means no cap; anything
+// below 1 could only be met by leaving everyone at zero hours.
+func ValidateMaxHoursRatio(ratio float64) error {
+	if ratio != 0 && ratio < 1 {
+		return ErrInvalidHoursRatio
+	}
+	return nil
+}
+
+// ValidateScoreWeights checks the weights of the optimal search's score
+func ValidateScoreWeights(fill, fairness float64) error {
+	if fill < 0 || fairness < 0 {
+		return ErrInvalidScoreWeights
+	}
+	return nil
+}
+
+// HoursCap returns the most hours anyone may work under MaxHoursRatio, or 0
+// without a cap. The mean is projected from the hours already assigned plus
+// every open required slot, so it doesn't move as the solver fills slots.
+func (s *Scheduler) HoursCap() float64 {
+	if s.MaxHoursRatio <= 0 || len(s.Volunteers) == 0 {
+		return 0
+	}
+	if s.hoursCap == 0 {
+		total := 0.0
+		for _, v := range s.Volunteers {
+			total += v.AssignedHours
+		}
+		for _, sh := range s.Shifts {
+			filled := s.FilledByGroup(sh)
+			for g, count := range sh.RequiredGroups {
+				if open := count - filled[g]; open > 0 {
+					total += float64(open) * s.DurationHours(sh.Start, sh.End)
+				}
+			}
+		}
+		s.hoursCap = s.MaxHoursRatio * total / float64(len(s.Volunteers))
+	}
+	return s.hoursCap
+}
+
+// ExceedsHoursCap checks if a shift would take a volunteer past HoursCap
+func (s *Scheduler) ExceedsHoursCap(volunteer *models.Volunteer, duration float64) bool {
+	limit := s.HoursCap()
+	return limit > 0 && volunteer.AssignedHours+duration > limit
+}
+
+// FairnessScore returns the score (0-100) for the scheduler's selected
+// FairnessMetric. Optimizers use this to target the caller's chosen metric.
+func (s *Scheduler) FairnessScore() float64 {
+	return s.FairnessScoreFor(s.FairnessMetric)
+}
+
+// FairnessScoreFor returns the score (0-100) for a fairness metric, where 100 is perfectly fair
+func (s *Scheduler) FairnessScoreFor(metric string) float64 {
+	switch metric {
+	case FairnessMinMax:
+		return minMaxScore(s.hoursList())
+	case FairnessGini:
+		return giniScore(s.hoursList())
+	case FairnessPerGroup:
+		groups := s.GroupFairness()
+		if len(groups) == 0 {
+			return 100.0
+		}
+		sum := 0.0
+		for _, score := range groups {
+			sum += score
+		}
+		return sum / float64(len(groups))
+	}
+	return s.CalculateFairnessScore()
+}
+
+// FairnessByDimension scores each dimension with the selected FairnessMetric
+func (s *Scheduler) FairnessByDimension(dimensions []string) map[string]float64 {
+	if len(dimensions) == 0 {
+		return nil
+	}
+	scores := make(map[string]float64, len(dimensions))
+	for _, d := range dimensions {
+		scores[d] = s.DimensionScore(s.FairnessMetric, d)
+	}
+	return scores
+}
+
+// DimensionScore returns the score (0-100) for a fairness metric computed
+// over a dimension instead of hours
+func (s *Scheduler) DimensionScore(metric, dimension string) float64 {
+	if dimension == DimensionHours {
+		return s.FairnessScoreFor(metric)
+	}
+
+	byGroup := make(map[string][]float64)
+	values := make([]float64, 0, len(s.Volunteers))
+	for _, v := range s.Volunteers {
+		count := 0.0
+		for _, shiftID := range v.AssignedShifts {
+			sh, ok := s.Shifts[shiftID]
+			if !ok {
+				continue
+			}
+			switch {
+			case dimension == DimensionShifts,
+				dimension == DimensionNight && isNight(sh),
+				dimension == DimensionWeekend && isWeekend(sh):
+				count++
+			}
+		}
+		values = append(values, count)
+		byGroup[v.Group] = append(byGroup[v.Group], count)
+	}
+
+	switch metric {
+	case FairnessMinMax:
+		return minMaxScore(values)
+	case FairnessGini:
+		return giniScore(values)
+	case FairnessPerGroup:
+		if len(byGroup) == 0 {
+			return 100.0
+		}
+		sum := 0.0
+		for _, group := range byGroup {
+			sum += stdDevScore(group)
+		}
+		return sum / float64(len(byGroup))
+	}
+	return stdDevScore(values)
+}
+
+// isNight reports whether any of a shift falls after 22:00 or before 06:00 in
+// its own timezone
+func isNight(sh *models.Shift) bool {
+	day := dayOf(sh.Start)
+	return sh.Start.Before(day.Add(6*time.Hour)) || sh.End.After(day.Add(22*time.Hour))
+}
+
+// isWeekend reports whether a shift starts on a Saturday or Sunday
+func isWeekend(sh *models.Shift) bool {
+	day := sh.Start.Weekday()
+	return day == time.Saturday || day == time.Sunday
+}
+
+// GroupFairness returns the stddev-based fairness score within each group.
+// Volunteers count toward their primary group only, so multi-skill volunteers
+// are not double counted.
+func (s *Scheduler) GroupFairness() map[string]float64 {
+	hoursByGroup := make(map[string][]float64)
+	for _, v := range s.Volunteers {
+		hoursByGroup[v.Group] = append(hoursByGroup[v.Group], v.AssignedHours)
+	}
+	scores := make(map[string]float64, len(hoursByGroup))
+	for g, hours := range hoursByGroup {
+		scores[g] = stdDevScore(hours)
+	}
+	return scores
+}
+
+// hoursList returns every volunteer's assigned hours
+func (s *Scheduler) hoursList() []float64 {
+	hours := make([]float64, 0, len(s.Volunteers))
+	for _, v := range s.Volunteers {
+		hours = append(hours, v.AssignedHours)
+	}
+	return hours
+}
+
+// stdDevScore is 100 minus the coefficient of variation as a percentage, floored at 0
+func stdDevScore(hours []float64) float64 {
+	if len(hours) == 0 {
+		return 100.0
+	}
+	sum := 0.0
+	for _, h := range hours {
+		sum += h
+	}
+	if sum == 0 {
+		return 100.0
+	}
+	mean := sum / float64(len(hours))
+	varianceSum := 0.0
+	for _, h := range hours {
+		varianceSum += (h - mean) * (h - mean)
+	}
+	score := (1.0 - math.Sqrt(varianceSum/float64(len(hours)))/mean) * 100.0
+	return math.Max(score, 0)
+}
+
+// minMaxScore compares the busiest and least busy volunteers: 100 when they
+// match, 0 when someone has no hours while others do
+func minMaxScore(hours []float64) float64 {
+	if len(hours) == 0 {
+		return 100.0
+	}
+	lo, hi := hours[0], hours[0]
+	for _, h := range hours[1:] {
+		lo = math.Min(lo, h)
+		hi = math.Max(hi, h)
+	}
+	if hi == 0 {
+		return 100.0
+	}
+	return (1.0 - (hi-lo)/hi) * 100.0
+}
+
+// giniScore is 100 times one minus the Gini coefficient of assigned hours
+func giniScore(hours []float64) float64 {
+	n := len(hours)
+	if n == 0 {
+		return 100.0
+	}
+	sorted := append([]float64(nil), hours...)
+	sort.Float64s(sorted)
+
+	sum, weighted := 0.0, 0.0
+	for i, h := range sorted {
+		sum += h
+		weighted += float64(i+1) * h
+	}
+	if sum == 0 {
+		return 100.0
+	}
+	gini := (2*weighted)/(float64(n)*sum) - float64(n+1)/float64(n)
+	return (1.0 - gini) * 100.0
+}
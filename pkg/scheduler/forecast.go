@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Forecast compares, for every shift and required group, the slots still
+// open with the volunteers who could fill them, without assigning anyone.
+// Each volunteer is judged against the current assignments alone, so one
+// volunteer may count toward several shifts that they could not all work:
+// the eligible counts are an upper bound, and any gap is a certain shortfall.
+func (s *Scheduler) Forecast() []models.StaffingForecast {
+	var out []models.StaffingForecast
+	for _, shift := range s.Shifts {
+		filled := s.FilledByGroup(shift)
+		duration := s.DurationHours(shift.Start, shift.End)
+		for group, required := range shift.RequiredGroups {
+			f := models.StaffingForecast{
+				ShiftID:    shift.ID,
+				Group:      group,
+				Start:      shift.Start,
+				End:        shift.End,
+				Required:   required,
+				Assigned:   filled[group],
+				ExcludedBy: make(map[string]int),
+			}
+			for _, vol := range s.Volunteers {
+				if !InGroup(vol, group) || slices.Contains(shift.Assigned, vol.ID) {
+					continue
+				}
+				e := s.CheckEligibility(vol, shift, duration)
+				if e.OK() {
+					f.Eligible++
+					continue
+				}
+				for _, name := range e.Failed() {
+					f.ExcludedBy[name]++
+				}
+			}
+			f.Gap = max(0, required-f.Assigned-f.Eligible)
+			out = append(out, f)
+		}
+	}
+	slices.SortFunc(out, func(a, b models.StaffingForecast) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.ShiftID, b.ShiftID), cmp.Compare(a.Group, b.Group))
+	})
+	return out
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync/atomic"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// warmStart applies the hints that still fill an open slot and pass every
+// constraint, then fills the rest greedily. Without hints it is a plain
+// greedy pass.
+func (s *Scheduler) warmStart(shuffle bool, volsByGroup map[string][]*models.Volunteer) {
+	if len(s.Hints) > 0 {
+		s.KeepPrevious(s.Hints)
+	}
+	s.AssignSimpleWithGroups(shuffle, volsByGroup)
+}
+
+// scorePass scores the current assignments the way the optimal search ranks them
+func (s *Scheduler) scorePass() alternative {
+	pass := alternative{score: s.Score(), fairness: s.FairnessScore(), standby: s.StandbyAssignments()}
+	_, pass.penalty = s.SoftViolations()
+	if s.minimizeHeadcount() {
+		pass.headcount = s.Headcount()
+	}
+	return pass
+}
+
+// hintedPass scores a warm start from the current state, which it leaves
+// unchanged. It returns nil without hints.
+func (s *Scheduler) hintedPass() *alternative {
+	if len(s.Hints) == 0 {
+		return nil
+	}
+	original := s.snapshot()
+	s.warmStart(false, s.GroupByGroup())
+	pass := s.scorePass()
+	pass.state = s.snapshot()
+	s.restore(original)
+	return &pass
+}
+
+// improve searches from a hinted schedule rather than from scratch: each
+// pass frees a few of the incumbent's assignments at random, refills
+// greedily, and is kept if it scores at least as well. The incumbent never
+// gets worse, so with a seed, feeding each result back in as hints gives
+// reproducible, steady improvement. Prefilled assignments are never freed.
+func (s *Scheduler) improve(ctx context.Context, done *atomic.Bool, original assignmentState, incumbent alternative, volsByGroup map[string][]*models.Volunteer) *alternative {
+	for ctx.Err() == nil && !done.Load() && !s.perfect(incumbent) {
+		s.restore(incumbent.state)
+
+		// The incumbent's assignments beyond the prefills, in a stable order
+		var free []models.Assignment
+		for _, shiftID := range slices.Sorted(maps.Keys(incumbent.state.shiftAssigned)) {
+			for _, volID := range incumbent.state.shiftAssigned[shiftID] {
+				if !slices.Contains(original.shiftAssigned[shiftID], volID) {
+					free = append(free, models.Assignment{ShiftID: shiftID, VolunteerID: volID})
+				}
+			}
+		}
+		if len(free) == 0 {
+			break
+		}
+		rng := s.random()
+		rng.Shuffle(len(free), func(i, j int) { free[i], free[j] = free[j], free[i] })
+		for _, a := range free[:1+rng.Intn(max(1, len(free)/5))] {
+			shift := s.Shifts[a.ShiftID]
+			s.removeAssignment(s.Volunteers[a.VolunteerID], shift, s.DurationHours(shift.Start, shift.End))
+		}
+
+		// Conflicts are recorded afresh for whatever stays open
+		s.Conflicts = append([]models.ConflictReason(nil), original.conflicts...)
+		s.AssignSimpleWithGroups(true, volsByGroup)
+
+		if pass := s.scorePass(); !incumbent.better(pass) {
+			pass.state = s.snapshot()
+			incumbent = pass
+		}
+	}
+	if s.perfect(incumbent) {
+		done.Store(true)
+	}
+	return &incumbent
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import "slices"
+
+// FillIdeal adds volunteers beyond each group's minimum, up to the shift's
+// ideal headcount. It runs after every minimum has been attempted and hands
+// out extras one per shift per round, so no shift hoards them. Standby
+// volunteers are kept for minimums. Open ideal slots are not conflicts.
+func (s *Scheduler) FillIdeal() {
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		if len(shift.IdealGroups) > 0 {
+			shiftKeys = append(shiftKeys, id)
+		}
+	}
+	if len(shiftKeys) == 0 {
+		return
+	}
+	slices.Sort(shiftKeys)
+
+	volsByGroup := s.GroupByGroup()
+	for progress := true; progress; {
+		progress = false
+		for _, id := range shiftKeys {
+			shift := s.Shifts[id]
+			duration := s.DurationHours(shift.Start, shift.End)
+			filled := s.FilledByGroup(shift)
+			for _, group := range sortedGroups(shift) {
+				if filled[group] >= shift.IdealGroups[group] {
+					continue
+				}
+				// Standby volunteers rank last, so one is only picked when nobody else fits
+				if best, _ := s.pickCandidate(shift, duration, volsByGroup[group]); best != nil && best.Tier != TierStandby {
+					s.assignUnit(best, shift, duration)
+					progress = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// BelowIdeal lists shifts that meet every minimum but fall short of an ideal headcount
+func (s *Scheduler) BelowIdeal() []string {
+	var ids []string
+	for id, shift := range s.Shifts {
+		filled := s.FilledByGroup(shift)
+		understaffed, belowIdeal := false, false
+		for _, group := range sortedGroups(shift) {
+			if filled[group] < shift.RequiredGroups[group] {
+				understaffed = true
+			}
+			if filled[group] < shift.IdealGroups[group] {
+				belowIdeal = true
+			}
+		}
+		if belowIdeal && !understaffed {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ValidateLinks checks that every linked shift exists and isn't the shift itself
+func (s *Scheduler) ValidateLinks() error {
+	for id, shift := range s.Shifts {
+		for _, other := range shift.LinkedShifts {
+			if other == id {
+				return fmt.Errorf("shift %s is linked to itself", id)
+			}
+			if _, ok := s.Shifts[other]; !ok {
+				return fmt.Errorf("%w: %q linked from shift %s", ErrUnknownShift, other, id)
+			}
+		}
+	}
+	return nil
+}
+
+// linked returns the other shifts in a shift's linked unit. Links work both
+// ways and chain, so a unit is every shift reachable through them.
+func (s *Scheduler) linked(shiftID string) []string {
+	if s.links == nil {
+		s.links = s.linkUnits()
+	}
+	return s.links[shiftID]
+}
+
+// linkUnits maps each linked shift to the rest of its unit
+func (s *Scheduler) linkUnits() map[string][]string {
+	adjacent := make(map[string][]string)
+	for id, shift := range s.Shifts {
+		for _, other := range shift.LinkedShifts {
+			if _, ok := s.Shifts[other]; ok && other != id {
+				adjacent[id] = append(adjacent[id], other)
+				adjacent[other] = append(adjacent[other], id)
+			}
+		}
+	}
+
+	units := make(map[string][]string, len(adjacent))
+	for start := range adjacent {
+		if _, done := units[start]; done {
+			continue
+		}
+		unit := []string{start}
+		seen := map[string]bool{start: true}
+		for i := 0; i < len(unit); i++ {
+			for _, next := range adjacent[unit[i]] {
+				if !seen[next] {
+					seen[next] = true
+					unit = append(unit, next)
+				}
+			}
+		}
+		slices.Sort(unit)
+		for _, id := range unit {
+			units[id] = slices.DeleteFunc(slices.Clone(unit), func(other string) bool { return other == id })
+		}
+	}
+	return units
+}
+
+// missingLinks returns the shifts linked to shift that the volunteer isn't on yet
+func (s *Scheduler) missingLinks(vol *models.Volunteer, shift *models.Shift) []*models.Shift {
+	var missing []*models.Shift
+	for _, id := range s.linked(shift.ID) {
+		if !slices.Contains(vol.AssignedShifts, id) {
+			missing = append(missing, s.Shifts[id])
+		}
+	}
+	return missing
+}
+
+// linkedHours is how many hours the volunteer would also take on through
+// the shift's linked shifts
+func (s *Scheduler) linkedHours(vol *models.Volunteer, shift *models.Shift) float64 {
+	hours := 0.0
+	for _, sh := range s.missingLinks(vol, shift) {
+		hours += s.DurationHours(sh.Start, sh.End)
+	}
+	return hours
+}
+
+// linkedOverlap reports whether any of the shift's linked shifts would
+// double book the volunteer
+func (s *Scheduler) linkedOverlap(vol *models.Volunteer, shift *models.Shift) bool {
+	for _, sh := range s.missingLinks(vol, shift) {
+		if s.WouldOverlap(vol, sh) {
+			return true
+		}
+	}
+	return false
+}
+
+// assignUnit places a volunteer on a shift and on every shift linked to it
+func (s *Scheduler) assignUnit(vol *models.Volunteer, shift *models.Shift, duration float64) {
+	missing := s.missingLinks(vol, shift)
+	s.assign(vol, shift, duration)
+	for _, sh := range missing {
+		s.assign(vol, sh, s.DurationHours(sh.Start, sh.End))
+	}
+}
+
+// EnforceLinks makes sure everyone on a linked shift works the whole unit.
+// Volunteers missing part of it are added where hours and overlaps allow;
+// otherwise they are taken off the unit and a conflict is recorded. Locked
+// assignments are kept.
+func (s *Scheduler) EnforceLinks() {
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id := range s.Shifts {
+		if len(s.linked(id)) > 0 {
+			shiftKeys = append(shiftKeys, id)
+		}
+	}
+	slices.Sort(shiftKeys)
+
+	for _, id := range shiftKeys {
+		shift := s.Shifts[id]
+		for _, volID := range slices.Clone(shift.Assigned) {
+			vol, ok := s.Volunteers[volID]
+			if !ok || !slices.Contains(shift.Assigned, volID) {
+				continue
+			}
+			missing := s.missingLinks(vol, shift)
+			if len(missing) == 0 {
+				continue
+			}
+			fitsShifts := vol.MaxShifts <= 0 || len(vol.AssignedShifts)+len(missing) <= vol.MaxShifts
+			if vol.AssignedHours+s.linkedHours(vol, shift) <= hoursLimit(vol) && fitsShifts && !s.linkedOverlap(vol, shift) {
+				for _, sh := range missing {
+					s.assign(vol, sh, s.DurationHours(sh.Start, sh.End))
+				}
+				continue
+			}
+
+			unit := append([]string{id}, s.linked(id)...)
+			if slices.ContainsFunc(unit, func(shiftID string) bool { return s.IsLocked(shiftID, volID) }) {
+				continue
+			}
+			for _, shiftID := range unit {
+				if slices.Contains(vol.AssignedShifts, shiftID) {
+					sh := s.Shifts[shiftID]
+					s.removeAssignment(vol, sh, s.DurationHours(sh.Start, sh.End))
+				}
+			}
+			group := ""
+			if groups := VolunteerGroups(vol); len(groups) > 0 {
+				group = groups[0]
+			}
+			s.Conflicts = append(s.Conflicts, models.ConflictReason{
+				ShiftID: id,
+				Group:   group,
+				Reasons: []string{fmt.Sprintf("removed %s because they couldn't also work linked shifts", vol.ID)},
+				Cause:   "unlinked",
+			})
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// BelowMinimum reports whether a volunteer has fewer hours or shifts than their minimum
+func BelowMinimum(vol *models.Volunteer) bool {
+	return (vol.MinHours > 0 && vol.AssignedHours < vol.MinHours) ||
+		(vol.MinShifts > 0 && len(vol.AssignedShifts) < vol.MinShifts)
+}
+
+// canGiveUp reports whether removing a shift of the given duration would keep
+// a volunteer at or above their minimums
+func canGiveUp(vol *models.Volunteer, duration float64) bool {
+	if vol.MinHours > 0 && vol.AssignedHours-duration < vol.MinHours {
+		return false
+	}
+	return vol.MinShifts == 0 || len(vol.AssignedShifts)-1 >= vol.MinShifts
+}
+
+// FillMinimums is a post-pass that hands shifts to volunteers below their
+// minimums, taking them from volunteers who can spare them. Locked
+// assignments are never moved, and every swap re-checks all constraints.
+func (s *Scheduler) FillMinimums() {
+	var short []*models.Volunteer
+	for _, vol := range s.Volunteers {
+		if BelowMinimum(vol) {
+			short = append(short, vol)
+		}
+	}
+	if len(short) == 0 {
+		return
+	}
+	sort.Slice(short, func(i, j int) bool { return short[i].ID < short[j].ID })
+
+	shifts := make([]*models.Shift, 0, len(s.Shifts))
+	for _, shift := range s.Shifts {
+		shifts = append(shifts, shift)
+	}
+	sort.Slice(shifts, func(i, j int) bool {
+		if !shifts[i].Start.Equal(shifts[j].Start) {
+			return shifts[i].Start.Before(shifts[j].Start)
+		}
+		return shifts[i].ID < shifts[j].ID
+	})
+
+	for _, vol := range short {
+		for _, shift := range shifts {
+			if !BelowMinimum(vol) {
+				break
+			}
+			if slices.Contains(shift.Assigned, vol.ID) {
+				continue
+			}
+			s.takeShift(vol, shift)
+		}
+	}
+}
+
+// takeShift moves one of a shift's assignments over to vol, if a donor in a
+// shared group can spare it and vol passes every constraint once it is free
+func (s *Scheduler) takeShift(vol *models.Volunteer, shift *models.Shift) bool {
+	duration := s.DurationHours(shift.Start, shift.End)
+	for _, group := range sortedGroups(shift) {
+		if !InGroup(vol, group) {
+			continue
+		}
+		for _, donorID := range slices.Clone(shift.Assigned) {
+			donor, ok := s.Volunteers[donorID]
+			if !ok || s.IsLocked(shift.ID, donorID) || !InGroup(donor, group) || !canGiveUp(donor, duration) {
+				continue
+			}
+			s.removeAssignment(donor, shift, duration)
+			if s.CheckEligibility(vol, shift, duration).OK() {
+				s.assign(vol, shift, duration)
+				// Undo if the donor was someone's required partner
+				if len(s.unpaired(shift)) == 0 {
+					return true
+				}
+				s.removeAssignment(vol, shift, duration)
+			}
+			s.assign(donor, shift, duration)
+		}
+	}
+	return false
+}
+
+// Shortfalls lists volunteers still below their minimums, sorted by ID
+func (s *Scheduler) Shortfalls() []models.Shortfall {
+	var out []models.Shortfall
+	for _, vol := range s.Volunteers {
+		if BelowMinimum(vol) {
+			out = append(out, models.Shortfall{
+				VolunteerID:    vol.ID,
+				MinHours:       vol.MinHours,
+				AssignedHours:  vol.AssignedHours,
+				MinShifts:      vol.MinShifts,
+				AssignedShifts: len(vol.AssignedShifts),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VolunteerID < out[j].VolunteerID })
+	return out
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"errors"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Objectives choose who gets picked among otherwise equal candidates.
+// ObjectiveMaximizeParticipation, the default, spreads shifts over as many
+// volunteers as possible; ObjectiveMinimizeHeadcount gives them to as few.
+const (
+	ObjectiveMaximizeParticipation = "maximize_participation"
+	ObjectiveMinimizeHeadcount     = "minimize_headcount"
+)
+
+// ErrUnknownObjective is returned for an objective that doesn't exist
+var ErrUnknownObjective = errors.New("objective must be maximize_participation or minimize_headcount")
+
+// ValidateObjective checks an objective name. An empty name means maximize_participation.
+func ValidateObjective(objective string) error {
+	switch objective {
+	case "", ObjectiveMaximizeParticipation, ObjectiveMinimizeHeadcount:
+		return nil
+	}
+	return ErrUnknownObjective
+}
+
+// minimizeHeadcount reports whether the run should use as few volunteers as it can
+func (s *Scheduler) minimizeHeadcount() bool {
+	return s.Objective == ObjectiveMinimizeHeadcount
+}
+
+// Headcount counts the volunteers working at least one shift
+func (s *Scheduler) Headcount() int {
+	n := 0
+	for _, vol := range s.Volunteers {
+		if len(vol.AssignedShifts) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// idle reports whether a volunteer has no shifts yet, so picking them
+// would add to the headcount
+func idle(vol *models.Volunteer) bool {
+	return len(vol.AssignedShifts) == 0
+}
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// interval is the time an assigned shift takes up
+type interval struct {
+	start, end time.Time
+}
+
+// busyTimes holds a volunteer's assigned shift times sorted by start, along
+// with the latest end so far at each position. Any interval starting before a
+// query ends lies in a prefix found by binary search, and the query overlaps
+// one of them exactly when that prefix's latest end is after the query starts.
+type busyTimes struct {
+	intervals []interval
+	maxEnd    []time.Time
+}
+
+// overlaps reports whether any interval overlaps [start, end)
+func (b *busyTimes) overlaps(start, end time.Time) bool {
+	i, _ := slices.BinarySearchFunc(b.intervals, end, func(iv interval, t time.Time) int {
+		return iv.start.Compare(t)
+	})
+	return i > 0 && b.maxEnd[i-1].After(start)
+}
+
+// add inserts an interval in start order
+func (b *busyTimes) add(iv interval) {
+	i, _ := slices.BinarySearchFunc(b.intervals, iv.start, func(x interval, t time.Time) int {
+		return x.start.Compare(t)
+	})
+	b.intervals = slices.Insert(b.intervals, i, iv)
+	b.maxEnd = slices.Insert(b.maxEnd, i, time.Time{})
+	b.updateFrom(i)
+}
+
+// remove deletes one interval equal to iv, if present
+func (b *busyTimes) remove(iv interval) {
+	i, found := slices.BinarySearchFunc(b.intervals, iv.start, func(x interval, t time.Time) int {
+		return x.start.Compare(t)
+	})
+	if !found {
+		return
+	}
+	for ; i < len(b.intervals) && b.intervals[i].start.Equal(iv.start); i++ {
+		if b.intervals[i].end.Equal(iv.end) {
+			b.intervals = slices.Delete(b.intervals, i, i+1)
+			b.maxEnd = slices.Delete(b.maxEnd, i, i+1)
+			b.updateFrom(i)
+			return
+		}
+	}
+}
+
+// updateFrom recomputes the running latest end from position i on
+func (b *busyTimes) updateFrom(i int) {
+	for ; i < len(b.intervals); i++ {
+		b.maxEnd[i] = b.intervals[i].end
+		if i > 0 && b.maxEnd[i-1].After(b.maxEnd[i]) {
+			b.maxEnd[i] = b.maxEnd[i-1]
+		}
+	}
+}
+
+// busyTimesFor returns a volunteer's busy times, indexing their assigned
+// shifts on first use. assign and the removal helpers keep it current;
+// anything that replaces AssignedShifts wholesale must call forgetBusyTimes.
+func (s *Scheduler) busyTimesFor(volunteer *models.Volunteer) *busyTimes {
+	if b, ok := s.busy[volunteer.ID]; ok {
+		return b
+	}
+	b := &busyTimes{}
+	for _, shiftID := range volunteer.AssignedShifts {
+		sh, ok := s.Shifts[shiftID]
+		if !ok {
+			continue
+		}
+		b.intervals = append(b.intervals, interval{sh.Start, sh.End})
+	}
+	slices.SortFunc(b.intervals, func(x, y interval) int { return x.start.Compare(y.start) })
+	b.maxEnd = make([]time.Time, len(b.intervals))
+	b.updateFrom(0)
+	if s.busy == nil {
+		s.busy = make(map[string]*busyTimes)
+	}
+	s.busy[volunteer.ID] = b
+	return b
+}
+
+// trackAssign and trackUnassign update an indexed volunteer's busy times
+func (s *Scheduler) trackAssign(volunteer *models.Volunteer, shift *models.Shift) {
+	if b, ok := s.busy[volunteer.ID]; ok {
+		b.add(interval{shift.Start, shift.End})
+	}
+}
+
+func (s *Scheduler) trackUnassign(volunteer *models.Volunteer, shift *models.Shift) {
+	if b, ok := s.busy[volunteer.ID]; ok {
+		b.remove(interval{shift.Start, shift.End})
+	}
+}
+
+// forgetBusyTimes drops the index for one volunteer, or for everyone when
+// volunteer is nil
+func (s *Scheduler) forgetBusyTimes(volunteer *models.Volunteer) {
+	if volunteer == nil {
+		s.busy = nil
+		return
+	}
+	delete(s.busy, volunteer.ID)
+}
+
+// blockedTimesFor returns a volunteer's busy times. They never change during
+// a run, so everyone's are indexed together on first use and the index is
+// only read afterwards, even by clones.
+func (s *Scheduler) blockedTimesFor(volunteer *models.Volunteer) *busyTimes {
+	if s.blocked == nil {
+		s.blocked = make(map[string]*busyTimes, len(s.Volunteers))
+		for id, vol := range s.Volunteers {
+			b := &busyTimes{}
+			for _, w := range vol.BusyTimes {
+				b.intervals = append(b.intervals, interval{w.Start, w.End})
+			}
+			slices.SortFunc(b.intervals, func(x, y interval) int { return x.start.Compare(y.start) })
+			b.maxEnd = make([]time.Time, len(b.intervals))
+			b.updateFrom(0)
+			s.blocked[id] = b
+		}
+	}
+	if b, ok := s.blocked[volunteer.ID]; ok {
+		return b
+	}
+	return &busyTimes{}
+}
+
+// ValidateBusyTimes checks that every busy time ends after it starts
+func (s *Scheduler) ValidateBusyTimes() error {
+	for id, vol := range s.Volunteers {
+		for _, w := range vol.BusyTimes {
+			if !w.End.After(w.Start) {
+				return fmt.Errorf("volunteer %s has a busy time that doesn't end after it starts", id)
+			}
+		}
+	}
+	return nil
+}
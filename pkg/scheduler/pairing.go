@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// PairingOK checks a volunteer's pairing rules against who is already on a
+// shift: nobody they cannot work with (in either direction), and at least one
+// of their must_work_with partners if they have any
+func (s *Scheduler) PairingOK(volunteer *models.Volunteer, shift *models.Shift) bool {
+	hasPartner := len(volunteer.MustWorkWith) == 0
+	for _, id := range shift.Assigned {
+		if id == volunteer.ID {
+			continue
+		}
+		if slices.Contains(volunteer.CannotWorkWith, id) {
+			return false
+		}
+		if other, ok := s.Volunteers[id]; ok && slices.Contains(other.CannotWorkWith, volunteer.ID) {
+			return false
+		}
+		if slices.Contains(volunteer.MustWorkWith, id) {
+			hasPartner = true
+		}
+	}
+	return hasPartner
+}
+
+// unpaired returns the volunteers on a shift whose must_work_with partners are all absent
+func (s *Scheduler) unpaired(shift *models.Shift) []*models.Volunteer {
+	var out []*models.Volunteer
+	for _, id := range shift.Assigned {
+		vol, ok := s.Volunteers[id]
+		if !ok || len(vol.MustWorkWith) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(shift.Assigned, func(other string) bool {
+			return slices.Contains(vol.MustWorkWith, other)
+		}) {
+			out = append(out, vol)
+		}
+	}
+	return out
+}
+
+// EnforcePairs removes assignments left without a required partner, e.g.
+// after a partner was moved by a later pass, and records each as a conflict.
+// Locked assignments are kept.
+func (s *Scheduler) EnforcePairs() {
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id := range s.Shifts {
+		shiftKeys = append(shiftKeys, id)
+	}
+	slices.Sort(shiftKeys)
+
+	for _, id := range shiftKeys {
+		shift := s.Shifts[id]
+		duration := s.DurationHours(shift.Start, shift.End)
+		// Removing one volunteer can strand another, so repeat until stable
+		for removed := true; removed; {
+			removed = false
+			for _, vol := range s.unpaired(shift) {
+				if s.IsLocked(shift.ID, vol.ID) {
+					continue
+				}
+				s.removeAssignment(vol, shift, duration)
+				group := ""
+				if groups := VolunteerGroups(vol); len(groups) > 0 {
+					group = groups[0]
+				}
+				s.Conflicts = append(s.Conflicts, models.ConflictReason{
+					ShiftID: shift.ID,
+					Group:   group,
+					Reasons: []string{fmt.Sprintf("removed %s because none of their required partners were on the shift", vol.ID)},
+					Cause:   "missing_partner",
+				})
+				removed = true
+			}
+		}
+	}
+}
+
+// fillOrder returns a shift's groups in the order their slots should be
+// filled: supervisor groups first, then groups with fewer must_work_with
+// volunteers, so supervisors and partners are in place before they are needed
+func fillOrder(shift *models.Shift, volsByGroup map[string][]*models.Volunteer) []string {
+	groups := sortedGroups(shift)
+	rank := make(map[string]int, len(groups))
+	for _, g := range groups {
+		if isSupervisorGroup(shift, g) {
+			rank[g] = -1
+			continue
+		}
+		for _, vol := range volsByGroup[g] {
+			if len(vol.MustWorkWith) > 0 {
+				rank[g]++
+			}
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return rank[groups[i]] < rank[groups[j]]
+	})
+	return groups
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ratioCounts counts a shift's supervisors and supervised volunteers for one
+// rule. A volunteer in both groups counts as a supervisor.
+func (s *Scheduler) ratioCounts(shift *models.Shift, rule models.SupervisorRatio) (supervisors, supervised int) {
+	for _, id := range shift.Assigned {
+		vol, ok := s.Volunteers[id]
+		if !ok {
+			continue
+		}
+		if InGroup(vol, rule.Supervisor) {
+			supervisors++
+		} else if InGroup(vol, rule.Supervised) {
+			supervised++
+		}
+	}
+	return supervisors, supervised
+}
+
+// RatioOK checks that adding a volunteer to a shift keeps every supervisor
+// ratio. Only supervised volunteers can break a ratio by joining.
+func (s *Scheduler) RatioOK(volunteer *models.Volunteer, shift *models.Shift) bool {
+	for _, rule := range shift.SupervisorRatios {
+		if rule.Per <= 0 || InGroup(volunteer, rule.Supervisor) || !InGroup(volunteer, rule.Supervised) {
+			continue
+		}
+		supervisors, supervised := s.ratioCounts(shift, rule)
+		if supervised+1 > supervisors*rule.Per {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckRatios records a conflict for every shift that ends up breaking a
+// supervisor ratio, e.g. after a supervisor was moved by a later pass
+func (s *Scheduler) CheckRatios() {
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		if len(shift.SupervisorRatios) > 0 {
+			shiftKeys = append(shiftKeys, id)
+		}
+	}
+	slices.Sort(shiftKeys)
+
+	for _, id := range shiftKeys {
+		shift := s.Shifts[id]
+		for _, rule := range shift.SupervisorRatios {
+			if rule.Per <= 0 {
+				continue
+			}
+			supervisors, supervised := s.ratioCounts(shift, rule)
+			if supervised > supervisors*rule.Per {
+				s.Conflicts = append(s.Conflicts, models.ConflictReason{
+					ShiftID: id,
+					Group:   rule.Supervisor,
+					Reasons: []string{fmt.Sprintf("needs 1 %s per %d %s: has %d for %d", rule.Supervisor, rule.Per, rule.Supervised, supervisors, supervised)},
+					Cause:   "supervisor_shortage",
+				})
+			}
+		}
+	}
+}
+
+// isSupervisorGroup reports whether a group supervises another on the shift
+func isSupervisorGroup(shift *models.Shift, group string) bool {
+	return slices.ContainsFunc(shift.SupervisorRatios, func(r models.SupervisorRatio) bool {
+		return r.Supervisor == group
+	})
+}
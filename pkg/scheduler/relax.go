@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// maxRelaxations caps how many suggestions are returned for one slot
+const maxRelaxations = 3
+
+// relaxations suggests the smallest constraint changes that would let one of
+// the rejected candidates fill a slot, fewest changes first. Candidates that
+// would be double booked are skipped, since no setting allows that.
+func (s *Scheduler) relaxations(shift *models.Shift, duration float64, rejected []rejection) []models.Relaxation {
+	var out []models.Relaxation
+	for _, r := range rejected {
+		failed := r.Failed()
+		if slices.Contains(failed, "no_overlap") {
+			continue
+		}
+		changes := make([]string, 0, len(failed))
+		for _, name := range failed {
+			changes = append(changes, s.relaxation(name, r.volunteer, shift, duration))
+		}
+		out = append(out, models.Relaxation{VolunteerID: r.volunteer.ID, Changes: changes})
+	}
+	slices.SortStableFunc(out, func(a, b models.Relaxation) int {
+		return len(a.Changes) - len(b.Changes)
+	})
+	return out[:min(len(out), maxRelaxations)]
+}
+
+// relaxation describes the change to one failed constraint that would let the
+// volunteer take the shift
+func (s *Scheduler) relaxation(name string, vol *models.Volunteer, shift *models.Shift, duration float64) string {
+	switch name {
+	case "max_hours":
+		return raise("max_hours", vol.MaxHours, vol.AssignedHours+duration)
+	case "group_rules":
+		return fmt.Sprintf("allow group %s on shift %s", vol.Group, shift.ID)
+	case "availability":
+		return fmt.Sprintf("extend availability to cover shift %s", shift.ID)
+	case "min_rest_hours":
+		return fmt.Sprintf("lower min_rest_hours from %s to %s", hours(vol.MinRestHours), hours(math.Floor(s.restGap(vol, shift)*100)/100))
+	case "day_limits":
+		sameDay, run := s.dayLoad(vol, shift)
+		if vol.MaxShiftsPerDay > 0 && sameDay > vol.MaxShiftsPerDay {
+			return raise("max_shifts_per_day", float64(vol.MaxShiftsPerDay), float64(sameDay))
+		}
+		return raise("max_consecutive_days", float64(vol.MaxConsecutiveDays), float64(run))
+	case "max_hours_per_week":
+		return raise("max_hours_per_week", vol.MaxHoursPerWeek, s.peakWeeklyHours(vol, shift))
+	case "category_limits":
+		limit, _ := s.categoryLimit(vol, shift.Category)
+		return raise(shift.Category+" category limit", float64(limit), float64(s.assignedInCategory(vol, shift.Category)+1))
+	case "pairing":
+		return "relax must_work_with or cannot_work_with"
+	case "supervisor_ratios":
+		return fmt.Sprintf("add a supervisor to shift %s", shift.ID)
+	case "equipment":
+		return fmt.Sprintf("bring the equipment shift %s still needs", shift.ID)
+	case "max_shifts":
+		return fmt.Sprintf("raise max_shifts from %d to %d", vol.MaxShifts, len(vol.AssignedShifts)+1+len(s.missingLinks(vol, shift)))
+	case "certifications":
+		return fmt.Sprintf("certify %s for shift %s", vol.ID, shift.ID)
+	case "certification_expiry":
+		return fmt.Sprintf("renew %s's %s certification", vol.ID, strings.Join(s.expiredCertifications(vol, shift), ", "))
+	case "max_hours_ratio":
+		mean := s.HoursCap() / s.MaxHoursRatio
+		return raise("max_hours_ratio", s.MaxHoursRatio, (vol.AssignedHours+duration)/mean)
+	}
+	return "relax " + name
+}
+
+// restGap returns the shortest break, in hours, between a shift and the
+// volunteer's other shifts
+func (s *Scheduler) restGap(vol *models.Volunteer, shift *models.Shift) float64 {
+	gap := math.Inf(1)
+	for _, shiftID := range vol.AssignedShifts {
+		sh, ok := s.Shifts[shiftID]
+		if !ok {
+			continue
+		}
+		if sh.End.After(shift.Start) {
+			gap = min(gap, s.DurationHours(shift.End, sh.Start))
+		} else {
+			gap = min(gap, s.DurationHours(sh.End, shift.Start))
+		}
+	}
+	return gap
+}
+
+// raise describes raising a limit to at least the value needed, rounded up
+func raise(name string, from, to float64) string {
+	return fmt.Sprintf("raise %s from %s to %s", name, hours(from), hours(math.Ceil(to*100)/100))
+}
+
+// hours formats a number with at most two decimals
+func hours(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
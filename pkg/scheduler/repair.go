@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ErrUnknownVolunteer is returned by Repair for a volunteer that isn't in the schedule
+var ErrUnknownVolunteer = errors.New("unknown volunteer")
+
+// Repair patches a complete schedule after volunteers drop out or shifts are
+// cancelled. Cancelled shifts and dropped volunteers are taken out, then only
+// the slots the dropped volunteers leave behind are refilled; every other
+// assignment, and any slot that was already open, is left alone. It returns
+// the assignments removed and the replacements added.
+func (s *Scheduler) Repair(droppedVolunteers, cancelledShifts []string) (removed, added []models.Assignment, err error) {
+	for _, id := range cancelledShifts {
+		if _, ok := s.Shifts[id]; !ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrUnknownShift, id)
+		}
+	}
+	for _, id := range droppedVolunteers {
+		if _, ok := s.Volunteers[id]; !ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrUnknownVolunteer, id)
+		}
+	}
+
+	take := func(vol *models.Volunteer, shift *models.Shift) {
+		s.removeAssignment(vol, shift, s.DurationHours(shift.Start, shift.End))
+		a := models.Assignment{ShiftID: shift.ID, VolunteerID: vol.ID}
+		delete(s.Locked, a)
+		removed = append(removed, a)
+	}
+
+	for _, id := range cancelledShifts {
+		shift := s.Shifts[id]
+		for _, volID := range slices.Clone(shift.Assigned) {
+			if vol, ok := s.Volunteers[volID]; ok {
+				take(vol, shift)
+			}
+		}
+		delete(s.Shifts, id)
+	}
+	s.links = nil
+
+	// Refill only up to what each shift had before the dropouts
+	required := make(map[string]map[string]int, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		filled := s.FilledByGroup(shift)
+		required[id] = shift.RequiredGroups
+		capped := make(map[string]int, len(shift.RequiredGroups))
+		for g, count := range shift.RequiredGroups {
+			capped[g] = min(count, filled[g])
+		}
+		shift.RequiredGroups = capped
+	}
+
+	for _, volID := range droppedVolunteers {
+		vol := s.Volunteers[volID]
+		for _, shiftID := range slices.Clone(vol.AssignedShifts) {
+			if shift, ok := s.Shifts[shiftID]; ok {
+				take(vol, shift)
+			}
+		}
+		delete(s.Volunteers, volID)
+	}
+
+	kept := make(map[models.Assignment]bool)
+	for id, shift := range s.Shifts {
+		for _, volID := range shift.Assigned {
+			kept[models.Assignment{ShiftID: id, VolunteerID: volID}] = true
+		}
+	}
+
+	s.AssignSimple(false)
+
+	shiftIDs := make([]string, 0, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		shift.RequiredGroups = required[id]
+		shiftIDs = append(shiftIDs, id)
+	}
+	slices.Sort(shiftIDs)
+	for _, id := range shiftIDs {
+		for _, volID := range s.Shifts[id].Assigned {
+			if a := (models.Assignment{ShiftID: id, VolunteerID: volID}); !kept[a] {
+				added = append(added, a)
+			}
+		}
+	}
+	return removed, added, nil
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// inWindows checks if a shift falls fully inside one of the windows. An
+// empty list means no restriction.
+func inWindows(windows []models.TimeWindow, shift *models.Shift) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if !shift.Start.Before(w.Start) && !shift.End.After(w.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceFree checks that a resource is available for a shift and not
+// already booked for an overlapping one
+func (s *Scheduler) resourceFree(res *models.Resource, shift *models.Shift) bool {
+	if !inWindows(res.Availability, shift) {
+		return false
+	}
+	for shiftID, booked := range s.AssignedResources {
+		other, ok := s.Shifts[shiftID]
+		if ok && slices.Contains(booked, res.ID) && s.Overlap(other.Start, other.End, shift.Start, shift.End) {
+			return false
+		}
+	}
+	return true
+}
+
+// AssignResources books resources for every shift that requires them.
+// Shifts are handled in start order and each takes the free resources of
+// the right type with the fewest bookings, spreading wear across them.
+func (s *Scheduler) AssignResources() {
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		if len(shift.RequiredResources) > 0 {
+			shiftKeys = append(shiftKeys, id)
+		}
+	}
+	if len(shiftKeys) == 0 {
+		return
+	}
+	slices.SortFunc(shiftKeys, func(a, b string) int {
+		if c := s.Shifts[a].Start.Compare(s.Shifts[b].Start); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	resIDs := make([]string, 0, len(s.Resources))
+	for id := range s.Resources {
+		resIDs = append(resIDs, id)
+	}
+	slices.Sort(resIDs)
+
+	if s.AssignedResources == nil {
+		s.AssignedResources = make(map[string][]string)
+	}
+	bookings := make(map[string]int, len(s.Resources))
+	for _, booked := range s.AssignedResources {
+		for _, id := range booked {
+			bookings[id]++
+		}
+	}
+
+	for _, shiftID := range shiftKeys {
+		shift := s.Shifts[shiftID]
+		types := make([]string, 0, len(shift.RequiredResources))
+		for t := range shift.RequiredResources {
+			types = append(types, t)
+		}
+		slices.Sort(types)
+
+		var reasons []string
+		for _, t := range types {
+			var free []string
+			for _, id := range resIDs {
+				res := s.Resources[id]
+				if res.Type == t && !slices.Contains(s.AssignedResources[shiftID], id) && s.resourceFree(res, shift) {
+					free = append(free, id)
+				}
+			}
+			slices.SortStableFunc(free, func(a, b string) int { return bookings[a] - bookings[b] })
+
+			have := 0
+			for _, id := range s.AssignedResources[shiftID] {
+				if res, ok := s.Resources[id]; ok && res.Type == t {
+					have++
+				}
+			}
+			required := shift.RequiredResources[t]
+			take := min(max(required-have, 0), len(free))
+			for _, id := range free[:take] {
+				s.AssignedResources[shiftID] = append(s.AssignedResources[shiftID], id)
+				bookings[id]++
+			}
+			if have+take < required {
+				reasons = append(reasons, fmt.Sprintf("needs %d %s: only %d free", required, t, have+take))
+			}
+		}
+		if len(reasons) > 0 {
+			s.Conflicts = append(s.Conflicts, models.ConflictReason{ShiftID: shiftID, Reasons: reasons, Cause: "missing_resources"})
+		}
+	}
+}
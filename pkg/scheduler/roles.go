@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// RoleAssignments maps each shift with roles to the volunteers filling each
+// of its roles
+func (s *Scheduler) RoleAssignments() map[string]map[string][]string {
+	out := make(map[string]map[string][]string)
+	for id, shift := range s.Shifts {
+		if len(shift.Roles) == 0 {
+			continue
+		}
+		out[id] = s.shiftRoles(shift)
+	}
+	return out
+}
+
+// shiftRoles maps each of a shift's roles to the volunteers filling it.
+// Volunteers are placed by the group they fill, into that group's roles in
+// the order listed; any beyond the roles' counts, such as ideal extras, go
+// to the group's last role.
+func (s *Scheduler) shiftRoles(shift *models.Shift) map[string][]string {
+	roles := make(map[string][]string, len(shift.Roles))
+	for _, role := range shift.Roles {
+		roles[role.Name] = []string{}
+	}
+	s.attribute(shift, func(volID, group string) {
+		last := ""
+		for _, role := range shift.Roles {
+			if role.Group != group {
+				continue
+			}
+			if len(roles[role.Name]) < role.Count {
+				roles[role.Name] = append(roles[role.Name], volID)
+				return
+			}
+			last = role.Name
+		}
+		if last != "" {
+			roles[last] = append(roles[last], volID)
+		}
+	})
+	return roles
+}
+
+// ShiftAssignments lists each shift's volunteers with the requirement they
+// fill, ordered by requirement: roles in the order listed, otherwise groups
+// alphabetically, where the role is the group's name. Volunteers who fill no
+// requirement come last, with neither.
+func (s *Scheduler) ShiftAssignments() map[string][]models.ShiftAssignment {
+	out := make(map[string][]models.ShiftAssignment, len(s.Shifts))
+	for id, shift := range s.Shifts {
+		list := make([]models.ShiftAssignment, 0, len(shift.Assigned))
+		if len(shift.Roles) > 0 {
+			roles := s.shiftRoles(shift)
+			for _, role := range shift.Roles {
+				for _, volID := range roles[role.Name] {
+					list = append(list, models.ShiftAssignment{VolunteerID: volID, Group: role.Group, Role: role.Name})
+				}
+			}
+		} else {
+			s.attribute(shift, func(volID, group string) {
+				list = append(list, models.ShiftAssignment{VolunteerID: volID, Group: group, Role: group})
+			})
+			slices.SortStableFunc(list, func(a, b models.ShiftAssignment) int {
+				return strings.Compare(a.Group, b.Group)
+			})
+		}
+		for _, volID := range shift.Assigned {
+			if !slices.ContainsFunc(list, func(a models.ShiftAssignment) bool { return a.VolunteerID == volID }) {
+				list = append(list, models.ShiftAssignment{VolunteerID: volID})
+			}
+		}
+		out[id] = list
+	}
+	return out
+}
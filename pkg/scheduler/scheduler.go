@@ -1,9 +1,14 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/arnavshah/scheduler-api-go/pkg/models"
@@ -14,6 +19,67 @@ type Scheduler struct {
 	Volunteers map[string]*models.Volunteer
 	Shifts     map[string]*models.Shift
 	Conflicts  []models.ConflictReason
+	// PreferenceWeight lets a preferred shift win over a candidate with up to this many fewer hours
+	PreferenceWeight float64
+	// ConsecutiveWeight lets a shift adjoining one the volunteer already works
+	// at the same location that day win over a candidate with up to this many
+	// fewer hours
+	ConsecutiveWeight float64
+	// FairnessMetric selects the fairness score optimizers target; empty means stddev
+	FairnessMetric string
+	// Locked holds prefilled assignments that must never be removed, keyed without the Locked flag
+	Locked map[models.Assignment]bool
+	// CategoryLimits caps shifts per category for volunteers without their own limit
+	CategoryLimits map[string]int
+	// SoftConstraints maps constraint names to penalty weights. Soft
+	// constraints may be broken at that cost, counted in hours like PreferenceWeight.
+	SoftConstraints map[string]float64
+	// Resources are the non-human resources shifts can book, by ID
+	Resources map[string]*models.Resource
+	// AssignedResources maps shift IDs to the resources booked for them
+	AssignedResources map[string][]string
+	// MaxHoursRatio caps everyone's hours at this multiple of the mean; 0 means no cap
+	MaxHoursRatio float64
+	// FillWeight and FairnessWeight weigh fill rate against the fairness score
+	// in the optimal search; see Score
+	FillWeight     float64
+	FairnessWeight float64
+	// Alternatives is how many distinct schedules the optimal search keeps,
+	// best first; see UseAlternative
+	Alternatives int
+	// DuplicatePrefills counts prefilled assignments skipped as repeats
+	DuplicatePrefills int
+	// DetailedConflicts adds each rejected volunteer and the constraints they
+	// failed to slot conflicts
+	DetailedConflicts bool
+	// TravelMinutes is how long it takes to get between two locations; shifts
+	// closer together than that overlap
+	TravelMinutes map[string]map[string]int
+	// Hints are a suggested schedule the solvers start from, e.g. the last
+	// result for a slightly changed input. Unlike prefills they can be changed.
+	Hints []models.Assignment
+	// Objective is ObjectiveMaximizeParticipation (the default) or
+	// ObjectiveMinimizeHeadcount
+	Objective string
+	// Chunk is ChunkDay or ChunkWeek to solve one day or week at a time,
+	// carrying hours forward; empty solves everything at once
+	Chunk string
+	// Temperature randomizes greedy picks among near-equal candidates, for
+	// rotation over strict load balance; see pickWarm. Zero always takes
+	// the best.
+	Temperature float64
+
+	rng           *rand.Rand
+	hoursCap      float64
+	alternatives  []alternative
+	baseResources map[string][]string
+	busy          map[string]*busyTimes
+	blocked       map[string]*busyTimes
+	links         map[string][]string
+	travelMax     map[string]time.Duration
+	// pastCategories counts, per volunteer and category, the shifts a
+	// chunked run has left out of the current chunk
+	pastCategories map[string]map[string]int
 }
 
 // NewScheduler creates a new scheduler instance
@@ -24,18 +90,110 @@ func NewScheduler(volunteers map[string]*models.Volunteer, shifts map[string]*mo
 	}
 }
 
-// Prefill records existing assignments
+// Seed makes every random choice reproducible. Without a seed, the scheduler
+// seeds itself from the clock on first use.
+func (s *Scheduler) Seed(seed int64) {
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
+// random returns the scheduler's random source, creating it if needed
+func (s *Scheduler) random() *rand.Rand {
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return s.rng
+}
+
+// Prefill records existing assignments. Locked assignments are remembered so
+// modes that may move existing assignments never remove them. Repeats of an
+// assignment are skipped and counted in DuplicatePrefills.
 func (s *Scheduler) Prefill(assignments []models.Assignment) {
 	for _, asgn := range assignments {
 		vol, okVol := s.Volunteers[asgn.VolunteerID]
 		shift, okShift := s.Shifts[asgn.ShiftID]
 
 		if okVol && okShift {
-			shift.Assigned = append(shift.Assigned, vol.ID)
-			vol.AssignedShifts = append(vol.AssignedShifts, shift.ID)
-			vol.AssignedHours += s.DurationHours(shift.Start, shift.End)
+			if asgn.Locked {
+				if s.Locked == nil {
+					s.Locked = make(map[models.Assignment]bool)
+				}
+				s.Locked[models.Assignment{ShiftID: shift.ID, VolunteerID: vol.ID}] = true
+			}
+			if slices.Contains(shift.Assigned, vol.ID) {
+				s.DuplicatePrefills++
+				continue
+			}
+			s.assign(vol, shift, s.DurationHours(shift.Start, shift.End))
+		}
+	}
+}
+
+// assign places a volunteer on a shift and updates their totals
+func (s *Scheduler) assign(volunteer *models.Volunteer, shift *models.Shift, duration float64) {
+	shift.Assigned = append(shift.Assigned, volunteer.ID)
+	volunteer.AssignedHours += duration
+	volunteer.AssignedShifts = append(volunteer.AssignedShifts, shift.ID)
+	s.trackAssign(volunteer, shift)
+}
+
+// KeepPrevious re-applies assignments from a previous schedule wherever they
+// still fill an open slot and pass every constraint, so re-runs change as
+// little as possible. It returns the assignments that could not be kept.
+func (s *Scheduler) KeepPrevious(previous []models.Assignment) []models.Assignment {
+	var dropped []models.Assignment
+	for _, asgn := range previous {
+		vol, okVol := s.Volunteers[asgn.VolunteerID]
+		shift, okShift := s.Shifts[asgn.ShiftID]
+		if !okVol || !okShift {
+			dropped = append(dropped, asgn)
+			continue
 		}
+		if slices.Contains(shift.Assigned, vol.ID) {
+			continue // already prefilled
+		}
+
+		duration := s.DurationHours(shift.Start, shift.End)
+		if !s.fillsOpenSlot(vol, shift) || !s.CheckEligibility(vol, shift, duration).OK() {
+			dropped = append(dropped, asgn)
+			continue
+		}
+		s.assign(vol, shift, duration)
 	}
+	return dropped
+}
+
+// Churn compares the current assignments against a previous schedule
+func (s *Scheduler) Churn(previous []models.Assignment) models.ChurnReport {
+	report := models.ChurnReport{}
+	prev := make(map[models.Assignment]bool, len(previous))
+	for _, asgn := range previous {
+		key := models.Assignment{ShiftID: asgn.ShiftID, VolunteerID: asgn.VolunteerID}
+		if prev[key] {
+			continue
+		}
+		prev[key] = true
+		if shift, ok := s.Shifts[key.ShiftID]; ok && slices.Contains(shift.Assigned, key.VolunteerID) {
+			report.Kept++
+		} else {
+			report.Removed++
+		}
+	}
+	for id, shift := range s.Shifts {
+		for _, volID := range shift.Assigned {
+			if !prev[models.Assignment{ShiftID: id, VolunteerID: volID}] {
+				report.Added++
+			}
+		}
+	}
+	if len(prev) > 0 {
+		report.ChurnPercent = float64(report.Removed) / float64(len(prev)) * 100.0
+	}
+	return report
+}
+
+// IsLocked checks if a volunteer's assignment to a shift is pinned
+func (s *Scheduler) IsLocked(shiftID, volunteerID string) bool {
+	return s.Locked[models.Assignment{ShiftID: shiftID, VolunteerID: volunteerID}]
 }
 
 // DurationHours calculates the duration between two times in hours
@@ -48,32 +206,265 @@ func (s *Scheduler) Overlap(aStart, aEnd, bStart, bEnd time.Time) bool {
 	return aStart.Before(bEnd) && bStart.Before(aEnd)
 }
 
-// WouldOverlap checks if a volunteer's existing shifts overlap with a new one
+// WouldOverlap checks if a volunteer's existing shifts or busy times overlap
+// with a new shift, including the time it takes to travel between shift
+// locations
 func (s *Scheduler) WouldOverlap(volunteer *models.Volunteer, shift *models.Shift) bool {
+	if s.blockedTimesFor(volunteer).overlaps(shift.Start, shift.End) {
+		return true
+	}
+	// Padding by the longest trip rules most shifts out through the index
+	pad := s.maxTravel(shift.Location)
+	if !s.busyTimesFor(volunteer).overlaps(shift.Start.Add(-pad), shift.End.Add(pad)) {
+		return false
+	}
+	return pad == 0 || s.overlapsWithTravel(volunteer, shift)
+}
+
+// ViolatesRest checks if a new shift would start or end within the volunteer's
+// minimum rest gap of any shift they already hold
+func (s *Scheduler) ViolatesRest(volunteer *models.Volunteer, shift *models.Shift) bool {
+	if volunteer.MinRestHours <= 0 {
+		return false
+	}
+	// Padding the new shift by the gap is the same as padding every existing one
+	gap := time.Duration(volunteer.MinRestHours * float64(time.Hour))
+	return s.busyTimesFor(volunteer).overlaps(shift.Start.Add(-gap), shift.End.Add(gap))
+}
+
+// dayOf truncates a time to its calendar day in its own location
+func dayOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// ExceedsDayLimits checks if a new shift would break the volunteer's
+// max shifts per day or max consecutive working days
+func (s *Scheduler) ExceedsDayLimits(volunteer *models.Volunteer, shift *models.Shift) bool {
+	if volunteer.MaxShiftsPerDay <= 0 && volunteer.MaxConsecutiveDays <= 0 {
+		return false
+	}
+	sameDay, run := s.dayLoad(volunteer, shift)
+	return (volunteer.MaxShiftsPerDay > 0 && sameDay > volunteer.MaxShiftsPerDay) ||
+		(volunteer.MaxConsecutiveDays > 0 && run > volunteer.MaxConsecutiveDays)
+}
+
+// dayLoad returns how many shifts the volunteer would work on the new shift's
+// day, and the run of consecutive working days it would fall in
+func (s *Scheduler) dayLoad(volunteer *models.Volunteer, shift *models.Shift) (sameDay, run int) {
+	newDay := dayOf(shift.Start)
+	workedDays := make(map[time.Time]int)
+	for _, shiftID := range volunteer.AssignedShifts {
+		if sh, ok := s.Shifts[shiftID]; ok {
+			workedDays[dayOf(sh.Start.In(shift.Start.Location()))]++
+		}
+	}
+
+	run = 1
+	for d := newDay.AddDate(0, 0, -1); workedDays[d] > 0; d = d.AddDate(0, 0, -1) {
+		run++
+	}
+	for d := newDay.AddDate(0, 0, 1); workedDays[d] > 0; d = d.AddDate(0, 0, 1) {
+		run++
+	}
+	return workedDays[newDay] + 1, run
+}
+
+// ExceedsCategoryLimit checks if a new shift would put the volunteer over
+// their limit for the shift's category
+func (s *Scheduler) ExceedsCategoryLimit(volunteer *models.Volunteer, shift *models.Shift) bool {
+	limit, ok := s.categoryLimit(volunteer, shift.Category)
+	return ok && s.assignedInCategory(volunteer, shift.Category)+1 > limit
+}
+
+// categoryLimit returns the volunteer's limit for a category, falling back to
+// the schedule-wide one. ok is false when the category is unlimited.
+func (s *Scheduler) categoryLimit(volunteer *models.Volunteer, category string) (limit int, ok bool) {
+	if category == "" {
+		return 0, false
+	}
+	if limit, ok = volunteer.CategoryLimits[category]; !ok {
+		limit, ok = s.CategoryLimits[category]
+	}
+	return limit, ok
+}
+
+// assignedInCategory counts the volunteer's shifts of a category in this schedule
+func (s *Scheduler) assignedInCategory(volunteer *models.Volunteer, category string) int {
+	count := s.pastCategories[volunteer.ID][category]
+	for _, shiftID := range volunteer.AssignedShifts {
+		if sh, ok := s.Shifts[shiftID]; ok && sh.Category == category {
+			count++
+		}
+	}
+	return count
+}
+
+// RotationCount is how many shifts of a category the volunteer has worked,
+// counting their history from earlier schedules. Volunteers with the lowest
+// count are picked first for shifts of that category.
+func (s *Scheduler) RotationCount(volunteer *models.Volunteer, category string) int {
+	if category == "" {
+		return 0
+	}
+	return volunteer.CategoryHistory[category] + s.assignedInCategory(volunteer, category)
+}
+
+// hoursLimit is the most hours a volunteer may work. Volunteers capped by
+// shift count alone have no hour limit.
+func hoursLimit(volunteer *models.Volunteer) float64 {
+	if volunteer.MaxHours == 0 && volunteer.MaxShifts > 0 {
+		return math.Inf(1)
+	}
+	return volunteer.MaxHours
+}
+
+// ExceedsShiftLimit checks if a new shift, along with any shifts linked to
+// it, would take the volunteer past max_shifts
+func (s *Scheduler) ExceedsShiftLimit(volunteer *models.Volunteer, shift *models.Shift) bool {
+	if volunteer.MaxShifts <= 0 {
+		return false
+	}
+	return len(volunteer.AssignedShifts)+1+len(s.missingLinks(volunteer, shift)) > volunteer.MaxShifts
+}
+
+// ExceedsWeeklyHours checks if a new shift would put more than the
+// volunteer's max_hours_per_week into any rolling 7-day window
+func (s *Scheduler) ExceedsWeeklyHours(volunteer *models.Volunteer, shift *models.Shift) bool {
+	return volunteer.MaxHoursPerWeek > 0 && s.peakWeeklyHours(volunteer, shift) > volunteer.MaxHoursPerWeek
+}
+
+// peakWeeklyHours returns the most hours the volunteer would work in any
+// rolling 7-day window touching a new shift
+func (s *Scheduler) peakWeeklyHours(volunteer *models.Volunteer, shift *models.Shift) float64 {
+	const week = 7 * 24 * time.Hour
+	worked := []*models.Shift{shift}
 	for _, shiftID := range volunteer.AssignedShifts {
-		existingShift := s.Shifts[shiftID]
-		if s.Overlap(existingShift.Start, existingShift.End, shift.Start, shift.End) {
+		if sh, ok := s.Shifts[shiftID]; ok && s.Overlap(sh.Start, sh.End, shift.Start.Add(-week), shift.End.Add(week)) {
+			worked = append(worked, sh)
+		}
+	}
+
+	// The busiest window touching the new shift starts at some shift's start
+	// or ends at some shift's end, so only those windows need checking
+	peak := 0.0
+	for _, anchor := range worked {
+		for _, winStart := range []time.Time{anchor.Start, anchor.End.Add(-week)} {
+			winEnd := winStart.Add(week)
+			if !s.Overlap(winStart, winEnd, shift.Start, shift.End) {
+				continue
+			}
+			hours := 0.0
+			for _, sh := range worked {
+				start, end := sh.Start, sh.End
+				if start.Before(winStart) {
+					start = winStart
+				}
+				if end.After(winEnd) {
+					end = winEnd
+				}
+				if end.After(start) {
+					hours += s.DurationHours(start, end)
+				}
+			}
+			peak = max(peak, hours)
+		}
+	}
+	return peak
+}
+
+// IsAvailable checks if a shift falls fully inside one of the volunteer's
+// availability windows. Volunteers without windows are always available.
+func (s *Scheduler) IsAvailable(volunteer *models.Volunteer, shift *models.Shift) bool {
+	return inWindows(volunteer.Availability, shift)
+}
+
+// minutesOfDay parses an "HH:MM" string into minutes since midnight
+func minutesOfDay(hhmm string) (int, bool) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// Prefers checks if a shift matches one of the volunteer's preferred shift IDs,
+// or starts inside one of their preferred time-of-day ranges
+func (s *Scheduler) Prefers(volunteer *models.Volunteer, shift *models.Shift) bool {
+	for _, id := range volunteer.PreferredShifts {
+		if id == shift.ID {
+			return true
+		}
+	}
+	startMin := shift.Start.Hour()*60 + shift.Start.Minute()
+	for _, r := range volunteer.PreferredTimes {
+		from, okFrom := minutesOfDay(r.Start)
+		to, okTo := minutesOfDay(r.End)
+		if !okFrom || !okTo {
+			continue
+		}
+		if from <= to {
+			if startMin >= from && startMin < to {
+				return true
+			}
+		} else if startMin >= from || startMin < to {
 			return true
 		}
 	}
 	return false
 }
 
+// VolunteerGroups returns every group a volunteer can fill, without duplicates
+func VolunteerGroups(volunteer *models.Volunteer) []string {
+	groups := make([]string, 0, len(volunteer.Groups)+1)
+	groups = append(groups, volunteer.Group)
+	for _, g := range volunteer.Groups {
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// ApplyGroupHierarchy adds to each volunteer's groups every group their own
+// groups can stand in for, following the hierarchy transitively
+func (s *Scheduler) ApplyGroupHierarchy(hierarchy map[string][]string) {
+	if len(hierarchy) == 0 {
+		return
+	}
+	for _, vol := range s.Volunteers {
+		groups := VolunteerGroups(vol)
+		for i := 0; i < len(groups); i++ {
+			for _, covered := range hierarchy[groups[i]] {
+				if !slices.Contains(groups, covered) {
+					groups = append(groups, covered)
+				}
+			}
+		}
+		vol.Groups = groups[1:]
+	}
+}
+
+// InGroup checks if a volunteer belongs to a group
+func InGroup(volunteer *models.Volunteer, group string) bool {
+	return volunteer.Group == group || slices.Contains(volunteer.Groups, group)
+}
+
 // Allows checks if a volunteer is allowed to work a shift
 func (s *Scheduler) Allows(shift *models.Shift, volunteer *models.Volunteer) bool {
-	// Excluded groups
+	// Excluded groups: any matching group excludes the volunteer
 	if len(shift.ExcludedGroups) > 0 {
 		for _, g := range shift.ExcludedGroups {
-			if volunteer.Group == g {
+			if InGroup(volunteer, g) {
 				return false
 			}
 		}
 	}
-	// Allowed groups
+	// Allowed groups: at least one of the volunteer's groups must be allowed
 	if len(shift.AllowedGroups) > 0 {
 		found := false
 		for _, g := range shift.AllowedGroups {
-			if volunteer.Group == g {
+			if InGroup(volunteer, g) {
 				found = true
 				break
 			}
@@ -85,15 +476,234 @@ func (s *Scheduler) Allows(shift *models.Shift, volunteer *models.Volunteer) boo
 	return true
 }
 
-// GroupByGroup returns volunteers grouped by their group name
+// GroupByGroup returns volunteers grouped by their group name. Multi-skill
+// volunteers appear under each of their groups.
 func (s *Scheduler) GroupByGroup() map[string][]*models.Volunteer {
 	volsByGroup := make(map[string][]*models.Volunteer)
 	for _, vol := range s.Volunteers {
-		volsByGroup[vol.Group] = append(volsByGroup[vol.Group], vol)
+		for _, g := range VolunteerGroups(vol) {
+			volsByGroup[g] = append(volsByGroup[g], vol)
+		}
+	}
+	// Sort so candidate order (and therefore tie-breaking) doesn't depend on map iteration
+	for _, vols := range volsByGroup {
+		slices.SortFunc(vols, func(a, b *models.Volunteer) int { return strings.Compare(a.ID, b.ID) })
 	}
 	return volsByGroup
 }
 
+// sortedGroups returns a shift's required group names in a stable order
+func sortedGroups(shift *models.Shift) []string {
+	groups := make([]string, 0, len(shift.RequiredGroups))
+	for g := range shift.RequiredGroups {
+		groups = append(groups, g)
+	}
+	slices.Sort(groups)
+	return groups
+}
+
+// FilledByGroup counts how many of a shift's assigned volunteers fill each
+// required group. Each volunteer counts toward exactly one group, so
+// multi-skill volunteers are never double counted.
+func (s *Scheduler) FilledByGroup(shift *models.Shift) map[string]int {
+	return s.attribute(shift, nil)
+}
+
+// attribute decides which required group each of a shift's assigned
+// volunteers fills, calling each (when set) for every volunteer counted, and
+// returns the counts per group. Multi-skill volunteers are moved between
+// their groups where that lets more slots be filled, so a lead assigned
+// first doesn't take a helper slot someone else could have filled.
+func (s *Scheduler) attribute(shift *models.Shift, each func(volID, group string)) map[string]int {
+	ids := make([]string, 0, len(shift.Assigned))
+	groups := make([][]string, 0, len(shift.Assigned))
+	for _, volID := range shift.Assigned {
+		vol, ok := s.Volunteers[volID]
+		if !ok {
+			continue
+		}
+		var required []string
+		for _, g := range VolunteerGroups(vol) {
+			if _, ok := shift.RequiredGroups[g]; ok {
+				required = append(required, g)
+			}
+		}
+		if len(required) > 0 {
+			ids = append(ids, volID)
+			groups = append(groups, required)
+		}
+	}
+
+	match := make([]string, len(ids))
+	holders := make(map[string][]int)
+	place := func(i int, g string) {
+		match[i] = g
+		holders[g] = append(holders[g], i)
+	}
+	// augment finds volunteer i an open slot, moving others along if needed
+	var augment func(i int, seen map[string]bool) bool
+	augment = func(i int, seen map[string]bool) bool {
+		for _, g := range groups[i] {
+			if seen[g] {
+				continue
+			}
+			seen[g] = true
+			if len(holders[g]) < shift.RequiredGroups[g] {
+				place(i, g)
+				return true
+			}
+			for k, j := range holders[g] {
+				if augment(j, seen) {
+					holders[g][k] = i
+					match[i] = g
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for i := range ids {
+		// Prefer a group that still has open slots, falling back to any required group
+		open := ""
+		for _, g := range groups[i] {
+			if len(holders[g]) < shift.RequiredGroups[g] {
+				open = g
+				break
+			}
+		}
+		if open != "" {
+			place(i, open)
+		} else if !augment(i, make(map[string]bool)) {
+			match[i] = groups[i][0]
+		}
+	}
+
+	filled := make(map[string]int)
+	for i, volID := range ids {
+		filled[match[i]]++
+		if each != nil {
+			each(volID, match[i])
+		}
+	}
+	return filled
+}
+
+// HoursFromTarget returns how far a volunteer's hours are above their target
+// (or minimum, if higher), negative when under it. Without either this is just
+// their assigned hours.
+func HoursFromTarget(vol *models.Volunteer) float64 {
+	return vol.AssignedHours - max(vol.TargetHours, vol.MinHours)
+}
+
+// Eligibility records which hard constraints a volunteer passes for a shift
+type Eligibility struct {
+	FitsHours       bool
+	NoOverlap       bool
+	IsAllowed       bool
+	IsAvailable     bool
+	RestOK          bool
+	WithinDayLimits bool
+	WithinWeekCap   bool
+	WithinCategory  bool
+	PairingOK       bool
+	RatioOK         bool
+	EquipmentOK     bool
+	WithinHoursCap  bool
+	// HasCertifications fails for missing certifications, CertificationsCurrent
+	// for expired ones
+	HasCertifications     bool
+	CertificationsCurrent bool
+	WithinShiftLimit      bool
+	// Soft lists soft constraints that would be broken, costing Penalty in total
+	Soft    []string
+	Penalty float64
+}
+
+// OK reports whether every constraint passed
+func (e Eligibility) OK() bool {
+	return e.FitsHours && e.NoOverlap && e.IsAllowed && e.IsAvailable && e.RestOK && e.WithinDayLimits && e.WithinWeekCap && e.WithinCategory && e.PairingOK && e.RatioOK && e.EquipmentOK && e.WithinHoursCap && e.HasCertifications && e.CertificationsCurrent && e.WithinShiftLimit
+}
+
+// constraintResult is one constraint's outcome in an Eligibility
+type constraintResult struct {
+	ok   bool
+	name string
+}
+
+// results lists every constraint's outcome in a fixed order
+func (e Eligibility) results() []constraintResult {
+	return []constraintResult{
+		{e.FitsHours, "max_hours"},
+		{e.NoOverlap, "no_overlap"},
+		{e.IsAllowed, "group_rules"},
+		{e.IsAvailable, "availability"},
+		{e.RestOK, "min_rest_hours"},
+		{e.WithinDayLimits, "day_limits"},
+		{e.WithinWeekCap, "max_hours_per_week"},
+		{e.WithinCategory, "category_limits"},
+		{e.PairingOK, "pairing"},
+		{e.RatioOK, "supervisor_ratios"},
+		{e.EquipmentOK, "equipment"},
+		{e.WithinHoursCap, "max_hours_ratio"},
+		{e.HasCertifications, "certifications"},
+		{e.CertificationsCurrent, "certification_expiry"},
+		{e.WithinShiftLimit, "max_shifts"},
+	}
+}
+
+// Failed names the constraints that did not pass
+func (e Eligibility) Failed() []string {
+	var failed []string
+	for _, c := range e.results() {
+		if !c.ok {
+			failed = append(failed, c.name)
+		}
+	}
+	return failed
+}
+
+// Checks maps every constraint name to whether it passed
+func (e Eligibility) Checks() map[string]bool {
+	checks := make(map[string]bool)
+	for _, c := range e.results() {
+		checks[c.name] = c.ok
+	}
+	return checks
+}
+
+// CheckEligibility evaluates every hard constraint for placing a volunteer on
+// a shift. Soft constraints pass but add to the penalty.
+func (s *Scheduler) CheckEligibility(volunteer *models.Volunteer, shift *models.Shift, duration float64) Eligibility {
+	e := s.checkConstraints(volunteer, shift, duration)
+	s.soften(&e, volunteer, shift)
+	return e
+}
+
+// checkConstraints evaluates every constraint as if it were hard
+func (s *Scheduler) checkConstraints(volunteer *models.Volunteer, shift *models.Shift, duration float64) Eligibility {
+	e := Eligibility{
+		FitsHours:       volunteer.AssignedHours+duration+s.linkedHours(volunteer, shift) <= hoursLimit(volunteer),
+		NoOverlap:       !s.WouldOverlap(volunteer, shift) && !s.linkedOverlap(volunteer, shift),
+		IsAllowed:       s.Allows(shift, volunteer),
+		IsAvailable:     s.IsAvailable(volunteer, shift),
+		WithinDayLimits: !s.ExceedsDayLimits(volunteer, shift),
+		WithinWeekCap:   !s.ExceedsWeeklyHours(volunteer, shift),
+		WithinCategory:  !s.ExceedsCategoryLimit(volunteer, shift),
+		PairingOK:       s.PairingOK(volunteer, shift),
+		RatioOK:         s.RatioOK(volunteer, shift),
+		EquipmentOK:     s.EquipmentOK(volunteer, shift),
+		WithinHoursCap:  !s.ExceedsHoursCap(volunteer, duration),
+
+		HasCertifications:     s.HasCertifications(volunteer, shift),
+		CertificationsCurrent: s.CertificationsCurrent(volunteer, shift),
+		WithinShiftLimit:      !s.ExceedsShiftLimit(volunteer, shift),
+	}
+	// Only check rest gaps when there's no outright overlap, so reasons don't double count
+	e.RestOK = !e.NoOverlap || !s.ViolatesRest(volunteer, shift)
+	return e
+}
+
 // AssignSimple implements a greedy randomized assignment logic
 func (s *Scheduler) AssignSimple(shuffle bool) {
 	s.AssignSimpleWithGroups(shuffle, s.GroupByGroup())
@@ -114,13 +724,13 @@ func (s *Scheduler) AssignSimpleWithGroups(shuffle bool, volsByGroup map[string]
 	for k := range s.Shifts {
 		shiftKeys = append(shiftKeys, k)
 	}
+	slices.Sort(shiftKeys)
 
 	// To prioritize filling "as many slots as possible completely",
 	// we should shuffle the SHIFTS, but keep the slots for each shift contiguous.
 	// This way, we try to fully staff Shift A before moving to Shift B.
 	if shuffle && len(shiftKeys) > 0 {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		r.Shuffle(len(shiftKeys), func(i, j int) {
+		s.random().Shuffle(len(shiftKeys), func(i, j int) {
 			shiftKeys[i], shiftKeys[j] = shiftKeys[j], shiftKeys[i]
 		})
 	}
@@ -130,15 +740,10 @@ func (s *Scheduler) AssignSimpleWithGroups(shuffle bool, volsByGroup map[string]
 		shift := s.Shifts[shiftID]
 		shiftDurations[shiftID] = s.DurationHours(shift.Start, shift.End)
 
-		for group, count := range shift.RequiredGroups {
+		filled := s.FilledByGroup(shift)
+		for _, group := range fillOrder(shift, volsByGroup) {
 			// Find how many of this group are already assigned
-			countAlready := 0
-			for _, volID := range shift.Assigned {
-				if vol, ok := s.Volunteers[volID]; ok && vol.Group == group {
-					countAlready++
-				}
-			}
-			needed := count - countAlready
+			needed := shift.RequiredGroups[group] - filled[group]
 			if needed > 0 {
 				for i := 0; i < needed; i++ {
 					slots = append(slots, slot{shiftID, group})
@@ -150,70 +755,410 @@ func (s *Scheduler) AssignSimpleWithGroups(shuffle bool, volsByGroup map[string]
 	// NOTE: We do NOT shuffle the final slots array here,
 	// because we want to preserve the per-shift grouping from the loop above.
 
+	screened := make(map[slot]bitset)
 	for _, sl := range slots {
+		if _, ok := screened[sl]; !ok {
+			screened[sl] = s.screen(s.Shifts[sl.shiftID], volsByGroup[sl.group])
+		}
+	}
+
+	var candidates []*models.Volunteer
+	for i, sl := range slots {
 		shift := s.Shifts[sl.shiftID]
 		duration := shiftDurations[sl.shiftID]
 
-		var best *models.Volunteer
-		minHours := -1.0
-		var reasons []string
+		// A shift's slots for a group are adjacent, so they share a candidate list
+		if i == 0 || sl != slots[i-1] {
+			candidates = screened[sl].members(volsByGroup[sl.group], candidates[:0])
+		}
+		// Linked shifts can be filled early by whoever took their partner
+		if len(s.linked(sl.shiftID)) > 0 && s.FilledByGroup(shift)[sl.group] >= shift.RequiredGroups[sl.group] {
+			continue
+		}
+		best, _ := s.pickCandidate(shift, duration, candidates)
+		if best != nil {
+			s.assignUnit(best, shift, duration)
+			continue
+		}
+		// Check everyone again to explain the conflict, including those screened out
+		_, rejected := s.pickCandidate(shift, duration, volsByGroup[sl.group])
 
+		// Record conflict, counting why candidates were rejected
+		var reasons []string
 		maxHoursCount := 0
 		overlapCount := 0
 		disallowedCount := 0
-
-		// Use the pre-calculated volsByGroup for high performance
-		for _, vol := range volsByGroup[sl.group] {
-			// Check constraints and track why they fail
-			fitsHours := vol.AssignedHours+duration <= vol.MaxHours
-			noOverlap := !s.WouldOverlap(vol, shift)
-			isAllowed := s.Allows(shift, vol)
-
-			if fitsHours && noOverlap && isAllowed {
-				if best == nil || vol.AssignedHours < minHours {
-					best = vol
-					minHours = vol.AssignedHours
-				}
-			} else {
-				if !fitsHours {
-					maxHoursCount++
-				}
-				if !noOverlap {
-					overlapCount++
-				}
-				if !isAllowed {
-					disallowedCount++
-				}
+		unavailableCount := 0
+		restCount := 0
+		dayLimitCount := 0
+		weekCapCount := 0
+		categoryCount := 0
+		pairingCount := 0
+		ratioCount := 0
+		equipmentCount := 0
+		hoursCapCount := 0
+		uncertifiedCount := 0
+		expiredCount := 0
+		shiftLimitCount := 0
+		for _, e := range rejected {
+			if !e.FitsHours {
+				maxHoursCount++
 			}
-		}
-
-		if best != nil {
-			shift.Assigned = append(shift.Assigned, best.ID)
-			best.AssignedHours += duration
-			best.AssignedShifts = append(best.AssignedShifts, shift.ID)
-		} else {
-			// Record conflict
-			if maxHoursCount > 0 {
-				reasons = append(reasons, fmt.Sprintf("%d volunteers were at max hours", maxHoursCount))
+			if !e.NoOverlap {
+				overlapCount++
+			}
+			if !e.IsAllowed {
+				disallowedCount++
+			}
+			if !e.IsAvailable {
+				unavailableCount++
+			}
+			if !e.RestOK {
+				restCount++
+			}
+			if !e.WithinDayLimits {
+				dayLimitCount++
+			}
+			if !e.WithinWeekCap {
+				weekCapCount++
+			}
+			if !e.WithinCategory {
+				categoryCount++
 			}
-			if overlapCount > 0 {
-				// Changed message per user request
-				reasons = append(reasons, fmt.Sprintf("Prevented double booking for %d volunteers", overlapCount))
+			if !e.PairingOK {
+				pairingCount++
 			}
-			if disallowedCount > 0 {
-				reasons = append(reasons, fmt.Sprintf("%d volunteers were disallowed by group rules", disallowedCount))
+			if !e.RatioOK {
+				ratioCount++
 			}
-			if len(reasons) == 0 {
-				reasons = append(reasons, "no volunteers found in this group")
+			if !e.EquipmentOK {
+				equipmentCount++
 			}
+			if !e.WithinHoursCap {
+				hoursCapCount++
+			}
+			if !e.HasCertifications {
+				uncertifiedCount++
+			}
+			if !e.CertificationsCurrent {
+				expiredCount++
+			}
+			if !e.WithinShiftLimit {
+				shiftLimitCount++
+			}
+		}
 
-			s.Conflicts = append(s.Conflicts, models.ConflictReason{
-				ShiftID: sl.shiftID,
-				Group:   sl.group,
-				Reasons: reasons,
-			})
+		if maxHoursCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were at max hours", maxHoursCount))
+		}
+		if overlapCount > 0 {
+			// Changed message per user request
+			reasons = append(reasons, fmt.Sprintf("Prevented double booking for %d volunteers", overlapCount))
+		}
+		if disallowedCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were disallowed by group rules", disallowedCount))
+		}
+		if restCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers needed more rest between shifts", restCount))
+		}
+		if dayLimitCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were at their daily or consecutive-day limits", dayLimitCount))
+		}
+		if weekCapCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were at their weekly hour cap", weekCapCount))
+		}
+		if categoryCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were at their limit for %s shifts", categoryCount, shift.Category))
 		}
+		if pairingCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were ruled out by pairing rules", pairingCount))
+		}
+		if ratioCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers would have exceeded the supervisor ratio", ratioCount))
+		}
+		if equipmentCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers lacked equipment the shift still needs", equipmentCount))
+		}
+		if hoursCapCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers would have gone over %.1f hours, the fairness cap", hoursCapCount, s.HoursCap()))
+		}
+		if shiftLimitCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were at their max shifts", shiftLimitCount))
+		}
+		if uncertifiedCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers lacked a required certification", uncertifiedCount))
+		}
+		if expiredCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers had a required certification expire before the shift", expiredCount))
+		}
+		if unavailableCount > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d volunteers were outside their availability windows", unavailableCount))
+		}
+		if len(reasons) == 0 {
+			reasons = append(reasons, "no volunteers found in this group")
+		}
+
+		conflict := models.ConflictReason{
+			ShiftID:     sl.shiftID,
+			Group:       sl.group,
+			Reasons:     reasons,
+			Cause:       mainCause(rejected),
+			Relaxations: s.relaxations(shift, duration, rejected),
+		}
+		if s.DetailedConflicts {
+			for _, r := range rejected {
+				conflict.Details = append(conflict.Details, models.VolunteerRejection{VolunteerID: r.volunteer.ID, Failed: r.Failed()})
+			}
+		}
+		s.Conflicts = append(s.Conflicts, conflict)
+	}
+}
+
+// rejection is a candidate's eligibility for a slot they failed
+type rejection struct {
+	Eligibility
+	volunteer *models.Volunteer
+}
+
+// pickCandidate chooses the best eligible volunteer for a slot on a shift,
+// or with a Temperature set draws one weighted toward the best. It also
+// returns every rejected candidate so callers can explain an empty result.
+func (s *Scheduler) pickCandidate(shift *models.Shift, duration float64, candidates []*models.Volunteer) (*models.Volunteer, []rejection) {
+	var best *models.Volunteer
+	var bestRank Rank
+	var rejected []rejection
+	var eligible []rankedCandidate
+
+	for _, vol := range candidates {
+		// A multi-skill volunteer can only fill one slot per shift
+		if slices.Contains(shift.Assigned, vol.ID) {
+			continue
+		}
+
+		e := s.CheckEligibility(vol, shift, duration)
+		if !e.OK() {
+			rejected = append(rejected, rejection{e, vol})
+			continue
+		}
+
+		rank := s.RankCandidate(vol, shift, e)
+		if s.Temperature > 0 {
+			eligible = append(eligible, rankedCandidate{vol, rank})
+		}
+		if best == nil || rank.Before(bestRank) {
+			best = vol
+			bestRank = rank
+		}
+	}
+	if len(eligible) > 1 {
+		best = s.pickWarm(eligible, bestRank)
+	}
+	return best, rejected
+}
+
+// Rank orders eligible candidates for a slot
+type Rank struct {
+	Tier       int
+	Idle       bool
+	Rotation   int
+	Score      float64
+	Prefers    bool
+	GroupCount int
+}
+
+// RankCandidate scores an eligible volunteer for a shift
+func (s *Scheduler) RankCandidate(vol *models.Volunteer, shift *models.Shift, e Eligibility) Rank {
+	r := Rank{
+		Tier:       tierRank(vol),
+		Rotation:   s.RotationCount(vol, shift.Category),
+		Prefers:    s.Prefers(vol, shift),
+		Score:      HoursFromTarget(vol) + e.Penalty,
+		GroupCount: len(VolunteerGroups(vol)),
 	}
+	if s.minimizeHeadcount() {
+		// Busiest first, and anyone already working before anyone new
+		r.Idle = idle(vol)
+		r.Score = e.Penalty - vol.AssignedHours
+	}
+	if r.Prefers {
+		r.Score -= s.PreferenceWeight
+	}
+	if s.ConsecutiveWeight > 0 && s.extendsBlock(vol, shift) {
+		r.Score -= s.ConsecutiveWeight
+	}
+	return r
+}
+
+// Before reports whether r should be picked ahead of o. Higher tiers come
+// first, then, when minimizing headcount, volunteers already working.
+// Categorized shifts go to whoever has worked the fewest of them. After
+// that, furthest below target wins, or when minimizing headcount the
+// busiest; preferences shave off PreferenceWeight and break ties, and
+// extending a block of shifts shaves off ConsecutiveWeight.
+// Remaining ties go to the volunteer with fewer groups, keeping multi-skill
+// volunteers free for slots only they can fill.
+func (r Rank) Before(o Rank) bool {
+	if r.Tier != o.Tier {
+		return r.Tier < o.Tier
+	}
+	if r.Idle != o.Idle {
+		return o.Idle
+	}
+	if r.Rotation != o.Rotation {
+		return r.Rotation < o.Rotation
+	}
+	return r.Score < o.Score ||
+		(r.Score == o.Score && r.Prefers && !o.Prefers) ||
+		(r.Score == o.Score && r.Prefers == o.Prefers && r.GroupCount < o.GroupCount)
+}
+
+// ComplianceReport lists the policies the scheduler enforced for this run,
+// and those relaxed to soft constraints. Waivers are always empty today.
+func (s *Scheduler) ComplianceReport() models.ComplianceReport {
+	evaluated := []string{"max_hours", "no_overlap"}
+
+	hasAllowed, hasExcluded, hasRatios, hasEquipment, hasResources, hasLinks := false, false, false, false, false, false
+	hasCertifications := false
+	for _, sh := range s.Shifts {
+		if len(sh.RequiredCertifications) > 0 {
+			hasCertifications = true
+		}
+		if len(sh.LinkedShifts) > 0 {
+			hasLinks = true
+		}
+		if len(sh.RequiredResources) > 0 {
+			hasResources = true
+		}
+		if len(sh.RequiredEquipment) > 0 {
+			hasEquipment = true
+		}
+		if len(sh.SupervisorRatios) > 0 {
+			hasRatios = true
+		}
+		if len(sh.AllowedGroups) > 0 {
+			hasAllowed = true
+		}
+		if len(sh.ExcludedGroups) > 0 {
+			hasExcluded = true
+		}
+	}
+	if hasAllowed {
+		evaluated = append(evaluated, "allowed_groups")
+	}
+	if hasExcluded {
+		evaluated = append(evaluated, "excluded_groups")
+	}
+	if hasRatios {
+		evaluated = append(evaluated, "supervisor_ratios")
+	}
+	if hasEquipment {
+		evaluated = append(evaluated, "equipment")
+	}
+	if hasResources {
+		evaluated = append(evaluated, "resources")
+	}
+	if hasLinks {
+		evaluated = append(evaluated, "linked_shifts")
+	}
+	if hasCertifications {
+		evaluated = append(evaluated, "certifications")
+	}
+	hasAvailability, hasRest, hasDayLimits, hasMinimums, hasWeekCap, hasPairing := false, false, false, false, false, false
+	hasCategoryLimits := len(s.CategoryLimits) > 0
+	hasMaxShifts, hasBusy := false, false
+	for _, v := range s.Volunteers {
+		if len(v.BusyTimes) > 0 {
+			hasBusy = true
+		}
+		if v.MaxShifts > 0 {
+			hasMaxShifts = true
+		}
+		if len(v.CategoryLimits) > 0 {
+			hasCategoryLimits = true
+		}
+		if len(v.MustWorkWith) > 0 || len(v.CannotWorkWith) > 0 {
+			hasPairing = true
+		}
+		if v.MaxHoursPerWeek > 0 {
+			hasWeekCap = true
+		}
+		if v.MinHours > 0 || v.MinShifts > 0 {
+			hasMinimums = true
+		}
+		if v.MaxShiftsPerDay > 0 || v.MaxConsecutiveDays > 0 {
+			hasDayLimits = true
+		}
+		if len(v.Availability) > 0 {
+			hasAvailability = true
+		}
+		if v.MinRestHours > 0 {
+			hasRest = true
+		}
+	}
+	if hasMaxShifts {
+		evaluated = append(evaluated, "max_shifts")
+	}
+	if hasAvailability {
+		evaluated = append(evaluated, "availability")
+	}
+	if hasBusy {
+		evaluated = append(evaluated, "busy_times")
+	}
+	if len(s.TravelMinutes) > 0 {
+		evaluated = append(evaluated, "travel_minutes")
+	}
+	if hasRest {
+		evaluated = append(evaluated, "min_rest_hours")
+	}
+	if hasDayLimits {
+		evaluated = append(evaluated, "day_limits")
+	}
+	if hasWeekCap {
+		evaluated = append(evaluated, "max_hours_per_week")
+	}
+	if hasCategoryLimits {
+		evaluated = append(evaluated, "category_limits")
+	}
+	if hasPairing {
+		evaluated = append(evaluated, "pairing")
+	}
+	if hasMinimums {
+		evaluated = append(evaluated, "minimums")
+	}
+	if s.MaxHoursRatio > 0 {
+		evaluated = append(evaluated, "max_hours_ratio")
+	}
+
+	return models.ComplianceReport{
+		Evaluated: evaluated,
+		Relaxed:   append([]string{}, s.softNames()...),
+		Waivers:   []string{},
+	}
+}
+
+// CalculatePreferenceSatisfaction returns the percentage (0-100) of assignments
+// held by volunteers with declared preferences that matched one of them
+func (s *Scheduler) CalculatePreferenceSatisfaction() float64 {
+	total, matched := 0, 0
+	for _, v := range s.Volunteers {
+		if len(v.PreferredShifts) == 0 && len(v.PreferredTimes) == 0 {
+			continue
+		}
+		for _, shiftID := range v.AssignedShifts {
+			shift, ok := s.Shifts[shiftID]
+			if !ok {
+				continue
+			}
+			total++
+			if s.Prefers(v, shift) {
+				matched++
+			}
+		}
+	}
+	if total == 0 {
+		return 100.0
+	}
+	return float64(matched) / float64(total) * 100.0
 }
 
 // CalculateFairnessScore returns a percentage (0-100) representing how evenly
@@ -251,64 +1196,206 @@ func (s *Scheduler) CalculateFairnessScore() float64 {
 	return score
 }
 
-// AssignOptimal attempts a more thorough assignment (simplified backtracking)
-func (s *Scheduler) AssignOptimal(timeoutSeconds int) {
-	// For simplicity and speed in serverless, we'll use a multi-pass greedy strategy
-	// that tries different shuffles and keeps the best one (scored by unfilled slots)
-
-	bestScore := -1.0
-	var bestAssignments map[string][]string // shiftID -> []volunteerID
+// assignmentState is a copy of every shift's and volunteer's assignments
+type assignmentState struct {
+	shiftAssigned map[string][]string
+	volShifts     map[string][]string
+	volHours      map[string]float64
+	conflicts     []models.ConflictReason
+}
 
-	start := time.Now()
-	timeout := time.Duration(timeoutSeconds) * time.Second
+// snapshot copies the current assignment state
+func (s *Scheduler) snapshot() assignmentState {
+	st := assignmentState{
+		shiftAssigned: make(map[string][]string, len(s.Shifts)),
+		volShifts:     make(map[string][]string, len(s.Volunteers)),
+		volHours:      make(map[string]float64, len(s.Volunteers)),
+		conflicts:     append([]models.ConflictReason(nil), s.Conflicts...),
+	}
+	for id, sh := range s.Shifts {
+		st.shiftAssigned[id] = append([]string(nil), sh.Assigned...)
+	}
+	for id, v := range s.Volunteers {
+		st.volShifts[id] = append([]string(nil), v.AssignedShifts...)
+		st.volHours[id] = v.AssignedHours
+	}
+	return st
+}
 
-	// Keep track of original state
-	originalVols := make(map[string]float64)
+// restore resets the assignment state to a snapshot
+func (s *Scheduler) restore(st assignmentState) {
+	for id, sh := range s.Shifts {
+		sh.Assigned = append([]string(nil), st.shiftAssigned[id]...)
+	}
 	for id, v := range s.Volunteers {
-		originalVols[id] = v.AssignedHours
+		v.AssignedShifts = append([]string(nil), st.volShifts[id]...)
+		v.AssignedHours = st.volHours[id]
 	}
+	s.forgetBusyTimes(nil)
+	s.Conflicts = append([]models.ConflictReason(nil), st.conflicts...)
+}
 
-	volsByGroup := s.GroupByGroup()
+// FillRate returns the fraction (0-1) of required slots that are filled
+func (s *Scheduler) FillRate() float64 {
+	totalRequired := 0
+	filled := 0
+	for _, sh := range s.Shifts {
+		for _, count := range sh.RequiredGroups {
+			totalRequired += count
+		}
+		filled += len(sh.Assigned)
+	}
+	if totalRequired == 0 {
+		return 1.0
+	}
+	return float64(filled) / float64(totalRequired)
+}
+
+// Score rates the current assignments from 0 to 1 for the optimal search.
+// It is the fill rate unless FairnessWeight is set, in which case it blends
+// the fill rate with the fairness score; an unset FillWeight counts as 1.
+func (s *Scheduler) Score() float64 {
+	if s.FairnessWeight <= 0 {
+		return s.FillRate()
+	}
+	fill := s.FillWeight
+	if fill <= 0 {
+		fill = 1
+	}
+	return (fill*s.FillRate() + s.FairnessWeight*(s.FairnessScore()/100)) / (fill + s.FairnessWeight)
+}
+
+// AssignOptimal attempts a more thorough assignment (simplified backtracking).
+// Cancelling ctx stops the search early with the best schedule so far.
+func (s *Scheduler) AssignOptimal(ctx context.Context, timeoutSeconds int) {
+	// For simplicity and speed in serverless, we'll use a multi-pass greedy strategy
+	// that tries different shuffles and keeps the best one (see Score). Passes
+	// run on one worker per CPU by default (see Tuning), each with its own
+	// copy of the state.
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	// The cap depends on the prefilled state, so settle it before copying
+	s.HoursCap()
+
+	// A hinted schedule that can't be beaten needs no search
+	hinted := s.hintedPass()
+	if hinted != nil && s.perfect(*hinted) && s.Alternatives <= 1 {
+		s.restore(hinted.state)
+		return
+	}
 
-	for time.Since(start) < timeout {
-		// Reset
-		for _, v := range s.Volunteers {
-			v.AssignedHours = originalVols[v.ID]
-			v.AssignedShifts = nil // We'd need to properly reset prefills here if we wanted perfection
+	workers := make([]*Scheduler, CurrentTuning().Workers)
+	for i := range workers {
+		workers[i] = s.clone(s.random().Int63())
+	}
+	bests := make([]*alternative, len(workers))
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bests[i] = w.searchOptimal(ctx, &done, hinted)
+		}()
+	}
+	wg.Wait()
+
+	// Merge in worker order, so ties go to the lower worker the way they go
+	// to the earlier pass within one. The hinted schedule wins ties, keeping
+	// re-runs stable.
+	best := hinted
+	for i, w := range workers {
+		if bests[i] != nil && (best == nil || bests[i].better(*best)) {
+			best = bests[i]
 		}
-		for _, sh := range s.Shifts {
-			sh.Assigned = nil
+		for _, alt := range w.alternatives {
+			if s.wantsAlternative(alt) {
+				s.insertAlternative(alt)
+			}
 		}
+	}
+	if best != nil {
+		s.restore(best.state)
+	}
+}
 
-		s.AssignSimpleWithGroups(true, volsByGroup)
+// perfect reports whether a pass can't be improved on. Without an explicit
+// fairness metric or weight, any full schedule is good enough.
+func (s *Scheduler) perfect(pass alternative) bool {
+	return pass.score >= 1.0 && pass.penalty == 0 &&
+		((s.FairnessMetric == "" && s.FairnessWeight <= 0) || pass.fairness >= 100.0)
+}
+
+// searchOptimal runs optimal search passes from the current state until ctx
+// is done, a perfect schedule, or another worker reporting one through done.
+// It returns the best pass, or nil if there was no time for any. Given a
+// hinted start, it improves on that instead; see improve.
+func (s *Scheduler) searchOptimal(ctx context.Context, done *atomic.Bool, start *alternative) *alternative {
+	var best *alternative
+
+	// Keep track of original state, including prefilled assignments
+	original := s.snapshot()
 
-		// Score
-		score := 0.0
-		totalRequired := 0
-		filled := 0
-		for _, sh := range s.Shifts {
-			for _, count := range sh.RequiredGroups {
-				totalRequired += count
+	volsByGroup := s.GroupByGroup()
+	if start != nil && s.Alternatives <= 1 {
+		return s.improve(ctx, done, original, *start, volsByGroup)
+	}
+
+	for ctx.Err() == nil && !done.Load() {
+		// Reset back to the prefilled state
+		s.restore(original)
+
+		// Shuffling volunteers too lets tied candidates take turns, so
+		// passes can turn up distinct alternatives
+		if s.Alternatives > 1 {
+			for _, vols := range volsByGroup {
+				s.random().Shuffle(len(vols), func(i, j int) { vols[i], vols[j] = vols[j], vols[i] })
 			}
-			filled += len(sh.Assigned)
 		}
-		score = float64(filled) / float64(totalRequired)
+		s.AssignSimpleWithGroups(true, volsByGroup)
 
-		if score > bestScore {
-			bestScore = score
-			bestAssignments = make(map[string][]string)
-			for id, sh := range s.Shifts {
-				bestAssignments[id] = append([]string{}, sh.Assigned...)
-			}
+		// Score first, then standby assignments, then headcount when
+		// minimizing it, then soft constraint penalty, then the selected
+		// fairness metric breaks ties
+		pass := s.scorePass()
+		if best == nil || pass.better(*best) {
+			pass.state = s.snapshot()
+			best = &pass
+		}
+		if s.Alternatives > 1 {
+			s.keepAlternative(pass)
 		}
 
-		if bestScore >= 1.0 {
-			break // Perfect score
+		// Stop once there are as many alternatives as were asked for
+		if s.perfect(*best) && (s.Alternatives <= 1 || len(s.alternatives) >= s.Alternatives) {
+			done.Store(true) // Perfect score
+			break
 		}
 	}
+	return best
+}
 
-	// Restore best
-	for id, asgn := range bestAssignments {
-		s.Shifts[id].Assigned = asgn
+// clone copies the scheduler so a worker can assign volunteers without
+// touching the original. Settings and everything besides the assignments
+// are shared, and must not change while the copy is in use.
+func (s *Scheduler) clone(seed int64) *Scheduler {
+	c := *s
+	c.Volunteers = make(map[string]*models.Volunteer, len(s.Volunteers))
+	for id, v := range s.Volunteers {
+		vol := *v
+		vol.AssignedShifts = slices.Clone(v.AssignedShifts)
+		c.Volunteers[id] = &vol
+	}
+	c.Shifts = make(map[string]*models.Shift, len(s.Shifts))
+	for id, sh := range s.Shifts {
+		shift := *sh
+		shift.Assigned = slices.Clone(sh.Assigned)
+		c.Shifts[id] = &shift
 	}
+	c.Conflicts = slices.Clone(s.Conflicts)
+	c.alternatives = nil
+	c.busy = nil
+	c.Seed(seed)
+	return &c
 }
@@ -1,6 +1,13 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -76,3 +83,1805 @@ func TestAssignSimple_Overlap(t *testing.T) {
 		t.Errorf("Expected only 1 shift to be assigned due to overlap, got %d", assignedCount)
 	}
 }
+
+func TestComplianceReport(t *testing.T) {
+	start := time.Now()
+	shifts := map[string]*models.Shift{
+		"s1": {
+			ID:             "s1",
+			Start:          start,
+			End:            start.Add(time.Hour),
+			RequiredGroups: map[string]int{"A": 1},
+			ExcludedGroups: []string{"B"},
+		},
+	}
+
+	s := NewScheduler(map[string]*models.Volunteer{}, shifts)
+	report := s.ComplianceReport()
+
+	want := []string{"max_hours", "no_overlap", "excluded_groups"}
+	if len(report.Evaluated) != len(want) {
+		t.Fatalf("Expected evaluated policies %v, got %v", want, report.Evaluated)
+	}
+	for i, p := range want {
+		if report.Evaluated[i] != p {
+			t.Errorf("Expected policy %q at position %d, got %q", p, i, report.Evaluated[i])
+		}
+	}
+	if len(report.Relaxed) != 0 || len(report.Waivers) != 0 {
+		t.Errorf("Expected no relaxed policies or waivers, got %v / %v", report.Relaxed, report.Waivers)
+	}
+}
+
+func TestAssignSimple_Availability(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10, Availability: []models.TimeWindow{
+			{Start: start.Add(time.Hour), End: end.Add(time.Hour)},
+		}},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10, Availability: []models.TimeWindow{
+			{Start: start, End: end},
+		}},
+	}
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: end, RequiredGroups: map[string]int{"A": 2}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if len(shifts["s1"].Assigned) != 1 || shifts["s1"].Assigned[0] != "v2" {
+		t.Fatalf("Expected only v2 to be assigned, got %v", shifts["s1"].Assigned)
+	}
+	if len(s.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict for the unfilled slot, got %d", len(s.Conflicts))
+	}
+}
+
+func TestAssignSimple_DetailedConflicts(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 1},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10, Availability: []models.TimeWindow{{Start: start.Add(time.Hour), End: start.Add(5 * time.Hour)}}},
+	}
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.DetailedConflicts = true
+	s.AssignSimple(false)
+
+	if len(s.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", s.Conflicts)
+	}
+	details := map[string][]string{}
+	for _, d := range s.Conflicts[0].Details {
+		details[d.VolunteerID] = d.Failed
+	}
+	if !slices.Equal(details["v1"], []string{"max_hours"}) || !slices.Equal(details["v2"], []string{"availability"}) {
+		t.Errorf("Expected Alice at max hours and Bob unavailable, got %v", details)
+	}
+}
+
+func TestAssignSimple_Relaxations(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 1, MinRestHours: 8},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 3, AssignedHours: 2},
+	}
+	shifts := map[string]*models.Shift{
+		"s0": {ID: "s0", Start: start.Add(-4 * time.Hour), End: start.Add(-3 * time.Hour)},
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{{ShiftID: "s0", VolunteerID: "v1"}})
+	s.AssignSimple(false)
+
+	if len(s.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", s.Conflicts)
+	}
+	got := s.Conflicts[0].Relaxations
+	want := []models.Relaxation{
+		{VolunteerID: "v2", Changes: []string{"raise max_hours from 3 to 4"}},
+		{VolunteerID: "v1", Changes: []string{"raise max_hours from 1 to 3", "lower min_rest_hours from 8 to 3"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i].VolunteerID != want[i].VolunteerID || !slices.Equal(got[i].Changes, want[i].Changes) {
+			t.Errorf("Expected %v at %d, got %v", want[i], i, got[i])
+		}
+	}
+}
+
+func TestWouldOverlap_MatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	shifts := make(map[string]*models.Shift)
+	for i := range 200 {
+		// Overlapping shifts of varied length, as prefills can be
+		begin := start.Add(time.Duration(rng.Intn(500)) * time.Hour)
+		id := fmt.Sprintf("s%d", i)
+		shifts[id] = &models.Shift{ID: id, Start: begin, End: begin.Add(time.Duration(1+rng.Intn(30)) * time.Hour)}
+	}
+	vol := &models.Volunteer{ID: "v1", Name: "Alice", Group: "A"}
+	s := NewScheduler(map[string]*models.Volunteer{"v1": vol}, shifts)
+
+	for i := range 2000 {
+		shift := shifts[fmt.Sprintf("s%d", rng.Intn(len(shifts)))]
+		if got, want := s.WouldOverlap(vol, shift), linearOverlap(s, vol, shift); got != want {
+			t.Fatalf("Step %d: WouldOverlap(%s) = %v, linear scan says %v", i, shift.ID, got, want)
+		}
+		duration := s.DurationHours(shift.Start, shift.End)
+		if slices.Contains(vol.AssignedShifts, shift.ID) {
+			s.removeAssignment(vol, shift, duration)
+		} else {
+			s.assign(vol, shift, duration)
+		}
+	}
+}
+
+// linearOverlap is the scan over every assigned shift that WouldOverlap replaced
+func linearOverlap(s *Scheduler, vol *models.Volunteer, shift *models.Shift) bool {
+	for _, id := range vol.AssignedShifts {
+		if s.Overlap(s.Shifts[id].Start, s.Shifts[id].End, shift.Start, shift.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func BenchmarkWouldOverlap(b *testing.B) {
+	start := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for _, n := range []int{10, 100, 1000, 10000} {
+		shifts := make(map[string]*models.Shift, n+1)
+		vol := &models.Volunteer{ID: "v1", Name: "Alice", Group: "A"}
+		for i := range n {
+			id := fmt.Sprintf("s%d", i)
+			begin := start.Add(time.Duration(3*i) * time.Hour)
+			shifts[id] = &models.Shift{ID: id, Start: begin, End: begin.Add(2 * time.Hour)}
+			vol.AssignedShifts = append(vol.AssignedShifts, id)
+		}
+		// Lands in the last gap, so a linear scan has to look at every shift
+		probe := &models.Shift{ID: "probe", Start: start.Add(time.Duration(3*n-1) * time.Hour), End: start.Add(time.Duration(3*n) * time.Hour)}
+		s := NewScheduler(map[string]*models.Volunteer{"v1": vol}, shifts)
+
+		b.Run(fmt.Sprintf("indexed/%d", n), func(b *testing.B) {
+			for range b.N {
+				s.WouldOverlap(vol, probe)
+			}
+		})
+		b.Run(fmt.Sprintf("linear/%d", n), func(b *testing.B) {
+			for range b.N {
+				linearOverlap(s, vol, probe)
+			}
+		})
+	}
+}
+
+func BenchmarkAssignSimple_Availability(b *testing.B) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	const days = 28
+	volunteers := make(map[string]*models.Volunteer, 5000)
+	for i := range 5000 {
+		// Each volunteer is only around for one day of the month
+		day := start.AddDate(0, 0, i%days)
+		id := fmt.Sprintf("v%d", i)
+		volunteers[id] = &models.Volunteer{ID: id, Name: id, Group: "A", MaxHours: 40, Availability: []models.TimeWindow{
+			{Start: day, End: day.Add(24 * time.Hour)},
+		}}
+	}
+	shifts := make(map[string]*models.Shift, days*6)
+	for i := range days * 6 {
+		begin := start.Add(time.Duration(i) * 4 * time.Hour)
+		id := fmt.Sprintf("s%d", i)
+		shifts[id] = &models.Shift{ID: id, Start: begin, End: begin.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 20}}
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Seed(1)
+	original := s.snapshot()
+	volsByGroup := s.GroupByGroup()
+	b.ResetTimer()
+	for range b.N {
+		s.restore(original)
+		s.AssignSimpleWithGroups(true, volsByGroup)
+	}
+}
+
+func TestAssignSimple_MinRest(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 24, MinRestHours: 8},
+	}
+
+	start1 := time.Date(2026, 1, 10, 18, 0, 0, 0, time.UTC)
+	end1 := start1.Add(4 * time.Hour)
+	start2 := end1.Add(2 * time.Hour)
+	end2 := start2.Add(4 * time.Hour)
+
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start1, End: end1, RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start2, End: end2, RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if len(volunteers["v1"].AssignedShifts) != 1 {
+		t.Errorf("Expected only 1 shift assigned due to rest gap, got %d", len(volunteers["v1"].AssignedShifts))
+	}
+}
+
+func TestAssignSimple_SoftRest(t *testing.T) {
+	start1 := time.Date(2026, 1, 10, 18, 0, 0, 0, time.UTC)
+	start2 := start1.Add(6 * time.Hour)
+	newShifts := func() map[string]*models.Shift {
+		return map[string]*models.Shift{
+			"s1": {ID: "s1", Start: start1, End: start1.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+			"s2": {ID: "s2", Start: start2, End: start2.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		}
+	}
+
+	// Alone, Alice works both shifts and the short rest is charged
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 24, MinRestHours: 8},
+	}
+	s := NewScheduler(volunteers, newShifts())
+	s.SoftConstraints = map[string]float64{"min_rest_hours": 2}
+	s.AssignSimple(false)
+
+	if got := len(volunteers["v1"].AssignedShifts); got != 2 {
+		t.Fatalf("Expected both shifts with a soft rest gap, got %d", got)
+	}
+	violations, penalty := s.SoftViolations()
+	if len(violations) != 2 || penalty != 4 {
+		t.Errorf("Expected the short rest charged on both shifts, got %+v (%.1f)", violations, penalty)
+	}
+	if !slices.Contains(s.ComplianceReport().Relaxed, "min_rest_hours") {
+		t.Error("Expected min_rest_hours in the relaxed list")
+	}
+
+	// With a cheaper alternative, the penalty steers the second shift to Bob
+	volunteers = map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 24, MinRestHours: 8},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 24, AssignedHours: 3},
+	}
+	s = NewScheduler(volunteers, newShifts())
+	s.SoftConstraints = map[string]float64{"min_rest_hours": 2}
+	s.AssignSimple(false)
+
+	if violations, _ := s.SoftViolations(); len(violations) != 0 {
+		t.Errorf("Expected no soft violations, got %+v", violations)
+	}
+}
+
+func TestAssignSimple_DayLimits(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 100, MaxShiftsPerDay: 1, MaxConsecutiveDays: 2},
+	}
+
+	day1 := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"morning":   {ID: "morning", Start: day1, End: day1.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"afternoon": {ID: "afternoon", Start: day1.Add(5 * time.Hour), End: day1.Add(7 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"day2":      {ID: "day2", Start: day1.AddDate(0, 0, 1), End: day1.AddDate(0, 0, 1).Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"day3":      {ID: "day3", Start: day1.AddDate(0, 0, 2), End: day1.AddDate(0, 0, 2).Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if got := len(volunteers["v1"].AssignedShifts); got != 2 {
+		t.Errorf("Expected 2 shifts (1 per day, 2 consecutive days max), got %d: %v", got, volunteers["v1"].AssignedShifts)
+	}
+}
+
+func TestNormalizeTimezones_DayLimits(t *testing.T) {
+	// 16:00 and 02:00 UTC are different days in UTC but the same day in New York
+	start := time.Date(2026, 1, 10, 16, 0, 0, 0, time.UTC)
+	input := models.ScheduleInput{
+		Volunteers: []models.Volunteer{{ID: "v1", Name: "Alice", Group: "A", MaxHours: 100, MaxShiftsPerDay: 1}},
+		UnassignedShifts: []models.Shift{
+			{ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+			{ID: "s2", Start: start.Add(10 * time.Hour), End: start.Add(12 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		},
+		Timezone: "America/New_York",
+	}
+	if err := input.NormalizeTimezones(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScheduler(
+		map[string]*models.Volunteer{"v1": &input.Volunteers[0]},
+		map[string]*models.Shift{"s1": &input.UnassignedShifts[0], "s2": &input.UnassignedShifts[1]},
+	)
+	s.AssignSimple(false)
+
+	if got := len(input.Volunteers[0].AssignedShifts); got != 1 {
+		t.Errorf("Expected 1 shift on the local day, got %d: %v", got, input.Volunteers[0].AssignedShifts)
+	}
+
+	input.Timezone = "Mars/Olympus_Mons"
+	if err := input.NormalizeTimezones(); err == nil {
+		t.Error("Expected an error for an unknown timezone")
+	}
+}
+
+func TestAssignedToPrefills_CountsHours(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	input := models.ScheduleInput{
+		Volunteers: []models.Volunteer{
+			{ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+			{ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+		},
+		UnassignedShifts: []models.Shift{
+			{ID: "s1", Start: start, End: start.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 1}, Assigned: []string{"v1", "v1"}},
+			{ID: "s2", Start: start.Add(5 * time.Hour), End: start.Add(7 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		},
+	}
+	if err := input.AssignedToPrefills(); err != nil {
+		t.Fatal(err)
+	}
+	if len(input.CurrentAssignments) != 1 {
+		t.Fatalf("Expected one prefill from s1, got %v", input.CurrentAssignments)
+	}
+
+	s := NewScheduler(
+		map[string]*models.Volunteer{"v1": &input.Volunteers[0], "v2": &input.Volunteers[1]},
+		map[string]*models.Shift{"s1": &input.UnassignedShifts[0], "s2": &input.UnassignedShifts[1]},
+	)
+	s.Prefill(input.CurrentAssignments)
+	s.AssignSimple(false)
+
+	// With Alice's 4 hours counted, Bob gets s2
+	if input.Volunteers[0].AssignedHours != 4 || !slices.Equal(input.UnassignedShifts[1].Assigned, []string{"v2"}) {
+		t.Errorf("Expected Alice on 4 hours and Bob on s2, got %f and %v", input.Volunteers[0].AssignedHours, input.UnassignedShifts[1].Assigned)
+	}
+
+	input.UnassignedShifts[1].Assigned = []string{"ghost"}
+	if err := input.AssignedToPrefills(); err == nil {
+		t.Error("Expected an error for an unknown assigned volunteer")
+	}
+}
+
+func TestAssignSimple_WeeklyCap(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 100, MaxHoursPerWeek: 8},
+	}
+
+	// Four 8h shifts, three days apart: only the first and last are a full week apart
+	shifts := make(map[string]*models.Shift)
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	for i, id := range []string{"s1", "s2", "s3", "s4"} {
+		st := start.AddDate(0, 0, 3*i)
+		shifts[id] = &models.Shift{ID: id, Start: st, End: st.Add(8 * time.Hour), RequiredGroups: map[string]int{"A": 1}}
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if got := volunteers["v1"].AssignedHours; got != 16.0 {
+		t.Errorf("Expected the weekly cap to allow 16 hours across the period, got %f", got)
+	}
+	found := false
+	for _, c := range s.Conflicts {
+		if strings.Contains(strings.Join(c.Reasons, " "), "weekly hour cap") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a weekly hour cap conflict, got %+v", s.Conflicts)
+	}
+}
+
+func TestAssignSimple_CategoryLimits(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 100},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 100, CategoryLimits: map[string]int{"night": 3}},
+	}
+
+	// Four night shifts on separate days: Alice may only take two of them
+	shifts := make(map[string]*models.Shift)
+	for i, id := range []string{"n1", "n2", "n3", "n4"} {
+		start := time.Date(2026, 1, 10+i, 22, 0, 0, 0, time.UTC)
+		shifts[id] = &models.Shift{ID: id, Start: start, End: start.Add(8 * time.Hour), Category: "night", RequiredGroups: map[string]int{"A": 2}}
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.CategoryLimits = map[string]int{"night": 2}
+	s.AssignSimple(false)
+
+	if got := len(volunteers["v1"].AssignedShifts); got != 2 {
+		t.Errorf("Expected Alice on 2 night shifts, got %d", got)
+	}
+	if got := len(volunteers["v2"].AssignedShifts); got != 3 {
+		t.Errorf("Expected Bob on 3 night shifts under his own limit, got %d", got)
+	}
+	if !slices.Contains(s.ComplianceReport().Evaluated, "category_limits") {
+		t.Error("Expected category_limits in the compliance report")
+	}
+}
+
+func TestAssignSimple_RotatesCategories(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 100, CategoryHistory: map[string]int{"weekend": 3}},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 100, CategoryHistory: map[string]int{"weekend": 1}},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 100},
+	}
+
+	// Bob and Cara should share the weekend shifts until they catch up with
+	// Alice, even though Alice has the fewest hours
+	sat := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"sat": {ID: "sat", Start: sat, End: sat.Add(4 * time.Hour), Category: "weekend", RequiredGroups: map[string]int{"A": 1}},
+		"sun": {ID: "sun", Start: sat.AddDate(0, 0, 1), End: sat.AddDate(0, 0, 1).Add(4 * time.Hour), Category: "weekend", RequiredGroups: map[string]int{"A": 1}},
+		"mon": {ID: "mon", Start: sat.AddDate(0, 0, 2), End: sat.AddDate(0, 0, 2).Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+	volunteers["v2"].AssignedHours = 10
+	volunteers["v3"].AssignedHours = 10
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if slices.Contains(volunteers["v1"].AssignedShifts, "sat") || slices.Contains(volunteers["v1"].AssignedShifts, "sun") {
+		t.Errorf("Expected Alice to get no weekend shift, got %v", volunteers["v1"].AssignedShifts)
+	}
+	if !slices.Contains(volunteers["v1"].AssignedShifts, "mon") {
+		t.Errorf("Expected Alice on the weekday shift, got %v", volunteers["v1"].AssignedShifts)
+	}
+}
+
+func TestAssignSimple_Preferences(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10, PreferredTimes: []models.TimeOfDayRange{{Start: "08:00", End: "12:00"}}},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if shifts["s1"].Assigned[0] != "v2" {
+		t.Errorf("Expected tie to be broken in favour of v2, got %s", shifts["s1"].Assigned[0])
+	}
+	if got := s.CalculatePreferenceSatisfaction(); got != 100.0 {
+		t.Errorf("Expected 100%% preference satisfaction, got %f", got)
+	}
+}
+
+func TestAssignSimple_TargetHours(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10, TargetHours: 4},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if volunteers["v2"].AssignedHours != 4.0 {
+		t.Errorf("Expected v2 to be scheduled up to their 4 hour target, got %f", volunteers["v2"].AssignedHours)
+	}
+}
+
+func TestFillMinimums(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10, MinShifts: 2},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 10, MinHours: 4},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}, Assigned: []string{"v1"}},
+		"s2": {ID: "s2", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"A": 1}, Assigned: []string{"v1"}},
+	}
+	volunteers["v1"].AssignedHours = 4
+	volunteers["v1"].AssignedShifts = []string{"s1", "s2"}
+
+	s := NewScheduler(volunteers, shifts)
+	s.FillMinimums()
+
+	if got := volunteers["v2"].AssignedShifts; len(got) != 2 {
+		t.Errorf("Expected v2 to reach their minimum of 2 shifts, got %v", got)
+	}
+	shortfalls := s.Shortfalls()
+	if len(shortfalls) != 1 || shortfalls[0].VolunteerID != "v3" || shortfalls[0].AssignedHours != 0 {
+		t.Errorf("Expected only v3 to be reported short, got %+v", shortfalls)
+	}
+}
+
+func TestFillIdeal_MinimumsFirst(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}, IdealGroups: map[string]int{"A": 3}},
+		"s2": {ID: "s2", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}, IdealGroups: map[string]int{"A": 2}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	if err := s.Run(context.Background(), AlgorithmGreedy, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(shifts["s1"].Assigned) != 2 || len(shifts["s2"].Assigned) != 1 {
+		t.Errorf("Expected both minimums met before s1's extra, got s1=%v s2=%v", shifts["s1"].Assigned, shifts["s2"].Assigned)
+	}
+	if len(s.Conflicts) != 0 {
+		t.Errorf("Expected open ideal slots not to be conflicts, got %v", s.Conflicts)
+	}
+	if got := s.BelowIdeal(); len(got) != 2 {
+		t.Errorf("Expected both shifts below ideal, got %v", got)
+	}
+}
+
+func TestAssignSimple_Pairing(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"trainee": {ID: "trainee", Name: "Tess", Group: "crew", MaxHours: 10, MustWorkWith: []string{"mentor"}},
+		"mentor":  {ID: "mentor", Name: "Max", Group: "lead", MaxHours: 2},
+		"rival":   {ID: "rival", Name: "Rex", Group: "crew", MaxHours: 10, CannotWorkWith: []string{"mentor"}},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"crew": 1, "lead": 1}},
+		"s2": {ID: "s2", Start: start.Add(4 * time.Hour), End: start.Add(6 * time.Hour), RequiredGroups: map[string]int{"crew": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	if err := s.Run(context.Background(), AlgorithmGreedy, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The mentor's max hours allow only one shift, so the trainee can only work it
+	if got := shifts["s1"].Assigned; !slices.Contains(got, "mentor") || !slices.Contains(got, "trainee") {
+		t.Errorf("Expected trainee paired with mentor on s1, got %v", got)
+	}
+	if got := shifts["s2"].Assigned; len(got) != 1 || got[0] != "rival" {
+		t.Errorf("Expected rival alone on s2, got %v", got)
+	}
+}
+
+func TestAssignSimple_SupervisorRatio(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"lead": {ID: "lead", Name: "Lee", Group: "lead", MaxHours: 10},
+	}
+	for _, id := range []string{"c1", "c2", "c3", "c4"} {
+		volunteers[id] = &models.Volunteer{ID: id, Name: id, Group: "crew", MaxHours: 10}
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {
+			ID: "s1", Start: start, End: start.Add(2 * time.Hour),
+			RequiredGroups:   map[string]int{"crew": 4, "lead": 1},
+			SupervisorRatios: []models.SupervisorRatio{{Supervisor: "lead", Supervised: "crew", Per: 2}},
+		},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(true)
+
+	if got := len(shifts["s1"].Assigned); got != 3 {
+		t.Errorf("Expected 1 lead and 2 crew, got %v", shifts["s1"].Assigned)
+	}
+	found := false
+	for _, c := range s.Conflicts {
+		if strings.Contains(strings.Join(c.Reasons, " "), "supervisor ratio") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a supervisor ratio conflict, got %+v", s.Conflicts)
+	}
+}
+
+func TestAssignSimple_Equipment(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 100},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 100},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 100, AssignedHours: 20, HasEquipment: []string{"radio"}},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 2}, RequiredEquipment: map[string]int{"radio": 1, "forklift": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+	s.CheckEquipment()
+
+	if !slices.Contains(shifts["s1"].Assigned, "v3") {
+		t.Errorf("Expected the radio holder on the shift, got %v", shifts["s1"].Assigned)
+	}
+	found := false
+	for _, c := range s.Conflicts {
+		if c.ShiftID == "s1" && slices.ContainsFunc(c.Reasons, func(r string) bool { return strings.Contains(r, "forklift") }) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a conflict for the missing forklift, got %+v", s.Conflicts)
+	}
+}
+
+func TestAssignResources(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"a": {ID: "a", Start: start, End: start.Add(4 * time.Hour), RequiredResources: map[string]int{"van": 1}},
+		"b": {ID: "b", Start: start.Add(2 * time.Hour), End: start.Add(6 * time.Hour), RequiredResources: map[string]int{"van": 1, "room": 1}},
+		"c": {ID: "c", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredResources: map[string]int{"van": 1}},
+	}
+
+	s := NewScheduler(map[string]*models.Volunteer{}, shifts)
+	s.Resources = map[string]*models.Resource{
+		"van1": {ID: "van1", Type: "van"},
+		"van2": {ID: "van2", Type: "van"},
+		// The only room opens after shift b has started
+		"room1": {ID: "room1", Type: "room", Availability: []models.TimeWindow{{Start: start.Add(3 * time.Hour), End: start.Add(12 * time.Hour)}}},
+	}
+	s.AssignResources()
+
+	if got := s.AssignedResources["a"]; !slices.Equal(got, []string{"van1"}) {
+		t.Errorf("Expected van1 on shift a, got %v", got)
+	}
+	if got := s.AssignedResources["b"]; !slices.Equal(got, []string{"van2"}) {
+		t.Errorf("Expected van2 and no room on shift b, got %v", got)
+	}
+	if got := s.AssignedResources["c"]; len(got) != 0 {
+		t.Errorf("Expected no free van for shift c, got %v", got)
+	}
+	if len(s.Conflicts) != 2 {
+		t.Errorf("Expected conflicts for shifts b and c, got %+v", s.Conflicts)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10, AssignedHours: 4},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 1},
+		"v4": {ID: "v4", Name: "Dan", Group: "B", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	got, err := s.Explain("s1", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make([]string, len(got))
+	for i, x := range got {
+		order[i] = x.VolunteerID
+	}
+	if !slices.Equal(order, []string{"v2", "v1", "v3", "v4"}) {
+		t.Fatalf("Expected Bob ranked before Alice and the ineligible last, got %v", order)
+	}
+	if got[0].Rank != 1 || got[1].Rank != 2 || got[2].Rank != 0 {
+		t.Errorf("Expected ranks 1, 2 and 0, got %d, %d and %d", got[0].Rank, got[1].Rank, got[2].Rank)
+	}
+	if got[2].Checks["max_hours"] || !got[2].Checks["group"] {
+		t.Errorf("Expected Cara to fail only on max hours, got %v", got[2].Checks)
+	}
+	if got[3].Checks["group"] {
+		t.Error("Expected Dan to fail the group check")
+	}
+
+	if _, err := s.Explain("missing", "A"); !errors.Is(err, ErrUnknownShift) {
+		t.Errorf("Expected ErrUnknownShift, got %v", err)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 10},
+		"v4": {ID: "v4", Name: "Dan", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 2}},
+		// s2 was already short before the dropout and must stay that way
+		"s2": {ID: "s2", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"A": 2}},
+		"s3": {ID: "s3", Start: start.Add(6 * time.Hour), End: start.Add(8 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{
+		{ShiftID: "s1", VolunteerID: "v1"}, {ShiftID: "s1", VolunteerID: "v2"},
+		{ShiftID: "s2", VolunteerID: "v1"},
+		{ShiftID: "s3", VolunteerID: "v3"},
+	})
+
+	removed, added, err := s.Repair([]string{"v1"}, []string{"s3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(removed) != 3 {
+		t.Errorf("Expected Cara's s3 and both of Alice's shifts removed, got %v", removed)
+	}
+	if len(added) != 2 || added[0].ShiftID != "s1" || added[1].ShiftID != "s2" {
+		t.Fatalf("Expected one replacement each on s1 and s2, got %v", added)
+	}
+	if !slices.Contains(shifts["s1"].Assigned, "v2") || len(shifts["s2"].Assigned) != 1 {
+		t.Errorf("Expected Bob kept on s1 and s2 left one short, got s1=%v s2=%v", shifts["s1"].Assigned, shifts["s2"].Assigned)
+	}
+	if _, ok := s.Shifts["s3"]; ok {
+		t.Error("Expected the cancelled shift to be gone")
+	}
+	if shifts["s2"].RequiredGroups["A"] != 2 {
+		t.Errorf("Expected s2's requirement restored, got %v", shifts["s2"].RequiredGroups)
+	}
+
+	if _, _, err := s.Repair([]string{"ghost"}, nil); !errors.Is(err, ErrUnknownVolunteer) {
+		t.Errorf("Expected ErrUnknownVolunteer, got %v", err)
+	}
+}
+
+func TestSwaps(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		// Bob is busy at the same time, so he can only trade
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 10},
+		"v4": {ID: "v4", Name: "Dan", Group: "A", MaxHours: 1},
+	}
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{{ShiftID: "s1", VolunteerID: "v1"}, {ShiftID: "s2", VolunteerID: "v2"}})
+
+	direct, swaps, err := s.Swaps("s1", "v1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(direct) != 1 || direct[0].VolunteerID != "v3" {
+		t.Errorf("Expected only Cara to take s1 outright, got %v", direct)
+	}
+	if len(swaps) != 1 || swaps[0].VolunteerID != "v2" || swaps[0].GivesShiftID != "s2" {
+		t.Errorf("Expected Bob to trade s2, got %v", swaps)
+	}
+	if !slices.Equal(shifts["s1"].Assigned, []string{"v1"}) || volunteers["v1"].AssignedHours != 2 {
+		t.Errorf("Expected assignments left as found, got %v (%f hours)", shifts["s1"].Assigned, volunteers["v1"].AssignedHours)
+	}
+
+	if _, _, err := s.Swaps("s1", "v3", false); !errors.Is(err, ErrNotAssigned) {
+		t.Errorf("Expected ErrNotAssigned, got %v", err)
+	}
+}
+
+func TestApplyGroupHierarchy(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "senior_medic", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "medic", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"first_aid": 2}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.ApplyGroupHierarchy(map[string][]string{"senior_medic": {"medic"}, "medic": {"first_aid"}})
+	s.AssignSimple(false)
+
+	if len(shifts["s1"].Assigned) != 2 {
+		t.Errorf("Expected both medics to cover first_aid, got %v (conflicts: %v)", shifts["s1"].Assigned, s.Conflicts)
+	}
+	if InGroup(volunteers["v2"], "senior_medic") {
+		t.Error("Expected substitution to only work upwards")
+	}
+}
+
+func TestAssignSimple_MultiGroup(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "medic", Groups: []string{"driver"}, MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "medic", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"medic": 1, "driver": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if len(shifts["s1"].Assigned) != 2 {
+		t.Fatalf("Expected both slots filled, got %v (conflicts: %v)", shifts["s1"].Assigned, s.Conflicts)
+	}
+	if volunteers["v1"].AssignedHours != 2.0 {
+		t.Errorf("Expected multi-skill volunteer to be counted once (2.0 hours), got %f", volunteers["v1"].AssignedHours)
+	}
+}
+
+func TestAssignOptimal_KeepsPrefill(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{{ShiftID: "s1", VolunteerID: "v1", Locked: true}})
+	s.AssignOptimal(context.Background(), 1)
+
+	if len(shifts["s1"].Assigned) != 1 || shifts["s1"].Assigned[0] != "v1" {
+		t.Fatalf("Expected prefilled v1 to stay on s1, got %v", shifts["s1"].Assigned)
+	}
+	if len(shifts["s2"].Assigned) != 1 || shifts["s2"].Assigned[0] != "v2" {
+		t.Fatalf("Expected v2 on s2, got %v", shifts["s2"].Assigned)
+	}
+	if !s.IsLocked("s1", "v1") {
+		t.Errorf("Expected s1/v1 to be locked")
+	}
+	if volunteers["v1"].AssignedHours != 2.0 || len(volunteers["v1"].AssignedShifts) != 1 {
+		t.Errorf("Expected v1 to keep exactly its prefilled shift, got %v (%f hours)", volunteers["v1"].AssignedShifts, volunteers["v1"].AssignedHours)
+	}
+}
+
+func TestPrefill_SkipsDuplicates(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 2}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{
+		{ShiftID: "s1", VolunteerID: "v1"},
+		{ShiftID: "s1", VolunteerID: "v1", Locked: true},
+	})
+
+	if volunteers["v1"].AssignedHours != 2 || len(shifts["s1"].Assigned) != 1 {
+		t.Errorf("Expected the repeat to be skipped, got %f hours and %v", volunteers["v1"].AssignedHours, shifts["s1"].Assigned)
+	}
+	if s.DuplicatePrefills != 1 {
+		t.Errorf("Expected 1 duplicate counted, got %d", s.DuplicatePrefills)
+	}
+	if !s.IsLocked("s1", "v1") {
+		t.Error("Expected a locked repeat to still lock the assignment")
+	}
+}
+
+func TestRun_Alternatives(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Seed(1)
+	s.Alternatives = 3
+	if err := s.Run(context.Background(), AlgorithmGreedy, 1); !errors.Is(err, ErrAlternativesNeedOptimal) {
+		t.Fatalf("Expected ErrAlternativesNeedOptimal, got %v", err)
+	}
+	if err := s.Run(context.Background(), AlgorithmOptimal, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{shifts["s1"].Assigned[0]: true}
+	for i := 1; s.UseAlternative(i); i++ {
+		if len(shifts["s1"].Assigned) != 1 {
+			t.Fatalf("Expected alternative %d to fill s1, got %v", i, shifts["s1"].Assigned)
+		}
+		seen[shifts["s1"].Assigned[0]] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected three distinct schedules, got %v", seen)
+	}
+}
+
+func TestAssignOptimal_FairnessWeight(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s3": {ID: "s3", Start: start.Add(4 * time.Hour), End: start.Add(6 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	// Only 2 of the 8 full schedules split the 4 hours evenly
+	s := NewScheduler(volunteers, shifts)
+	s.Seed(3)
+	s.FairnessWeight = 0.5
+	if err := s.Run(context.Background(), AlgorithmOptimal, 1); err != nil {
+		t.Fatal(err)
+	}
+	if s.FillRate() != 1 {
+		t.Errorf("Expected every slot filled, got fill rate %v", s.FillRate())
+	}
+	if volunteers["v1"].AssignedHours != 2 || volunteers["v2"].AssignedHours != 2 {
+		t.Errorf("Expected 2 hours each, got %v and %v", volunteers["v1"].AssignedHours, volunteers["v2"].AssignedHours)
+	}
+	if s.Score() != 1 {
+		t.Errorf("Expected a perfect score, got %v", s.Score())
+	}
+	if err := ValidateScoreWeights(1, -1); !errors.Is(err, ErrInvalidScoreWeights) {
+		t.Errorf("Expected ErrInvalidScoreWeights, got %v", err)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		// Needs two volunteers, so no pass is ever perfect
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 2}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, algorithm := range []string{AlgorithmOptimal, AlgorithmBranchAndBound, AlgorithmAnneal} {
+		began := time.Now()
+		s := NewScheduler(volunteers, shifts)
+		if err := s.Run(ctx, algorithm, MaxTimeoutSeconds); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", algorithm, err)
+		}
+		if elapsed := time.Since(began); elapsed > time.Second {
+			t.Errorf("%s: expected cancellation to stop the run, took %v", algorithm, elapsed)
+		}
+		shifts["s1"].Assigned = nil
+		volunteers["v1"].AssignedShifts = nil
+		volunteers["v1"].AssignedHours = 0
+	}
+}
+
+func TestKeepPrevious_MinimizesChurn(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+	previous := []models.Assignment{
+		{ShiftID: "s1", VolunteerID: "v2"},
+		{ShiftID: "gone", VolunteerID: "v1"},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	dropped := s.KeepPrevious(previous)
+	s.AssignSimple(true)
+
+	if len(dropped) != 1 || dropped[0].ShiftID != "gone" {
+		t.Errorf("Expected only the assignment to the removed shift to be dropped, got %v", dropped)
+	}
+	if shifts["s1"].Assigned[0] != "v2" {
+		t.Errorf("Expected v2 to keep s1, got %v", shifts["s1"].Assigned)
+	}
+
+	churn := s.Churn(previous)
+	if churn.Kept != 1 || churn.Removed != 1 || churn.Added != 1 {
+		t.Errorf("Expected kept=1 removed=1 added=1, got %+v", churn)
+	}
+}
+
+func TestAssignBranchAndBound_BeatsGreedy(t *testing.T) {
+	// Greedy gives s1 to whichever of v1/v2 it sees first; only v1 can also
+	// work s2, so a bad pick leaves s2 unfilled. The search must fill both.
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}, ExcludedGroups: []string{"B"}},
+	}
+	volunteers["v2"].Groups = []string{"B"}
+
+	s := NewScheduler(volunteers, shifts)
+	if !s.AssignBranchAndBound(context.Background(), time.Second) {
+		t.Fatalf("Expected search to finish within the timeout")
+	}
+
+	if len(shifts["s1"].Assigned) != 1 || len(shifts["s2"].Assigned) != 1 {
+		t.Fatalf("Expected both shifts filled, got s1=%v s2=%v", shifts["s1"].Assigned, shifts["s2"].Assigned)
+	}
+	if shifts["s2"].Assigned[0] != "v1" {
+		t.Errorf("Expected v1 on s2, got %s", shifts["s2"].Assigned[0])
+	}
+	if len(s.Conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", s.Conflicts)
+	}
+}
+
+func TestAssignAnneal_FillsAndKeepsPrefill(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", Groups: []string{"B"}, MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "A", MaxHours: 10},
+	}
+
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}, ExcludedGroups: []string{"B"}},
+		"s3": {ID: "s3", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{{ShiftID: "s3", VolunteerID: "v3"}})
+	s.AssignAnneal(context.Background(), 200*time.Millisecond)
+
+	for id, sh := range shifts {
+		if len(sh.Assigned) != 1 {
+			t.Errorf("Expected %s to be filled, got %v", id, sh.Assigned)
+		}
+	}
+	if shifts["s3"].Assigned[0] != "v3" {
+		t.Errorf("Expected prefilled v3 to stay on s3, got %v", shifts["s3"].Assigned)
+	}
+}
+
+func TestAssignSimple_SeedIsDeterministic(t *testing.T) {
+	run := func() map[string][]string {
+		volunteers := map[string]*models.Volunteer{}
+		shifts := map[string]*models.Shift{}
+		start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+		for i := 0; i < 10; i++ {
+			id := string(rune('a' + i))
+			volunteers[id] = &models.Volunteer{ID: id, Group: "A", MaxHours: 4}
+			shifts[id] = &models.Shift{ID: id, Start: start.Add(time.Duration(i) * time.Hour), End: start.Add(time.Duration(i+2) * time.Hour), RequiredGroups: map[string]int{"A": 2}}
+		}
+
+		s := NewScheduler(volunteers, shifts)
+		s.Seed(42)
+		s.AssignSimple(true)
+
+		out := map[string][]string{}
+		for id, sh := range shifts {
+			out[id] = sh.Assigned
+		}
+		return out
+	}
+
+	first := run()
+	for i := 0; i < 5; i++ {
+		next := run()
+		for id := range first {
+			if strings.Join(first[id], ",") != strings.Join(next[id], ",") {
+				t.Fatalf("Expected identical assignments for shift %s, got %v and %v", id, first[id], next[id])
+			}
+		}
+	}
+}
+
+func TestAssignSimple_MaxHoursRatio(t *testing.T) {
+	// Only Alice can work these shifts, but with four 2h slots across two
+	// volunteers the mean is 4h, so a 1.5x cap stops her at 6h
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", Groups: []string{"B"}, MaxHours: 100},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 100},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s0": {ID: "s0", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"B": 1}},
+		"s1": {ID: "s1", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"B": 1}},
+		"s2": {ID: "s2", Start: start.Add(6 * time.Hour), End: start.Add(8 * time.Hour), RequiredGroups: map[string]int{"B": 1}},
+		"s3": {ID: "s3", Start: start.Add(9 * time.Hour), End: start.Add(11 * time.Hour), RequiredGroups: map[string]int{"B": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.MaxHoursRatio = 1.5
+	s.AssignSimple(false)
+
+	if got := volunteers["v1"].AssignedHours; got != 6 {
+		t.Errorf("Expected Alice capped at 6 hours, got %f", got)
+	}
+	if len(s.Conflicts) != 1 || !strings.Contains(strings.Join(s.Conflicts[0].Reasons, " "), "fairness cap") {
+		t.Errorf("Expected one fairness cap conflict, got %+v", s.Conflicts)
+	}
+	if err := ValidateMaxHoursRatio(0.5); err == nil {
+		t.Error("Expected a ratio below 1 to be rejected")
+	}
+}
+
+func TestFairnessByDimension(t *testing.T) {
+	// Saturday 2026-01-10: both work one day and one night shift, but only Alice works the weekend
+	sat := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	mon := sat.AddDate(0, 0, 2)
+	shifts := map[string]*models.Shift{
+		"sat_day":   {ID: "sat_day", Start: sat, End: sat.Add(4 * time.Hour)},
+		"sat_night": {ID: "sat_night", Start: sat.Add(13 * time.Hour), End: sat.Add(17 * time.Hour)},
+		"mon_day":   {ID: "mon_day", Start: mon, End: mon.Add(4 * time.Hour)},
+		"mon_night": {ID: "mon_night", Start: mon.Add(13 * time.Hour), End: mon.Add(17 * time.Hour)},
+	}
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Group: "A", AssignedHours: 8, AssignedShifts: []string{"sat_day", "sat_night"}},
+		"v2": {ID: "v2", Group: "A", AssignedHours: 8, AssignedShifts: []string{"mon_day", "mon_night"}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.FairnessMetric = FairnessMinMax
+
+	got := s.FairnessByDimension([]string{DimensionHours, DimensionShifts, DimensionNight, DimensionWeekend})
+	want := map[string]float64{DimensionHours: 100, DimensionShifts: 100, DimensionNight: 100, DimensionWeekend: 0}
+	for d, score := range want {
+		if got[d] != score {
+			t.Errorf("Expected %s score %f, got %f", d, score, got[d])
+		}
+	}
+	if err := ValidateFairnessDimensions([]string{"holidays"}); !errors.Is(err, ErrUnknownFairnessDimension) {
+		t.Errorf("Expected ErrUnknownFairnessDimension, got %v", err)
+	}
+}
+
+func TestFairnessMetrics(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Group: "A", AssignedHours: 0},
+		"v2": {ID: "v2", Group: "A", AssignedHours: 4},
+		"v3": {ID: "v3", Group: "B", AssignedHours: 4},
+		"v4": {ID: "v4", Group: "B", AssignedHours: 4},
+	}
+	s := NewScheduler(volunteers, map[string]*models.Shift{})
+
+	if got := s.FairnessScoreFor(FairnessMinMax); got != 0 {
+		t.Errorf("Expected min-max score 0 when someone has no hours, got %f", got)
+	}
+	if got := s.FairnessScoreFor(FairnessGini); math.Abs(got-75.0) > 1e-9 {
+		t.Errorf("Expected Gini score 75, got %f", got)
+	}
+	groups := s.GroupFairness()
+	if groups["B"] != 100.0 || groups["A"] != 0.0 {
+		t.Errorf("Expected group B perfectly fair and group A not, got %v", groups)
+	}
+	if got := s.FairnessScoreFor(FairnessPerGroup); got != 50.0 {
+		t.Errorf("Expected per-group score 50, got %f", got)
+	}
+}
+
+func TestRoleAssignments(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	input := models.ScheduleInput{
+		Volunteers: []models.Volunteer{
+			{ID: "v1", Name: "Alice", Group: "staff", MaxHours: 10},
+			{ID: "v2", Name: "Bob", Group: "staff", Groups: []string{"lead"}, MaxHours: 10},
+			{ID: "v3", Name: "Cara", Group: "staff", MaxHours: 10},
+		},
+		UnassignedShifts: []models.Shift{{
+			ID: "s1", Start: start, End: start.Add(2 * time.Hour),
+			Roles: []models.ShiftRole{{Name: "lead", Group: "lead", Count: 1}, {Name: "helper", Group: "staff", Count: 2}},
+		}},
+	}
+	if err := input.ExpandRoles(); err != nil {
+		t.Fatal(err)
+	}
+	if got := input.UnassignedShifts[0].RequiredGroups; got["lead"] != 1 || got["staff"] != 2 {
+		t.Fatalf("Expected required groups derived from roles, got %v", got)
+	}
+	// Expanding again is a no-op
+	if err := input.ExpandRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScheduler(
+		map[string]*models.Volunteer{"v1": &input.Volunteers[0], "v2": &input.Volunteers[1], "v3": &input.Volunteers[2]},
+		map[string]*models.Shift{"s1": &input.UnassignedShifts[0]},
+	)
+	s.AssignSimple(false)
+
+	roles := s.RoleAssignments()["s1"]
+	if !slices.Equal(roles["lead"], []string{"v2"}) {
+		t.Errorf("Expected Bob as lead, got %v", roles)
+	}
+	helpers := slices.Sorted(slices.Values(roles["helper"]))
+	if !slices.Equal(helpers, []string{"v1", "v3"}) {
+		t.Errorf("Expected Alice and Cara as helpers, got %v", roles)
+	}
+
+	input.UnassignedShifts[0].RequiredGroups = map[string]int{"staff": 3}
+	if err := input.ExpandRoles(); err == nil {
+		t.Error("Expected required_groups that disagree with roles to be rejected")
+	}
+	input.UnassignedShifts[0].Roles = append(input.UnassignedShifts[0].Roles, models.ShiftRole{Name: "lead", Group: "staff", Count: 1})
+	input.UnassignedShifts[0].RequiredGroups = nil
+	if err := input.ExpandRoles(); err == nil {
+		t.Error("Expected a duplicate role name to be rejected")
+	}
+}
+
+func TestConflictSummary(t *testing.T) {
+	// Two drivers can't cover four simultaneous driver slots, and nobody is
+	// in the medic group at all
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "driver", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "driver", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"driver": 3, "medic": 1}},
+		"s2": {ID: "s2", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"driver": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	summary := s.ConflictSummary()
+	if len(summary) != 2 {
+		t.Fatalf("Expected two causes, got %+v", summary)
+	}
+	driver := summary[0]
+	if driver.Cause != CauseGroupSize || driver.Count != 2 || !slices.Equal(driver.Shifts, []string{"s1", "s2"}) ||
+		driver.Summary != `2 slots unfilled because group "driver" has only 2 volunteers` {
+		t.Errorf("Expected 2 driver slots across s1 and s2 first, got %+v", driver)
+	}
+	if summary[1].Cause != CauseGroupSize || summary[1].Summary != `1 slots unfilled because group "medic" has no volunteers` {
+		t.Errorf("Expected the medic slot blamed on an empty group, got %+v", summary[1])
+	}
+}
+
+func TestLinkedShifts(t *testing.T) {
+	// Alice only has time for one of setup and teardown, so Bob works both
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 1.5},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"setup":    {ID: "setup", Start: start, End: start.Add(time.Hour), RequiredGroups: map[string]int{"A": 1}, LinkedShifts: []string{"teardown"}},
+		"teardown": {ID: "teardown", Start: start.Add(8 * time.Hour), End: start.Add(9 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	if err := s.ValidateLinks(); err != nil {
+		t.Fatal(err)
+	}
+	s.AssignSimple(false)
+	if !slices.Equal(shifts["setup"].Assigned, []string{"v2"}) || !slices.Equal(shifts["teardown"].Assigned, []string{"v2"}) {
+		t.Errorf("Expected Bob on setup and teardown, got %v and %v", shifts["setup"].Assigned, shifts["teardown"].Assigned)
+	}
+
+	// A prefill that can't be completed is taken off the unit
+	shifts["setup"].Assigned, shifts["teardown"].Assigned = nil, nil
+	volunteers["v2"].AssignedHours, volunteers["v2"].AssignedShifts = 0, nil
+	s = NewScheduler(volunteers, shifts)
+	s.Prefill([]models.Assignment{{ShiftID: "teardown", VolunteerID: "v1"}})
+	s.EnforceLinks()
+	if len(shifts["teardown"].Assigned) != 0 || volunteers["v1"].AssignedHours != 0 {
+		t.Errorf("Expected Alice taken off teardown, got %v", shifts["teardown"].Assigned)
+	}
+	if len(s.Conflicts) != 1 || s.Conflicts[0].Cause != "unlinked" {
+		t.Errorf("Expected one unlinked conflict, got %+v", s.Conflicts)
+	}
+
+	shifts["teardown"].LinkedShifts = []string{"cleanup"}
+	if err := s.ValidateLinks(); !errors.Is(err, ErrUnknownShift) {
+		t.Errorf("Expected an unknown linked shift to be rejected, got %v", err)
+	}
+}
+
+func TestAssignOptimal_Hints(t *testing.T) {
+	// Greedy would pick either; the hint picks Bob, and a full hinted
+	// schedule is kept without searching
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Seed(1)
+	s.Hints = []models.Assignment{{ShiftID: "s1", VolunteerID: "v2"}, {ShiftID: "s2", VolunteerID: "ghost"}}
+	started := time.Now()
+	s.AssignOptimal(context.Background(), 5)
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected a full hinted schedule to skip the search, took %v", elapsed)
+	}
+	if !slices.Equal(shifts["s1"].Assigned, []string{"v2"}) {
+		t.Errorf("Expected the hint to put Bob on s1, got %v", shifts["s1"].Assigned)
+	}
+	if len(shifts["s2"].Assigned) != 1 {
+		t.Errorf("Expected s2 filled despite its unknown hint, got %v", shifts["s2"].Assigned)
+	}
+}
+
+func TestAssignOptimal_ImprovesHints(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s2": {ID: "s2", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+		"s3": {ID: "s3", Start: start.Add(4 * time.Hour), End: start.Add(6 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+
+	// The hints fill every slot but give Alice all 4 hours; the search
+	// starts from them and only accepts passes at least as good
+	s := NewScheduler(volunteers, shifts)
+	s.Seed(1)
+	s.FairnessWeight = 0.5
+	s.Hints = []models.Assignment{
+		{ShiftID: "s1", VolunteerID: "v1"},
+		{ShiftID: "s2", VolunteerID: "v1"},
+		{ShiftID: "s3", VolunteerID: "v1"},
+	}
+	s.AssignOptimal(context.Background(), 2)
+
+	if s.FillRate() != 1 {
+		t.Errorf("Expected every slot to stay filled, got fill rate %v", s.FillRate())
+	}
+	if volunteers["v1"].AssignedHours != 2 || volunteers["v2"].AssignedHours != 2 {
+		t.Errorf("Expected the hinted schedule improved to 2 hours each, got %v and %v", volunteers["v1"].AssignedHours, volunteers["v2"].AssignedHours)
+	}
+}
+
+func TestWouldOverlap_TravelTime(t *testing.T) {
+	vol := &models.Volunteer{ID: "v1", Name: "Alice", Group: "A", MaxHours: 10}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"north": {ID: "north", Start: start, End: start.Add(2 * time.Hour), Location: "north_hall"},
+		// 30 minutes after north ends, but 40 minutes away
+		"river": {ID: "river", Start: start.Add(150 * time.Minute), End: start.Add(4 * time.Hour), Location: "riverside"},
+		// Same gap at the same venue
+		"annex": {ID: "annex", Start: start.Add(150 * time.Minute), End: start.Add(4 * time.Hour), Location: "north_hall"},
+		// An hour after north ends, across town
+		"late": {ID: "late", Start: start.Add(3 * time.Hour), End: start.Add(5 * time.Hour), Location: "riverside"},
+	}
+	s := NewScheduler(map[string]*models.Volunteer{"v1": vol}, shifts)
+	s.TravelMinutes = map[string]map[string]int{"riverside": {"north_hall": 40}}
+	s.assign(vol, shifts["north"], 2)
+
+	if !s.WouldOverlap(vol, shifts["river"]) {
+		t.Error("Expected a 30 minute gap to be too short for a 40 minute trip")
+	}
+	if s.WouldOverlap(vol, shifts["annex"]) || s.WouldOverlap(vol, shifts["late"]) {
+		t.Error("Expected shifts at the same venue or with time to travel not to overlap")
+	}
+	if err := ValidateTravelMinutes(map[string]map[string]int{"a": {"b": -5}}); !errors.Is(err, ErrInvalidTravelMinutes) {
+		t.Errorf("Expected negative travel time to be rejected, got %v", err)
+	}
+}
+
+func TestShiftAssignments(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "medic", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "driver", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "cook", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"medic": 1, "driver": 1}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	// Cara fills no requirement, so she comes last
+	s.Prefill([]models.Assignment{{ShiftID: "s1", VolunteerID: "v3"}, {ShiftID: "s1", VolunteerID: "v1"}, {ShiftID: "s1", VolunteerID: "v2"}})
+
+	want := []models.ShiftAssignment{
+		{VolunteerID: "v2", Group: "driver", Role: "driver"},
+		{VolunteerID: "v1", Group: "medic", Role: "medic"},
+		{VolunteerID: "v3"},
+	}
+	if got := s.ShiftAssignments()["s1"]; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestAssignSimple_Certifications(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "medic", MaxHours: 10, Certifications: []models.Certification{{Name: "first_aid", Expires: "2026-01-09"}}},
+		"v2": {ID: "v2", Name: "Bob", Group: "medic", MaxHours: 10},
+		"v3": {ID: "v3", Name: "Cara", Group: "medic", MaxHours: 10, Certifications: []models.Certification{{Name: "first_aid", Expires: "2026-01-10"}}},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"medic": 2}, RequiredCertifications: []string{"first_aid"}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	// Cara's certification is valid through the day of the shift
+	if got := shifts["s1"].Assigned; !slices.Equal(got, []string{"v3"}) {
+		t.Fatalf("Expected only v3 to be assigned, got %v", got)
+	}
+	if len(s.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(s.Conflicts))
+	}
+	reasons := s.Conflicts[0].Reasons
+	if !slices.Contains(reasons, "1 volunteers lacked a required certification") ||
+		!slices.Contains(reasons, "1 volunteers had a required certification expire before the shift") {
+		t.Errorf("Expected missing and expired certifications to be reported separately, got %v", reasons)
+	}
+
+	bad := NewScheduler(map[string]*models.Volunteer{
+		"v1": {ID: "v1", Certifications: []models.Certification{{Name: "first_aid", Expires: "10/01/2026"}}},
+	}, shifts)
+	if err := bad.ValidateCertifications(); err == nil {
+		t.Error("Expected an invalid expiry date to be rejected")
+	}
+}
+
+func TestWouldOverlap_BusyTimes(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	vol := &models.Volunteer{ID: "v1", Name: "Alice", Group: "A", MaxHours: 10, BusyTimes: []models.TimeWindow{
+		{Start: start, End: start.Add(8 * time.Hour)},
+	}}
+	shifts := map[string]*models.Shift{
+		"day":     {ID: "day", Start: start.Add(2 * time.Hour), End: start.Add(4 * time.Hour)},
+		"evening": {ID: "evening", Start: start.Add(8 * time.Hour), End: start.Add(10 * time.Hour)},
+	}
+	s := NewScheduler(map[string]*models.Volunteer{"v1": vol}, shifts)
+
+	if !s.WouldOverlap(vol, shifts["day"]) {
+		t.Error("Expected a shift during a busy time to overlap")
+	}
+	if s.WouldOverlap(vol, shifts["evening"]) {
+		t.Error("Expected a shift after a busy time not to overlap")
+	}
+	vol.BusyTimes = append(vol.BusyTimes, models.TimeWindow{Start: start, End: start})
+	if err := s.ValidateBusyTimes(); err == nil {
+		t.Error("Expected an empty busy time to be rejected")
+	}
+}
+
+func TestAssignSimple_Tiers(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"a": {ID: "a", Name: "Alice", Group: "A", MaxHours: 10, Tier: TierStandby},
+		"b": {ID: "b", Name: "Bob", Group: "A", MaxHours: 10},
+		"c": {ID: "c", Name: "Cara", Group: "A", MaxHours: 10, Tier: TierCore},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(2 * time.Hour), RequiredGroups: map[string]int{"A": 2}},
+		"s2": {ID: "s2", Start: start.Add(time.Hour), End: start.Add(3 * time.Hour), RequiredGroups: map[string]int{"A": 1}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	got := slices.Sorted(slices.Values(shifts["s1"].Assigned))
+	if !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("Expected core and regular volunteers on s1, got %v", got)
+	}
+	// Only the standby volunteer is free for the overlapping shift
+	if !slices.Equal(shifts["s2"].Assigned, []string{"a"}) {
+		t.Errorf("Expected the standby volunteer on s2, got %v", shifts["s2"].Assigned)
+	}
+	if n := s.StandbyAssignments(); n != 1 {
+		t.Errorf("Expected 1 standby assignment, got %d", n)
+	}
+
+	volunteers["a"].Tier = "reserve"
+	if err := s.ValidateTiers(); !errors.Is(err, ErrUnknownTier) {
+		t.Errorf("Expected unknown tier to be rejected, got %v", err)
+	}
+}
+
+func TestAssignSimple_MaxShifts(t *testing.T) {
+	// Bob has no hour limit, only a shift limit
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Bob", Group: "A", MaxShifts: 2},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := make(map[string]*models.Shift)
+	for i := range 3 {
+		id := fmt.Sprintf("s%d", i+1)
+		day := start.AddDate(0, 0, i)
+		shifts[id] = &models.Shift{ID: id, Start: day, End: day.Add(time.Duration(4+i) * time.Hour), RequiredGroups: map[string]int{"A": 1}}
+	}
+	s := NewScheduler(volunteers, shifts)
+	s.AssignSimple(false)
+
+	if got := volunteers["v1"].AssignedShifts; len(got) != 2 {
+		t.Fatalf("Expected 2 shifts, got %v", got)
+	}
+	if len(s.Conflicts) != 1 || !slices.Contains(s.Conflicts[0].Reasons, "1 volunteers were at their max shifts") {
+		t.Errorf("Expected the third shift to report the shift limit, got %+v", s.Conflicts)
+	}
+	if !slices.Contains(s.ComplianceReport().Evaluated, "max_shifts") {
+		t.Error("Expected max_shifts in the compliance report")
+	}
+}
+
+func TestAssignSimple_MinimizeHeadcount(t *testing.T) {
+	build := func(objective string) *Scheduler {
+		volunteers := make(map[string]*models.Volunteer)
+		for i := range 3 {
+			id := fmt.Sprintf("v%d", i+1)
+			volunteers[id] = &models.Volunteer{ID: id, Name: id, Group: "A", MaxHours: 40}
+		}
+		start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+		shifts := make(map[string]*models.Shift)
+		for i := range 3 {
+			id := fmt.Sprintf("s%d", i+1)
+			day := start.AddDate(0, 0, i)
+			shifts[id] = &models.Shift{ID: id, Start: day, End: day.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 1}}
+		}
+		s := NewScheduler(volunteers, shifts)
+		s.Objective = objective
+		return s
+	}
+
+	s := build("")
+	s.AssignSimple(false)
+	if got := s.Headcount(); got != 3 {
+		t.Errorf("Expected maximize_participation to use 3 volunteers, got %d", got)
+	}
+
+	s = build(ObjectiveMinimizeHeadcount)
+	s.AssignSimple(false)
+	if got := s.Headcount(); got != 1 {
+		t.Errorf("Expected minimize_headcount to use 1 volunteer, got %d", got)
+	}
+	if s.filledSlots() != 3 {
+		t.Errorf("Expected every shift filled, got %d", s.filledSlots())
+	}
+
+	s = build(ObjectiveMinimizeHeadcount)
+	s.AssignBranchAndBound(context.Background(), time.Second)
+	if got := s.Headcount(); got != 1 {
+		t.Errorf("Expected branch and bound to use 1 volunteer, got %d", got)
+	}
+
+	if err := ValidateObjective("fewest"); !errors.Is(err, ErrUnknownObjective) {
+		t.Errorf("Expected ErrUnknownObjective, got %v", err)
+	}
+}
+
+func TestAssignSimple_ConsecutiveWeight(t *testing.T) {
+	build := func(weight float64) *Scheduler {
+		volunteers := map[string]*models.Volunteer{
+			"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 20},
+			"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 20},
+		}
+		morning := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+		shifts := map[string]*models.Shift{
+			"s1": {ID: "s1", Start: morning, End: morning.Add(3 * time.Hour), Location: "hall", RequiredGroups: map[string]int{"A": 1}},
+			"s2": {ID: "s2", Start: morning.Add(3 * time.Hour), End: morning.Add(6 * time.Hour), Location: "hall", RequiredGroups: map[string]int{"A": 1}},
+		}
+		s := NewScheduler(volunteers, shifts)
+		s.ConsecutiveWeight = weight
+		return s
+	}
+
+	s := build(0)
+	s.AssignSimple(false)
+	if s.Shifts["s1"].Assigned[0] == s.Shifts["s2"].Assigned[0] {
+		t.Errorf("Expected the shifts spread without a consecutive weight, got %v twice", s.Shifts["s1"].Assigned)
+	}
+
+	s = build(5)
+	s.AssignSimple(false)
+	if s.Shifts["s1"].Assigned[0] != s.Shifts["s2"].Assigned[0] {
+		t.Errorf("Expected one volunteer to work both shifts, got %v and %v", s.Shifts["s1"].Assigned, s.Shifts["s2"].Assigned)
+	}
+
+	// A different location isn't a block
+	s = build(5)
+	s.Shifts["s2"].Location = "riverside"
+	s.AssignSimple(false)
+	if s.Shifts["s1"].Assigned[0] == s.Shifts["s2"].Assigned[0] {
+		t.Errorf("Expected shifts at different locations spread, got %v twice", s.Shifts["s1"].Assigned)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 10},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 2},
+		"v3": {ID: "v3", Name: "Cara", Group: "B", MaxHours: 10},
+	}
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shifts := map[string]*models.Shift{
+		"s1": {ID: "s1", Start: start, End: start.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 2}},
+	}
+	s := NewScheduler(volunteers, shifts)
+	forecast := s.Forecast()
+
+	if len(forecast) != 1 {
+		t.Fatalf("Expected one forecast entry, got %+v", forecast)
+	}
+	f := forecast[0]
+	if f.Required != 2 || f.Eligible != 1 || f.Gap != 1 {
+		t.Errorf("Expected 2 required, 1 eligible and a gap of 1, got %+v", f)
+	}
+	if f.ExcludedBy["max_hours"] != 1 {
+		t.Errorf("Expected Bob excluded by max_hours, got %v", f.ExcludedBy)
+	}
+	if len(volunteers["v1"].AssignedShifts) != 0 {
+		t.Error("Expected the forecast not to assign anyone")
+	}
+}
+
+func TestRun_Chunked(t *testing.T) {
+	for _, chunk := range []string{ChunkDay, ChunkWeek} {
+		volunteers := map[string]*models.Volunteer{
+			"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 12},
+			"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 12},
+			"v3": {ID: "v3", Name: "Cara", Group: "B", MaxHours: 40},
+		}
+		start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+		shifts := make(map[string]*models.Shift)
+		for i := range 5 {
+			id := fmt.Sprintf("s%d", i+1)
+			day := start.AddDate(0, 0, i)
+			shifts[id] = &models.Shift{ID: id, Start: day, End: day.Add(6 * time.Hour), RequiredGroups: map[string]int{"A": 1}}
+		}
+		// These overlap across midnight, so fall in different days
+		late := time.Date(2026, 1, 10, 22, 0, 0, 0, time.UTC)
+		shifts["late"] = &models.Shift{ID: "late", Start: late, End: late.Add(4 * time.Hour), RequiredGroups: map[string]int{"B": 1}}
+		shifts["early"] = &models.Shift{ID: "early", Start: late.Add(3 * time.Hour), End: late.Add(7 * time.Hour), RequiredGroups: map[string]int{"B": 1}}
+
+		s := NewScheduler(volunteers, shifts)
+		s.Chunk = chunk
+		if err := s.Run(context.Background(), AlgorithmOptimal, 1); err != nil {
+			t.Fatalf("%s: %v", chunk, err)
+		}
+
+		// Hours carry across chunks: 30 hours of A shifts, 24 allowed
+		if volunteers["v1"].AssignedHours > 12 || volunteers["v2"].AssignedHours > 12 {
+			t.Errorf("%s: expected max_hours kept across chunks, got %v and %v", chunk, volunteers["v1"].AssignedHours, volunteers["v2"].AssignedHours)
+		}
+		if got := s.filledSlots(); got != 5 {
+			t.Errorf("%s: expected 5 slots filled, got %d", chunk, got)
+		}
+		if len(volunteers["v3"].AssignedShifts) != 1 {
+			t.Errorf("%s: expected Cara on one of the overlapping shifts, got %v", chunk, volunteers["v3"].AssignedShifts)
+		}
+		if len(s.Conflicts) != 2 {
+			t.Errorf("%s: expected conflicts for the two slots left open, got %+v", chunk, s.Conflicts)
+		}
+	}
+
+	if err := ValidateChunk("month"); !errors.Is(err, ErrUnknownChunk) {
+		t.Errorf("Expected ErrUnknownChunk, got %v", err)
+	}
+}
+
+// TestRun_ChunkedWindow checks that shifts which fall out of the chunks'
+// lookback still count towards category limits
+func TestRun_ChunkedWindow(t *testing.T) {
+	volunteers := map[string]*models.Volunteer{
+		"v1": {ID: "v1", Name: "Alice", Group: "A", MaxHours: 100, CategoryLimits: map[string]int{"night": 1}},
+		"v2": {ID: "v2", Name: "Bob", Group: "A", MaxHours: 100, CategoryLimits: map[string]int{"night": 1}},
+	}
+	start := time.Date(2026, 1, 5, 22, 0, 0, 0, time.UTC)
+	shifts := make(map[string]*models.Shift)
+	for i := range 6 {
+		id := fmt.Sprintf("n%d", i+1)
+		night := start.AddDate(0, 0, 14*i)
+		shifts[id] = &models.Shift{ID: id, Start: night, End: night.Add(8 * time.Hour), RequiredGroups: map[string]int{"A": 1}, Category: "night"}
+	}
+
+	s := NewScheduler(volunteers, shifts)
+	s.Chunk = ChunkWeek
+	if lookback := s.chunkLookback(); lookback >= 14*24*time.Hour {
+		t.Fatalf("Expected a lookback shorter than the gap between shifts, got %v", lookback)
+	}
+	if err := s.Run(context.Background(), AlgorithmOptimal, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.filledSlots(); got != 2 {
+		t.Errorf("Expected one night each, 2 slots filled, got %d", got)
+	}
+	if s.pastCategories != nil {
+		t.Error("Expected the category tally to be cleared after the run")
+	}
+}
+
+func TestPickCandidate_Temperature(t *testing.T) {
+	start := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	shift := &models.Shift{ID: "s1", Start: start, End: start.Add(4 * time.Hour), RequiredGroups: map[string]int{"A": 1}}
+	build := func(temperature float64) (*Scheduler, []*models.Volunteer) {
+		// Alice is an hour less busy than Bob; Cara is on standby
+		vols := []*models.Volunteer{
+			{ID: "v1", Name: "Alice", Group: "A", MaxHours: 40, AssignedHours: 1},
+			{ID: "v2", Name: "Bob", Group: "A", MaxHours: 40, AssignedHours: 2},
+			{ID: "v3", Name: "Cara", Group: "A", MaxHours: 40, Tier: TierStandby},
+		}
+		volunteers := make(map[string]*models.Volunteer)
+		for _, v := range vols {
+			volunteers[v.ID] = v
+		}
+		s := NewScheduler(volunteers, map[string]*models.Shift{"s1": shift})
+		s.Temperature = temperature
+		s.Seed(1)
+		return s, vols
+	}
+
+	picks := make(map[string]int)
+	s, vols := build(0)
+	for range 200 {
+		best, _ := s.pickCandidate(shift, 4, vols)
+		picks[best.ID]++
+	}
+	if picks["v1"] != 200 {
+		t.Errorf("Expected Alice every time without a temperature, got %v", picks)
+	}
+
+	clear(picks)
+	s, vols = build(1)
+	for range 2000 {
+		best, _ := s.pickCandidate(shift, 4, vols)
+		picks[best.ID]++
+	}
+	// Bob is an hour behind, so picked about 1/e as often as Alice
+	if picks["v2"] < 400 || picks["v2"] > 700 || picks["v1"] <= picks["v2"] {
+		t.Errorf("Expected Bob picked about 27%% of the time, got %v", picks)
+	}
+	if picks["v3"] != 0 {
+		t.Errorf("Expected standby never drawn over regulars, got %v", picks)
+	}
+
+	if err := ValidateTemperature(-1); !errors.Is(err, ErrInvalidTemperature) {
+		t.Errorf("Expected ErrInvalidTemperature, got %v", err)
+	}
+}
+
+func TestTuneFor(t *testing.T) {
+	tests := []struct {
+		cpus, workers, timeout int
+	}{
+		{0, 1, 2 * DefaultTimeoutSeconds},
+		{1, 1, 2 * DefaultTimeoutSeconds},
+		{3, 3, DefaultTimeoutSeconds * 4 / 3},
+		{16, 16, DefaultTimeoutSeconds},
+	}
+	for _, tt := range tests {
+		got := TuneFor(tt.cpus)
+		if got.Workers != tt.workers || got.DefaultTimeoutSeconds != tt.timeout {
+			t.Errorf("TuneFor(%d) = %+v, expected %d workers and %ds", tt.cpus, got, tt.workers, tt.timeout)
+		}
+	}
+
+	t.Cleanup(func() { SetTuning(Tuning{}) })
+	SetTuning(Tuning{Workers: 3})
+	if got := CurrentTuning(); got.Workers != 3 || got.DefaultTimeoutSeconds != DefaultTimeoutSeconds {
+		t.Errorf("Expected 3 workers and the default timeout, got %+v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"math/bits"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// bitset is a fixed-size set of positions in a volunteer list
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+// members appends the volunteers at the set positions to dst, in list order
+func (b bitset) members(vols []*models.Volunteer, dst []*models.Volunteer) []*models.Volunteer {
+	for w, word := range b {
+		for word != 0 {
+			dst = append(dst, vols[w*64+bits.TrailingZeros64(word)])
+			word &= word - 1
+		}
+	}
+	return dst
+}
+
+// screen marks the volunteers that pass the checks which can't change during
+// a run: group rules, certifications, and availability unless it is a soft
+// constraint.
+// Everyone else can be skipped for every slot on the shift.
+func (s *Scheduler) screen(shift *models.Shift, vols []*models.Volunteer) bitset {
+	_, softAvailability := s.SoftConstraints["availability"]
+	b := newBitset(len(vols))
+	for i, vol := range vols {
+		if s.Allows(shift, vol) && s.HasCertifications(vol, shift) && s.CertificationsCurrent(vol, shift) && (softAvailability || s.IsAvailable(vol, shift)) {
+			b.set(i)
+		}
+	}
+	return b
+}
@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// SoftConstraintPreferences penalizes placing a volunteer with preferences on
+// a shift they don't prefer. The other soft constraints share their
+// Eligibility names.
+const SoftConstraintPreferences = "preferences"
+
+// softenable lists the constraints that can be made soft
+var softenable = []string{SoftConstraintPreferences, "availability", "min_rest_hours", "day_limits", "max_hours_per_week", "category_limits", "max_hours_ratio"}
+
+// ErrUnknownSoftConstraint is returned for a soft constraint that doesn't exist or can't be softened
+var ErrUnknownSoftConstraint = errors.New("unknown soft constraint")
+
+// ValidateSoftConstraints checks soft constraint names and weights
+func ValidateSoftConstraints(weights map[string]float64) error {
+	for name, weight := range weights {
+		if !slices.Contains(softenable, name) {
+			return fmt.Errorf("%w: %q", ErrUnknownSoftConstraint, name)
+		}
+		if weight < 0 {
+			return fmt.Errorf("soft constraint %q: weight must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// flag returns the Eligibility field for a constraint name, or nil
+func (e *Eligibility) flag(name string) *bool {
+	switch name {
+	case "availability":
+		return &e.IsAvailable
+	case "min_rest_hours":
+		return &e.RestOK
+	case "day_limits":
+		return &e.WithinDayLimits
+	case "max_hours_per_week":
+		return &e.WithinWeekCap
+	case "category_limits":
+		return &e.WithinCategory
+	case "max_hours_ratio":
+		return &e.WithinHoursCap
+	}
+	return nil
+}
+
+// softNames returns the scheduler's soft constraints in a stable order
+func (s *Scheduler) softNames() []string {
+	names := make([]string, 0, len(s.SoftConstraints))
+	for name := range s.SoftConstraints {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// brokenSoft lists the soft constraints a raw eligibility check breaks
+func (s *Scheduler) brokenSoft(e *Eligibility, volunteer *models.Volunteer, shift *models.Shift) []string {
+	var broken []string
+	for _, name := range s.softNames() {
+		if name == SoftConstraintPreferences {
+			hasPrefs := len(volunteer.PreferredShifts) > 0 || len(volunteer.PreferredTimes) > 0
+			if hasPrefs && !s.Prefers(volunteer, shift) {
+				broken = append(broken, name)
+			}
+		} else if f := e.flag(name); f != nil && !*f {
+			broken = append(broken, name)
+		}
+	}
+	return broken
+}
+
+// soften lets soft constraints pass, charging their weight as a penalty instead
+func (s *Scheduler) soften(e *Eligibility, volunteer *models.Volunteer, shift *models.Shift) {
+	if len(s.SoftConstraints) == 0 {
+		return
+	}
+	for _, name := range s.brokenSoft(e, volunteer, shift) {
+		if f := e.flag(name); f != nil {
+			*f = true
+		}
+		e.Soft = append(e.Soft, name)
+		e.Penalty += s.SoftConstraints[name]
+	}
+}
+
+// withoutAssignment runs fn with the volunteer temporarily taken off the
+// shift, so an existing assignment can be checked as if it were being made
+func (s *Scheduler) withoutAssignment(vol *models.Volunteer, shift *models.Shift, fn func(duration float64)) {
+	assigned, shifts, hours := shift.Assigned, vol.AssignedShifts, vol.AssignedHours
+	duration := s.DurationHours(shift.Start, shift.End)
+	if slices.Contains(assigned, vol.ID) {
+		shift.Assigned = slices.DeleteFunc(slices.Clone(assigned), func(id string) bool { return id == vol.ID })
+		vol.AssignedShifts = slices.DeleteFunc(slices.Clone(shifts), func(id string) bool { return id == shift.ID })
+		vol.AssignedHours -= duration
+		s.forgetBusyTimes(vol)
+	}
+	fn(duration)
+	shift.Assigned, vol.AssignedShifts, vol.AssignedHours = assigned, shifts, hours
+	s.forgetBusyTimes(vol)
+}
+
+// SoftViolations lists every soft constraint the current assignments break,
+// with the total penalty
+func (s *Scheduler) SoftViolations() ([]models.SoftViolation, float64) {
+	if len(s.SoftConstraints) == 0 {
+		return nil, 0
+	}
+	shiftKeys := make([]string, 0, len(s.Shifts))
+	for id := range s.Shifts {
+		shiftKeys = append(shiftKeys, id)
+	}
+	slices.Sort(shiftKeys)
+
+	var violations []models.SoftViolation
+	total := 0.0
+	for _, shiftID := range shiftKeys {
+		shift := s.Shifts[shiftID]
+		for _, volID := range slices.Clone(shift.Assigned) {
+			vol, ok := s.Volunteers[volID]
+			if !ok {
+				continue
+			}
+			var broken []string
+			s.withoutAssignment(vol, shift, func(duration float64) {
+				e := s.checkConstraints(vol, shift, duration)
+				broken = s.brokenSoft(&e, vol, shift)
+			})
+
+			for _, name := range broken {
+				violations = append(violations, models.SoftViolation{
+					Constraint:  name,
+					ShiftID:     shiftID,
+					VolunteerID: volID,
+					Penalty:     s.SoftConstraints[name],
+				})
+				total += s.SoftConstraints[name]
+			}
+		}
+	}
+	return violations, total
+}
@@ -0,0 +1,322 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Algorithms selectable via the schedule request's "algorithm" field
+const (
+	AlgorithmGreedy         = "greedy"
+	AlgorithmOptimal        = "optimal"
+	AlgorithmBranchAndBound = "branch_and_bound"
+)
+
+// DefaultTimeoutSeconds and MaxTimeoutSeconds bound the search-based
+// algorithms. SetTuning may raise the default on small machines.
+const (
+	DefaultTimeoutSeconds = 5
+	MaxTimeoutSeconds     = 25
+)
+
+// ErrUnknownAlgorithm is returned by Run for an unsupported algorithm name
+var ErrUnknownAlgorithm = errors.New("unknown algorithm")
+
+// ValidateAlgorithm checks an algorithm name. An empty name means greedy.
+func ValidateAlgorithm(algorithm string) error {
+	switch algorithm {
+	case "", AlgorithmGreedy, AlgorithmOptimal, AlgorithmBranchAndBound, AlgorithmAnneal:
+		return nil
+	}
+	return ErrUnknownAlgorithm
+}
+
+// Run assigns volunteers using the named algorithm. An empty name means greedy.
+// If ctx is cancelled first, the search stops early and Run returns ctx's error.
+func (s *Scheduler) Run(ctx context.Context, algorithm string, timeoutSeconds int) error {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = CurrentTuning().DefaultTimeoutSeconds
+	}
+	if timeoutSeconds > MaxTimeoutSeconds {
+		timeoutSeconds = MaxTimeoutSeconds
+	}
+
+	if s.Alternatives > 1 && algorithm != AlgorithmOptimal {
+		return ErrAlternativesNeedOptimal
+	}
+
+	var err error
+	if s.Chunk != "" {
+		err = s.runChunked(ctx, algorithm, timeoutSeconds)
+	} else {
+		err = s.solve(ctx, algorithm, timeoutSeconds)
+	}
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.baseResources = cloneBookings(s.AssignedResources)
+	s.finish()
+	return nil
+}
+
+// solve runs the named algorithm without the passes that follow it
+func (s *Scheduler) solve(ctx context.Context, algorithm string, timeoutSeconds int) error {
+	switch algorithm {
+	case "", AlgorithmGreedy:
+		s.warmStart(true, s.GroupByGroup())
+	case AlgorithmOptimal:
+		s.AssignOptimal(ctx, timeoutSeconds)
+	case AlgorithmBranchAndBound:
+		s.AssignBranchAndBound(ctx, time.Duration(timeoutSeconds)*time.Second)
+	case AlgorithmAnneal:
+		s.AssignAnneal(ctx, time.Duration(timeoutSeconds)*time.Second)
+	default:
+		return ErrUnknownAlgorithm
+	}
+	return nil
+}
+
+// finish runs the passes that follow every algorithm
+func (s *Scheduler) finish() {
+	s.FillIdeal()
+	s.FillMinimums()
+	s.EnforcePairs()
+	s.EnforceLinks()
+	s.CheckRatios()
+	s.CheckEquipment()
+	s.AssignResources()
+}
+
+// bnbSlot is one open (shift, group) position in the branch-and-bound search
+type bnbSlot struct {
+	shift      *models.Shift
+	group      string
+	duration   float64
+	candidates []*models.Volunteer
+}
+
+// branchAndBound holds the search state for AssignBranchAndBound
+type branchAndBound struct {
+	s             *Scheduler
+	slots         []bnbSlot
+	picks         []int // candidate index chosen per slot, -1 when left empty
+	ctx           context.Context
+	nodes         int
+	stopped       bool
+	bestFilled    int
+	bestStandby   int
+	bestHeadcount int
+	bestSq        float64
+	best          assignmentState
+}
+
+// AssignBranchAndBound searches for the assignment that fills the most slots,
+// breaking ties by the fewest standby assignments, then, when minimizing
+// headcount, the fewest volunteers, then the lowest sum of squared volunteer
+// hours (the fairest spread). The greedy solution seeds the search, so if the
+// timeout is hit the result is never worse than greedy, as it is when ctx is
+// cancelled. It reports whether the search finished.
+func (s *Scheduler) AssignBranchAndBound(ctx context.Context, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	original := s.snapshot()
+	volsByGroup := s.GroupByGroup()
+
+	b := &branchAndBound{s: s, ctx: ctx}
+	b.slots = b.collectSlots(volsByGroup)
+	b.picks = make([]int, len(b.slots))
+
+	// Seed the incumbent with the greedy solution, warm started from any hints
+	s.warmStart(false, volsByGroup)
+	b.bestFilled = s.filledSlots()
+	b.bestStandby = s.StandbyAssignments()
+	b.bestHeadcount = b.headcount()
+	b.bestSq = s.squaredHours()
+	b.best = s.snapshot()
+	s.restore(original)
+
+	b.search(0, s.filledSlots(), s.StandbyAssignments(), b.headcount(), s.squaredHours())
+
+	// Restore the incumbent, then let a final greedy pass fill anything the
+	// search left open and record conflicts for the rest
+	s.restore(b.best)
+	s.Conflicts = nil
+	s.AssignSimpleWithGroups(false, volsByGroup)
+	return !b.stopped
+}
+
+// collectSlots lists open slots, most constrained first, keeping identical slots adjacent
+func (b *branchAndBound) collectSlots(volsByGroup map[string][]*models.Volunteer) []bnbSlot {
+	s := b.s
+	var slots []bnbSlot
+	for _, shift := range s.Shifts {
+		duration := s.DurationHours(shift.Start, shift.End)
+		filled := s.FilledByGroup(shift)
+		for group, count := range shift.RequiredGroups {
+			if count-filled[group] <= 0 {
+				continue
+			}
+			// Static filters only; hours, overlaps and rest are checked during search
+			var candidates []*models.Volunteer
+			for _, vol := range volsByGroup[group] {
+				if s.Allows(shift, vol) && s.IsAvailable(vol, shift) {
+					candidates = append(candidates, vol)
+				}
+			}
+			sort.Slice(candidates, func(i, j int) bool {
+				if ti, tj := tierRank(candidates[i]), tierRank(candidates[j]); ti != tj {
+					return ti < tj
+				}
+				if ri, rj := s.RotationCount(candidates[i], shift.Category), s.RotationCount(candidates[j], shift.Category); ri != rj {
+					return ri < rj
+				}
+				if hi, hj := HoursFromTarget(candidates[i]), HoursFromTarget(candidates[j]); hi != hj {
+					return hi < hj
+				}
+				return candidates[i].ID < candidates[j].ID
+			})
+			for i := 0; i < count-filled[group]; i++ {
+				slots = append(slots, bnbSlot{shift: shift, group: group, duration: duration, candidates: candidates})
+			}
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		if len(slots[i].candidates) != len(slots[j].candidates) {
+			return len(slots[i].candidates) < len(slots[j].candidates)
+		}
+		if slots[i].shift.ID != slots[j].shift.ID {
+			return slots[i].shift.ID < slots[j].shift.ID
+		}
+		return slots[i].group < slots[j].group
+	})
+	return slots
+}
+
+// headcount is the number of volunteers working when minimizing headcount,
+// and zero otherwise so it never decides between schedules
+func (b *branchAndBound) headcount() int {
+	if !b.s.minimizeHeadcount() {
+		return 0
+	}
+	return b.s.Headcount()
+}
+
+// sameAsPrevious checks if slot i is interchangeable with slot i-1
+func (b *branchAndBound) sameAsPrevious(i int) bool {
+	return i > 0 && b.slots[i].shift == b.slots[i-1].shift && b.slots[i].group == b.slots[i-1].group
+}
+
+// search explores assignments for slots[i:], pruning branches that cannot beat the incumbent
+func (b *branchAndBound) search(i, filled, standby, heads int, sq float64) {
+	if b.stopped {
+		return
+	}
+	b.nodes++
+	if b.nodes%1024 == 0 && b.ctx.Err() != nil {
+		b.stopped = true
+		return
+	}
+
+	// Standby assignments, headcount and squared hours only grow as
+	// volunteers are added, so they are lower bounds
+	remaining := len(b.slots) - i
+	if filled+remaining < b.bestFilled {
+		return
+	}
+	if filled+remaining == b.bestFilled && cmp.Or(
+		cmp.Compare(standby, b.bestStandby),
+		cmp.Compare(heads, b.bestHeadcount),
+		cmp.Compare(sq, b.bestSq),
+	) >= 0 {
+		return
+	}
+
+	if i == len(b.slots) {
+		b.bestFilled = filled
+		b.bestStandby = standby
+		b.bestHeadcount = heads
+		b.bestSq = sq
+		b.best = b.s.snapshot()
+		return
+	}
+
+	slot := b.slots[i]
+
+	// Break symmetry between identical slots: picks must be strictly increasing,
+	// and once one is left empty the rest are too
+	first := 0
+	if b.sameAsPrevious(i) {
+		if b.picks[i-1] < 0 {
+			b.picks[i] = -1
+			b.search(i+1, filled, standby, heads, sq)
+			return
+		}
+		first = b.picks[i-1] + 1
+	}
+
+	for idx := first; idx < len(slot.candidates); idx++ {
+		vol := slot.candidates[idx]
+		if slices.Contains(slot.shift.Assigned, vol.ID) {
+			continue
+		}
+		if !b.s.CheckEligibility(vol, slot.shift, slot.duration).OK() {
+			continue
+		}
+
+		before := vol.AssignedHours
+		after := before + slot.duration
+		addedStandby, addedHead := 0, 0
+		if vol.Tier == TierStandby {
+			addedStandby = 1
+		}
+		if b.s.minimizeHeadcount() && idle(vol) {
+			addedHead = 1
+		}
+		b.picks[i] = idx
+		b.s.assign(vol, slot.shift, slot.duration)
+		b.search(i+1, filled+1, standby+addedStandby, heads+addedHead, sq-before*before+after*after)
+		b.s.unassign(vol, slot.shift, slot.duration)
+
+		if b.stopped {
+			return
+		}
+	}
+
+	b.picks[i] = -1
+	b.search(i+1, filled, standby, heads, sq)
+}
+
+// unassign reverses the most recent assign of a volunteer to a shift
+func (s *Scheduler) unassign(volunteer *models.Volunteer, shift *models.Shift, duration float64) {
+	shift.Assigned = shift.Assigned[:len(shift.Assigned)-1]
+	volunteer.AssignedShifts = volunteer.AssignedShifts[:len(volunteer.AssignedShifts)-1]
+	volunteer.AssignedHours -= duration
+	s.trackUnassign(volunteer, shift)
+}
+
+// filledSlots counts assigned volunteers across all shifts
+func (s *Scheduler) filledSlots() int {
+	filled := 0
+	for _, sh := range s.Shifts {
+		filled += len(sh.Assigned)
+	}
+	return filled
+}
+
+// squaredHours sums every volunteer's assigned hours squared
+func (s *Scheduler) squaredHours() float64 {
+	sq := 0.0
+	for _, v := range s.Volunteers {
+		sq += v.AssignedHours * v.AssignedHours
+	}
+	return sq
+}
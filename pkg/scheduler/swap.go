@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ErrNotAssigned is returned by Swaps when the volunteer doesn't hold the shift
+var ErrNotAssigned = errors.New("volunteer is not assigned to the shift")
+
+// Swaps finds who could take a shift a volunteer wants to drop without
+// breaking any constraint. Direct candidates take the shift outright, best
+// first. With twoWay, it also lists swaps where the candidate hands one of
+// their own shifts to the volunteer in return. Assignments are left as found.
+func (s *Scheduler) Swaps(shiftID, volunteerID string, twoWay bool) (direct, swaps []models.SwapCandidate, err error) {
+	shift, ok := s.Shifts[shiftID]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownShift, shiftID)
+	}
+	dropper, ok := s.Volunteers[volunteerID]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownVolunteer, volunteerID)
+	}
+	if !slices.Contains(shift.Assigned, volunteerID) {
+		return nil, nil, ErrNotAssigned
+	}
+
+	original := s.snapshot()
+	defer s.restore(original)
+	duration := s.DurationHours(shift.Start, shift.End)
+	s.removeAssignment(dropper, shift, duration)
+	released := s.snapshot()
+
+	type ranked struct {
+		candidate models.SwapCandidate
+		rank      Rank
+	}
+	var best []ranked
+	for _, vol := range s.Volunteers {
+		if vol == dropper || slices.Contains(shift.Assigned, vol.ID) {
+			continue
+		}
+		if e := s.CheckEligibility(vol, shift, duration); s.fillsOpenSlot(vol, shift) && e.OK() {
+			best = append(best, ranked{models.SwapCandidate{VolunteerID: vol.ID, Name: vol.Name}, s.RankCandidate(vol, shift, e)})
+		}
+		if !twoWay {
+			continue
+		}
+		for _, givenID := range slices.Clone(vol.AssignedShifts) {
+			given, ok := s.Shifts[givenID]
+			if !ok || slices.Contains(given.Assigned, dropper.ID) {
+				continue
+			}
+			if s.swapFits(vol, dropper, shift, given) {
+				swaps = append(swaps, models.SwapCandidate{VolunteerID: vol.ID, Name: vol.Name, GivesShiftID: givenID})
+			}
+			s.restore(released)
+		}
+	}
+
+	slices.SortFunc(best, func(a, b ranked) int {
+		if a.rank.Before(b.rank) {
+			return -1
+		}
+		if b.rank.Before(a.rank) {
+			return 1
+		}
+		return strings.Compare(a.candidate.VolunteerID, b.candidate.VolunteerID)
+	})
+	for _, r := range best {
+		direct = append(direct, r.candidate)
+	}
+	slices.SortFunc(swaps, func(a, b models.SwapCandidate) int {
+		return strings.Compare(a.VolunteerID+"\x00"+a.GivesShiftID, b.VolunteerID+"\x00"+b.GivesShiftID)
+	})
+	return direct, swaps, nil
+}
+
+// swapFits trades a shift between two volunteers and reports whether both
+// placements are valid. The trade is left in place for the caller to undo.
+func (s *Scheduler) swapFits(vol, dropper *models.Volunteer, shift, given *models.Shift) bool {
+	s.removeAssignment(vol, given, s.DurationHours(given.Start, given.End))
+
+	duration := s.DurationHours(shift.Start, shift.End)
+	if !s.fillsOpenSlot(vol, shift) || !s.CheckEligibility(vol, shift, duration).OK() {
+		return false
+	}
+	s.assign(vol, shift, duration)
+
+	duration = s.DurationHours(given.Start, given.End)
+	return s.fillsOpenSlot(dropper, given) && s.CheckEligibility(dropper, given, duration).OK()
+}
+
+// fillsOpenSlot reports whether a volunteer belongs to a group the shift still needs
+func (s *Scheduler) fillsOpenSlot(vol *models.Volunteer, shift *models.Shift) bool {
+	filled := s.FilledByGroup(shift)
+	for _, g := range VolunteerGroups(vol) {
+		if filled[g] < shift.RequiredGroups[g] {
+			return true
+		}
+	}
+	return false
+}
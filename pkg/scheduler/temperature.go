@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"errors"
+	"math"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ErrInvalidTemperature is returned for a negative temperature
+var ErrInvalidTemperature = errors.New("temperature must not be negative")
+
+// ValidateTemperature checks a pick temperature
+func ValidateTemperature(temperature float64) error {
+	if temperature < 0 {
+		return ErrInvalidTemperature
+	}
+	return nil
+}
+
+// rankedCandidate is an eligible volunteer and their rank for a slot
+type rankedCandidate struct {
+	volunteer *models.Volunteer
+	rank      Rank
+}
+
+// pickWarm draws a volunteer from those level with best on tier, headcount
+// and rotation, weighting each by exp(-(score gap)/Temperature). A candidate
+// Temperature hours behind the best is picked e times less often, so low
+// temperatures only shuffle near-ties and high ones rotate freely.
+func (s *Scheduler) pickWarm(eligible []rankedCandidate, best Rank) *models.Volunteer {
+	weights := make([]float64, len(eligible))
+	total := 0.0
+	for i, c := range eligible {
+		if c.rank.Tier != best.Tier || c.rank.Idle != best.Idle || c.rank.Rotation != best.Rotation {
+			continue
+		}
+		weights[i] = math.Exp(-(c.rank.Score - best.Score) / s.Temperature)
+		total += weights[i]
+	}
+	r := s.random().Float64() * total
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if r < w {
+			return eligible[i].volunteer
+		}
+		r -= w
+	}
+	// Rounding can leave r just past the last weight
+	for i := len(weights) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return eligible[i].volunteer
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// Volunteer tiers. Higher tiers are drawn on first, and standby volunteers
+// only when nobody else can fill a slot. Volunteers without a tier are regular.
+const (
+	TierCore    = "core"
+	TierRegular = "regular"
+	TierStandby = "standby"
+)
+
+// ErrUnknownTier is returned for a tier other than core, regular or standby
+var ErrUnknownTier = errors.New("tier must be core, regular or standby")
+
+// ValidateTiers checks every volunteer's tier
+func (s *Scheduler) ValidateTiers() error {
+	for id, vol := range s.Volunteers {
+		switch vol.Tier {
+		case "", TierCore, TierRegular, TierStandby:
+		default:
+			return fmt.Errorf("%w: volunteer %s has %q", ErrUnknownTier, id, vol.Tier)
+		}
+	}
+	return nil
+}
+
+// tierRank orders tiers for candidate selection, lowest first
+func tierRank(vol *models.Volunteer) int {
+	switch vol.Tier {
+	case TierCore:
+		return 0
+	case TierStandby:
+		return 2
+	}
+	return 1
+}
+
+// StandbyAssignments counts the assignments held by standby volunteers
+func (s *Scheduler) StandbyAssignments() int {
+	n := 0
+	for _, vol := range s.Volunteers {
+		if vol.Tier == TierStandby {
+			n += len(vol.AssignedShifts)
+		}
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+)
+
+// ErrInvalidTravelMinutes is returned for a negative travel time
+var ErrInvalidTravelMinutes = errors.New("travel_minutes must not be negative")
+
+// ValidateTravelMinutes checks a travel-time matrix
+func ValidateTravelMinutes(travel map[string]map[string]int) error {
+	for _, row := range travel {
+		for _, minutes := range row {
+			if minutes < 0 {
+				return ErrInvalidTravelMinutes
+			}
+		}
+	}
+	return nil
+}
+
+// travelTime is how long it takes to get between two locations. Times are
+// looked up in either direction; unknown pairs and shifts without a
+// location need none.
+func (s *Scheduler) travelTime(from, to string) time.Duration {
+	if from == "" || to == "" || from == to {
+		return 0
+	}
+	minutes, ok := s.TravelMinutes[from][to]
+	if !ok {
+		minutes = s.TravelMinutes[to][from]
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// maxTravel is the longest trip to or from a location
+func (s *Scheduler) maxTravel(location string) time.Duration {
+	if location == "" || len(s.TravelMinutes) == 0 {
+		return 0
+	}
+	if s.travelMax == nil {
+		s.travelMax = make(map[string]time.Duration)
+		for from, row := range s.TravelMinutes {
+			for to, minutes := range row {
+				d := time.Duration(minutes) * time.Minute
+				s.travelMax[from] = max(s.travelMax[from], d)
+				s.travelMax[to] = max(s.travelMax[to], d)
+			}
+		}
+	}
+	return s.travelMax[location]
+}
+
+// overlapsWithTravel checks a volunteer's shifts one by one, counting the
+// time needed to travel between locations as part of each shift
+func (s *Scheduler) overlapsWithTravel(volunteer *models.Volunteer, shift *models.Shift) bool {
+	for _, shiftID := range volunteer.AssignedShifts {
+		sh, ok := s.Shifts[shiftID]
+		if !ok {
+			continue
+		}
+		gap := s.travelTime(sh.Location, shift.Location)
+		if s.Overlap(sh.Start, sh.End.Add(gap), shift.Start, shift.End.Add(gap)) {
+			return true
+		}
+	}
+	return false
+}
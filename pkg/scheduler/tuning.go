@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"cmp"
+	"runtime"
+	"sync/atomic"
+)
+
+// referenceCPUs is the machine size DefaultTimeoutSeconds was chosen for
+const referenceCPUs = 4
+
+// Tuning sizes the search-based algorithms for the machine they run on.
+// Zero fields keep the defaults: one optimal search worker per GOMAXPROCS,
+// and DefaultTimeoutSeconds.
+type Tuning struct {
+	Workers               int `json:"workers"`
+	DefaultTimeoutSeconds int `json:"default_timeout_seconds"`
+}
+
+var tuning atomic.Pointer[Tuning]
+
+// SetTuning sets the tuning for every later run
+func SetTuning(t Tuning) {
+	tuning.Store(&t)
+}
+
+// CurrentTuning returns the tuning in effect, defaults filled in
+func CurrentTuning() Tuning {
+	var t Tuning
+	if p := tuning.Load(); p != nil {
+		t = *p
+	}
+	t.Workers = cmp.Or(t.Workers, runtime.GOMAXPROCS(0))
+	t.DefaultTimeoutSeconds = min(cmp.Or(t.DefaultTimeoutSeconds, DefaultTimeoutSeconds), MaxTimeoutSeconds)
+	return t
+}
+
+// TuneFor suggests a tuning for a machine with the given number of usable
+// CPUs: a worker each, and a default timeout stretched on machines smaller
+// than referenceCPUs so they search about as much, up to twice as long.
+func TuneFor(cpus int) Tuning {
+	cpus = max(cpus, 1)
+	return Tuning{
+		Workers:               cpus,
+		DefaultTimeoutSeconds: min(max(DefaultTimeoutSeconds*referenceCPUs/cpus, DefaultTimeoutSeconds), 2*DefaultTimeoutSeconds),
+	}
+}
@@ -0,0 +1,153 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// PropertySchemer is implemented by structs whose JSON form differs from their
+// fields, e.g. through a custom UnmarshalJSON. The schemas it returns replace
+// the reflected ones for the named properties.
+type PropertySchemer interface {
+	JSONSchemaProperties() map[string]any
+}
+
+// Generate builds a JSON Schema (draft 2020-12) for a Go value by reflecting
+// over its type and json struct tags. Fields tagged omitempty are optional.
+// A schema tag adjusts a field further: schema:"optional" drops it from
+// required, and schema:"readonly" marks it as output only.
+// Recursive types are emitted once and referred to with $ref; the schema's
+// $id is its title, so the refs resolve within it wherever it is embedded.
+func Generate(v any, title string) map[string]any {
+	root := reflect.TypeOf(v)
+	for root.Kind() == reflect.Pointer {
+		root = root.Elem()
+	}
+	g := &generator{
+		root:      root,
+		building:  map[reflect.Type]bool{},
+		recursive: map[reflect.Type]bool{},
+		defs:      map[string]any{},
+	}
+	s := g.forType(root)
+	if len(g.defs) > 0 {
+		s["$defs"] = g.defs
+	}
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = title
+	s["title"] = title
+	return s
+}
+
+// generator holds the state of one Generate call: the structs being built,
+// to catch recursion, and the definitions recursive structs are moved to
+type generator struct {
+	root      reflect.Type
+	building  map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+	defs      map[string]any
+}
+
+// forType returns the schema for a single Go type
+func (g *generator) forType(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.forType(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.forType(t.Elem())}
+	case reflect.Struct:
+		return g.forStructRef(t)
+	}
+	// interface{} and anything else accepts any value
+	return map[string]any{}
+}
+
+// forStructRef returns a struct's schema, or a $ref to it when the struct
+// contains itself: the root is referred to as "#", others through $defs
+func (g *generator) forStructRef(t reflect.Type) map[string]any {
+	if g.building[t] {
+		g.recursive[t] = true
+		return refTo(t, g.root)
+	}
+	g.building[t] = true
+	s := g.forStruct(t)
+	delete(g.building, t)
+	if g.recursive[t] && t != g.root {
+		g.defs[t.Name()] = s
+		return refTo(t, g.root)
+	}
+	return s
+}
+
+func refTo(t, root reflect.Type) map[string]any {
+	if t == root {
+		return map[string]any{"$ref": "#"}
+	}
+	return map[string]any{"$ref": "#/$defs/" + t.Name()}
+}
+
+// forStruct returns an object schema for a struct's exported, json-tagged fields
+func (g *generator) forStruct(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.forType(f.Type)
+		optional := strings.Contains(opts, "omitempty")
+		switch f.Tag.Get("schema") {
+		case "optional":
+			optional = true
+		case "readonly":
+			prop["readOnly"] = true
+			optional = true
+		}
+		properties[name] = prop
+		if !optional {
+			required = append(required, name)
+		}
+	}
+
+	if ps, ok := reflect.Zero(t).Interface().(PropertySchemer); ok {
+		for name, prop := range ps.JSONSchemaProperties() {
+			properties[name] = prop
+		}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
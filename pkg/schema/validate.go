@@ -0,0 +1,153 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Validate checks a decoded JSON value (as produced by json.Unmarshal into
+// an any) against a schema. It understands the keywords Generate emits:
+// type, format, properties, required, additionalProperties, items, oneOf
+// and $ref. The error names the path of the first mismatch.
+func Validate(s map[string]any, v any) error {
+	return validator{root: s}.validate(s, v, "$")
+}
+
+// validator resolves $refs against the root schema
+type validator struct {
+	root map[string]any
+}
+
+func (vd validator) validate(s map[string]any, v any, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := vd.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		s = target
+	}
+
+	if options, ok := s["oneOf"].([]any); ok {
+		matched := 0
+		for _, o := range options {
+			if sub, ok := o.(map[string]any); ok && vd.validate(sub, v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: matches %d of the oneOf schemas, want 1", path, matched)
+		}
+	}
+
+	typ, _ := s["type"].(string)
+	switch typ {
+	case "":
+		return nil
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a string", path)
+		}
+		if s["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: want a date-time", path)
+			}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want a boolean", path)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: want a number", path)
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: want an integer", path)
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want an array", path)
+		}
+		if sub, ok := s["items"].(map[string]any); ok {
+			for i, item := range items {
+				if err := vd.validate(sub, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want an object", path)
+		}
+		return vd.validateObject(s, obj, path)
+	default:
+		return fmt.Errorf("%s: unknown type %q in schema", path, typ)
+	}
+	return nil
+}
+
+func (vd validator) validateObject(s map[string]any, obj map[string]any, path string) error {
+	switch required := s["required"].(type) {
+	case []string:
+		for _, name := range required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+	case []any:
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+	}
+
+	// Walk the keys in order so the reported path is stable
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	properties, _ := s["properties"].(map[string]any)
+	for _, name := range names {
+		sub, ok := properties[name].(map[string]any)
+		if !ok {
+			switch extra := s["additionalProperties"].(type) {
+			case map[string]any:
+				sub = extra
+			case bool:
+				if !extra {
+					return fmt.Errorf("%s: unknown property %q", path, name)
+				}
+			}
+		}
+		if sub == nil {
+			continue
+		}
+		if err := vd.validate(sub, obj[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve looks up "#" or "#/$defs/Name"
+func (vd validator) resolve(ref string) (map[string]any, error) {
+	if ref == "#" {
+		return vd.root, nil
+	}
+	if name, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		defs, _ := vd.root["$defs"].(map[string]any)
+		if s, ok := defs[name].(map[string]any); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unresolved $ref %q", ref)
+}
@@ -0,0 +1,154 @@
+// Package testutil helps test the API's handlers end to end: a Gin engine
+// backed by a fresh in-memory database, credentials for it, and golden-file
+// assertions for schedule responses.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arnavshah/scheduler-api-go/pkg/auth"
+	"github.com/arnavshah/scheduler-api-go/pkg/database"
+	"github.com/arnavshah/scheduler-api-go/pkg/handlers"
+	"github.com/arnavshah/scheduler-api-go/pkg/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// update rewrites golden files with the current output instead of comparing
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// Server is a Gin engine and handler sharing one in-memory database.
+// Register the routes under test on Engine, as the entry points do.
+type Server struct {
+	Engine  *gin.Engine
+	Handler *handlers.Handler
+	DB      *gorm.DB
+}
+
+// NewServer starts a server on a fresh in-memory database, closed when the
+// test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	// Jobs an earlier test left running read the mode, so it's only set once
+	if gin.Mode() != gin.TestMode {
+		gin.SetMode(gin.TestMode)
+	}
+
+	db, err := database.OpenMemory()
+	if err != nil {
+		t.Fatalf("open in-memory database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	h := &handlers.Handler{DB: db}
+	r := gin.New()
+	r.Use(gin.Recovery(), h.TrackWrites())
+	return &Server{Engine: r, Handler: h, DB: db}
+}
+
+// AdminToken mints a JWT for the admin routes
+func (s *Server) AdminToken(t testing.TB) string {
+	t.Helper()
+	token, err := auth.CreateToken("admin")
+	if err != nil {
+		t.Fatalf("create admin token: %v", err)
+	}
+	return token
+}
+
+// APIKey mints a signed API key for the given user and stores its record
+func (s *Server) APIKey(t testing.TB, userID string) *database.APIKey {
+	t.Helper()
+	raw := auth.GenerateHMACKey(userID)
+	key := &database.APIKey{
+		Key:        raw,
+		Name:       userID,
+		KeyPreview: raw[:3] + "..." + raw[len(raw)-4:],
+		RateLimit:  10000,
+	}
+	if err := s.DB.Create(key).Error; err != nil {
+		t.Fatalf("create API key: %v", err)
+	}
+	return key
+}
+
+// Do sends a request to the engine with the token as its bearer
+// credential. A body that isn't nil is sent as JSON.
+func (s *Server) Do(t testing.TB, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.Engine.ServeHTTP(w, req)
+	return w
+}
+
+// DecodeSchedule reads a ScheduleResponse from a successful response
+func DecodeSchedule(t testing.TB, w *httptest.ResponseRecorder) models.ScheduleResponse {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.ScheduleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode schedule response: %v", err)
+	}
+	return resp
+}
+
+// AssertGolden compares a schedule response with testdata/<name>.golden.json
+// in the test's package, ignoring the schedule ID, which changes every run.
+// Run the tests with -update to write the golden file.
+func AssertGolden(t testing.TB, name string, resp models.ScheduleResponse) {
+	t.Helper()
+	resp.ScheduleID = ""
+	got, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		t.Fatalf("encode schedule response: %v", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("response differs from %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}